@@ -9,7 +9,5 @@ import (
 var version = "dev"
 
 func main() {
-	if err := cli.Execute(version); err != nil {
-		os.Exit(1)
-	}
+	os.Exit(cli.Execute(version))
 }
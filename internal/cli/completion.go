@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/druarnfield/shhh/internal/config"
+	"github.com/druarnfield/shhh/internal/logging"
+	"github.com/druarnfield/shhh/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func newCompletionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "completion [bash|zsh|fish|powershell]",
+		Short: "Generate a shell completion script",
+		Long: "Print a completion script for the given shell. Module names complete for 'setup', " +
+			"'explain' and --select.\n\n" +
+			"PowerShell: add this line to your $PROFILE\n" +
+			"  shhh completion powershell | Out-String | Invoke-Expression\n\n" +
+			"bash:  source <(shhh completion bash)\n" +
+			"zsh:   source <(shhh completion zsh)\n" +
+			"fish:  shhh completion fish | source",
+		ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := cmd.Root()
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletionV2(os.Stdout, true)
+			case "zsh":
+				return root.GenZshCompletion(os.Stdout)
+			case "fish":
+				return root.GenFishCompletion(os.Stdout, true)
+			case "powershell":
+				return root.GenPowerShellCompletionWithDesc(os.Stdout)
+			}
+			return fmt.Errorf("unsupported shell %q", args[0])
+		},
+	}
+}
+
+// completeModules completes module IDs, with their descriptions, leaving
+// out any already given. The registry is built from the current config
+// (or the defaults, if it doesn't load) so the list matches what setup
+// would offer; nothing is run.
+func completeModules(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	cfg, _, _, err := loadConfig()
+	if err != nil {
+		cfg = config.Defaults()
+	}
+	deps := newDependencies(cfg, &state.State{}, slog.New(logging.NopHandler{}))
+	deps.Preview = false
+
+	var completions []string
+	for _, m := range newRegistry(deps).All() {
+		if !slices.Contains(args, m.ID) {
+			completions = append(completions, m.ID+"\t"+m.Description)
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeSelect completes the comma-separated --select value: the modules
+// already listed become the prefix of each completion and are not offered
+// again.
+func completeSelect(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	prefix := ""
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix = toComplete[:i+1]
+	}
+	completions, directive := completeModules(cmd, strings.Split(prefix, ","), "")
+	for i, c := range completions {
+		completions[i] = prefix + c
+	}
+	return completions, directive | cobra.ShellCompDirectiveNoSpace
+}
@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/druarnfield/shhh/internal/config"
+	"github.com/druarnfield/shhh/internal/module/setup"
+	toml "github.com/pelletier/go-toml/v2"
+	"github.com/spf13/cobra"
+)
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Work with shhh.toml config files",
+	}
+	cmd.AddCommand(newConfigValidateCmd())
+	cmd.AddCommand(newConfigSchemaCmd())
+	return cmd
+}
+
+func newConfigValidateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "validate [path]",
+		Aliases: []string{"check"},
+		Short:   "Validate a config file without running setup",
+		Long: "Load a shhh.toml and report every problem found, with the line it is on, before the " +
+			"config is shared: proxy, registry and cert source URLs must be absolute http(s) URLs, " +
+			"version settings must look like versions, certs.extra (and certs.import_existing) files " +
+			"must exist and hold PEM certificates, and module and tool lists must name known modules " +
+			"and allowed tools. Nothing on the system is changed. Without a path, checks the config " +
+			"'shhh setup' would use. Exits non-zero if the config is invalid, so it can run in CI.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: runConfigValidate,
+	}
+}
+
+func newConfigSchemaCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema",
+		Short: "Print a JSON Schema for shhh.toml",
+		Long: "Print a JSON Schema describing shhh.toml (sections, field types, allowed values and " +
+			"defaults), for editors that validate and complete TOML against a schema, e.g. " +
+			"'shhh config schema > shhh.schema.json'.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := config.Schema()
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		},
+	}
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	var path string
+	if len(args) == 1 {
+		path = args[0]
+	} else {
+		found, ok := config.FindConfig()
+		if !ok {
+			found = config.ConfigFilePath()
+		}
+		path = found
+	}
+
+	// Problems are printed here in full; cobra's one-line "Error:" and the
+	// usage text would only repeat them.
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	cfg, err := config.LoadFromFile(path)
+	if err != nil {
+		fmt.Println(wrapLine("", fmt.Sprintf("%s: %v", path, err), 2))
+		var decodeErr *toml.DecodeError
+		if errors.As(err, &decodeErr) {
+			fmt.Println(decodeErr.String())
+		}
+		return err
+	}
+	data, _ := os.ReadFile(path)
+	// Unknown keys are warnings, not problems: they may be settings a
+	// newer shhh understands.
+	for _, w := range cfg.Warnings {
+		fmt.Println(wrapLine("", fmt.Sprintf("%s: warning: %s", path, w), 2))
+	}
+
+	err = errors.Join(cfg.Lint(), cfg.Modules.Check(setup.ModuleIDs, setup.ModuleDependencies))
+	if err == nil {
+		fmt.Printf("%s: OK\n", path)
+		return nil
+	}
+
+	problems := flattenErrors(err)
+	noun := "problems"
+	if len(problems) == 1 {
+		noun = "problem"
+	}
+	fmt.Printf("%s: %d %s\n", path, len(problems), noun)
+	for _, p := range problems {
+		msg := p.Error()
+		if key, _, ok := strings.Cut(msg, ":"); ok {
+			if line := keyLine(string(data), key); line > 0 {
+				msg = fmt.Sprintf("line %d: %s", line, msg)
+			}
+		}
+		fmt.Println(wrapLine("  - ", msg, 4))
+	}
+	return errors.New("invalid config")
+}
+
+// tomlSection matches a [section] header.
+var tomlSection = regexp.MustCompile(`^\s*\[\s*([A-Za-z0-9_.-]+)\s*\]`)
+
+// keyLine returns the 1-based line of the dotted key (e.g. "proxy.http") in
+// the TOML text data, or 0 if key isn't a section.name key set there.
+func keyLine(data, key string) int {
+	section, name, ok := strings.Cut(key, ".")
+	if !ok || strings.ContainsAny(name, " .") {
+		return 0
+	}
+	assign := regexp.MustCompile(`^\s*` + regexp.QuoteMeta(name) + `\s*=`)
+	current := ""
+	for i, line := range strings.Split(data, "\n") {
+		if m := tomlSection.FindStringSubmatch(line); m != nil {
+			current = m[1]
+			continue
+		}
+		if current == section && assign.MatchString(line) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// flattenErrors lists the errors joined into err, however deeply, or just
+// err if it isn't a join.
+func flattenErrors(err error) []error {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+	var errs []error
+	for _, e := range joined.Unwrap() {
+		errs = append(errs, flattenErrors(e)...)
+	}
+	return errs
+}
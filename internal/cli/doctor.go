@@ -0,0 +1,232 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/druarnfield/shhh/internal/config"
+	"github.com/druarnfield/shhh/internal/logging"
+	"github.com/druarnfield/shhh/internal/module/setup"
+	"github.com/druarnfield/shhh/internal/platform"
+	"github.com/druarnfield/shhh/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var flagDoctorFix bool
+
+func newDoctorCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check that your machine is set up the way your config says",
+		Long: "Diagnose a setup without re-running it. Runs setup's pre-flight checks (free space where " +
+			"tooling installs, a usable [paths] root, and a working PowerShell), then the check of every step in each module " +
+			"you have set up, reporting each as satisfied, not configured, or check errored. Then checks " +
+			"the CA bundle is a readable PEM file and that SSL_CERT_FILE, REQUESTS_CA_BUNDLE, PIP_CERT, " +
+			"NODE_EXTRA_CA_CERTS, CARGO_HTTP_CAINFO and git's http.sslCAInfo point at it (for the modules that set them). " +
+			"Finally compares each environment variable shhh manages with the value the current config " +
+			"would set (e.g. an old PyPI mirror after a config change), and lists any file shhh wrote " +
+			"that has since been changed or deleted, and any directory shhh added to PATH that no " +
+			"longer exists (e.g. the shim directory of a Scoop package removed by hand).\n\n" +
+			"Each failure comes with a hint on fixing it. Exits non-zero if anything fails, so it can " +
+			"be used in CI. --fix takes the missing directories off PATH. --max-concurrency sets how many " +
+			"step checks run at once.",
+		Args: cobra.NoArgs,
+		RunE: runDoctor,
+	}
+	cmd.Flags().BoolVar(&flagDoctorFix, "fix", false, "Remove PATH entries shhh added whose directory no longer exists")
+	addMaxConcurrencyFlag(cmd)
+	return cmd
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	if err := checkMaxConcurrency(); err != nil {
+		return err
+	}
+	cfg, _, _, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	st, err := state.Load(config.StateFilePath())
+	if err != nil {
+		return fmt.Errorf("loading state: %w", err)
+	}
+	cfg = withStateRoot(cfg, st)
+
+	env := platform.NewUserEnv()
+	drift, err := setup.AuditEnv(env, cfg, st.ManagedEnvVars)
+	if err != nil {
+		return fmt.Errorf("auditing environment: %w", err)
+	}
+	files := fileDrift(st.ManagedFiles)
+	stale, err := setup.StalePathEntries(env, st.ManagedPathEntries)
+	if err != nil && !errors.Is(err, platform.ErrNotSupported) {
+		return fmt.Errorf("checking PATH: %w", err)
+	}
+
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	deps := newDependencies(cfg, st, slog.New(logging.NopHandler{}))
+	deps.Preview = false
+	var failed []string
+
+	checks, preflightErr := setup.Preflight(ctx, deps, runtime.GOOS)
+	fmt.Println("Pre-flight:")
+	fmt.Print(renderTable(preflightRows(checks)))
+	fmt.Println()
+	if preflightErr != nil {
+		failed = append(failed, "pre-flight checks")
+	}
+
+	reg := newRegistry(deps)
+	var installed []string
+	for _, m := range reg.All() {
+		if slices.Contains(st.InstalledModules, m.ID) {
+			installed = append(installed, m.ID)
+		}
+	}
+	if len(installed) == 0 {
+		fmt.Println("No modules set up yet; run 'shhh setup'.")
+	} else {
+		steps, err := setup.CheckSteps(ctx, reg, installed, flagMaxConcurrency)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Steps:")
+		fmt.Print(renderTable(stepCheckRows(steps)))
+		for _, c := range steps {
+			if c.Status != setup.StepSatisfied {
+				failed = append(failed, "setup steps")
+				break
+			}
+		}
+
+		ca := setup.CAChecks(ctx, deps, st.InstalledModules)
+		if len(ca) > 0 {
+			fmt.Println("\nCA bundle:")
+			fmt.Print(renderTable(preflightRows(ca)))
+			if slices.ContainsFunc(ca, func(c setup.PreflightCheck) bool { return c.Err != nil }) {
+				failed = append(failed, "CA bundle checks")
+			}
+		}
+	}
+	fmt.Println()
+
+	if len(drift) == 0 && len(files) == 0 {
+		fmt.Println("Managed environment variables and files match your config.")
+	}
+	if len(drift) > 0 {
+		fmt.Println("Managed environment variables out of date:")
+		rows := make([][]string, len(drift))
+		for i, d := range drift {
+			current := fmt.Sprintf("%q", d.Current)
+			if d.Current == "" {
+				current = "(not set)"
+			}
+			want := fmt.Sprintf("config wants %q", d.Want)
+			if d.Want == "" {
+				want = "config no longer sets it"
+			}
+			rows[i] = []string{d.Key, current, want}
+		}
+		fmt.Print(renderTable(rows))
+	}
+	if len(files) > 0 {
+		fmt.Println("Managed files changed since shhh wrote them:")
+		fmt.Print(renderTable(files))
+	}
+	if len(drift) > 0 || len(files) > 0 {
+		fmt.Println("\nRun 'shhh setup' to bring them up to date.")
+		failed = append(failed, "managed environment")
+	}
+
+	if len(stale) > 0 {
+		fmt.Println("\nPATH entries shhh added whose directory no longer exists:")
+		rows := make([][]string, len(stale))
+		for i, dir := range stale {
+			rows[i] = []string{dir, "missing"}
+		}
+		fmt.Print(renderTable(rows))
+		if flagDoctorFix {
+			err := setup.RemoveStalePathEntries(env, st, stale)
+			if saveErr := state.Save(config.StateFilePath(), st); saveErr != nil {
+				err = errors.Join(err, fmt.Errorf("saving state: %w", saveErr))
+			}
+			if err != nil {
+				fmt.Printf("\nCouldn't remove them: %v\n", err)
+				failed = append(failed, "stale PATH entries")
+			} else {
+				fmt.Println("\nRemoved them from PATH. Restart your shell to pick up the change.")
+			}
+		} else {
+			fmt.Println("\nRun 'shhh doctor --fix' to remove them from PATH.")
+			failed = append(failed, "stale PATH entries")
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("doctor found problems: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// stepCheckRows returns a "module / step, status, hint" row for each step
+// check; a step that isn't satisfied gets the setup command that runs it.
+func stepCheckRows(checks []setup.StepCheck) [][]string {
+	rows := make([][]string, len(checks))
+	for i, c := range checks {
+		hint := ""
+		switch c.Status {
+		case setup.StepNotConfigured:
+			hint = "run: shhh setup " + c.Module.ID
+		case setup.StepErrored:
+			hint = fmt.Sprintf("%v; try: shhh run-step %s %s", c.Err, c.Module.ID, c.Step.ID())
+		}
+		rows[i] = []string{c.Module.ID + " / " + c.Step.Name, c.Status.String(), hint}
+	}
+	return rows
+}
+
+// preflightRows returns a "name, ok/FAILED, detail" row for each check. A
+// failure's detail is its error, followed by its Fix hint if it has one.
+func preflightRows(checks []setup.PreflightCheck) [][]string {
+	rows := make([][]string, len(checks))
+	for i, c := range checks {
+		rows[i] = []string{c.Name, "ok", c.Detail}
+		if c.Err != nil {
+			detail := c.Err.Error()
+			if c.Fix != "" {
+				detail += "; " + c.Fix
+			}
+			rows[i] = []string{c.Name, "FAILED", detail}
+		}
+	}
+	return rows
+}
+
+// fileDrift returns a "path, what changed" row for each managed file that no
+// longer holds what shhh wrote.
+func fileDrift(files []state.ManagedFile) [][]string {
+	var rows [][]string
+	for _, f := range files {
+		status, err := f.Status()
+		switch {
+		case err != nil:
+			rows = append(rows, []string{f.Path, fmt.Sprintf("can't be read: %v", err)})
+		case status == state.FileModified:
+			rows = append(rows, []string{f.Path, "modified"})
+		case status == state.FileMissing:
+			rows = append(rows, []string{f.Path, "missing"})
+		}
+	}
+	return rows
+}
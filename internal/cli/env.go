@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/druarnfield/shhh/internal/config"
+	"github.com/druarnfield/shhh/internal/module/setup"
+	"github.com/druarnfield/shhh/internal/platform"
+	"github.com/druarnfield/shhh/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagEnvFormat      string
+	flagEnvShowSecrets bool
+)
+
+func newEnvCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "env",
+		Short: "Work with the environment shhh manages",
+	}
+	cmd.AddCommand(newEnvExportCmd())
+	return cmd
+}
+
+func newEnvExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Print the managed environment variables as a .env file or PowerShell script",
+		Long: "Print every environment variable shhh manages (as recorded in the state file) with its " +
+			"current persistent value, so another process or a CI container can load the same " +
+			"environment, e.g. 'shhh env export > shhh.env' or " +
+			"'shhh env export --format ps1 > shhh-env.ps1'. Variables that aren't set are left out.\n\n" +
+			"Values that look secret are redacted: variables named like a token, password or secret, " +
+			"and passwords in URLs such as an authenticating proxy. --show-secrets prints them as they are.",
+		Args: cobra.NoArgs,
+		RunE: runEnvExport,
+	}
+	cmd.Flags().StringVar(&flagEnvFormat, "format", "dotenv", "Output format: dotenv or ps1")
+	cmd.Flags().BoolVar(&flagEnvShowSecrets, "show-secrets", false, "Print secret-looking values instead of redacting them")
+	_ = cmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{"dotenv", "ps1"}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+func runEnvExport(cmd *cobra.Command, args []string) error {
+	var format func([]setup.ExportedVar) string
+	switch flagEnvFormat {
+	case "dotenv":
+		format = setup.FormatDotenv
+	case "ps1":
+		format = setup.FormatPS1
+	default:
+		return fmt.Errorf("--format: %q is not \"dotenv\" or \"ps1\"", flagEnvFormat)
+	}
+
+	st, err := state.Load(config.StateFilePath())
+	if err != nil {
+		return fmt.Errorf("loading state: %w", err)
+	}
+	vars := setup.ExportEnv(platform.NewUserEnv(), st, !flagEnvShowSecrets)
+	for _, v := range vars {
+		if v.Redacted {
+			fmt.Fprintf(os.Stderr, "Redacted %s (--show-secrets prints it).\n", v.Key)
+		}
+	}
+	fmt.Print(format(vars))
+	return nil
+}
@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/druarnfield/shhh/internal/config"
+	"github.com/druarnfield/shhh/internal/logging"
+	"github.com/druarnfield/shhh/internal/module"
+	"github.com/druarnfield/shhh/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func newExplainCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "explain [module...]",
+		Short: "Describe what a module would do with the current config",
+		Long: "Print each step a module contains for the loaded config, whether it is already done, why it " +
+			"matters, and what it would do. Nothing is changed. Modules are config-dependent, so the output reflects your shhh.toml.\n\n" +
+			"With no modules, every module is explained in the order setup would run them, so the whole " +
+			"setup can be read up front. While the current state is checked, a \"Checking current state… (n/N)\" " +
+			"line is shown on stderr.",
+		Args:              cobra.ArbitraryArgs,
+		ValidArgsFunction: completeModules,
+		RunE:              runExplain,
+	}
+	addMaxConcurrencyFlag(cmd)
+	return cmd
+}
+
+func runExplain(cmd *cobra.Command, args []string) error {
+	if err := checkMaxConcurrency(); err != nil {
+		return err
+	}
+	cfg, _, _, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	// State is read so DryRun text matches a real run, but never saved.
+	st, err := state.Load(config.StateFilePath())
+	if err != nil {
+		st = &state.State{}
+	}
+
+	// Explaining never writes files, even with --dry-run.
+	deps := newDependencies(cfg, st, slog.New(logging.NopHandler{}))
+	deps.Preview = false
+	reg := newRegistry(deps)
+	if err := reg.Validate(); err != nil {
+		return fmt.Errorf("invalid module definition: %w", err)
+	}
+
+	ids := args
+	if len(ids) == 0 {
+		ids, err = reg.ResolveDeps(moduleIDs(reg))
+		if err != nil {
+			return fmt.Errorf("resolving dependencies: %w", err)
+		}
+	}
+
+	return explainModules(cmd.Context(), os.Stdout, reg, ids)
+}
+
+// flagMaxConcurrency is how many steps' checks explain and setup
+// --explain-all evaluate at once (--max-concurrency).
+var flagMaxConcurrency int
+
+// defaultMaxConcurrency is the --max-concurrency default: setup's checks
+// mostly wait on scoop, go and PowerShell, so a few at a time cuts the wait,
+// but more than the CPUs, or than 4, only crowds the machine.
+var defaultMaxConcurrency = min(runtime.NumCPU(), 4)
+
+// addMaxConcurrencyFlag adds --max-concurrency to cmd.
+func addMaxConcurrencyFlag(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&flagMaxConcurrency, "max-concurrency", defaultMaxConcurrency,
+		"How many steps' checks to run at once while working out what would change (1 runs them one at a time)")
+}
+
+// checkMaxConcurrency rejects a --max-concurrency below 1.
+func checkMaxConcurrency() error {
+	if flagMaxConcurrency < 1 {
+		return fmt.Errorf("--max-concurrency must be at least 1, got %d", flagMaxConcurrency)
+	}
+	return nil
+}
+
+// planStatus returns the options explain builds plans with, running
+// --max-concurrency checks at once, with a Progress that keeps a "Checking
+// current state… (12/40)" line on stderr while a plan is built, and a func
+// that clears the line once it is. The line is only shown when stderr is a
+// terminal and --quiet is off.
+//
+// Only explain builds a checked plan up front, so this is the only place
+// planning progress appears. setup and the wizard check each step as they
+// reach it, and show that on their progress output instead.
+func planStatus() (opts module.PlanOptions, done func()) {
+	opts = module.PlanOptions{Concurrency: flagMaxConcurrency}
+	if flagQuiet || !fileIsTerminal(os.Stderr) {
+		return opts, func() {}
+	}
+	width := 0
+	opts.Progress = func(checked, total int) {
+		line := fmt.Sprintf("Checking current state… (%d/%d)", checked, total)
+		width = max(width, utf8.RuneCountInString(line))
+		fmt.Fprintf(os.Stderr, "\r%-*s", width, line)
+	}
+	return opts, func() {
+		if width > 0 {
+			fmt.Fprintf(os.Stderr, "\r%s\r", strings.Repeat(" ", width))
+		}
+	}
+}
+
+// explainModules writes explainModule output for each module in ids, in
+// order, separated by blank lines.
+func explainModules(ctx context.Context, w io.Writer, reg *module.Registry, ids []string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var mods []*module.Module
+	for _, id := range ids {
+		m := reg.Get(id)
+		if m == nil {
+			return fmt.Errorf("unknown module %q (available: %s)", id, strings.Join(moduleIDs(reg), ", "))
+		}
+		mods = append(mods, m)
+	}
+
+	opts, done := planStatus()
+	plan := module.PlanModules(ctx, mods, opts)
+	done()
+	for i, mp := range plan.Modules {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		explainModule(w, mp)
+	}
+	return nil
+}
+
+// explainModule writes a plain-text description of a planned module and
+// its steps to w.
+func explainModule(w io.Writer, mp module.ModulePlan) {
+	m := mp.Module
+	fmt.Fprintf(w, "%s (%s) — %s\n", m.Name, m.ID, m.Description)
+	if len(m.Dependencies) > 0 {
+		fmt.Fprintf(w, "Depends on: %s\n", strings.Join(m.Dependencies, ", "))
+	}
+	if len(mp.Steps) == 0 {
+		fmt.Fprintln(w, "\nNo steps for the current config.")
+		return
+	}
+
+	for i, sp := range mp.Steps {
+		fmt.Fprintf(w, "\n%d. %s (%s)\n", i+1, sp.Step.Name, sp.Reason)
+		if sp.Step.Description != "" {
+			fmt.Fprintf(w, "   %s\n", sp.Step.Description)
+		}
+		if sp.Step.Explain != "" {
+			fmt.Fprintf(w, "   Why:   %s\n", sp.Step.Explain)
+		}
+		if sp.Step.DryRun != nil {
+			fmt.Fprintf(w, "   Would: %s\n", sp.DryRun)
+		}
+	}
+}
+
+// moduleIDs returns the IDs of every registered module.
+func moduleIDs(reg *module.Registry) []string {
+	var ids []string
+	for _, m := range reg.All() {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
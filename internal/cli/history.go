@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/druarnfield/shhh/internal/config"
+	"github.com/druarnfield/shhh/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func newHistoryCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "history",
+		Short: "List recent setup runs and how each module fared",
+		Long: fmt.Sprintf("Show the last %d setup runs, newest first, with each module's outcome. "+
+			"Useful for answering questions like \"when did node setup last succeed?\".", state.MaxHistory),
+		Args: cobra.NoArgs,
+		RunE: runHistory,
+	}
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	st, err := state.Load(config.StateFilePath())
+	if err != nil {
+		return fmt.Errorf("loading state: %w", err)
+	}
+	if len(st.History) == 0 {
+		fmt.Println("No runs recorded yet.")
+		return nil
+	}
+
+	for i := len(st.History) - 1; i >= 0; i-- {
+		run := st.History[i]
+		selected := "all modules"
+		if len(run.Selected) > 0 {
+			selected = strings.Join(run.Selected, ", ")
+		}
+		header := fmt.Sprintf("%s  %s", run.Time.Local().Format("2006-01-02 15:04"), selected)
+		if run.DryRun {
+			header += " (dry run)"
+		}
+		fmt.Println(header)
+
+		rows := make([][]string, len(run.Modules))
+		for j, o := range run.Modules {
+			if o.Succeeded() {
+				rows[j] = []string{o.ID + ":", "done", fmt.Sprintf("(%d completed, %d skipped)", o.Completed, o.Skipped)}
+				continue
+			}
+			rows[j] = []string{o.ID + ":", fmt.Sprintf("FAILED at %q", o.FailedStep), o.Error}
+		}
+		fmt.Print(renderTable(rows))
+	}
+	return nil
+}
@@ -1,16 +1,39 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/spf13/cobra"
 )
 
+// Exit codes returned by Execute.
+const (
+	ExitOK        = 0
+	ExitFailure   = 1
+	ExitUnchanged = 3 // setup --detailed-exit-code: succeeded, nothing changed
+	ExitAborted   = 4 // setup: the changes it was about to make were declined
+)
+
+// errUnchanged is returned by setup --detailed-exit-code when it succeeded
+// without changing anything; Execute turns it into ExitUnchanged.
+var errUnchanged = errors.New("nothing changed")
+
+// errAborted is returned by setup when the user declines the changes it
+// previewed; Execute turns it into ExitAborted.
+var errAborted = errors.New("aborted")
+
 var (
 	flagExplain bool
 	flagQuiet   bool
 	flagDryRun  bool
 	flagVerbose bool
+	flagTrace   bool
+	flagASCII   bool
+	flagIcons   string
+	flagCompact bool
+	flagNoAlt   bool
+	flagSystem  bool
 )
 
 func newRootCmd(version string) *cobra.Command {
@@ -24,12 +47,31 @@ func newRootCmd(version string) *cobra.Command {
 	}
 
 	cmd.PersistentFlags().BoolVar(&flagExplain, "explain", false, "Show explanations for each step")
-	cmd.PersistentFlags().BoolVar(&flagQuiet, "quiet", false, "Suppress explanations, show progress only")
+	cmd.PersistentFlags().BoolVar(&flagQuiet, "quiet", false, "Print nothing unless setup fails (the exit code reports the result)")
 	cmd.PersistentFlags().BoolVar(&flagDryRun, "dry-run", false, "Show what would happen without doing it")
 	cmd.PersistentFlags().BoolVar(&flagVerbose, "verbose", false, "Show detailed log output")
+	cmd.PersistentFlags().BoolVar(&flagTrace, "trace", false, "Log every command run and every value a check compares (add --verbose to also print to stderr)")
+	cmd.PersistentFlags().BoolVar(&flagASCII, "ascii", false, "Use plain ASCII status icons (same as --icons ascii)")
+	cmd.PersistentFlags().StringVar(&flagIcons, "icons", "auto", "Status icon set: auto, unicode, ascii, or nerd")
+	cmd.PersistentFlags().BoolVar(&flagCompact, "compact", false, "Show a one-line progress view in the wizard (toggle with c)")
+	cmd.PersistentFlags().BoolVar(&flagSystem, "system", false, "Write proxy variables and SSL_CERT_FILE machine-wide instead of per user (needs an elevated shell; same as [proxy] scope = \"system\")")
+	cmd.PersistentFlags().BoolVar(&flagNoAlt, "no-altscreen", false, "Draw the wizard inline instead of on the alternate screen (also SHHH_NO_ALTSCREEN=1)")
 
 	cmd.AddCommand(newVersionCmd(version))
 	cmd.AddCommand(newSetupCmd())
+	cmd.AddCommand(newExplainCmd())
+	cmd.AddCommand(newStateCmd())
+	cmd.AddCommand(newUninstallCmd())
+	cmd.AddCommand(newEnvCmd())
+	cmd.AddCommand(newHistoryCmd())
+	cmd.AddCommand(newConfigCmd())
+	cmd.AddCommand(newDoctorCmd())
+	cmd.AddCommand(newRunStepCmd())
+
+	// Replace cobra's default completion command with one that documents
+	// PowerShell setup; module names complete via completeModules.
+	cmd.CompletionOptions.DisableDefaultCmd = true
+	cmd.AddCommand(newCompletionCmd())
 
 	return cmd
 }
@@ -44,6 +86,17 @@ func newVersionCmd(version string) *cobra.Command {
 	}
 }
 
-func Execute(version string) error {
-	return newRootCmd(version).Execute()
+// Execute runs the shhh command line and returns the process exit code.
+func Execute(version string) int {
+	err := newRootCmd(version).Execute()
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, errUnchanged):
+		return ExitUnchanged
+	case errors.Is(err, errAborted):
+		return ExitAborted
+	default:
+		return ExitFailure
+	}
 }
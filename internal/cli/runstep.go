@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/druarnfield/shhh/internal/config"
+	"github.com/druarnfield/shhh/internal/logging"
+	"github.com/druarnfield/shhh/internal/module"
+	"github.com/druarnfield/shhh/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var flagRunStepForce bool
+
+func newRunStepCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run-step <module> <step>",
+		Short: "Run a single setup step, for debugging",
+		Long: "Build a module against the loaded config and run just one of its steps, printing the " +
+			"result of its check and then of the step itself. Dependencies and the module's other " +
+			"steps are not run. A step is named by its ID, as listed when the step isn't found " +
+			"(e.g. 'shhh run-step golang set-gopath'), or by its name in quotes.\n\n" +
+			"A step whose check passes is not run unless --force is given. With --dry-run the step " +
+			"only describes what it would do.",
+		Hidden:            true,
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeStep,
+		RunE:              runStep,
+	}
+	cmd.Flags().BoolVar(&flagRunStepForce, "force", false, "Run the step even if its check passes")
+	return cmd
+}
+
+func runStep(cmd *cobra.Command, args []string) error {
+	// A failing step is the expected outcome here, not a usage mistake.
+	cmd.SilenceUsage = true
+
+	cfg, _, _, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	logger, err := logging.Setup(config.LogFilePath(), flagVerbose)
+	if err != nil {
+		logger = slog.New(logging.NopHandler{})
+	}
+	st, err := state.Load(config.StateFilePath())
+	if err != nil {
+		st = &state.State{}
+	}
+
+	deps := newDependencies(cfg, st, logger)
+	reg := newRegistry(deps)
+	mod := reg.Get(args[0])
+	if mod == nil {
+		return fmt.Errorf("unknown module %q (available: %s)", args[0], strings.Join(moduleIDs(reg), ", "))
+	}
+	if err := mod.Validate(); err != nil {
+		return fmt.Errorf("invalid module definition: %w", err)
+	}
+	step := mod.Step(args[1])
+	if step == nil {
+		return fmt.Errorf("module %q has no step %q (steps: %s)", mod.ID, args[1], strings.Join(stepIDs(mod), ", "))
+	}
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	fmt.Printf("Step:  %s (%s/%s)\n", step.Name, mod.ID, step.ID())
+	satisfied := step.Check != nil && step.Check(ctx)
+	switch {
+	case step.Check == nil:
+		fmt.Println("Check: none (always runs)")
+	case satisfied:
+		fmt.Println("Check: satisfied")
+	default:
+		fmt.Println("Check: not satisfied")
+	}
+	if satisfied && !flagRunStepForce {
+		fmt.Println("Run:   skipped (use --force to run it anyway)")
+		return nil
+	}
+
+	if flagDryRun {
+		desc := "(no description)"
+		if step.DryRun != nil {
+			desc = step.DryRun(ctx)
+		}
+		fmt.Println(wrapLine("Run:   ", desc, 7))
+		return nil
+	}
+
+	// Run through the runner, as setup does, so retries and logging match;
+	// the check has already been reported.
+	one := *step
+	one.Check = nil
+	runner := module.NewRunner(logger, false)
+	runner.SetDefaultTimeout(defaultStepTimeout)
+	start := time.Now()
+	result := runner.RunModule(ctx, &module.Module{ID: mod.ID, Name: mod.Name, Steps: []module.Step{one}})
+	if result.Err != nil {
+		fmt.Println(wrapLine("Run:   ", "failed: "+result.Err.Error(), 7))
+	} else {
+		fmt.Printf("Run:   ok (%s)\n", time.Since(start).Round(time.Millisecond))
+	}
+	if note := result.AttemptNote(step.Name); note != "" {
+		fmt.Println(wrapLine("       ", note, 7))
+	}
+
+	// Steps record what they changed (managed files, env vars) in state,
+	// even when they fail partway.
+	if err := state.Save(config.StateFilePath(), st); err != nil {
+		logger.Error("failed to save state", "error", err)
+	}
+	return result.Err
+}
+
+// stepIDs returns the ID of every step in m.
+func stepIDs(m *module.Module) []string {
+	ids := make([]string, len(m.Steps))
+	for i, s := range m.Steps {
+		ids[i] = s.ID()
+	}
+	return ids
+}
+
+// completeStep completes run-step's arguments: a module, then one of its
+// step IDs.
+func completeStep(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch len(args) {
+	case 0:
+		return completeModules(cmd, args, toComplete)
+	case 1:
+		cfg, _, _, err := loadConfig()
+		if err != nil {
+			cfg = config.Defaults()
+		}
+		deps := newDependencies(cfg, &state.State{}, slog.New(logging.NopHandler{}))
+		deps.Preview = false
+		mod := newRegistry(deps).Get(args[0])
+		if mod == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var completions []string
+		for _, s := range mod.Steps {
+			completions = append(completions, s.ID()+"\t"+s.Name)
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
@@ -1,11 +1,16 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -15,40 +20,169 @@ import (
 	"github.com/druarnfield/shhh/internal/module"
 	"github.com/druarnfield/shhh/internal/module/setup"
 	"github.com/druarnfield/shhh/internal/platform"
+	"github.com/druarnfield/shhh/internal/report"
 	"github.com/druarnfield/shhh/internal/state"
+	"github.com/druarnfield/shhh/internal/tui/components"
 	"github.com/druarnfield/shhh/internal/tui/wizard"
 	"github.com/spf13/cobra"
 )
 
+var (
+	flagExplainAll   bool
+	flagSelectCerts  bool
+	flagSelect       []string
+	flagAutoConfirm  bool
+	flagKeepGoing    bool
+	flagShowDiffs    bool
+	flagDetailedExit bool
+	flagSetupYes     bool
+	flagTUI          bool
+	flagNoTUI        bool
+	flagForce        bool
+	flagRecheck      bool
+	flagInstallRoot  string
+	flagNoPreflight  bool
+	flagTrustState   bool
+	flagSetupAll     bool
+	flagReport       string
+	flagDetectProxy  bool
+	flagOnlyStep     []string
+	flagSkipStep     []string
+	flagConfirmPath  bool
+)
+
 func newSetupCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "setup [module...]",
 		Short: "Set up your development environment",
-		Long:  "Run the setup wizard. Optionally specify module names (e.g., 'shhh setup base') to run specific modules only.",
-		RunE:  runSetup,
+		Long: "Run the setup wizard. Optionally specify module names (e.g., 'shhh setup base') to run specific modules only.\n\n" +
+			"The wizard uses the terminal's alternate screen; set SHHH_NO_ALTSCREEN=1 (or pass --no-altscreen) " +
+			"if your terminal, e.g. over SSH, mishandles it. With TERM=dumb, or when output is piped, plain " +
+			"text output is used instead of the wizard; --tui or --no-tui overrides that detection. Text output lists the environment variable and PATH " +
+			"changes setup is about to make and, on a terminal, asks before making them (--yes skips the question).\n\n" +
+			"With [proxy] on_conflict = \"ask\", a proxy variable that something other than shhh set is only " +
+			"replaced after asking (text output on a terminal) or with --force.\n\n" +
+			"Exit codes: 0 when setup succeeds, 1 when it fails. With --detailed-exit-code, a successful " +
+			"run in which every step was already done (nothing changed, e.g. no shell restart needed) " +
+			"exits 3 instead of 0. Declining the changes setup asks about exits 4.\n\n" +
+			"Before anything is installed, setup checks there is enough free space where tooling installs, " +
+			"that a [paths] root is a directory or can be created, and that PowerShell works, and stops if not (--no-preflight skips this; 'shhh doctor' reports the same checks).\n\n" +
+			"--trust-state is a fast path for re-runs on a machine you know is set up: modules the state file " +
+			"records as installed are skipped without running any of their checks, so only modules new to " +
+			"this machine run. Anything changed since (a removed tool, an edited variable) is not noticed or " +
+			"repaired; run without it, or use 'shhh doctor', to find that. It is unrelated to --force, which " +
+			"only decides whether proxy variables set elsewhere may be replaced.\n\n" +
+			"After a run in which every module succeeds, setup stores a fingerprint of what it set up: the " +
+			"effective config, the modules' steps, the variables and PATH entries shhh manages, the files it " +
+			"wrote, the profile's managed block, the system root certificates, and the tool versions and " +
+			"configs the modules' checks read (the Scoop packages installed, go version, the fnm, uv and " +
+			"rustup toolchains, .gitconfig, .npmrc and the like). It isn't stored after a run with " +
+			"--trust-state. In text output, a re-run of the same modules whose fingerprint still matches " +
+			"stops at \"Already up to date\" without running any step's checks; --recheck checks every " +
+			"step anyway, for a change the fingerprint doesn't cover.\n\n" +
+			"Modules [modules] disabled lists are left out altogether: setup can't run them and the wizard " +
+			"doesn't offer them, so a config that disables a module another enabled one depends on (such " +
+			"as base) is rejected before anything runs. Without module " +
+			"arguments, setup runs the modules [modules] enabled lists (all of them if it is empty), and the " +
+			"wizard pre-selects them. --all ignores both lists.\n\n" +
+			"--report writes a human-readable record of the run, for onboarding sign-off or an audit " +
+			"ticket: the config (passwords masked), each module and step with its outcome and duration, " +
+			"warnings such as retried steps, and the final status. A path ending .md gives Markdown, .html " +
+			"gives HTML. It is written even when setup fails.\n\n" +
+			"--detect-proxy fills in the proxy from Windows' Internet Options (WinINET) settings when the " +
+			"config sets neither [proxy] http nor https and its mode isn't \"direct\". Its bypass list " +
+			"becomes no_proxy unless that is set. Proxy auto-config (PAC) scripts aren't read.\n\n" +
+			"--only-step and --skip-step pick steps by name: each value matches every step whose name " +
+			"contains it, ignoring case (e.g. --skip-step install leaves out the Scoop installs, --only-step " +
+			"cert runs just the certificate steps). Both may be repeated; a step runs if it matches an " +
+			"--only-step (when any are given) and no --skip-step. Steps left out are reported as filtered. " +
+			"Modules still run in dependency order, but nothing checks that a step you run doesn't rely on " +
+			"one you filtered out (e.g. configuring a tool whose install you skipped): that's up to you.\n\n" +
+			"With [safety] confirm_path_changes = true (or --confirm-path), setup shows the current PATH " +
+			"and asks before adding a directory to it, or before running the Scoop or standalone uv " +
+			"installer, which add their own: at the prompt in text output, in a dialog in the wizard. " +
+			"--yes answers yes. Declining fails that step; without a terminal to ask on, the step fails " +
+			"unless --yes is given. PATH entries a Scoop package's manifest adds aren't asked about.",
+		ValidArgsFunction: completeModules,
+		RunE:              runSetup,
 	}
+	cmd.Flags().BoolVar(&flagKeepGoing, "keep-going", false, "Carry on with the remaining modules after one fails (modules depending on it are not run)")
+	cmd.Flags().BoolVarP(&flagSetupYes, "yes", "y", false, "Apply environment changes without asking, including PATH changes [safety] confirm_path_changes would ask about")
+	cmd.Flags().BoolVar(&flagDetailedExit, "detailed-exit-code", false, "Exit 3 instead of 0 when setup succeeds without changing anything")
+	cmd.Flags().BoolVar(&flagShowDiffs, "show-diffs", false, "List the config files setup changed, with a brief diff of each, in the summary")
+	cmd.Flags().BoolVar(&flagExplainAll, "explain-all", false, "Print every step's explanation, in run order, and exit without running")
+	cmd.Flags().BoolVar(&flagSelectCerts, "select-certs", false, "Choose which system certificates go into the CA bundle")
+	cmd.Flags().StringSliceVar(&flagSelect, "select", nil, "Modules to pre-check in the wizard, e.g. --select golang,node")
+	cmd.Flags().BoolVar(&flagAutoConfirm, "auto-confirm", false, "Skip the wizard's picker and run the --select modules straight away")
+	cmd.Flags().StringVar(&flagInstallRoot, "install-root", "", "Install Scoop, GOPATH, the CA bundle and Python/Node.js versions under this directory, e.g. D:\\dev (overrides [paths] root)")
+	cmd.Flags().BoolVar(&flagNoPreflight, "no-preflight", false, "Skip the free space and PowerShell checks made before anything is installed")
+	cmd.Flags().BoolVar(&flagForce, "force", false, "Replace proxy variables set by something other than shhh without asking ([proxy] on_conflict = \"ask\")")
+	cmd.Flags().BoolVar(&flagRecheck, "recheck", false, "Check every step even if nothing setup fingerprints has changed since the last successful run")
+	cmd.Flags().BoolVar(&flagTrustState, "trust-state", false, "Skip modules the state file records as installed without running their checks (fast, but changes since are not noticed)")
+	cmd.Flags().BoolVar(&flagSetupAll, "all", false, "Ignore [modules]: run (or offer in the wizard) every module, including disabled ones")
+	cmd.Flags().StringVar(&flagReport, "report", "", "Write a run report for sign-off to this .md or .html file")
+	cmd.Flags().BoolVar(&flagTUI, "tui", false, "Always run the wizard, even if the terminal isn't detected as one (e.g. mintty)")
+	cmd.Flags().BoolVar(&flagNoTUI, "no-tui", false, "Never run the wizard; use plain text output")
+	cmd.Flags().BoolVar(&flagDetectProxy, "detect-proxy", false, "Use the Windows system proxy when the config doesn't set one")
+	cmd.Flags().StringArrayVar(&flagOnlyStep, "only-step", nil, "Run only steps whose name contains this (case-insensitive; repeatable)")
+	cmd.Flags().StringArrayVar(&flagSkipStep, "skip-step", nil, "Leave out steps whose name contains this (case-insensitive; repeatable)")
+	cmd.Flags().BoolVar(&flagConfirmPath, "confirm-path", false, "Ask before adding a directory to PATH (as [safety] confirm_path_changes = true)")
+	addMaxConcurrencyFlag(cmd)
+	cmd.MarkFlagsMutuallyExclusive("tui", "no-tui")
+	_ = cmd.RegisterFlagCompletionFunc("select", completeSelect)
+	return cmd
 }
 
 func runSetup(cmd *cobra.Command, args []string) error {
-	// Load config
-	cfgPath := config.ConfigFilePath()
-	cfg, err := config.LoadFromFile(cfgPath)
+	if err := checkMaxConcurrency(); err != nil {
+		return err
+	}
+	cfg, cfgPath, loaded, err := loadConfig()
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			if flagQuiet || !isTerminal() {
-				fmt.Println("No config file found, using defaults.")
-				fmt.Printf("Create %s to customize.\n\n", cfgPath)
-			}
-			cfg = config.Defaults()
+		return err
+	}
+	if flagReport != "" {
+		if _, err := report.FormatFor(flagReport); err != nil {
+			return fmt.Errorf("--report: %w", err)
+		}
+	}
+	if !loaded {
+		cfgPath = ""
+	}
+	if flagInstallRoot != "" {
+		root, err := filepath.Abs(flagInstallRoot)
+		if err != nil {
+			return fmt.Errorf("--install-root: %w", err)
+		}
+		cfg.Paths.Root = root
+	}
+	if flagConfirmPath {
+		cfg.Safety.ConfirmPathChanges = true
+	}
+	var proxyNotice string
+	if flagDetectProxy {
+		detected, err := applySystemProxy(cfg)
+		switch {
+		case err != nil:
+			proxyNotice = fmt.Sprintf("Not using the Windows system proxy (--detect-proxy): %v", err)
+		case detected != "":
+			proxyNotice = fmt.Sprintf("Using the Windows system proxy %s (--detect-proxy).", detected)
+		}
+	}
+	if !useWizard() {
+		if !loaded {
+			infof("No config file found, using defaults.\n")
+			infof("Create %s to customize.\n\n", cfgPath)
 		} else {
-			return fmt.Errorf("loading config: %w", err)
+			infof("Config: %s\n", cfgPath)
+			if cfg.Org.Name != "" {
+				infof("Org:    %s\n", cfg.Org.Name)
+			}
+			infof("\n")
 		}
-	} else if flagQuiet || !isTerminal() {
-		fmt.Printf("Config: %s\n", cfgPath)
-		if cfg.Org.Name != "" {
-			fmt.Printf("Org:    %s\n", cfg.Org.Name)
+		if proxyNotice != "" {
+			infof("%s\n\n", proxyNotice)
 		}
-		fmt.Println()
 	}
 
 	// Set up logging
@@ -63,76 +197,519 @@ func runSetup(cmd *cobra.Command, args []string) error {
 		st = &state.State{}
 	}
 
-	// Create platform backends
-	env := platform.NewUserEnv()
-	prof := platform.NewProfileManager()
+	deps := newDependencies(cfg, st, logger)
+	if flagForce {
+		deps.ConfirmOverwrite = func(string, string, string) bool { return true }
+	}
+	if flagSetupYes {
+		deps.ConfirmPath = func([]platform.PathEntry, string) bool { return true }
+	}
+	reg := newRegistry(deps)
+	if err := reg.Validate(); err != nil {
+		return fmt.Errorf("invalid module definition: %w", err)
+	}
+	if err := cfg.Modules.Check(setup.ModuleIDs, setup.ModuleDependencies); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	if flagTrustState {
+		trustState(reg, st)
+	}
+	if flagAutoConfirm && len(flagSelect) == 0 {
+		return errors.New("--auto-confirm needs --select")
+	}
+	for _, id := range flagSelect {
+		if reg.Get(id) == nil && slices.Contains(cfg.Modules.Disabled, id) {
+			return fmt.Errorf("--select: module %q is disabled in [modules] (--all runs it anyway)", id)
+		}
+		if reg.Get(id) == nil {
+			return fmt.Errorf("--select: unknown module %q", id)
+		}
+	}
 
-	// Build dependencies
-	deps := &setup.Dependencies{
-		Config:    cfg,
-		Env:       env,
-		Profile:   prof,
-		CertStore: platform.NewCertStore(),
-		Exec:      &exec.DefaultRunner{},
-		State:     st,
+	if flagExplainAll {
+		ids := args
+		if len(ids) == 0 {
+			ids = defaultModules(reg, cfg)
+		}
+		resolved, err := reg.ResolveDeps(ids)
+		if err != nil {
+			return fmt.Errorf("resolving dependencies: %w", err)
+		}
+		deps.Preview = false
+		return explainModules(cmd.Context(), os.Stdout, reg, resolved)
 	}
 
-	// Build module registry
-	reg := module.NewRegistry()
-	reg.Register(setup.NewBaseModule(deps))
-	reg.Register(setup.NewGolangModule(deps))
-	reg.Register(setup.NewPythonModule(deps))
-	reg.Register(setup.NewNodeModule(deps))
-	reg.Register(setup.NewToolsModule(deps))
+	// A dry run downloads nothing, so it has nothing to check for.
+	if !flagNoPreflight && !flagDryRun {
+		if _, err := setup.Preflight(cmd.Context(), deps, runtime.GOOS); err != nil {
+			return fmt.Errorf("pre-flight checks failed (--no-preflight skips them):\n%w", err)
+		}
+	}
 
 	// Create runner
 	runner := module.NewRunner(logger, flagDryRun)
+	runner.SetKeepGoing(flagKeepGoing)
+	runner.SetDefaultTimeout(defaultStepTimeout)
+	if len(flagOnlyStep) > 0 || len(flagSkipStep) > 0 {
+		if err := checkStepPatterns(reg, flagOnlyStep, flagSkipStep); err != nil {
+			return err
+		}
+		runner.SetStepFilter(stepFilter(flagOnlyStep, flagSkipStep))
+	}
 
-	if flagQuiet || !isTerminal() {
-		return runSetupCLI(runner, reg, st, logger, args)
+	if flagQuiet || !useWizard() {
+		if flagSelectCerts {
+			return errors.New("--select-certs needs the interactive wizard (a terminal, without --quiet)")
+		}
+		// Without the wizard, --select means the same as module arguments.
+		if len(args) == 0 {
+			args = flagSelect
+		}
+		if len(args) == 0 {
+			args = defaultModules(reg, cfg)
+		}
+		// Catch missing settings (e.g. an empty python.version) before any
+		// step runs, for just the modules about to run. The wizard learns
+		// the selection later; there the modules fail on their own.
+		resolved, err := reg.ResolveDeps(args)
+		if errors.Is(err, module.ErrModuleRemoved) {
+			return fmt.Errorf("%w: [modules] disables it (--all runs it anyway)", err)
+		}
+		if err == nil {
+			if err := cfg.Validate(resolved...); err != nil {
+				return fmt.Errorf("invalid config: %w", err)
+			}
+		}
+		return quietExit(cmd, runSetupCLI(runner, reg, deps, logger, cfgPath, args))
 	}
 
-	return runSetupTUI(runner, reg, st, logger, args)
+	return quietExit(cmd, runSetupTUI(runner, reg, deps, logger, cfgPath, proxyNotice, args))
 }
 
-// runSetupCLI runs the existing text-based output path.
-func runSetupCLI(runner *module.Runner, reg *module.Registry, st *state.State, logger *slog.Logger, args []string) error {
+// applySystemProxy fills in cfg's proxy from the Windows system proxy
+// (--detect-proxy) when the config doesn't set one. It returns the proxy
+// used, or "" if cfg was left alone.
+func applySystemProxy(cfg *config.Config) (string, error) {
+	if cfg.Proxy.Mode == "direct" || cfg.Proxy.HTTP != "" || cfg.Proxy.HTTPS != "" {
+		return "", nil
+	}
+	sys, ok := platform.DetectSystemProxy()
+	if !ok {
+		return "", nil
+	}
+	return applyProxy(cfg, sys)
+}
+
+// applyProxy is applySystemProxy for a detected proxy sys. Internet
+// Options accepts addresses the proxy variables can't hold, so sys is
+// checked as the config's own proxy would be; if it doesn't pass, cfg is
+// left alone and the error says why.
+func applyProxy(cfg *config.Config, sys platform.ProxyConfig) (string, error) {
+	detected := config.ProxyConfig{HTTP: sys.HTTP, HTTPS: sys.HTTPS}
+	if err := detected.CheckURLs(); err != nil {
+		return "", err
+	}
+	cfg.Proxy.HTTP, cfg.Proxy.HTTPS = sys.HTTP, sys.HTTPS
+	if cfg.Proxy.NoProxy == "" {
+		cfg.Proxy.NoProxy = sys.NoProxy
+	}
+	if sys.HTTPS != "" {
+		return sys.HTTPS, nil
+	}
+	return sys.HTTP, nil
+}
+
+// stepFilter returns the runner's step filter for --only-step and
+// --skip-step: a step runs if its name contains one of only (or only is
+// empty) and none of skip, ignoring case.
+func stepFilter(only, skip []string) module.StepFilter {
+	matches := func(patterns []string, name string) bool {
+		name = strings.ToLower(name)
+		return slices.ContainsFunc(patterns, func(p string) bool {
+			return strings.Contains(name, strings.ToLower(p))
+		})
+	}
+	return func(_ *module.Module, step *module.Step) bool {
+		return (len(only) == 0 || matches(only, step.Name)) && !matches(skip, step.Name)
+	}
+}
+
+// checkStepPatterns reports each --only-step and --skip-step value that
+// matches no step of any module in reg, which is almost always a typo: an
+// --only-step that matches nothing would otherwise filter out every step.
+func checkStepPatterns(reg *module.Registry, only, skip []string) error {
+	var errs []error
+	for _, flag := range []struct {
+		name     string
+		patterns []string
+	}{{"--only-step", only}, {"--skip-step", skip}} {
+		for _, p := range flag.patterns {
+			matched := slices.ContainsFunc(reg.All(), func(m *module.Module) bool {
+				return slices.ContainsFunc(m.Steps, func(step module.Step) bool {
+					return strings.Contains(strings.ToLower(step.Name), strings.ToLower(p))
+				})
+			})
+			if !matched {
+				errs = append(errs, fmt.Errorf("%s: %q matches no step ('shhh explain' lists them)", flag.name, p))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// defaultModules returns the modules setup runs when none are named: those
+// [modules] enabled lists (every module in reg when it is empty), in
+// registry order. With --all, every module.
+func defaultModules(reg *module.Registry, cfg *config.Config) []string {
+	ids := moduleIDs(reg)
+	if flagSetupAll || len(cfg.Modules.Enabled) == 0 {
+		return ids
+	}
+	return slices.DeleteFunc(ids, func(id string) bool {
+		return !slices.Contains(cfg.Modules.Enabled, id)
+	})
+}
+
+// trustState marks every module st records as installed as satisfied
+// (--trust-state), so setup skips it without running its checks.
+func trustState(reg *module.Registry, st *state.State) {
+	for _, m := range reg.All() {
+		if slices.Contains(st.InstalledModules, m.ID) {
+			m.AssumeSatisfied()
+		}
+	}
+}
+
+// runFingerprint returns setup.Fingerprint for running ids, with their
+// dependencies.
+func runFingerprint(reg *module.Registry, deps *setup.Dependencies, ids []string) (string, error) {
+	plan, err := module.BuildPlan(context.Background(), reg, ids, module.PlanOptions{SkipChecks: true, SkipDryRun: true})
+	if err != nil {
+		return "", err
+	}
+	mods := make([]*module.Module, len(plan.Modules))
+	for i, mp := range plan.Modules {
+		mods[i] = mp.Module
+	}
+	return setup.Fingerprint(context.Background(), deps, mods)
+}
+
+// upToDate reports whether running ids would find nothing to do: the
+// environment still has the fingerprint the last successful run recorded.
+func upToDate(reg *module.Registry, deps *setup.Dependencies, ids []string) bool {
+	if deps.State.Fingerprint == "" {
+		return false
+	}
+	fp, err := runFingerprint(reg, deps, ids)
+	return err == nil && fp == deps.State.Fingerprint
+}
+
+// recordFingerprint stores the fingerprint of the environment results left
+// behind if every module in them succeeded, and clears it otherwise, so only
+// a run that completed can be skipped next time. A --trust-state run never
+// records one: the modules it assumed satisfied weren't checked. Dry runs
+// change nothing.
+func recordFingerprint(reg *module.Registry, deps *setup.Dependencies, results []module.ModuleResult) {
+	if flagDryRun {
+		return
+	}
+	deps.State.Fingerprint = ""
+	if flagTrustState {
+		return
+	}
+	ids := make([]string, len(results))
+	for i, r := range results {
+		// A module with filtered steps may not be fully set up.
+		if r.Err != nil || r.Unsupported != "" || r.Filtered > 0 {
+			return
+		}
+		ids[i] = r.ModuleID
+	}
+	if fp, err := runFingerprint(reg, deps, ids); err == nil {
+		deps.State.Fingerprint = fp
+	}
+}
+
+// defaultStepTimeout bounds each Run of a step that doesn't set its own
+// Timeout: long enough for a slow download, short enough that a hung one
+// fails rather than freezing setup.
+const defaultStepTimeout = 30 * time.Minute
+
+// quietExit stops cobra printing errUnchanged or errAborted, which only
+// set the exit code, as an error.
+func quietExit(cmd *cobra.Command, err error) error {
+	if errors.Is(err, errUnchanged) || errors.Is(err, errAborted) {
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+	}
+	return err
+}
+
+// unchanged reports whether --detailed-exit-code applies to a successful
+// run: no step completed (or, in a dry run, would run).
+func unchanged(results []module.ModuleResult) bool {
+	if !flagDetailedExit {
+		return false
+	}
+	for _, r := range results {
+		if r.Completed > 0 || r.WouldRun > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// loadConfig loads the repo-local shhh.toml if there is one, otherwise the
+// global config. A missing file is not an error: defaults are returned and
+// loaded is false. path is the file that was (or would have been) read.
+// Warnings about the file, such as unknown keys, are printed to stderr.
+// --system overrides [proxy] scope.
+func loadConfig() (cfg *config.Config, path string, loaded bool, err error) {
+	path, found := config.FindConfig()
+	if !found {
+		path = config.ConfigFilePath()
+	}
+	cfg, err = config.LoadFromFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		cfg = config.Defaults()
+	case err != nil:
+		return nil, path, false, fmt.Errorf("loading config: %w", err)
+	default:
+		loaded = true
+		for _, w := range cfg.Warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", path, w)
+		}
+	}
+	if flagSystem {
+		cfg.Proxy.Scope = "system"
+	}
+	return cfg, path, loaded, nil
+}
+
+// withStateRoot returns cfg with [paths] root defaulted to st.InstallRoot,
+// the root tooling was last installed under. A root given once with
+// setup --install-root isn't in the config, so commands that inspect or
+// undo what setup did need it from state to look in the right place. cfg
+// itself is left unchanged.
+func withStateRoot(cfg *config.Config, st *state.State) *config.Config {
+	if cfg.Paths.Root != "" || st.InstallRoot == "" {
+		return cfg
+	}
+	c := *cfg
+	c.Paths.Root = st.InstallRoot
+	return &c
+}
+
+// newDependencies wires the platform backends used by every setup module.
+// With --trace, every command that actually runs (cache misses) is logged;
+// with --dry-run, the CA bundle step writes a preview of the bundle. The
+// user environment is tracked so the summary only asks for a shell restart
+// when a persistent change was made.
+func newDependencies(cfg *config.Config, st *state.State, logger *slog.Logger) *setup.Dependencies {
+	var runner exec.Runner = &exec.DefaultRunner{}
+	if flagTrace {
+		runner = exec.NewTracingRunner(runner, logger)
+	}
+	return &setup.Dependencies{
+		Config:    cfg,
+		Env:       platform.TrackRestart(platform.NewUserEnv()),
+		Profile:   platform.NewProfileManager(cfg.Profile.UTF8BOM, runner),
+		CertStore: platform.NewCertStore(platform.CertStoreOptions{WindowsStores: cfg.Certs.WindowsStores}),
+		Exec:      exec.NewCachingRunner(runner, "scoop list", "scoop bucket list"),
+		State:     st,
+		Logger:    logger,
+		Trace:     flagTrace,
+		Preview:   flagDryRun,
+	}
+}
+
+// newRegistry builds the registry of setup modules for deps, less those
+// [modules] disabled lists (unless setup --all). Modules are
+// config-conditional, so the steps they contain depend on deps.Config.
+func newRegistry(deps *setup.Dependencies) *module.Registry {
+	reg := allModules(deps)
+	if !flagSetupAll {
+		for _, id := range deps.Config.Modules.Disabled {
+			reg.Remove(id)
+		}
+	}
+	return reg
+}
+
+// allModules is newRegistry including the disabled modules, e.g. to check
+// module IDs in the config against.
+func allModules(deps *setup.Dependencies) *module.Registry {
+	reg := module.NewRegistry()
+	for _, m := range setup.Modules(deps) {
+		reg.Register(m)
+	}
+	return reg
+}
+
+// runSetupCLI runs the existing text-based output path for the modules in
+// args, which runSetup has already defaulted.
+func runSetupCLI(runner *module.Runner, reg *module.Registry, deps *setup.Dependencies, logger *slog.Logger, cfgPath string, args []string) error {
+	st := deps.State
 	runner.SetCallback(cliStepCallback)
+	runner.SetModuleCallback(cliModuleCallback)
 
 	moduleIDs := args
 	if len(moduleIDs) == 0 {
-		for _, m := range reg.All() {
-			moduleIDs = append(moduleIDs, m.ID)
+		infof("No modules to set up: [modules] disables them all (--all runs every module).\n")
+		return nil
+	}
+
+	if !flagRecheck && !flagDryRun && upToDate(reg, deps, moduleIDs) {
+		infof("Already up to date: nothing has changed since the last successful run (--recheck checks every step).\n")
+		if unchanged(nil) {
+			return errUnchanged
 		}
+		return nil
 	}
 
 	if flagDryRun {
-		fmt.Println("=== DRY RUN ===")
-		fmt.Println()
+		infof("=== DRY RUN ===\n\n")
+	} else if !flagQuiet && !flagSetupYes {
+		if !previewEnv(reg, deps.Env, moduleIDs) {
+			fmt.Println("Aborted.")
+			return errAborted
+		}
+	}
+
+	// Text output can ask before replacing a proxy value set elsewhere; the
+	// wizard can't, so there the step fails unless --force.
+	if deps.ConfirmOverwrite == nil && !flagQuiet && stdinIsTerminal() {
+		deps.ConfirmOverwrite = confirmOverwrite
+	}
+	if deps.ConfirmPath == nil && !flagQuiet && stdinIsTerminal() {
+		deps.ConfirmPath = confirmPathChange
 	}
 
 	ctx := context.Background()
 	results, err := runner.RunModules(ctx, reg, moduleIDs)
 
-	fmt.Println()
-	printSummary(results)
+	// --quiet only speaks up when something went wrong.
+	if err != nil || !flagQuiet {
+		printSummary(results)
+		if flagShowDiffs {
+			printChanges(results)
+		}
+		if platform.RestartRequired(deps.Env) {
+			fmt.Println()
+			fmt.Println(wrapLine("", "Restart your shell (or open a new terminal) to pick up the environment changes.", 0))
+		}
+	}
 
-	saveState(st, results, logger)
+	recordFingerprint(reg, deps, results)
+	saveState(st, moduleIDs, results, logger)
+	reportErr := writeReport(deps, reg, cfgPath, results)
 
 	if err != nil {
 		fmt.Println()
 		fmt.Println("Setup failed. Fix the issue and re-run — completed steps will be skipped.")
-		return err
+		return errors.Join(err, reportErr)
+	}
+	if reportErr != nil {
+		return reportErr
 	}
 
+	if unchanged(results) {
+		return errUnchanged
+	}
 	return nil
 }
 
+// previewEnv prints the user environment changes setup would make to run
+// ids and, when stdin is a terminal, asks to go ahead. It reports whether to
+// continue: with nothing to change, no terminal to ask on, or a platform
+// whose environment can't be read, it doesn't ask.
+func previewEnv(reg *module.Registry, env platform.UserEnv, ids []string) bool {
+	plan, err := module.BuildPlan(context.Background(), reg, ids, module.PlanOptions{SkipChecks: true, SkipDryRun: true})
+	if err != nil {
+		return true // RunModules reports it
+	}
+	changes, dirs, err := setup.PreviewEnv(env, plan)
+	if err != nil || (len(changes) == 0 && len(dirs) == 0) {
+		return true
+	}
+
+	var rows [][]string
+	for _, c := range changes {
+		rows = append(rows, []string{c.Key + ":", envValue(c.Current) + " → " + envValue(c.Want)})
+	}
+	for _, dir := range dirs {
+		rows = append(rows, []string{"PATH:", "+ " + dir})
+	}
+	fmt.Println("The following will change:")
+	fmt.Print(renderTable(rows))
+	fmt.Println()
+
+	if !stdinIsTerminal() {
+		return true
+	}
+	return confirm("Proceed?")
+}
+
+// envValue shows an environment value in the preview, naming the empty one.
+func envValue(v string) string {
+	if v == "" {
+		return "(unset)"
+	}
+	return v
+}
+
+// infof prints normal progress output, which --quiet suppresses. Errors are
+// always printed directly.
+func infof(format string, a ...any) {
+	if flagQuiet {
+		return
+	}
+	fmt.Printf(format, a...)
+}
+
 // runSetupTUI launches the Bubble Tea wizard.
-func runSetupTUI(runner *module.Runner, reg *module.Registry, st *state.State, logger *slog.Logger, _ []string) error {
-	model := wizard.New(reg, runner, flagExplain, flagDryRun)
+func runSetupTUI(runner *module.Runner, reg *module.Registry, deps *setup.Dependencies, logger *slog.Logger, cfgPath, notice string, _ []string) error {
+	st := deps.State
+	icons, err := iconSet()
+	if err != nil {
+		return err
+	}
+	// [modules]: check the enabled modules (and any --select). The
+	// disabled ones aren't in reg, so aren't offered.
+	preselect := flagSelect
+	if !flagSetupAll {
+		preselect = append(slices.Clone(deps.Config.Modules.Enabled), flagSelect...)
+	}
+	model := wizard.New(reg, runner, flagExplain, flagDryRun).
+		WithStyles(components.StylesWithIcons(icons)).
+		WithCompact(flagCompact).
+		WithSelection(preselect, flagAutoConfirm).
+		WithEstimates(st.Estimates()).
+		WithShowDiffs(flagShowDiffs).
+		WithNotice(notice).
+		WithRestartCheck(func() bool { return platform.RestartRequired(deps.Env) })
+	if deps.ConfirmPath == nil {
+		// Without --yes, [safety] confirm_path_changes asks in a dialog.
+		confirmer := &wizard.PathConfirmer{}
+		deps.ConfirmPath = confirmer.Confirm
+		model = model.WithPathConfirmer(confirmer)
+	}
+	if flagSelectCerts {
+		choices, err := certChoices(deps)
+		if err != nil {
+			return err
+		}
+		model = model.WithCertSelection(choices, func(choices []wizard.CertChoice) {
+			st.ExcludedCerts = excludedCerts(choices)
+		})
+	}
 
-	p := tea.NewProgram(model, tea.WithAltScreen())
+	var opts []tea.ProgramOption
+	if useAltScreen() {
+		opts = append(opts, tea.WithAltScreen())
+	}
+	p := tea.NewProgram(model, opts...)
 	finalModel, err := p.Run()
 	if err != nil {
 		return fmt.Errorf("TUI error: %w", err)
@@ -142,82 +719,285 @@ func runSetupTUI(runner *module.Runner, reg *module.Registry, st *state.State, l
 	if wm, ok := finalModel.(wizard.WizardModel); ok {
 		results := wm.Results()
 		if len(results) > 0 {
-			saveState(st, results, logger)
+			recordFingerprint(reg, deps, results)
+			saveState(st, wm.Selected(), results, logger)
+			if err := writeReport(deps, reg, cfgPath, results); err != nil {
+				return err
+			}
 		}
 
 		if wm.RunError() != nil {
 			return wm.RunError()
 		}
+		// With --keep-going several modules may have failed; report them all.
+		var errs []error
 		for _, r := range results {
 			if r.Err != nil {
-				return r.Err
+				errs = append(errs, r.Err)
 			}
 		}
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+		if len(results) > 0 && unchanged(results) {
+			return errUnchanged
+		}
 	}
 
 	return nil
 }
 
-// saveState persists run results to the state file.
-func saveState(st *state.State, results []module.ModuleResult, logger *slog.Logger) {
+// certChoices lists the system root certificates for the cert selection
+// screen, with those excluded on an earlier run unticked.
+func certChoices(deps *setup.Dependencies) ([]wizard.CertChoice, error) {
+	certs, err := deps.CertStore.SystemRoots()
+	if err != nil {
+		return nil, fmt.Errorf("reading system certificates: %w", err)
+	}
+	excluded := make(map[string]bool, len(deps.State.ExcludedCerts))
+	for _, fp := range deps.State.ExcludedCerts {
+		excluded[fp] = true
+	}
+	choices := make([]wizard.CertChoice, len(certs))
+	for i, cert := range certs {
+		choices[i] = wizard.CertChoice{Cert: cert, Selected: !excluded[setup.CertFingerprint(cert)]}
+	}
+	return choices, nil
+}
+
+// excludedCerts returns the fingerprints of the unticked certificates.
+func excludedCerts(choices []wizard.CertChoice) []string {
+	var fps []string
+	for _, c := range choices {
+		if !c.Selected {
+			fps = append(fps, setup.CertFingerprint(c.Cert))
+		}
+	}
+	return fps
+}
+
+// iconSet resolves --ascii / --icons, auto-detecting when neither is given.
+func iconSet() (components.IconSet, error) {
+	if flagASCII {
+		return components.IconsASCII, nil
+	}
+	if flagIcons == "" || flagIcons == "auto" {
+		return components.DetectIconSet(), nil
+	}
+	return components.ParseIconSet(flagIcons)
+}
+
+// saveState persists run results to the state file, recording the run in
+// the history shown by 'shhh history'.
+func saveState(st *state.State, selected []string, results []module.ModuleResult, logger *slog.Logger) {
 	st.LastRun = time.Now()
+	run := state.Run{Time: st.LastRun, Selected: selected, DryRun: flagDryRun}
 	for _, r := range results {
-		if r.Err == nil {
+		// A module with filtered steps may not be fully set up.
+		if r.Err == nil && r.Unsupported == "" && r.Filtered == 0 {
 			st.AddModule(r.ModuleID)
 		}
+		outcome := state.ModuleOutcome{
+			ID:         r.ModuleID,
+			Completed:  r.Completed,
+			Skipped:    r.Skipped,
+			FailedStep: r.FailedStep,
+		}
+		if r.Err != nil {
+			outcome.Error = r.Err.Error()
+		}
+		run.Modules = append(run.Modules, outcome)
+		for step, d := range r.Durations {
+			st.RecordTiming(module.StepKey(r.ModuleID, step), d)
+		}
 	}
+	st.AddRun(run)
 	if saveErr := state.Save(config.StateFilePath(), st); saveErr != nil {
 		logger.Error("failed to save state", "error", saveErr)
 	}
 }
 
-// isTerminal checks if stdout is a terminal (not piped).
-func isTerminal() bool {
-	fi, err := os.Stdout.Stat()
+// writeReport writes the --report document for results, if asked for.
+// cfgPath is the config file used, or "" for the defaults.
+func writeReport(deps *setup.Dependencies, reg *module.Registry, cfgPath string, results []module.ModuleResult) error {
+	if flagReport == "" {
+		return nil
+	}
+	format, err := report.FormatFor(flagReport)
 	if err != nil {
-		return false
+		return err
+	}
+	var b bytes.Buffer
+	if err := report.New(withStateRoot(deps.Config, deps.State), cfgPath, reg, results, flagDryRun).Write(&b, format); err != nil {
+		return fmt.Errorf("rendering report: %w", err)
+	}
+	if err := os.WriteFile(flagReport, b.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("writing report: %w", err)
 	}
-	return fi.Mode()&os.ModeCharDevice != 0
+	infof("Report written to %s\n", flagReport)
+	return nil
 }
 
-func cliStepCallback(mod *module.Module, step *module.Step, index int, total int, skipped bool, err error) {
+// printChanges prints the files the run changed, each with its added and
+// removed line counts and a brief diff (--show-diffs).
+func printChanges(results []module.ModuleResult) {
+	var changes []module.FileChange
+	for _, r := range results {
+		changes = append(changes, r.Changes...)
+	}
+	if len(changes) == 0 {
+		fmt.Println("\nNo files changed.")
+		return
+	}
+
+	rows := make([][]string, len(changes))
+	for i, c := range changes {
+		added, removed := c.DiffStat()
+		rows[i] = []string{c.Path, fmt.Sprintf("+%d -%d", added, removed)}
+	}
+	lines := tableLines(rows)
+
+	fmt.Println("\nChanged files:")
+	for i, c := range changes {
+		fmt.Print(lines[i])
+		for _, line := range c.BriefDiff() {
+			fmt.Println("    " + line)
+		}
+	}
+}
+
+// confirmOverwrite asks whether to replace key's current value, which
+// something other than shhh set, with want.
+func confirmOverwrite(key, current, want string) bool {
+	fmt.Println(wrapLine("  ", fmt.Sprintf("%s is set to %q by something other than shhh (another tool or group policy).", key, current), 2))
+	return confirm(fmt.Sprintf("  Replace it with %q?", want))
+}
+
+// confirmPathChange shows the current PATH and asks whether to add dir to
+// it ([safety] confirm_path_changes).
+func confirmPathChange(current []platform.PathEntry, dir string) bool {
+	fmt.Println("  Current PATH:")
+	for _, e := range current {
+		line := fmt.Sprintf("    %s (%s)", e.Dir, e.Source)
+		if !e.Exists {
+			line += " missing"
+		}
+		fmt.Println(line)
+	}
+	return confirm(fmt.Sprintf("  Add %s to PATH?", dir))
+}
+
+// cliModuleCallback prints a header before each module's steps and a
+// one-line result after them.
+func cliModuleCallback(mod *module.Module, phase module.ModulePhase, result *module.ModuleResult) {
+	if len(mod.Steps) == 0 {
+		// Nothing to do; the config left the module empty.
+		return
+	}
+	switch phase {
+	case module.ModuleStarted:
+		infof("%s\n", mod.Name)
+	case module.ModuleFinished:
+		if result.Err != nil {
+			fmt.Printf("  %s failed at %q\n\n", mod.Name, result.FailedStep)
+			return
+		}
+		if result.Unsupported != "" {
+			infof("  %s skipped: it %s\n\n", mod.Name, result.Unsupported)
+			return
+		}
+		if flagDryRun {
+			infof("  %s: %d would run, %d skipped\n\n", mod.Name, result.WouldRun, result.Skipped)
+			return
+		}
+		infof("  %s: %d completed, %d skipped\n\n", mod.Name, result.Completed, result.Skipped)
+	}
+}
+
+func cliStepCallback(mod *module.Module, step *module.Step, index int, total int, outcome module.StepOutcome, err error) {
 	prefix := fmt.Sprintf("  [%d/%d]", index+1, total)
 
-	if skipped {
-		fmt.Printf("%s  %s (already done)\n", prefix, step.Name)
+	switch outcome {
+	case module.StepSkipped:
+		infof("%s  %s (already done)\n", prefix, step.Name)
+		return
+	case module.StepFiltered:
+		infof("%s  %s (filtered out)\n", prefix, step.Name)
 		return
 	}
 
 	if err != nil {
-		fmt.Printf("%s  %s FAILED: %v\n", prefix, step.Name, err)
+		fmt.Println(wrapLine(prefix+"  ", fmt.Sprintf("%s FAILED: %v", step.Name, err), len(prefix)+2))
 		return
 	}
 
-	fmt.Printf("%s  %s\n", prefix, step.Name)
+	if flagDryRun {
+		infof("%s  %s (would run)\n", prefix, step.Name)
+	} else {
+		infof("%s  %s\n", prefix, step.Name)
+	}
 
 	if flagExplain && step.Explain != "" {
-		fmt.Printf("         %s\n", step.Explain)
+		infof("%s\n", wrapLine("         ", step.Explain, 9))
 	}
 }
 
 func printSummary(results []module.ModuleResult) {
+	// Modules with no steps had nothing to do and get no row.
+	results = slices.DeleteFunc(slices.Clone(results), func(r module.ModuleResult) bool {
+		return r.Total == 0 && r.Err == nil
+	})
+
 	totalCompleted := 0
 	totalSkipped := 0
+	totalWouldRun := 0
 	totalSteps := 0
 
-	for _, r := range results {
+	rows := make([][]string, len(results))
+	for i, r := range results {
+		status := "done"
+		switch {
+		case errors.Is(r.Err, module.ErrDependencyFailed):
+			status = "NOT RUN"
+		case r.Err != nil:
+			status = fmt.Sprintf("FAILED at %q", r.FailedStep)
+		case r.Unsupported != "":
+			status = "skipped: " + r.Unsupported
+		}
+		counts := fmt.Sprintf("(%d completed, %d skipped)", r.Completed, r.Skipped)
+		if flagDryRun {
+			counts = fmt.Sprintf("(%d would run, %d skipped)", r.WouldRun, r.Skipped)
+		}
+		if r.Filtered > 0 {
+			counts = strings.TrimSuffix(counts, ")") + fmt.Sprintf(", %d filtered)", r.Filtered)
+		}
+		rows[i] = []string{r.ModuleID + ":", status, counts}
+	}
+	lines := tableLines(rows)
+
+	for i, r := range results {
 		totalCompleted += r.Completed
 		totalSkipped += r.Skipped
+		totalWouldRun += r.WouldRun
 		totalSteps += r.Total
 
-		status := "done"
-		if r.Err != nil {
-			status = fmt.Sprintf("FAILED at %q", r.FailedStep)
+		fmt.Print(lines[i])
+		for _, name := range r.RetriedSteps() {
+			fmt.Println(wrapLine("    ", r.AttemptNote(name), 4))
+		}
+		if errors.Is(r.Err, exec.ErrCommandNotFound) {
+			fmt.Println(wrapLine("    ", "A required tool is missing. Run 'shhh setup base' to install Scoop, then re-run.", 4))
+		}
+		if errors.Is(r.Err, platform.ErrPolicyLocked) {
+			fmt.Println(wrapLine("    ", "Your IT policy may be preventing environment changes; contact your admin or run shhh elevated.", 4))
 		}
-		fmt.Printf("  %s: %s (%d completed, %d skipped)\n",
-			r.ModuleID, status, r.Completed, r.Skipped)
 	}
 
-	fmt.Printf("\nTotal: %d steps (%d completed, %d skipped)\n",
-		totalSteps, totalCompleted, totalSkipped)
+	if flagDryRun {
+		fmt.Printf("\nTotal: %d steps (%d would run, %d skipped)\n",
+			totalSteps, totalWouldRun, totalSkipped)
+	} else {
+		fmt.Printf("\nTotal: %d steps (%d completed, %d skipped)\n",
+			totalSteps, totalCompleted, totalSkipped)
+	}
 }
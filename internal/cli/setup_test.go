@@ -0,0 +1,185 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/druarnfield/shhh/internal/config"
+	"github.com/druarnfield/shhh/internal/exec"
+	"github.com/druarnfield/shhh/internal/module"
+	"github.com/druarnfield/shhh/internal/module/setup"
+	"github.com/druarnfield/shhh/internal/platform"
+	"github.com/druarnfield/shhh/internal/platform/mock"
+	"github.com/druarnfield/shhh/internal/state"
+)
+
+func TestWithStateRoot(t *testing.T) {
+	cfg := config.Defaults()
+	st := &state.State{InstallRoot: `D:\dev`}
+
+	got := withStateRoot(cfg, st)
+	if got.Paths.Root != `D:\dev` {
+		t.Errorf("root = %q, want the one from state", got.Paths.Root)
+	}
+	if cfg.Paths.Root != "" {
+		t.Error("withStateRoot should not change the config it was given")
+	}
+
+	cfg.Paths.Root = `E:\tools`
+	if got := withStateRoot(cfg, st); got.Paths.Root != `E:\tools` {
+		t.Errorf("root = %q, want the configured one", got.Paths.Root)
+	}
+}
+
+func TestApplyProxy(t *testing.T) {
+	cfg := config.Defaults()
+	got, err := applyProxy(cfg, platform.ProxyConfig{HTTP: "http://proxy:8080", HTTPS: "http://proxy:8443", NoProxy: "localhost"})
+	if err != nil {
+		t.Fatalf("applyProxy: %v", err)
+	}
+	if got != "http://proxy:8443" {
+		t.Errorf("used %q, want the https proxy", got)
+	}
+	if cfg.Proxy.HTTP != "http://proxy:8080" || cfg.Proxy.HTTPS != "http://proxy:8443" || cfg.Proxy.NoProxy != "localhost" {
+		t.Errorf("proxy = %+v, want the detected one", cfg.Proxy)
+	}
+}
+
+func TestApplyProxy_RejectsMalformed(t *testing.T) {
+	cfg := config.Defaults()
+	// WinINET takes "http=[::1" as a server; it isn't a URL.
+	got, err := applyProxy(cfg, platform.ProxyConfig{HTTP: "http://[::1", HTTPS: "http://proxy:8443"})
+	if err == nil || !strings.Contains(err.Error(), "proxy.http") {
+		t.Fatalf("err = %v, want one naming proxy.http", err)
+	}
+	if got != "" || cfg.Proxy.HTTP != "" || cfg.Proxy.HTTPS != "" {
+		t.Errorf("used %q, proxy = %+v; want the config left alone", got, cfg.Proxy)
+	}
+}
+
+func TestCheckStepPatterns(t *testing.T) {
+	reg := module.NewRegistry()
+	reg.Register(&module.Module{ID: "base", Steps: []module.Step{{Name: "Set HTTP_PROXY"}, {Name: "Build CA bundle"}}})
+
+	if err := checkStepPatterns(reg, []string{"proxy"}, []string{"CA BUNDLE"}); err != nil {
+		t.Errorf("checkStepPatterns = %v, want nil for matching patterns", err)
+	}
+	err := checkStepPatterns(reg, []string{"prxy"}, []string{"bundel"})
+	for _, want := range []string{`--only-step: "prxy" matches no step`, `--skip-step: "bundel" matches no step`} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("checkStepPatterns = %v, want it to mention %s", err, want)
+		}
+	}
+}
+
+// fingerprintFixture returns dependencies backed by mocks, with the state
+// file under a temp home, and a registry holding one module whose step
+// runs run.
+func fingerprintFixture(t *testing.T, run func(context.Context) error) (*setup.Dependencies, *module.Registry) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("USERPROFILE", dir)
+	setFlag(t, &flagSetupYes, true)
+	deps := &setup.Dependencies{
+		Config:    config.Defaults(),
+		Env:       mock.NewUserEnv(),
+		Profile:   mock.NewProfileManager(filepath.Join(dir, "profile.ps1")),
+		CertStore: mock.NewCertStore(nil),
+		Exec:      &exec.MockRunner{},
+		State:     &state.State{},
+	}
+	reg := module.NewRegistry()
+	reg.Register(&module.Module{ID: "base", Steps: []module.Step{{
+		Name:  "Configure",
+		Check: func(context.Context) bool { return false },
+		Run:   run,
+	}}})
+	return deps, reg
+}
+
+// setFlag sets a package flag for the rest of the test.
+func setFlag(t *testing.T, flag *bool, v bool) {
+	t.Helper()
+	old := *flag
+	*flag = v
+	t.Cleanup(func() { *flag = old })
+}
+
+func runCLI(t *testing.T, deps *setup.Dependencies, reg *module.Registry) error {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return runSetupCLI(module.NewRunner(logger, false), reg, deps, logger, "", []string{"base"})
+}
+
+func TestRunSetupCLI_FingerprintRecordedOnlyAfterSuccess(t *testing.T) {
+	fail := errors.New("boom")
+	var runErr error
+	deps, reg := fingerprintFixture(t, func(context.Context) error { return runErr })
+
+	if err := runCLI(t, deps, reg); err != nil {
+		t.Fatalf("runSetupCLI: %v", err)
+	}
+	if deps.State.Fingerprint == "" {
+		t.Fatal("no fingerprint recorded after a successful run")
+	}
+
+	runErr = fail
+	setFlag(t, &flagRecheck, true)
+	if err := runCLI(t, deps, reg); !errors.Is(err, fail) {
+		t.Fatalf("runSetupCLI = %v, want %v", err, fail)
+	}
+	if deps.State.Fingerprint != "" {
+		t.Error("fingerprint kept after a failed run")
+	}
+}
+
+func TestRunSetupCLI_TrustStateRecordsNoFingerprint(t *testing.T) {
+	deps, reg := fingerprintFixture(t, func(context.Context) error { return nil })
+	setFlag(t, &flagTrustState, true)
+	deps.State.Fingerprint = "stale"
+
+	if err := runCLI(t, deps, reg); err != nil {
+		t.Fatalf("runSetupCLI: %v", err)
+	}
+	if deps.State.Fingerprint != "" {
+		t.Errorf("fingerprint = %q after a --trust-state run, want none", deps.State.Fingerprint)
+	}
+}
+
+func TestRunSetupCLI_SkipsWhenFingerprintMatches(t *testing.T) {
+	runs := 0
+	deps, reg := fingerprintFixture(t, func(context.Context) error { runs++; return nil })
+
+	if err := runCLI(t, deps, reg); err != nil {
+		t.Fatalf("runSetupCLI: %v", err)
+	}
+	if err := runCLI(t, deps, reg); err != nil {
+		t.Fatalf("runSetupCLI: %v", err)
+	}
+	if runs != 1 {
+		t.Errorf("step ran %d times, want the re-run with a matching fingerprint skipped", runs)
+	}
+
+	deps.Env.Set("HTTP_PROXY", "http://proxy:8080")
+	deps.State.AddEnvVar("base", "HTTP_PROXY")
+	if err := runCLI(t, deps, reg); err != nil {
+		t.Fatalf("runSetupCLI: %v", err)
+	}
+	if runs != 2 {
+		t.Errorf("step didn't run after the environment changed")
+	}
+
+	setFlag(t, &flagRecheck, true)
+	if err := runCLI(t, deps, reg); err != nil {
+		t.Fatalf("runSetupCLI: %v", err)
+	}
+	if runs != 3 {
+		t.Errorf("step didn't run with --recheck")
+	}
+}
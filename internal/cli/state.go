@@ -0,0 +1,207 @@
+package cli
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/druarnfield/shhh/internal/config"
+	"github.com/druarnfield/shhh/internal/platform"
+	"github.com/druarnfield/shhh/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagPruneYes    bool
+	flagPruneRevert bool
+)
+
+func newStateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "Inspect and maintain the shhh state file",
+	}
+	cmd.AddCommand(newStatePruneCmd())
+	cmd.AddCommand(newStateTimingsCmd())
+	return cmd
+}
+
+func newStateTimingsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "timings",
+		Short: "Show how long each setup step has been taking",
+		Long: fmt.Sprintf("List every step setup has timed, slowest first, with its typical (median) "+
+			"duration and its last %d durations, oldest first, so you can see which steps are slow and "+
+			"whether they are getting slower. Dry runs aren't timed.", state.MaxTimings),
+		Args: cobra.NoArgs,
+		RunE: runStateTimings,
+	}
+}
+
+func runStateTimings(cmd *cobra.Command, args []string) error {
+	st, err := state.Load(config.StateFilePath())
+	if err != nil {
+		return fmt.Errorf("loading state: %w", err)
+	}
+	est := st.Estimates()
+	if len(est) == 0 {
+		fmt.Println("No step timings recorded yet.")
+		return nil
+	}
+
+	// Slowest first; ties in step order.
+	steps := slices.Sorted(maps.Keys(est))
+	slices.SortStableFunc(steps, func(a, b string) int {
+		return cmp.Compare(est[b], est[a])
+	})
+	rows := make([][]string, len(steps))
+	for i, step := range steps {
+		recent := make([]string, len(st.StepTimings[step]))
+		for j, d := range st.StepTimings[step] {
+			recent[j] = d.Round(100 * time.Millisecond).String()
+		}
+		rows[i] = []string{step + ":", est[step].Round(100 * time.Millisecond).String(), strings.Join(recent, " ")}
+	}
+	fmt.Print(renderTable(rows))
+	return nil
+}
+
+func newStatePruneCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune <module...>",
+		Short: "Stop tracking modules you no longer use",
+		Long: "Drop state for every module not listed (base is always kept). Entries still owned by a " +
+			"listed module are kept. With --revert, pruned environment variables and PATH entries are " +
+			"also removed from your user environment, and files shhh wrote are deleted unless you have " +
+			"changed them. Scoop packages are never uninstalled.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: runStatePrune,
+	}
+	cmd.Flags().BoolVarP(&flagPruneYes, "yes", "y", false, "Prune without asking for confirmation")
+	cmd.Flags().BoolVar(&flagPruneRevert, "revert", false, "Also remove pruned env vars and PATH entries from the user environment")
+	return cmd
+}
+
+func runStatePrune(cmd *cobra.Command, args []string) error {
+	path := config.StateFilePath()
+	st, err := state.Load(path)
+	if err != nil {
+		return fmt.Errorf("loading state: %w", err)
+	}
+
+	active := append([]string{"base"}, args...)
+	res := st.Prune(active)
+	if res.Empty() {
+		fmt.Println("Nothing to prune.")
+		return nil
+	}
+
+	fmt.Println("Will stop tracking:")
+	fmt.Print(renderTable(pruneRows(res)))
+	if flagPruneRevert {
+		fmt.Println("\nEnv vars and PATH entries above will also be removed from your user environment,")
+		fmt.Println("and files deleted unless you have changed them since shhh wrote them.")
+	}
+	fmt.Println()
+
+	if !flagPruneYes {
+		if !isTerminal() {
+			return errors.New("refusing to prune without --yes when not running interactively")
+		}
+		if !confirm("Proceed?") {
+			fmt.Println("Aborted.")
+			return nil
+		}
+	}
+
+	if flagPruneRevert {
+		if err := revertPruned(platform.NewUserEnv(), res); err != nil {
+			return err
+		}
+	}
+
+	if err := state.Save(path, st); err != nil {
+		return fmt.Errorf("saving state: %w", err)
+	}
+	fmt.Println("State pruned.")
+	return nil
+}
+
+// revertPruned removes pruned env vars (from the user or system
+// environment shhh wrote them to) and PATH entries from the persistent
+// environment and the current process, and deletes pruned files that still
+// hold what shhh wrote.
+func revertPruned(env platform.UserEnv, res state.PruneResult) error {
+	var errs []error
+	for _, key := range res.EnvVars {
+		scope := platform.SourceUser
+		if slices.Contains(res.SystemEnvVars, key) {
+			scope = platform.SourceSystem
+		}
+		if err := env.DeleteScoped(key, scope); err != nil {
+			errs = append(errs, fmt.Errorf("deleting %s: %w", key, err))
+			continue
+		}
+		os.Unsetenv(key)
+	}
+	for _, dir := range res.PathEntries {
+		if err := env.RemovePath(dir); err != nil {
+			errs = append(errs, fmt.Errorf("removing %s from PATH: %w", dir, err))
+		}
+	}
+	for _, f := range res.Files {
+		removed, err := f.Remove()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("removing %s: %w", f.Path, err))
+		} else if !removed {
+			fmt.Printf("Kept %s: changed or removed since shhh wrote it.\n", f.Path)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// filePaths returns the path of each file.
+func filePaths(files []state.ManagedFile) []string {
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.Path
+	}
+	return paths
+}
+
+// pruneRows lists each non-empty category of res as a "label: items" row.
+func pruneRows(res state.PruneResult) [][]string {
+	var rows [][]string
+	for _, c := range []struct {
+		label string
+		items []string
+	}{
+		{"modules", res.Modules},
+		{"env vars", res.EnvVars},
+		{"PATH", res.PathEntries},
+		{"scoop", res.ScoopPackages},
+		{"files", filePaths(res.Files)},
+	} {
+		if len(c.items) > 0 {
+			rows = append(rows, []string{c.label + ":", strings.Join(c.items, ", ")})
+		}
+	}
+	return rows
+}
+
+// confirm asks a yes/no question on stdin, defaulting to no.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
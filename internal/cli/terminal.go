@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/mattn/go-isatty"
+)
+
+// isTerminal checks if stdout is a terminal (not piped).
+func isTerminal() bool {
+	return fileIsTerminal(os.Stdout)
+}
+
+// stdinIsTerminal reports whether stdin is a terminal someone can answer
+// prompts on.
+func stdinIsTerminal() bool {
+	return fileIsTerminal(os.Stdin)
+}
+
+// fileIsTerminal asks the OS whether f is a console rather than relying on
+// os.ModeCharDevice, which Windows reports for NUL too. mintty (Git Bash)
+// and other Cygwin/MSYS2 terminals connect programs through named pipes,
+// so those pipes count as terminals as well.
+func fileIsTerminal(f *os.File) bool {
+	return term.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// interactiveTerminal reports whether the setup wizard can run: stdout is a
+// terminal that can redraw in place. TERM=dumb terminals (e.g. some editor
+// consoles) can't, so setup uses the plain text output there.
+func interactiveTerminal() bool {
+	return isTerminal() && os.Getenv("TERM") != "dumb"
+}
+
+// useWizard reports whether setup runs the wizard. --tui and --no-tui win
+// over terminal detection, for terminals it gets wrong.
+func useWizard() bool {
+	switch {
+	case flagTUI:
+		return true
+	case flagNoTUI:
+		return false
+	}
+	return interactiveTerminal()
+}
+
+// useAltScreen reports whether the wizard should draw on the terminal's
+// alternate screen. --no-altscreen or a non-empty SHHH_NO_ALTSCREEN keeps
+// it inline, for remote and SSH terminals that misbehave with the alt
+// screen.
+func useAltScreen() bool {
+	return !flagNoAlt && os.Getenv("SHHH_NO_ALTSCREEN") == ""
+}
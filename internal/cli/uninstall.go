@@ -0,0 +1,277 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/druarnfield/shhh/internal/config"
+	"github.com/druarnfield/shhh/internal/exec"
+	"github.com/druarnfield/shhh/internal/module/setup"
+	"github.com/druarnfield/shhh/internal/platform"
+	"github.com/druarnfield/shhh/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagUninstallYes   bool
+	flagUninstallTools bool
+)
+
+func newUninstallCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "uninstall",
+		Short: "Revert every change shhh has made",
+		Long: "Remove the environment variables and PATH entries shhh set, the managed block in your " +
+			"PowerShell profile, the files shhh wrote (unless you have changed them) and the CA bundle, " +
+			"along with the git and npm settings that point at it, then delete the state file. With " +
+			"--tools, Scoop packages shhh installed are uninstalled " +
+			"too. Anything you have already removed by hand is skipped.\n\n" +
+			"With --dry-run, each change is listed and nothing is done. If any change fails the state " +
+			"file is kept, so running uninstall again retries what is left.",
+		Args: cobra.NoArgs,
+		RunE: runUninstall,
+	}
+	cmd.Flags().BoolVarP(&flagUninstallYes, "yes", "y", false, "Uninstall without asking for confirmation")
+	cmd.Flags().BoolVar(&flagUninstallTools, "tools", false, "Also 'scoop uninstall' the packages shhh installed")
+	return cmd
+}
+
+// reversal is one change uninstall makes.
+type reversal struct {
+	desc  string
+	apply func() error
+}
+
+func runUninstall(cmd *cobra.Command, args []string) error {
+	path := config.StateFilePath()
+	st, err := state.Load(path)
+	if err != nil {
+		return fmt.Errorf("loading state: %w", err)
+	}
+	cfg, _, _, err := loadConfig()
+	if err != nil {
+		cfg = config.Defaults()
+	}
+	cfg = withStateRoot(cfg, st)
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	env := platform.NewUserEnv()
+	runner := &exec.DefaultRunner{}
+	profile := platform.NewProfileManager(cfg.Profile.UTF8BOM, runner)
+	steps, kept := uninstallPlan(ctx, st, cfg, env, profile, runner, flagUninstallTools)
+
+	for _, f := range kept {
+		fmt.Printf("Keeping %s: changed since shhh wrote it.\n", f)
+	}
+	if len(steps) == 0 {
+		fmt.Println("Nothing to uninstall.")
+		if flagDryRun {
+			return nil
+		}
+		return clearState(path)
+	}
+
+	if flagDryRun {
+		fmt.Println("Would:")
+	} else {
+		fmt.Println("Will:")
+	}
+	for _, r := range steps {
+		fmt.Println("  " + r.desc)
+	}
+	if !flagDryRun {
+		fmt.Println("  delete the state file " + path)
+	}
+	fmt.Println()
+	if flagDryRun {
+		return nil
+	}
+
+	if !flagUninstallYes {
+		if !stdinIsTerminal() {
+			return errors.New("refusing to uninstall without --yes when not running interactively")
+		}
+		if !confirm("Proceed?") {
+			fmt.Println("Aborted.")
+			return nil
+		}
+	}
+
+	var errs []error
+	for _, r := range steps {
+		if err := r.apply(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.desc, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("state file kept so uninstall can be run again: %w", errors.Join(errs...))
+	}
+	if err := clearState(path); err != nil {
+		return err
+	}
+	fmt.Println("shhh uninstalled. Restart your terminal to pick up the environment changes.")
+	return nil
+}
+
+// uninstallPlan lists the reversals that undo what st records, skipping
+// anything that is already gone. kept lists managed files left alone
+// because they were changed after shhh wrote them.
+func uninstallPlan(ctx context.Context, st *state.State, cfg *config.Config, env platform.UserEnv,
+	profile platform.ProfileManager, runner exec.Runner, tools bool) (steps []reversal, kept []string) {
+	for _, key := range st.ManagedEnvVars {
+		if value, _, err := env.Get(key); err == nil && value == "" {
+			continue
+		}
+		scope, desc := platform.SourceUser, "remove environment variable "+key
+		if st.SystemEnv(key) {
+			scope, desc = platform.SourceSystem, desc+" from the system environment"
+		}
+		steps = append(steps, reversal{
+			desc: desc,
+			apply: func() error {
+				if err := env.DeleteScoped(key, scope); err != nil {
+					return err
+				}
+				os.Unsetenv(key)
+				return nil
+			},
+		})
+	}
+
+	onPath := func(string) bool { return true }
+	if entries, err := env.ListPath(); err == nil {
+		onPath = func(dir string) bool {
+			return slices.ContainsFunc(entries, func(e platform.PathEntry) bool { return strings.EqualFold(e.Dir, dir) })
+		}
+	}
+	for _, dir := range st.ManagedPathEntries {
+		if !onPath(dir) {
+			continue
+		}
+		steps = append(steps, reversal{
+			desc:  "remove " + dir + " from PATH",
+			apply: func() error { return env.RemovePath(dir) },
+		})
+	}
+
+	if block, err := profile.ManagedBlock(); err == nil && block != "" {
+		steps = append(steps, reversal{
+			desc:  "remove the shhh block from " + profile.Path(),
+			apply: profile.RemoveManagedBlock,
+		})
+	}
+
+	caPath := cfg.CABundlePath()
+	var deleting []string
+	for _, f := range st.ManagedFiles {
+		if f.Path == caPath {
+			continue
+		}
+		switch status, err := f.Status(); {
+		case err != nil || status == state.FileMissing:
+		case status == state.FileModified:
+			kept = append(kept, f.Path)
+		default:
+			deleting = append(deleting, f.Path)
+			steps = append(steps, reversal{
+				desc:  "delete " + f.Path,
+				apply: func() error { _, err := f.Remove(); return err },
+			})
+		}
+	}
+	if _, err := os.Stat(caPath); err == nil {
+		steps = append(steps, caSettingReversals(ctx, cfg, runner, deleting)...)
+		steps = append(steps, reversal{
+			desc:  "delete the CA bundle " + caPath,
+			apply: func() error { return os.Remove(caPath) },
+		})
+	}
+
+	if tools && len(st.ScoopPackages) > 0 {
+		listed, err := runner.Run(ctx, "scoop", "list")
+		// Uninstall in reverse, so tools installed later (which may
+		// depend on earlier ones) go first.
+		for _, pkg := range slices.Backward(st.ScoopPackages) {
+			if err == nil && !setup.ScoopListed(listed.Stdout, pkg) {
+				continue
+			}
+			steps = append(steps, reversal{
+				desc: "scoop uninstall " + pkg,
+				apply: func() error {
+					_, err := runner.Run(ctx, "scoop", "uninstall", pkg)
+					return err
+				},
+			})
+		}
+	}
+	return steps, kept
+}
+
+// caSettingReversals undoes the settings outside the environment that
+// point tools at the CA bundle, so deleting it doesn't leave git and npm
+// failing to read a file that is gone: git's http.sslCAInfo and npm's
+// cafile. The CA variables themselves are managed and removed with the
+// rest. Files in deleting are about to go and need no edit.
+func caSettingReversals(ctx context.Context, cfg *config.Config, runner exec.Runner, deleting []string) []reversal {
+	caPath := cfg.CABundlePath()
+	var steps []reversal
+
+	if !cfg.Git.UseInclude || !slices.Contains(deleting, config.GitIncludePath()) {
+		args := append([]string{"config"}, setup.GitScope(cfg)...)
+		got, err := exec.RunOutput(ctx, runner, "git", slices.Concat(args, []string{"--get", "http.sslCAInfo"})...)
+		if err == nil && strings.EqualFold(got, caPath) {
+			steps = append(steps, reversal{
+				desc: "unset git http.sslCAInfo",
+				apply: func() error {
+					_, err := runner.Run(ctx, "git", slices.Concat(args, []string{"--unset", "http.sslCAInfo"})...)
+					return err
+				},
+			})
+		}
+	}
+
+	npmrc := setup.NPMRCPath()
+	if data, err := os.ReadFile(npmrc); err == nil && !slices.Contains(deleting, npmrc) {
+		if kept, ok := withoutNPMCafile(string(data), caPath); ok {
+			steps = append(steps, reversal{
+				desc:  "remove cafile from " + npmrc,
+				apply: func() error { return os.WriteFile(npmrc, []byte(kept), 0o644) },
+			})
+		}
+	}
+	return steps
+}
+
+// withoutNPMCafile returns .npmrc content with the cafile lines that name
+// caPath removed, and whether there were any.
+func withoutNPMCafile(content, caPath string) (string, bool) {
+	lines := strings.SplitAfter(content, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		key, value, ok := strings.Cut(line, "=")
+		if ok && strings.TrimSpace(key) == "cafile" &&
+			strings.EqualFold(strings.Trim(strings.TrimSpace(value), `"'`), caPath) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if len(kept) == len(lines) {
+		return content, false
+	}
+	return strings.Join(kept, ""), true
+}
+
+// clearState deletes the state file; one that doesn't exist is fine.
+func clearState(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("deleting state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/druarnfield/shhh/internal/config"
+	"github.com/druarnfield/shhh/internal/exec"
+	"github.com/druarnfield/shhh/internal/platform"
+	"github.com/druarnfield/shhh/internal/platform/mock"
+	"github.com/druarnfield/shhh/internal/state"
+)
+
+// uninstallFixture points the CA bundle, the home directory and .npmrc at
+// a temp dir and returns a config using it.
+func uninstallFixture(t *testing.T) (*config.Config, string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("USERPROFILE", dir)
+	t.Setenv("NPM_CONFIG_USERCONFIG", filepath.Join(dir, ".npmrc"))
+	cfg := config.Defaults()
+	cfg.Paths.Root = dir
+	return cfg, dir
+}
+
+func descs(steps []reversal) []string {
+	var out []string
+	for _, r := range steps {
+		out = append(out, r.desc)
+	}
+	return out
+}
+
+func TestUninstallPlan_RevertsCASettingsBeforeDeletingBundle(t *testing.T) {
+	cfg, dir := uninstallFixture(t)
+	caPath := cfg.CABundlePath()
+	os.MkdirAll(filepath.Dir(caPath), 0o755)
+	os.WriteFile(caPath, []byte("certs"), 0o644)
+	npmrc := filepath.Join(dir, ".npmrc")
+	os.WriteFile(npmrc, []byte("registry=https://npm.corp\ncafile="+caPath+"\n"), 0o644)
+
+	env := mock.NewUserEnv()
+	env.Set("SSL_CERT_FILE", caPath)
+	st := &state.State{}
+	st.AddEnvVar("base", "SSL_CERT_FILE")
+	runner := &exec.MockRunner{Results: map[string]exec.Result{
+		"git config --global --get http.sslCAInfo":   {Stdout: caPath + "\n"},
+		"git config --global --unset http.sslCAInfo": {},
+	}}
+
+	steps, _ := uninstallPlan(context.Background(), st, cfg, env, mock.NewProfileManager(filepath.Join(dir, "profile.ps1")), runner, false)
+	want := []string{
+		"remove environment variable SSL_CERT_FILE",
+		"unset git http.sslCAInfo",
+		"remove cafile from " + npmrc,
+		"delete the CA bundle " + caPath,
+	}
+	if !slices.Equal(descs(steps), want) {
+		t.Fatalf("plan = %q, want %q", descs(steps), want)
+	}
+	for _, r := range steps {
+		if err := r.apply(); err != nil {
+			t.Fatalf("%s: %v", r.desc, err)
+		}
+	}
+
+	if !slices.Contains(runner.Calls, "git config --global --unset http.sslCAInfo") {
+		t.Errorf("git http.sslCAInfo not unset; calls: %q", runner.Calls)
+	}
+	if data, _ := os.ReadFile(npmrc); string(data) != "registry=https://npm.corp\n" {
+		t.Errorf(".npmrc = %q, want only the user's registry left", data)
+	}
+	if _, err := os.Stat(caPath); !os.IsNotExist(err) {
+		t.Errorf("CA bundle still exists: %v", err)
+	}
+	if _, _, err := env.Get("SSL_CERT_FILE"); err == nil {
+		t.Error("SSL_CERT_FILE still set")
+	}
+}
+
+func TestUninstallPlan_LeavesOtherCASettings(t *testing.T) {
+	cfg, dir := uninstallFixture(t)
+	caPath := cfg.CABundlePath()
+	os.MkdirAll(filepath.Dir(caPath), 0o755)
+	os.WriteFile(caPath, []byte("certs"), 0o644)
+	os.WriteFile(filepath.Join(dir, ".npmrc"), []byte("cafile=C:\\corp\\root.pem\n"), 0o644)
+
+	runner := &exec.MockRunner{Results: map[string]exec.Result{
+		"git config --global --get http.sslCAInfo": {Stdout: `C:\corp\root.pem`},
+	}}
+
+	steps, _ := uninstallPlan(context.Background(), &state.State{}, cfg, mock.NewUserEnv(),
+		mock.NewProfileManager(filepath.Join(dir, "profile.ps1")), runner, false)
+	if want := []string{"delete the CA bundle " + caPath}; !slices.Equal(descs(steps), want) {
+		t.Errorf("plan = %q, want %q", descs(steps), want)
+	}
+}
+
+func TestUninstallPlan_DeletesFromRecordedScope(t *testing.T) {
+	cfg, dir := uninstallFixture(t)
+	env := mock.NewUserEnv()
+	env.SetScoped("HTTP_PROXY", "http://proxy:8080", platform.SourceSystem)
+	env.Set("GOPATH", `C:\dev\go`)
+	st := &state.State{}
+	st.AddEnvVar("base", "HTTP_PROXY")
+	st.SetSystemEnv("HTTP_PROXY", true)
+	st.AddEnvVar("golang", "GOPATH")
+
+	steps, _ := uninstallPlan(context.Background(), st, cfg, env,
+		mock.NewProfileManager(filepath.Join(dir, "profile.ps1")), &exec.MockRunner{}, false)
+	want := []string{
+		"remove environment variable HTTP_PROXY from the system environment",
+		"remove environment variable GOPATH",
+	}
+	if !slices.Equal(descs(steps), want) {
+		t.Fatalf("plan = %q, want %q", descs(steps), want)
+	}
+	for _, r := range steps {
+		if err := r.apply(); err != nil {
+			t.Fatalf("%s: %v", r.desc, err)
+		}
+	}
+	for _, key := range []string{"HTTP_PROXY", "GOPATH"} {
+		if _, _, err := env.Get(key); err == nil {
+			t.Errorf("%s still set", key)
+		}
+	}
+}
+
+func TestUninstallPlan_KeepsChangedFiles(t *testing.T) {
+	cfg, dir := uninstallFixture(t)
+	unchanged := filepath.Join(dir, "unchanged.toml")
+	changed := filepath.Join(dir, "changed.toml")
+	gone := filepath.Join(dir, "gone.toml")
+	st := &state.State{}
+	for _, path := range []string{unchanged, changed, gone} {
+		os.WriteFile(path, []byte("shhh"), 0o644)
+		st.AddManagedFile("rust", path, []byte("shhh"))
+	}
+	os.WriteFile(changed, []byte("edited by hand"), 0o644)
+	os.Remove(gone)
+
+	steps, kept := uninstallPlan(context.Background(), st, cfg, mock.NewUserEnv(),
+		mock.NewProfileManager(filepath.Join(dir, "profile.ps1")), &exec.MockRunner{}, false)
+	if want := []string{"delete " + unchanged}; !slices.Equal(descs(steps), want) {
+		t.Errorf("plan = %q, want %q", descs(steps), want)
+	}
+	if want := []string{changed}; !slices.Equal(kept, want) {
+		t.Errorf("kept = %q, want %q", kept, want)
+	}
+}
+
+func TestUninstallPlan_SkipsPathEntriesAlreadyGone(t *testing.T) {
+	cfg, dir := uninstallFixture(t)
+	env := mock.NewUserEnv()
+	env.AppendPath(`C:\dev\go\bin`)
+	st := &state.State{}
+	st.AddPathEntry("go", `C:\dev\go\bin`)
+	st.AddPathEntry("node", `C:\dev\fnm`)
+
+	steps, _ := uninstallPlan(context.Background(), st, cfg, env,
+		mock.NewProfileManager(filepath.Join(dir, "profile.ps1")), &exec.MockRunner{}, false)
+	if want := []string{`remove C:\dev\go\bin from PATH`}; !slices.Equal(descs(steps), want) {
+		t.Errorf("plan = %q, want %q", descs(steps), want)
+	}
+}
+
+func TestUninstallPlan_ToolsInReverseOrder(t *testing.T) {
+	cfg, dir := uninstallFixture(t)
+	st := &state.State{}
+	for _, pkg := range []string{"git", "jq", "ripgrep"} {
+		st.AddScoopPackage("tools", pkg)
+	}
+	runner := &exec.MockRunner{Results: map[string]exec.Result{
+		"scoop list": {Stdout: "Name    Version\n----    -------\ngit     2.45.1\nripgrep 14.1.0\n"},
+	}}
+
+	steps, _ := uninstallPlan(context.Background(), st, cfg, mock.NewUserEnv(),
+		mock.NewProfileManager(filepath.Join(dir, "profile.ps1")), runner, true)
+	if want := []string{"scoop uninstall ripgrep", "scoop uninstall git"}; !slices.Equal(descs(steps), want) {
+		t.Errorf("plan = %q, want %q", descs(steps), want)
+	}
+}
@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+
+	"github.com/druarnfield/shhh/internal/tui/components"
+)
+
+// defaultWidth is used when stdout is not a terminal or its size is unknown.
+const defaultWidth = 80
+
+var (
+	widthOnce sync.Once
+	width     int
+)
+
+// terminalWidth returns the width of the terminal on stdout. It is queried
+// once; the CLI path doesn't react to resizes mid-run.
+func terminalWidth() int {
+	widthOnce.Do(func() {
+		width = defaultWidth
+		if w, _, err := term.GetSize(os.Stdout.Fd()); err == nil && w > 0 {
+			width = w
+		}
+	})
+	return width
+}
+
+// wrapLine word-wraps prefix+text to the terminal width. Continuation lines
+// are indented by indent spaces so they line up under the text.
+func wrapLine(prefix, text string, indent int) string {
+	return wrapTo(prefix, text, indent, terminalWidth())
+}
+
+// wrapTo is wrapLine for a given width. Widths are measured in terminal
+// cells, so wide characters and styled text wrap where they appear to.
+func wrapTo(prefix, text string, indent, width int) string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return prefix
+	}
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	col := lipgloss.Width(prefix)
+	pad := strings.Repeat(" ", indent)
+
+	for i, w := range words {
+		switch {
+		case i == 0:
+		case col+1+lipgloss.Width(w) > width && col > indent:
+			b.WriteString("\n" + pad)
+			col = indent
+		default:
+			b.WriteByte(' ')
+			col++
+		}
+		b.WriteString(w)
+		col += lipgloss.Width(w)
+	}
+	return b.String()
+}
+
+// renderTable is components.RenderTable with each row's last cell
+// word-wrapped to the terminal width; see tableLines.
+func renderTable(rows [][]string) string {
+	return strings.Join(tableLines(rows), "")
+}
+
+// tableLines renders rows with components.RenderTable and returns one
+// string per row, each ending in a newline. A row's last cell (often an
+// error message) is wrapped to the terminal width and continues under
+// itself, or four spaces in when the other columns take more than half
+// the width.
+func tableLines(rows [][]string) []string {
+	return tableLinesTo(rows, terminalWidth())
+}
+
+func tableLinesTo(rows [][]string, width int) []string {
+	lines := strings.SplitAfter(components.RenderTable(rows, components.DefaultStyles()), "\n")
+	out := make([]string, len(rows))
+	for i, row := range rows {
+		out[i] = lines[i]
+		if len(row) < 2 {
+			continue
+		}
+		line := strings.TrimSuffix(lines[i], "\n")
+		last := row[len(row)-1]
+		if !strings.HasSuffix(line, last) {
+			continue
+		}
+		prefix := strings.TrimSuffix(line, last)
+		indent := lipgloss.Width(prefix)
+		if indent > width/2 {
+			indent = 4
+		}
+		out[i] = wrapTo(prefix, last, indent, width) + "\n"
+	}
+	return out
+}
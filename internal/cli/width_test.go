@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestWrapTo(t *testing.T) {
+	got := wrapTo("- ", "one two three four", 2, 12)
+	want := "- one two\n  three four"
+	if got != want {
+		t.Errorf("wrapTo = %q, want %q", got, want)
+	}
+}
+
+func TestWrapTo_MeasuresCells(t *testing.T) {
+	// Each character is 3 bytes in UTF-8 but 2 cells wide, so measuring
+	// bytes would wrap after every word.
+	text := "证书 代理 路径 变量"
+	for _, line := range strings.Split(wrapTo("", text, 0, 14), "\n") {
+		if w := lipgloss.Width(line); w > 14 {
+			t.Errorf("line %q is %d cells wide, want at most 14", line, w)
+		}
+	}
+	if got := wrapTo("", text, 0, 14); got != "证书 代理 路径\n变量" {
+		t.Errorf("wrapTo = %q", got)
+	}
+}
+
+func TestWrapTo_StyledPrefix(t *testing.T) {
+	prefix := "\x1b[1m>\x1b[0m "
+	if got := wrapTo(prefix, "fits here", 2, 12); strings.Contains(got, "\n") {
+		t.Errorf("escape codes in the prefix should not count toward the width: %q", got)
+	}
+}
+
+func TestTableLinesTo_WrapsLastCell(t *testing.T) {
+	rows := [][]string{
+		{"base:", "done", "(3 completed, 0 skipped)"},
+		{"node:", "FAILED", "installing fnm: scoop exited with status 1"},
+	}
+	lines := tableLinesTo(rows, 40)
+	if len(lines) != 2 {
+		t.Fatalf("got %d rows, want 2", len(lines))
+	}
+	want := "  node:  FAILED  installing fnm: scoop\n" +
+		"                 exited with status 1\n"
+	if lines[1] != want {
+		t.Errorf("row = %q, want %q", lines[1], want)
+	}
+	for _, line := range strings.Split(strings.Join(lines, ""), "\n") {
+		if w := lipgloss.Width(line); w > 40 {
+			t.Errorf("line %q is %d cells wide, want at most 40", line, w)
+		}
+	}
+}
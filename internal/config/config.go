@@ -1,8 +1,19 @@
 package config
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 
 	toml "github.com/pelletier/go-toml/v2"
 )
@@ -18,7 +29,19 @@ type Config struct {
 	Tools      ToolsConfig      `toml:"tools"`
 	Python     PythonConfig     `toml:"python"`
 	Golang     GolangConfig     `toml:"golang"`
+	Rust       RustConfig       `toml:"rust"`
 	Node       NodeConfig       `toml:"node"`
+	Profile    ProfileConfig    `toml:"profile"`
+	Cloud      CloudConfig      `toml:"cloud"`
+	Docker     DockerConfig     `toml:"docker"`
+	Env        EnvConfig        `toml:"env"`
+	Paths      PathsConfig      `toml:"paths"`
+	Modules    ModulesConfig    `toml:"modules"`
+	Safety     SafetyConfig     `toml:"safety"`
+
+	// Warnings are problems LoadFromFile found that didn't stop it loading
+	// the file, such as keys shhh doesn't know.
+	Warnings []string `toml:"-"`
 }
 
 type OrgConfig struct {
@@ -26,19 +49,92 @@ type OrgConfig struct {
 }
 
 type ProxyConfig struct {
+	// Mode is "manual" (default: set the variables below) or "direct" (no
+	// proxy: remove any proxy variables from the environment).
+	Mode    string `toml:"mode" enum:"manual,direct"`
 	HTTP    string `toml:"http"`
 	HTTPS   string `toml:"https"`
 	NoProxy string `toml:"no_proxy"`
+
+	// AutoNoProxy adds the internal hosts named elsewhere in the config
+	// (GitLab, registries, a cert source URL) to NO_PROXY; see
+	// Config.EffectiveNoProxy.
+	AutoNoProxy bool `toml:"auto_no_proxy"`
+
+	// OnConflict is what setup does when a proxy variable holds a value
+	// something other than shhh set: "overwrite" (default) replaces it,
+	// "ask" asks first, or fails the step unless setup --force is given.
+	OnConflict string `toml:"on_conflict" enum:"overwrite,ask"`
+
+	// Scope is where the proxy variables and SSL_CERT_FILE are written:
+	// "user" (default) or "system", the machine-wide environment, for
+	// shared build agents. "system" needs an elevated shell.
+	Scope string `toml:"scope" enum:"user,system"`
 }
 
 type CertsConfig struct {
 	Source string   `toml:"source"`
 	Extra  []string `toml:"extra"`
+
+	// ImportExisting is a combined PEM bundle already on the machine (e.g.
+	// from an imaging script). Its certificates are merged into the shhh
+	// bundle, skipping any already present, rather than appended verbatim.
+	ImportExisting string `toml:"import_existing"`
+
+	// WindowsStores names the Windows system stores the "system" source
+	// reads: "ROOT" (trusted roots) and/or "CA" (intermediates).
+	WindowsStores []string `toml:"windows_stores" enum:"ROOT,CA"`
+
+	// VerifySignatures requires each Extra file to have a detached
+	// signature beside it (see SignaturePath), made with the Ed25519 key
+	// whose public half is TrustedKey, before it is bundled.
+	VerifySignatures bool   `toml:"verify_signatures"`
+	TrustedKey       string `toml:"trusted_key"`
+}
+
+// SignaturePath returns where the signature of the extra cert file at path
+// is kept: path with ".sig" appended. The file holds the base64-encoded
+// Ed25519 signature of the cert file's exact bytes.
+func SignaturePath(path string) string {
+	return path + ".sig"
+}
+
+// PublicKey decodes TrustedKey, a base64-encoded raw Ed25519 public key or
+// a PEM "PUBLIC KEY" block (as 'openssl pkey -pubout' writes).
+func (c CertsConfig) PublicKey() (ed25519.PublicKey, error) {
+	key := strings.TrimSpace(c.TrustedKey)
+	if key == "" {
+		return nil, errors.New("certs.trusted_key: required when certs.verify_signatures is on")
+	}
+	if block, _ := pem.Decode([]byte(key)); block != nil {
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("certs.trusted_key: %w", err)
+		}
+		edKey, ok := pub.(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.New("certs.trusted_key: not an Ed25519 public key")
+		}
+		return edKey, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("certs.trusted_key: not a base64 Ed25519 public key (%d bytes)", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(raw), nil
 }
 
 type GitConfig struct {
 	DefaultBranch string   `toml:"default_branch"`
 	SSHHosts      []string `toml:"ssh_hosts"`
+
+	// UseInclude writes shhh's git settings to GitIncludePath and includes
+	// that file from the global config, instead of editing it directly.
+	UseInclude bool `toml:"use_include"`
+
+	// Version pins git (e.g. "2.45.1"), installed in the base module as
+	// git@Version via Scoop and held there. Empty leaves git to [tools].
+	Version string `toml:"version"`
 }
 
 type GitLabConfig struct {
@@ -50,10 +146,64 @@ type RegistriesConfig struct {
 	PyPIMirror  string `toml:"pypi_mirror"`
 	NPMRegistry string `toml:"npm_registry"`
 	GoProxy     string `toml:"go_proxy"`
+
+	// CargoRegistry is a crates.io mirror; cargo's sparse+https:// form is
+	// accepted.
+	CargoRegistry string `toml:"cargo_registry"`
 }
 
 type ScoopConfig struct {
 	Buckets []string `toml:"buckets"`
+
+	// Update runs 'scoop update' (at most daily) before anything is
+	// installed, so stale buckets don't cause "couldn't find manifest".
+	Update bool `toml:"update"`
+
+	// Version pins Scoop itself (e.g. "0.5.2"). When set, an installed Scoop
+	// reporting another version is updated with 'scoop update scoop'.
+	Version string `toml:"version"`
+
+	// SetExecutionPolicy lets the Scoop bootstrap set the user's PowerShell
+	// execution policy to RemoteSigned. When false, or when that fails, the
+	// installer runs with -ExecutionPolicy Bypass for that process only.
+	SetExecutionPolicy bool `toml:"set_execution_policy"`
+
+	// AutoBucket adds a standard bucket (extras, versions, nerd-fonts) when
+	// a tool fails to install because its manifest lives there, then tries
+	// again. When false the error names the bucket to add instead.
+	AutoBucket bool `toml:"auto_bucket"`
+
+	// Allowed, when set, is the only Scoop packages shhh may install;
+	// Denied are never installed. Both are enforced when modules are built
+	// and checked by Validate; see CheckPackage.
+	Allowed []string `toml:"allowed"`
+	Denied  []string `toml:"denied"`
+}
+
+// CheckPackage reports whether the [scoop] allowed and denied lists let shhh
+// install app. A bucket prefix (extras/vscode) or version (git@2.45.1) on
+// app is ignored, and names match case-insensitively.
+func (s ScoopConfig) CheckPackage(app string) error {
+	name := scoopPackageName(app)
+	listed := func(list []string) bool {
+		return slices.ContainsFunc(list, func(p string) bool { return strings.EqualFold(scoopPackageName(p), name) })
+	}
+	if listed(s.Denied) {
+		return fmt.Errorf("tool %q is denied by [scoop] denied", app)
+	}
+	if len(s.Allowed) > 0 && !listed(s.Allowed) {
+		return fmt.Errorf("tool %q is not in the approved list ([scoop] allowed)", app)
+	}
+	return nil
+}
+
+// scoopPackageName strips any bucket prefix and version from a Scoop app.
+func scoopPackageName(app string) string {
+	if i := strings.LastIndex(app, "/"); i >= 0 {
+		app = app[i+1:]
+	}
+	app, _, _ = strings.Cut(app, "@")
+	return app
 }
 
 type ToolsConfig struct {
@@ -63,28 +213,128 @@ type ToolsConfig struct {
 }
 
 type PythonConfig struct {
-	Version string `toml:"version"`
+	Version         string `toml:"version"`
+	UVInstallMethod string `toml:"uv_install_method" enum:"scoop,standalone"`
 }
 
 type GolangConfig struct {
 	Version string `toml:"version"`
 }
 
+type RustConfig struct {
+	// Version is the rustup toolchain made the default, e.g. "stable" or
+	// "1.82.0".
+	Version string `toml:"version"`
+}
+
 type NodeConfig struct {
 	Version string `toml:"version"`
 }
 
+type CloudConfig struct {
+	Tools []string `toml:"tools"`
+}
+
+type DockerConfig struct {
+	// Install installs the docker CLI and buildx via Scoop. It is off by
+	// default, so running every module doesn't put Docker on every machine.
+	Install bool `toml:"install"`
+
+	// RegistryMirror is pulled from instead of Docker Hub, e.g. an internal
+	// pull-through cache. Empty leaves the daemon config alone.
+	RegistryMirror string `toml:"registry_mirror"`
+}
+
+type EnvConfig struct {
+	// Reset lists variables to delete from the user and process environment
+	// before shhh sets its own values, so a pre-existing value cannot win.
+	Reset []string `toml:"reset"`
+}
+
+type PathsConfig struct {
+	// Root relocates Scoop, GOPATH, the CA bundle and the Python and Node.js
+	// installs under one directory, e.g. on a larger D: drive; see
+	// Config.InstallPath. Empty keeps them under the home directory.
+	Root string `toml:"root"`
+}
+
+type ModulesConfig struct {
+	// Enabled is the modules setup runs, and the wizard pre-selects, when
+	// none are named. Empty means every module.
+	Enabled []string `toml:"enabled"`
+
+	// Disabled is the modules setup leaves out, and the wizard doesn't
+	// offer, unless --all is given. A module another one that isn't
+	// disabled depends on can't be disabled; see Check.
+	Disabled []string `toml:"disabled"`
+}
+
+type SafetyConfig struct {
+	// ConfirmPathChanges makes setup show the current PATH and ask before
+	// adding a directory to it, including before running the Scoop and
+	// standalone uv installers, which add their own. Text output asks at
+	// the prompt and the wizard in a dialog; --yes answers yes, and without
+	// a terminal to ask on the step fails. PATH entries a Scoop package's
+	// manifest adds aren't asked about.
+	ConfirmPathChanges bool `toml:"confirm_path_changes"`
+}
+
+// Check reports module IDs in the enabled and disabled lists that aren't
+// in known, any ID listed in both, and any disabled module that a module
+// left enabled depends on, which setup couldn't then run. dependsOn maps a
+// module ID to the IDs it depends on.
+func (m ModulesConfig) Check(known []string, dependsOn map[string][]string) error {
+	var errs []error
+	for _, list := range []struct {
+		key string
+		ids []string
+	}{{"modules.enabled", m.Enabled}, {"modules.disabled", m.Disabled}} {
+		for _, id := range list.ids {
+			if !slices.Contains(known, id) {
+				errs = append(errs, fmt.Errorf("%s: unknown module %q (known: %s)", list.key, id, strings.Join(known, ", ")))
+			}
+		}
+	}
+	for _, id := range m.Enabled {
+		if slices.Contains(m.Disabled, id) {
+			errs = append(errs, fmt.Errorf("modules: %q is both enabled and disabled", id))
+		}
+	}
+	for _, id := range known {
+		if slices.Contains(m.Disabled, id) {
+			continue
+		}
+		for _, dep := range dependsOn[id] {
+			if slices.Contains(m.Disabled, dep) {
+				errs = append(errs, fmt.Errorf("modules.disabled: %q is needed by %q; disable %q too, or don't disable %q", dep, id, id, dep))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type ProfileConfig struct {
+	// UTF8BOM writes the PowerShell profile with a UTF-8 byte order mark so
+	// Windows PowerShell 5.1 decodes non-ASCII content correctly.
+	UTF8BOM bool `toml:"utf8_bom"`
+}
+
 func Defaults() *Config {
 	return &Config{
-		Certs:  CertsConfig{Source: "system"},
-		Git:    GitConfig{DefaultBranch: "main"},
-		GitLab: GitLabConfig{SSHPort: 22},
-		Python: PythonConfig{Version: "3.12"},
-		Golang: GolangConfig{Version: "1.23"},
-		Node:   NodeConfig{Version: "22"},
+		Certs:   CertsConfig{Source: "system", WindowsStores: []string{"ROOT", "CA"}},
+		Git:     GitConfig{DefaultBranch: "main"},
+		GitLab:  GitLabConfig{SSHPort: 22},
+		Scoop:   ScoopConfig{Update: true, SetExecutionPolicy: true},
+		Python:  PythonConfig{Version: "3.12", UVInstallMethod: "scoop"},
+		Golang:  GolangConfig{Version: "1.23"},
+		Rust:    RustConfig{Version: "stable"},
+		Node:    NodeConfig{Version: "22"},
+		Profile: ProfileConfig{UTF8BOM: true},
 	}
 }
 
+// LoadFromFile reads the config at path over the defaults. Keys shhh doesn't
+// know are not an error; they are listed in the config's Warnings.
 func LoadFromFile(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -92,9 +342,289 @@ func LoadFromFile(path string) (*Config, error) {
 	}
 
 	cfg := Defaults()
-	if err := toml.Unmarshal(data, cfg); err != nil {
-		return nil, fmt.Errorf("parsing config: %w", err)
+	dec := toml.NewDecoder(bytes.NewReader(data)).DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		// Unknown keys are most likely typos, but may be settings from a
+		// newer shhh: warn about them and keep the rest of the file.
+		var strict *toml.StrictMissingError
+		if !errors.As(err, &strict) {
+			return nil, fmt.Errorf("parsing config: %w", err)
+		}
+		for _, e := range strict.Errors {
+			row, _ := e.Position()
+			cfg.Warnings = append(cfg.Warnings,
+				fmt.Sprintf("line %d: unknown key %q is ignored", row, strings.Join(e.Key(), ".")))
+		}
 	}
+	cfg.Expand()
 
 	return cfg, nil
 }
+
+// Expand substitutes environment variables in the settings that hold paths
+// and URLs: the [certs] sources, the [registries] URLs, the [docker]
+// registry mirror, the [proxy] values and [paths] root. Windows %VAR% and
+// braced ${VAR} references are expanded, and an unset variable expands to
+// "". In URLs a bare $ is left alone, so a proxy password containing one
+// survives; in the file paths (certs.extra, certs.import_existing and
+// paths.root) a Unix $VAR such as $HOME is expanded too. LoadFromFile
+// calls it.
+func (c *Config) Expand() {
+	for _, s := range []*string{
+		&c.Certs.Source,
+		&c.Registries.PyPIMirror, &c.Registries.NPMRegistry, &c.Registries.GoProxy, &c.Registries.CargoRegistry,
+		&c.Docker.RegistryMirror,
+		&c.Proxy.HTTP, &c.Proxy.HTTPS, &c.Proxy.NoProxy,
+	} {
+		*s = expandEnv(*s)
+	}
+	for _, s := range []*string{&c.Certs.ImportExisting, &c.Paths.Root} {
+		*s = expandPath(*s)
+	}
+	for i := range c.Certs.Extra {
+		c.Certs.Extra[i] = expandPath(c.Certs.Extra[i])
+	}
+}
+
+// windowsEnvVar matches a %VAR% reference. A name must start with a letter
+// or underscore, so percent-encoding in a URL (%2F) isn't mistaken for one.
+var windowsEnvVar = regexp.MustCompile(`%([A-Za-z_][A-Za-z0-9_]*)%`)
+
+// bracedEnvVar matches a ${VAR} reference. Unlike os.ExpandEnv, a bare
+// $VAR is not one: "pa$$word" in a proxy URL must come through intact.
+var bracedEnvVar = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// bareEnvVar matches a Unix $VAR reference, which only paths expand.
+var bareEnvVar = regexp.MustCompile(`\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// expandEnv expands %VAR% and ${VAR} in s from the process environment.
+// A value without any is returned unchanged.
+func expandEnv(s string) string {
+	if !strings.ContainsAny(s, "%$") {
+		return s
+	}
+	s = windowsEnvVar.ReplaceAllStringFunc(s, func(ref string) string {
+		return os.Getenv(ref[1 : len(ref)-1])
+	})
+	return bracedEnvVar.ReplaceAllStringFunc(s, func(ref string) string {
+		return os.Getenv(ref[2 : len(ref)-1])
+	})
+}
+
+// expandPath is expandEnv for a file or directory path, which also
+// expands a bare $VAR.
+func expandPath(s string) string {
+	return bareEnvVar.ReplaceAllStringFunc(expandEnv(s), func(ref string) string {
+		return os.Getenv(ref[1:])
+	})
+}
+
+// Validate checks values that would otherwise only fail partway through
+// setup, and reports every problem found rather than stopping at the first.
+// Module-specific requirements (see CheckModule) are checked for the given
+// module IDs, or for every module when none are given.
+func (c *Config) Validate(modules ...string) error {
+	var errs []error
+
+	switch c.Proxy.Mode {
+	case "", "manual", "direct":
+	default:
+		errs = append(errs, fmt.Errorf("proxy.mode: %q is not \"manual\" or \"direct\"", c.Proxy.Mode))
+	}
+	switch c.Proxy.OnConflict {
+	case "", "overwrite", "ask":
+	default:
+		errs = append(errs, fmt.Errorf("proxy.on_conflict: %q is not \"overwrite\" or \"ask\"", c.Proxy.OnConflict))
+	}
+	switch c.Proxy.Scope {
+	case "", "user", "system":
+	default:
+		errs = append(errs, fmt.Errorf("proxy.scope: %q is not \"user\" or \"system\"", c.Proxy.Scope))
+	}
+	errs = append(errs, c.Proxy.CheckURLs())
+	errs = append(errs, checkURL("registries.pypi_mirror", c.Registries.PyPIMirror))
+	errs = append(errs, checkURL("registries.npm_registry", c.Registries.NPMRegistry))
+	errs = append(errs, checkURL("registries.go_proxy", c.Registries.GoProxy))
+	errs = append(errs, checkURL("registries.cargo_registry", strings.TrimPrefix(c.Registries.CargoRegistry, "sparse+")))
+	errs = append(errs, checkURL("docker.registry_mirror", c.Docker.RegistryMirror))
+
+	for _, store := range c.Certs.WindowsStores {
+		if store != "ROOT" && store != "CA" {
+			errs = append(errs, fmt.Errorf("certs.windows_stores: %q is not \"ROOT\" or \"CA\"", store))
+		}
+	}
+
+	if c.Certs.VerifySignatures {
+		if _, err := c.Certs.PublicKey(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.Paths.Root != "" && !filepath.IsAbs(c.Paths.Root) {
+		errs = append(errs, fmt.Errorf("paths.root: %q must be an absolute path", c.Paths.Root))
+	}
+
+	if c.GitLab.SSHPort < 1 || c.GitLab.SSHPort > 65535 {
+		errs = append(errs, fmt.Errorf("gitlab.ssh_port: %d is not a valid port", c.GitLab.SSHPort))
+	}
+
+	switch c.Python.UVInstallMethod {
+	case "", "scoop", "standalone":
+	default:
+		errs = append(errs, fmt.Errorf("python.uv_install_method: %q is not \"scoop\" or \"standalone\"", c.Python.UVInstallMethod))
+	}
+
+	if len(modules) == 0 {
+		for _, v := range c.moduleVersions() {
+			modules = append(modules, v.module)
+		}
+		modules = append(modules, "base", "tools", "cloud")
+	}
+	for _, id := range modules {
+		errs = append(errs, c.CheckModule(id))
+	}
+
+	return errors.Join(errs...)
+}
+
+// EffectiveNoProxy returns the NO_PROXY value setup writes: proxy.no_proxy,
+// followed, with proxy.auto_no_proxy, by the hosts of gitlab.host, the
+// registry URLs and any cert source URLs that it doesn't already list.
+func (c *Config) EffectiveNoProxy() string {
+	if !c.Proxy.AutoNoProxy {
+		return c.Proxy.NoProxy
+	}
+
+	var entries []string
+	seen := make(map[string]bool)
+	add := func(host string) {
+		host = strings.TrimSpace(host)
+		if host == "" || seen[strings.ToLower(host)] {
+			return
+		}
+		seen[strings.ToLower(host)] = true
+		entries = append(entries, host)
+	}
+	for _, entry := range strings.Split(c.Proxy.NoProxy, ",") {
+		add(entry)
+	}
+
+	// gitlab.host is usually a bare host name, but accept a URL too.
+	if strings.Contains(c.GitLab.Host, "://") {
+		add(urlHost(c.GitLab.Host))
+	} else {
+		add(c.GitLab.Host)
+	}
+	add(urlHost(c.Registries.PyPIMirror))
+	add(urlHost(c.Registries.NPMRegistry))
+	add(urlHost(c.Registries.GoProxy))
+	add(urlHost(strings.TrimPrefix(c.Registries.CargoRegistry, "sparse+")))
+	add(urlHost(c.Docker.RegistryMirror))
+	add(urlHost(c.Certs.Source))
+	for _, extra := range c.Certs.Extra {
+		add(urlHost(extra))
+	}
+
+	return strings.Join(entries, ",")
+}
+
+// urlHost returns the host name of an http(s) URL, or "" for anything
+// else (a file path, "system", an empty setting).
+func urlHost(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// moduleVersion is the version setting a language module installs.
+type moduleVersion struct {
+	module, key, name, value string
+}
+
+func (c *Config) moduleVersions() []moduleVersion {
+	return []moduleVersion{
+		{"python", "python.version", "Python", c.Python.Version},
+		{"golang", "golang.version", "Go", c.Golang.Version},
+		{"rust", "rust.version", "Rust", c.Rust.Version},
+		{"node", "node.version", "Node.js", c.Node.Version},
+	}
+}
+
+// CheckModule reports whether the config has what the module with the given
+// ID needs to run, e.g. a language module's version, and whether the tools
+// it installs are allowed by [scoop] allowed and denied. Modules without
+// requirements always pass.
+func (c *Config) CheckModule(id string) error {
+	for _, v := range c.moduleVersions() {
+		if v.module == id && v.value == "" {
+			return fmt.Errorf("%s is required to set up %s", v.key, v.name)
+		}
+	}
+	var errs []error
+	for _, list := range c.moduleTools() {
+		if list.module != id {
+			continue
+		}
+		for _, tool := range list.tools {
+			if err := c.Scoop.CheckPackage(tool); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", list.key, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DockerPackages are the Scoop packages the docker module installs: the
+// Docker CLI and the buildx plugin.
+var DockerPackages = []string{"docker", "docker-buildx"}
+
+// moduleTool is a list of Scoop packages a module installs.
+type moduleTool struct {
+	module, key string
+	tools       []string
+}
+
+func (c *Config) moduleTools() []moduleTool {
+	tools := []moduleTool{
+		{"tools", "tools.core", c.Tools.Core},
+		{"tools", "tools.data", c.Tools.Data},
+		{"tools", "tools.optional", c.Tools.Optional},
+		{"cloud", "cloud.tools", c.Cloud.Tools},
+		{"golang", "golang", []string{"go"}},
+		{"node", "node", []string{"fnm"}},
+	}
+	if c.Docker.Install {
+		tools = append(tools, moduleTool{"docker", "docker.install", DockerPackages})
+	}
+	if c.Git.Version != "" {
+		tools = append(tools, moduleTool{"base", "git.version", []string{"git"}})
+	}
+	if c.Python.UVInstallMethod != "standalone" {
+		tools = append(tools, moduleTool{"python", "python.uv_install_method", []string{"uv"}})
+	}
+	return tools
+}
+
+// checkURL reports an error if value is set but isn't an absolute http(s)
+// URL. An empty value means the setting is unused.
+// CheckURLs reports whether p's http and https proxies, where set, are
+// http:// or https:// URLs, as Validate does.
+func (p ProxyConfig) CheckURLs() error {
+	return errors.Join(checkURL("proxy.http", p.HTTP), checkURL("proxy.https", p.HTTPS))
+}
+
+func checkURL(key, value string) error {
+	if value == "" {
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s: %q must be an http:// or https:// URL", key, value)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// versionPatterns say what each version setting may hold: what uv, Scoop,
+// fnm and rustup accept for it.
+var versionPatterns = []struct {
+	key     string
+	value   func(*Config) string
+	pattern *regexp.Regexp
+}{
+	{"python.version", func(c *Config) string { return c.Python.Version }, regexp.MustCompile(`^\d+\.\d+(\.\d+)?$`)},
+	{"golang.version", func(c *Config) string { return c.Golang.Version }, regexp.MustCompile(`^\d+\.\d+(\.\d+)?$`)},
+	{"node.version", func(c *Config) string { return c.Node.Version }, regexp.MustCompile(`^(v?\d+(\.\d+){0,2}|latest|lts-latest|lts/[a-z]+)$`)},
+	{"rust.version", func(c *Config) string { return c.Rust.Version }, regexp.MustCompile(`^((stable|beta|nightly)(-\d{4}-\d{2}-\d{2})?|\d+\.\d+(\.\d+)?)$`)},
+	{"git.version", func(c *Config) string { return c.Git.Version }, regexp.MustCompile(`^\d+(\.\d+)*$`)},
+	{"scoop.version", func(c *Config) string { return c.Scoop.Version }, regexp.MustCompile(`^v?\d+(\.\d+)*$`)},
+}
+
+// Lint runs Validate and the checks worth making before a config is shared
+// ('shhh config validate'): that version settings look like versions, and
+// that the certs.extra and certs.import_existing files exist and hold PEM
+// certificates. It reads those files and changes nothing. Every problem
+// found is reported.
+func (c *Config) Lint() error {
+	errs := []error{c.Validate()}
+
+	for _, v := range versionPatterns {
+		if value := v.value(c); value != "" && !v.pattern.MatchString(value) {
+			errs = append(errs, fmt.Errorf("%s: %q doesn't look like a version", v.key, value))
+		}
+	}
+
+	for _, path := range c.Certs.Extra {
+		if urlHost(path) == "" {
+			errs = append(errs, checkPEMFile("certs.extra", path))
+		}
+	}
+	if c.Certs.ImportExisting != "" {
+		errs = append(errs, checkPEMFile("certs.import_existing", c.Certs.ImportExisting))
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkPEMFile reports an error unless path is a readable file holding at
+// least one PEM block.
+func checkPEMFile(key, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	if block, _ := pem.Decode(data); block == nil {
+		return fmt.Errorf("%s: %s holds no PEM certificates", key, path)
+	}
+	return nil
+}
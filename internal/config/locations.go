@@ -24,6 +24,47 @@ func ConfigFilePath() string {
 	return filepath.Join(ConfigDir(), "shhh.toml")
 }
 
+// FindConfig looks for a repo-local shhh.toml, like git looks for .git: it
+// checks the current directory and each parent up to and including the home
+// directory (or the filesystem root when the working directory is outside
+// home).
+//
+// Config precedence in runSetup is:
+//  1. shhh.toml found by FindConfig
+//  2. shhh.toml next to the shhh executable
+//  3. ~/.config/shhh/shhh.toml
+func FindConfig() (string, bool) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+	home, _ := os.UserHomeDir()
+	return findConfigFrom(cwd, home)
+}
+
+// findConfigFrom walks from dir towards the root looking for shhh.toml,
+// stopping after stop has been checked.
+func findConfigFrom(dir, stop string) (string, bool) {
+	dir = filepath.Clean(dir)
+	if stop != "" {
+		stop = filepath.Clean(stop)
+	}
+	for {
+		candidate := filepath.Join(dir, "shhh.toml")
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, true
+		}
+		if dir == stop {
+			return "", false
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
 func StateFilePath() string {
 	return filepath.Join(ConfigDir(), "state.json")
 }
@@ -35,3 +76,36 @@ func LogFilePath() string {
 func CABundlePath() string {
 	return filepath.Join(ConfigDir(), "ca-bundle.pem")
 }
+
+// CABundlePath is where setup writes the CA bundle: under [paths] root when
+// one is set, otherwise the package-level CABundlePath.
+func (c *Config) CABundlePath() string {
+	if c.Paths.Root != "" {
+		return filepath.Join(c.Paths.Root, "shhh", "ca-bundle.pem")
+	}
+	return CABundlePath()
+}
+
+// InstallPath joins elem to [paths] root, or to the home directory when no
+// root is set, e.g. InstallPath("scoop") for Scoop's install directory.
+func (c *Config) InstallPath(elem ...string) string {
+	base := c.Paths.Root
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = "."
+		}
+		base = home
+	}
+	return filepath.Join(append([]string{base}, elem...)...)
+}
+
+// GitIncludePath is where shhh writes its git settings when [git]
+// use_include is set; the global git config includes it.
+func GitIncludePath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".", ".gitconfig-shhh")
+	}
+	return filepath.Join(home, ".gitconfig-shhh")
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// schemaDialect is the JSON Schema draft Schema generates.
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema returns a JSON Schema describing shhh.toml, for editors that
+// validate and complete TOML against one. It is generated from the Config
+// structs: every toml tag is a property with the field's type and its
+// value from Defaults, if any. A field tagged enum:"a,b" may only hold
+// those values (for a list, each item may).
+func Schema() ([]byte, error) {
+	schema := typeSchema(reflect.TypeOf(Config{}), reflect.ValueOf(*Defaults()))
+	schema["$schema"] = schemaDialect
+	schema["title"] = "shhh.toml"
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// typeSchema describes t. def is t's default value, used for the defaults
+// of struct fields.
+func typeSchema(t reflect.Type, def reflect.Value) map[string]any {
+	switch t.Kind() {
+	case reflect.Struct:
+		props := make(map[string]any)
+		for i := range t.NumField() {
+			f := t.Field(i)
+			key, _, _ := strings.Cut(f.Tag.Get("toml"), ",")
+			if key == "" || key == "-" || !f.IsExported() {
+				continue
+			}
+			prop := typeSchema(f.Type, def.Field(i))
+			if enum := f.Tag.Get("enum"); enum != "" {
+				target := prop
+				if items, ok := prop["items"].(map[string]any); ok {
+					target = items
+				}
+				target["enum"] = strings.Split(enum, ",")
+			}
+			if v := def.Field(i); f.Type.Kind() != reflect.Struct && !v.IsZero() {
+				prop["default"] = v.Interface()
+			}
+			props[key] = prop
+		}
+		return map[string]any{
+			"type":                 "object",
+			"properties":           props,
+			"additionalProperties": false,
+		}
+	case reflect.Slice:
+		return map[string]any{
+			"type":  "array",
+			"items": typeSchema(t.Elem(), reflect.Zero(t.Elem())),
+		}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	default:
+		return map[string]any{"type": "string"}
+	}
+}
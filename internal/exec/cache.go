@@ -0,0 +1,149 @@
+package exec
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// CachingRunner wraps a Runner and memoises the results of commands that have
+// been explicitly marked cacheable, so read-only queries such as "scoop list"
+// run at most once per shhh invocation no matter how many steps ask.
+//
+// Any command that is not cacheable is assumed to change the system (e.g.
+// "scoop install") and clears the cache, so later queries see fresh output.
+type CachingRunner struct {
+	runner    Runner
+	cacheable map[string]bool
+
+	mu      sync.Mutex
+	results map[string]cachedResult
+}
+
+type cachedResult struct {
+	result Result
+	err    error
+}
+
+// NewCachingRunner returns a CachingRunner that caches the given command keys.
+// Keys use the same "name arg1 arg2 ..." form as MockRunner.
+func NewCachingRunner(r Runner, cacheable ...string) *CachingRunner {
+	c := &CachingRunner{
+		runner:    r,
+		cacheable: make(map[string]bool, len(cacheable)),
+		results:   make(map[string]cachedResult),
+	}
+	for _, key := range cacheable {
+		c.cacheable[key] = true
+	}
+	return c
+}
+
+// Run returns the cached result for cacheable commands, running them on first
+// use. Concurrent callers of the same cacheable command wait for the first
+// run rather than starting their own.
+func (c *CachingRunner) Run(ctx context.Context, name string, args ...string) (Result, error) {
+	key := commandKey(name, args)
+
+	if !c.cacheable[key] {
+		if cache, ok := ctx.Value(readOnlyKey{}).(*readOnlyCache); ok {
+			return cache.run(ctx, c.runner, key, name, args)
+		}
+		result, err := c.runner.Run(ctx, name, args...)
+		c.Invalidate()
+		return result, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached, ok := c.results[key]; ok {
+		return cached.result, cached.err
+	}
+
+	result, err := c.runner.Run(ctx, name, args...)
+	// Don't remember cancellations; the next caller may have a live context.
+	if ctx.Err() == nil {
+		c.results[key] = cachedResult{result: result, err: err}
+	}
+	return result, err
+}
+
+// Invalidate drops every cached result.
+func (c *CachingRunner) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.results)
+}
+
+// readOnlyKey is the context key WithReadOnlyCache stores its cache under.
+type readOnlyKey struct{}
+
+// readOnlyCache memoises every command run under one WithReadOnlyCache
+// context. Callers of a command already running wait for its result.
+type readOnlyCache struct {
+	mu      sync.Mutex
+	entries map[string]*readOnlyEntry
+}
+
+type readOnlyEntry struct {
+	done chan struct{} // closed once result is set, or cancelled is
+	cachedResult
+
+	// cancelled means the run was cut short by its caller's context, so
+	// nothing was stored and waiters should run the command themselves.
+	cancelled bool
+}
+
+// WithReadOnlyCache returns a context under which a CachingRunner runs each
+// distinct command at most once and doesn't take any of them to change the
+// system, so queries like "go env GOPROXY" are shared by every caller, even
+// concurrent ones, instead of clearing the cache. Use it only where every
+// command is read-only, e.g. while evaluating step Checks for a plan.
+func WithReadOnlyCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyKey{}, &readOnlyCache{entries: make(map[string]*readOnlyEntry)})
+}
+
+func (c *readOnlyCache) run(ctx context.Context, r Runner, key, name string, args []string) (Result, error) {
+	for {
+		c.mu.Lock()
+		e, ok := c.entries[key]
+		if !ok {
+			break // with c.mu held
+		}
+		c.mu.Unlock()
+		select {
+		case <-e.done:
+			if !e.cancelled {
+				return e.result, e.err
+			}
+		case <-ctx.Done():
+			return Result{}, ctx.Err()
+		}
+	}
+	e := &readOnlyEntry{done: make(chan struct{})}
+	c.entries[key] = e
+	c.mu.Unlock()
+
+	result, err := r.Run(ctx, name, args...)
+	// Don't remember cancellations, e.g. one check's timeout; the next
+	// caller may have a live context.
+	if ctx.Err() != nil {
+		c.mu.Lock()
+		delete(c.entries, key)
+		c.mu.Unlock()
+		e.cancelled = true
+	} else {
+		e.result, e.err = result, err
+	}
+	close(e.done)
+	return result, err
+}
+
+// commandKey formats a command as "name arg1 arg2 ...".
+func commandKey(name string, args []string) string {
+	if len(args) == 0 {
+		return name
+	}
+	return name + " " + strings.Join(args, " ")
+}
@@ -0,0 +1,152 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestCachingRunner_CachesMarkedCommands(t *testing.T) {
+	mock := &MockRunner{Results: map[string]Result{
+		"scoop list": {Stdout: "git\njq\n"},
+	}}
+	c := NewCachingRunner(mock, "scoop list")
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		result, err := c.Run(ctx, "scoop", "list")
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		if result.Stdout != "git\njq\n" {
+			t.Errorf("stdout = %q", result.Stdout)
+		}
+	}
+
+	if len(mock.Calls) != 1 {
+		t.Errorf("underlying calls = %v, want 1 call", mock.Calls)
+	}
+}
+
+func TestCachingRunner_CachesErrors(t *testing.T) {
+	mock := &MockRunner{Results: map[string]Result{}}
+	c := NewCachingRunner(mock, "scoop list")
+	ctx := context.Background()
+
+	if _, err := c.Run(ctx, "scoop", "list"); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := c.Run(ctx, "scoop", "list"); err == nil {
+		t.Fatal("expected cached error")
+	}
+	if len(mock.Calls) != 1 {
+		t.Errorf("underlying calls = %v, want 1 call", mock.Calls)
+	}
+}
+
+func TestCachingRunner_OtherCommandsInvalidate(t *testing.T) {
+	mock := &MockRunner{Results: map[string]Result{
+		"scoop list":       {Stdout: "git\n"},
+		"scoop install jq": {},
+	}}
+	c := NewCachingRunner(mock, "scoop list")
+	ctx := context.Background()
+
+	c.Run(ctx, "scoop", "list")
+	c.Run(ctx, "scoop", "install", "jq")
+	c.Run(ctx, "scoop", "list")
+
+	want := []string{"scoop list", "scoop install jq", "scoop list"}
+	if len(mock.Calls) != len(want) {
+		t.Fatalf("calls = %v, want %v", mock.Calls, want)
+	}
+	for i := range want {
+		if mock.Calls[i] != want[i] {
+			t.Errorf("calls[%d] = %q, want %q", i, mock.Calls[i], want[i])
+		}
+	}
+}
+
+func TestCachingRunner_ReadOnlyCacheRunsEachCommandOnce(t *testing.T) {
+	mock := &MockRunner{Results: map[string]Result{
+		"go env GOPATH": {Stdout: "C:\\go\n"},
+		"scoop list":    {Stdout: "git\n"},
+	}}
+	c := NewCachingRunner(mock, "scoop list")
+	ctx := WithReadOnlyCache(context.Background())
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			c.Run(ctx, "go", "env", "GOPATH")
+			c.Run(ctx, "scoop", "list")
+		})
+	}
+	wg.Wait()
+	if len(mock.Calls) != 2 {
+		t.Errorf("underlying calls = %v, want each command once", mock.Calls)
+	}
+
+	// Outside the read-only context, other commands run and invalidate as usual.
+	c.Run(context.Background(), "go", "env", "GOPATH")
+	c.Run(context.Background(), "scoop", "list")
+	if len(mock.Calls) != 4 {
+		t.Errorf("underlying calls = %v, want go env and scoop list run again", mock.Calls)
+	}
+}
+
+// slowRunner's first Run blocks until its context is done; later ones
+// return ok.
+type slowRunner struct {
+	started chan struct{}
+	mu      sync.Mutex
+	calls   int
+}
+
+func (r *slowRunner) Run(ctx context.Context, name string, args ...string) (Result, error) {
+	r.mu.Lock()
+	r.calls++
+	first := r.calls == 1
+	r.mu.Unlock()
+	if first {
+		close(r.started)
+		<-ctx.Done()
+		return Result{}, ctx.Err()
+	}
+	return Result{Stdout: "ok"}, nil
+}
+
+func TestCachingRunner_ReadOnlyCacheForgetsCancelledRuns(t *testing.T) {
+	slow := &slowRunner{started: make(chan struct{})}
+	c := NewCachingRunner(slow)
+	ctx := WithReadOnlyCache(context.Background())
+	first, cancel := context.WithCancel(ctx)
+
+	firstErr := make(chan error)
+	go func() {
+		_, err := c.Run(first, "scoop", "list")
+		firstErr <- err
+	}()
+	<-slow.started
+
+	waiter := make(chan Result)
+	go func() {
+		result, _ := c.Run(ctx, "scoop", "list")
+		waiter <- result
+	}()
+	cancel()
+
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled caller got %v, want context.Canceled", err)
+	}
+	if result := <-waiter; result.Stdout != "ok" {
+		t.Errorf("waiting caller got %q, want the result of its own run", result.Stdout)
+	}
+	if result, err := c.Run(ctx, "scoop", "list"); err != nil || result.Stdout != "ok" {
+		t.Errorf("later caller got %q, %v, want the cached result", result.Stdout, err)
+	}
+	if slow.calls != 2 {
+		t.Errorf("underlying calls = %d, want the cancelled run and one more", slow.calls)
+	}
+}
@@ -3,11 +3,29 @@ package exec
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os/exec"
+	"regexp"
 	"strings"
+	"sync"
 )
 
+// ErrCommandNotFound is returned (wrapped) when the command to run is not on
+// PATH, as opposed to running and failing.
+var ErrCommandNotFound = errors.New("command not found")
+
+// ErrExit is matched (via errors.Is) by the error returned when a command
+// ran and exited with a non-zero code.
+var ErrExit = errors.New("command exited with a non-zero code")
+
+// exitError marks err as a non-zero exit without changing its message.
+type exitError struct{ err error }
+
+func (e *exitError) Error() string        { return e.err.Error() }
+func (e *exitError) Unwrap() error        { return e.err }
+func (e *exitError) Is(target error) bool { return target == ErrExit }
+
 // Result holds the output and exit code of a command execution.
 type Result struct {
 	Stdout   string
@@ -46,8 +64,12 @@ func Run(ctx context.Context, name string, args ...string) (Result, error) {
 	}
 
 	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return result, fmt.Errorf("command %q: %w", name, ErrCommandNotFound)
+		}
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			result.ExitCode = exitErr.ExitCode()
+			err = &exitError{err}
 		}
 		return result, fmt.Errorf("command %q failed: %w\nstderr: %s", name, err, stderr.String())
 	}
@@ -55,6 +77,17 @@ func Run(ctx context.Context, name string, args ...string) (Result, error) {
 	return result, nil
 }
 
+// RunOutput runs the named command with runner and returns its stdout with
+// Windows line endings turned into "\n" and surrounding whitespace trimmed,
+// the form Checks compare against config values. The error is runner's.
+func RunOutput(ctx context.Context, runner Runner, name string, args ...string) (string, error) {
+	result, err := runner.Run(ctx, name, args...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.ReplaceAll(result.Stdout, "\r\n", "\n")), nil
+}
+
 // CommandExists checks whether a command is available on the system PATH.
 func CommandExists(name string) bool {
 	_, err := exec.LookPath(name)
@@ -65,20 +98,66 @@ func CommandExists(name string) bool {
 type MockRunner struct {
 	Results map[string]Result
 	Calls   []string
+
+	// Missing lists command names that behave as if not installed: any
+	// invocation fails with ErrCommandNotFound.
+	Missing []string
+
+	// Match is consulted when no Results key matches exactly, so a test can
+	// answer a whole family of commands (see MatchGlobs). It reports whether
+	// it handled the command. Optional.
+	Match func(name string, args []string) (Result, bool)
+
+	mu sync.Mutex // guards Calls, for concurrent planning
 }
 
-// Run looks up the command key in the Results map and returns the matching result.
-// The key is formed as "name arg1 arg2 ...".
-func (m *MockRunner) Run(ctx context.Context, name string, args ...string) (Result, error) {
-	key := name
-	if len(args) > 0 {
-		key = name + " " + strings.Join(args, " ")
+// Glob pairs a command-key pattern with the result MatchGlobs returns for
+// it. In Pattern, * matches any run of characters (including spaces and
+// path separators), e.g. "scoop install *".
+type Glob struct {
+	Pattern string
+	Result  Result
+}
+
+// MatchGlobs returns a MockRunner.Match function that answers with the
+// result of the first glob whose pattern matches the whole command key.
+func MatchGlobs(globs ...Glob) func(name string, args []string) (Result, bool) {
+	res := make([]*regexp.Regexp, len(globs))
+	for i, g := range globs {
+		res[i] = regexp.MustCompile("^" + strings.ReplaceAll(regexp.QuoteMeta(g.Pattern), `\*`, ".*") + "$")
 	}
+	return func(name string, args []string) (Result, bool) {
+		key := commandKey(name, args)
+		for i, re := range res {
+			if re.MatchString(key) {
+				return globs[i].Result, true
+			}
+		}
+		return Result{}, false
+	}
+}
+
+// Run looks up the command key in the Results map and returns the matching result,
+// falling back to Match. The key is formed as "name arg1 arg2 ...".
+func (m *MockRunner) Run(ctx context.Context, name string, args ...string) (Result, error) {
+	key := commandKey(name, args)
+	m.mu.Lock()
 	m.Calls = append(m.Calls, key)
+	m.mu.Unlock()
+
+	for _, missing := range m.Missing {
+		if missing == name {
+			return Result{}, fmt.Errorf("command %q: %w", name, ErrCommandNotFound)
+		}
+	}
 
-	if result, ok := m.Results[key]; ok {
+	result, ok := m.Results[key]
+	if !ok && m.Match != nil {
+		result, ok = m.Match(name, args)
+	}
+	if ok {
 		if result.ExitCode != 0 {
-			return result, fmt.Errorf("command %q exited with code %d", key, result.ExitCode)
+			return result, &exitError{fmt.Errorf("command %q exited with code %d", key, result.ExitCode)}
 		}
 		return result, nil
 	}
@@ -2,8 +2,10 @@ package exec
 
 import (
 	"context"
+	"errors"
 	"runtime"
 	"testing"
+	"time"
 )
 
 func TestRun_SimpleCommand(t *testing.T) {
@@ -30,14 +32,41 @@ func TestRun_FailingCommand(t *testing.T) {
 
 	_, err := Run(context.Background(), "false")
 	if err == nil {
-		t.Error("expected error for failing command")
+		t.Fatal("expected error for failing command")
+	}
+	if !errors.Is(err, ErrExit) {
+		t.Errorf("error = %v, want ErrExit", err)
 	}
 }
 
 func TestRun_CommandNotFound(t *testing.T) {
 	_, err := Run(context.Background(), "nonexistent_command_12345")
 	if err == nil {
-		t.Error("expected error for missing command")
+		t.Fatal("expected error for missing command")
+	}
+	if !errors.Is(err, ErrCommandNotFound) {
+		t.Errorf("error = %v, want ErrCommandNotFound", err)
+	}
+	if errors.Is(err, ErrExit) {
+		t.Error("a missing command never ran, so it didn't exit")
+	}
+}
+
+func TestRun_CancelKillsChild(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses unix commands")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := Run(ctx, "sleep", "10")
+	if err == nil {
+		t.Fatal("expected error from cancelled command")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("child was not killed on cancel (took %v)", elapsed)
 	}
 }
 
@@ -54,6 +83,26 @@ func TestCommandExists(t *testing.T) {
 	}
 }
 
+func TestRunOutput(t *testing.T) {
+	mock := &MockRunner{
+		Results: map[string]Result{
+			"git config http.sslCAInfo": {Stdout: "  C:\\certs\\ca.pem\r\n"},
+			"fnm list":                  {Stdout: "* v20.11.0 default\r\n* v22.1.0\r\n"},
+		},
+	}
+	ctx := context.Background()
+
+	if got, err := RunOutput(ctx, mock, "git", "config", "http.sslCAInfo"); err != nil || got != `C:\certs\ca.pem` {
+		t.Errorf("RunOutput = %q, %v; want trimmed path", got, err)
+	}
+	if got, _ := RunOutput(ctx, mock, "fnm", "list"); got != "* v20.11.0 default\n* v22.1.0" {
+		t.Errorf("CRLF not normalised: %q", got)
+	}
+	if got, err := RunOutput(ctx, mock, "go", "version"); err == nil || got != "" {
+		t.Errorf("failed command should return an error and no output, got %q, %v", got, err)
+	}
+}
+
 func TestMockRunner(t *testing.T) {
 	mock := &MockRunner{
 		Results: map[string]Result{
@@ -68,4 +117,35 @@ func TestMockRunner(t *testing.T) {
 	if result.Stdout != "git version 2.43.0\n" {
 		t.Errorf("stdout = %q", result.Stdout)
 	}
+
+	mock.Results["git fetch"] = Result{ExitCode: 128}
+	if _, err := mock.Run(context.Background(), "git", "fetch"); !errors.Is(err, ErrExit) {
+		t.Errorf("non-zero exit error = %v, want ErrExit", err)
+	}
+}
+
+func TestMockRunner_MatchGlobs(t *testing.T) {
+	mock := &MockRunner{
+		Results: map[string]Result{
+			"npm config set cafile /exact": {Stdout: "exact\n"},
+		},
+		Match: MatchGlobs(
+			Glob{Pattern: "npm config set cafile *", Result: Result{Stdout: "glob\n"}},
+			Glob{Pattern: "scoop install *", Result: Result{ExitCode: 1}},
+		),
+	}
+	ctx := context.Background()
+
+	if r, err := mock.Run(ctx, "npm", "config", "set", "cafile", "/exact"); err != nil || r.Stdout != "exact\n" {
+		t.Errorf("exact key should win over globs: %q, %v", r.Stdout, err)
+	}
+	if r, err := mock.Run(ctx, "npm", "config", "set", "cafile", `C:\Users\dev\ca.pem`); err != nil || r.Stdout != "glob\n" {
+		t.Errorf("glob should match any path: %q, %v", r.Stdout, err)
+	}
+	if _, err := mock.Run(ctx, "scoop", "install", "jq"); err == nil {
+		t.Error("glob result with a non-zero exit code should fail")
+	}
+	if _, err := mock.Run(ctx, "npm", "config", "get", "cafile"); err == nil {
+		t.Error("unmatched command should fail")
+	}
 }
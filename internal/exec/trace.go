@@ -0,0 +1,41 @@
+package exec
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// TracingRunner wraps a Runner and logs every command it runs with its raw
+// output, exit code, and duration. It backs --trace, for working out why a
+// step's Check keeps failing.
+type TracingRunner struct {
+	runner Runner
+	logger *slog.Logger
+}
+
+// NewTracingRunner returns a TracingRunner that logs r's commands to logger.
+func NewTracingRunner(r Runner, logger *slog.Logger) *TracingRunner {
+	return &TracingRunner{runner: r, logger: logger}
+}
+
+// Run runs the command through the wrapped Runner and logs the outcome at
+// debug level.
+func (t *TracingRunner) Run(ctx context.Context, name string, args ...string) (Result, error) {
+	start := time.Now()
+	result, err := t.runner.Run(ctx, name, args...)
+
+	attrs := []any{
+		slog.String("cmd", commandKey(name, args)),
+		slog.Int("exit_code", result.ExitCode),
+		slog.String("stdout", result.Stdout),
+		slog.String("stderr", result.Stderr),
+		slog.Duration("elapsed", time.Since(start)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	t.logger.Debug("trace exec", attrs...)
+
+	return result, err
+}
@@ -0,0 +1,46 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestTracingRunner_LogsCommandAndOutput(t *testing.T) {
+	mock := &MockRunner{Results: map[string]Result{
+		"go env GOPROXY": {Stdout: "https://proxy.golang.org\n"},
+	}}
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	tr := NewTracingRunner(mock, logger)
+	result, err := tr.Run(context.Background(), "go", "env", "GOPROXY")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.Stdout != "https://proxy.golang.org\n" {
+		t.Errorf("stdout = %q", result.Stdout)
+	}
+
+	out := buf.String()
+	for _, want := range []string{`cmd="go env GOPROXY"`, "proxy.golang.org", "exit_code=0"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("trace log missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestTracingRunner_LogsErrors(t *testing.T) {
+	mock := &MockRunner{Results: map[string]Result{}}
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	if _, err := NewTracingRunner(mock, logger).Run(context.Background(), "scoop", "list"); err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(buf.String(), "error=") {
+		t.Errorf("trace log should include the error:\n%s", buf.String())
+	}
+}
@@ -100,41 +100,37 @@ func TestFullSetupFlow(t *testing.T) {
 		Results: map[string]exec.Result{
 			// Base module
 			"git config --global init.defaultBranch":                        {Stdout: "", ExitCode: 1},
-			"git config --global init.defaultBranch main":                   {Stdout: "", ExitCode: 0},
 			"git config --global http.sslCAInfo":                            {Stdout: "", ExitCode: 1},
-			"git config --global http.sslCAInfo " + config.CABundlePath():   {Stdout: "", ExitCode: 0},
 			"scoop --version":                                               {Stdout: "", ExitCode: 1},
-			"powershell -NoProfile -Command Set-ExecutionPolicy RemoteSigned -Scope CurrentUser -Force; irm get.scoop.sh | iex": {ExitCode: 0},
+			"powershell -NoProfile -Command Set-ExecutionPolicy RemoteSigned -Scope CurrentUser -Force": {ExitCode: 0},
+			"powershell -NoProfile -Command irm get.scoop.sh | iex": {ExitCode: 0},
 			"scoop bucket list":                {Stdout: "", ExitCode: 0},
-			"scoop bucket add extras":          {ExitCode: 0},
-			"scoop bucket add versions":        {ExitCode: 0},
+			"scoop update":                     {ExitCode: 0},
 			// Go module
 			"go version":                       {Stdout: "", ExitCode: 1},
-			"scoop install go":                 {ExitCode: 0},
 			"go env GOPROXY":                   {Stdout: "", ExitCode: 1},
 			"go env -w GOPROXY=https://goproxy.example.com": {ExitCode: 0},
 			// Python module
 			"uv --version":                     {Stdout: "", ExitCode: 1},
-			"scoop install uv":                 {ExitCode: 0},
 			"uv python list --only-installed":  {Stdout: "", ExitCode: 1},
 			"uv python install 3.12":           {ExitCode: 0},
 			// Node module
 			"fnm --version":                    {Stdout: "", ExitCode: 1},
-			"scoop install fnm":                {ExitCode: 0},
 			"fnm list":                         {Stdout: "", ExitCode: 1},
 			"fnm install 22":                   {ExitCode: 0},
 			"fnm default 22":                   {ExitCode: 0},
 			"fnm exec --using 22 -- npm config get cafile":                             {Stdout: "", ExitCode: 1},
-			"fnm exec --using 22 -- npm config set cafile " + config.CABundlePath():    {ExitCode: 0},
 			"fnm exec --using 22 -- npm config get registry":                           {Stdout: "", ExitCode: 1},
-			"fnm exec --using 22 -- npm config set registry https://npm.example.com/":  {ExitCode: 0},
 			// Tools module
 			"scoop list":                       {Stdout: "", ExitCode: 0},
-			"scoop install git":                {ExitCode: 0},
-			"scoop install jq":                 {ExitCode: 0},
-			"scoop install sqlcmd":             {ExitCode: 0},
-			"scoop install bat":                {ExitCode: 0},
 		},
+		// Every install and config write succeeds.
+		Match: exec.MatchGlobs(
+			exec.Glob{Pattern: "git config --global * *"},
+			exec.Glob{Pattern: "scoop install *"},
+			exec.Glob{Pattern: "scoop bucket add *"},
+			exec.Glob{Pattern: "fnm exec --using 22 -- npm config set * *"},
+		),
 	}
 	st := &state.State{}
 
@@ -158,6 +154,8 @@ func TestFullSetupFlow(t *testing.T) {
 	// Run all modules
 	logger := slog.New(logging.NopHandler{})
 	runner := module.NewRunner(logger, false)
+	// The modules need Windows; the mocks stand in for it.
+	runner.SetOS("windows")
 
 	allIDs := []string{"base", "golang", "python", "node", "tools"}
 	results, err := runner.RunModules(context.Background(), reg, allIDs)
@@ -287,6 +285,7 @@ func TestFullSetupFlow(t *testing.T) {
 	reg2.Register(setup.NewToolsModule(deps2))
 
 	runner2 := module.NewRunner(logger, false)
+	runner2.SetOS("windows")
 	results2, err := runner2.RunModules(context.Background(), reg2, allIDs)
 	if err != nil {
 		t.Fatalf("second RunModules: %v", err)
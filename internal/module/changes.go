@@ -0,0 +1,124 @@
+package module
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// FileChange is a text file a step changed, with its content before the
+// step first touched it and after the step that last touched it.
+type FileChange struct {
+	Path   string
+	Before string
+	After  string
+}
+
+// changesKey is the context key under which the runner passes steps the
+// list RecordChange appends to.
+type changesKey struct{}
+
+// withChanges returns a copy of ctx in which RecordChange appends to
+// changes.
+func withChanges(ctx context.Context, changes *[]FileChange) context.Context {
+	return context.WithValue(ctx, changesKey{}, changes)
+}
+
+// RecordChange notes that a step changed a file; the runner collects the
+// changes into ModuleResult.Changes. Changes to a path already recorded are
+// merged, keeping the original Before. Outside a runner it does nothing.
+func RecordChange(ctx context.Context, change FileChange) {
+	changes, ok := ctx.Value(changesKey{}).(*[]FileChange)
+	if !ok {
+		return
+	}
+	for i, c := range *changes {
+		if c.Path != change.Path {
+			continue
+		}
+		if c.Before == change.After {
+			*changes = append((*changes)[:i], (*changes)[i+1:]...)
+		} else {
+			(*changes)[i].After = change.After
+		}
+		return
+	}
+	if change.Before != change.After {
+		*changes = append(*changes, change)
+	}
+}
+
+// Diff returns the lines removed from Before ("- " prefix) and added in
+// After ("+ " prefix), in file order. Unchanged lines are left out. Line
+// endings are normalised, so a CRLF-only change shows no lines.
+func (c FileChange) Diff() []string {
+	before := splitLines(c.Before)
+	after := splitLines(c.After)
+
+	// lcs[i][j] is the length of the longest common subsequence of
+	// before[i:] and after[j:].
+	lcs := make([][]int, len(before)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case i < len(before) && j < len(after) && before[i] == after[j]:
+			i++
+			j++
+		case j < len(after) && (i == len(before) || lcs[i][j+1] >= lcs[i+1][j]):
+			lines = append(lines, "+ "+after[j])
+			j++
+		default:
+			lines = append(lines, "- "+before[i])
+			i++
+		}
+	}
+	return lines
+}
+
+// BriefDiffLines is how many Diff lines BriefDiff shows.
+const BriefDiffLines = 10
+
+// BriefDiff returns the first BriefDiffLines lines of Diff, followed by a
+// count of the rest if there are more.
+func (c FileChange) BriefDiff() []string {
+	lines := c.Diff()
+	if len(lines) <= BriefDiffLines {
+		return lines
+	}
+	more := len(lines) - BriefDiffLines
+	return append(lines[:BriefDiffLines], fmt.Sprintf("… %d more lines", more))
+}
+
+// DiffStat returns how many lines Diff reports added and removed.
+func (c FileChange) DiffStat() (added, removed int) {
+	for _, line := range c.Diff() {
+		if strings.HasPrefix(line, "+") {
+			added++
+		} else {
+			removed++
+		}
+	}
+	return added, removed
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
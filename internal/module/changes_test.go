@@ -0,0 +1,72 @@
+package module
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFileChange_Diff(t *testing.T) {
+	c := FileChange{
+		Path:   "profile.ps1",
+		Before: "# mine\r\nSet-Alias ll ls\r\n",
+		After:  "# mine\nSet-Alias ll ls\nfnm env | Invoke-Expression\n",
+	}
+
+	want := []string{"+ fnm env | Invoke-Expression"}
+	if got := c.Diff(); !slices.Equal(got, want) {
+		t.Errorf("Diff() = %q, want %q", got, want)
+	}
+
+	c = FileChange{Before: "a\nb\nc\n", After: "a\nB\nc\nd\n"}
+	want = []string{"+ B", "- b", "+ d"}
+	if got := c.Diff(); !slices.Equal(got, want) {
+		t.Errorf("Diff() = %q, want %q", got, want)
+	}
+	if added, removed := c.DiffStat(); added != 2 || removed != 1 {
+		t.Errorf("DiffStat() = +%d -%d, want +2 -1", added, removed)
+	}
+}
+
+func TestFileChange_BriefDiff(t *testing.T) {
+	var after strings.Builder
+	for range BriefDiffLines + 3 {
+		after.WriteString("line\n")
+	}
+	lines := FileChange{After: after.String()}.BriefDiff()
+
+	if len(lines) != BriefDiffLines+1 {
+		t.Fatalf("got %d lines, want %d", len(lines), BriefDiffLines+1)
+	}
+	if last := lines[BriefDiffLines]; last != "… 3 more lines" {
+		t.Errorf("last line = %q", last)
+	}
+}
+
+func TestRunner_CollectsChanges(t *testing.T) {
+	mod := &Module{
+		ID: "test",
+		Steps: []Step{
+			{Name: "write", Run: func(ctx context.Context) error {
+				RecordChange(ctx, FileChange{Path: "a", Before: "1\n", After: "2\n"})
+				RecordChange(ctx, FileChange{Path: "b", Before: "x\n", After: "x\n"})
+				return nil
+			}},
+			{Name: "write again", Run: func(ctx context.Context) error {
+				RecordChange(ctx, FileChange{Path: "a", Before: "2\n", After: "3\n"})
+				return nil
+			}},
+		},
+	}
+
+	result := NewRunner(nopLogger(), false).RunModule(context.Background(), mod)
+
+	want := []FileChange{{Path: "a", Before: "1\n", After: "3\n"}}
+	if !slices.Equal(result.Changes, want) {
+		t.Errorf("Changes = %+v, want %+v", result.Changes, want)
+	}
+
+	// Outside a runner RecordChange is a no-op.
+	RecordChange(context.Background(), FileChange{Path: "a", After: "x"})
+}
@@ -4,7 +4,18 @@ package module
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/druarnfield/shhh/internal/exec"
+	"github.com/druarnfield/shhh/internal/platform"
 )
 
 // Category classifies modules into logical groups.
@@ -43,13 +54,82 @@ type Step struct {
 	Explain string
 
 	// Check returns true if the step is already satisfied (i.e. Run can be skipped).
+	// It must only look: planning may call it, and DryRun, concurrently with
+	// other steps' Checks, before any step has run.
 	Check func(ctx context.Context) bool
 
 	// Run executes the step. It should be idempotent.
 	Run func(ctx context.Context) error
 
+	// Retries is how many more times Run is attempted after it fails, for
+	// steps that depend on the network. Zero means Run is called once.
+	Retries int
+
+	// RetryBackoff is how long to wait before the first retry; the wait
+	// doubles before each one after. Zero retries straight away.
+	RetryBackoff time.Duration
+
+	// Retriable reports whether a failed Run is worth retrying. Nil means
+	// DefaultRetriable.
+	Retriable func(err error) bool
+
+	// Timeout bounds each call to Run, by cancelling its context, so a
+	// download hung behind a flaky proxy fails instead of freezing setup.
+	// A step that times out is not retried. Zero means the Runner's default
+	// (see Runner.SetDefaultTimeout). Check and DryRun are not bounded.
+	Timeout time.Duration
+
 	// DryRun describes what Run would do without making changes.
 	DryRun func(ctx context.Context) string
+
+	// Env lists the user environment variables Run sets, with the value it
+	// sets; "" means Run deletes the variable. Path lists the directories
+	// Run adds to PATH. Both are optional and only used to preview a run.
+	Env  map[string]string
+	Path []string
+}
+
+// ID returns a stable identifier for the step within its module, derived
+// from its Name: lower case, with each run of other characters than letters
+// and digits replaced by a hyphen ("Set git ssl.caInfo" is
+// "set-git-ssl-cainfo").
+func (s Step) ID() string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(s.Name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	return b.String()
+}
+
+// DefaultRetriable is the retry policy for steps without a Retriable. Only
+// failures that can be transient are retried: network errors, timeouts, and
+// commands that ran and exited non-zero (such as a scoop install whose
+// download dropped). Anything else, such as a missing command, a denied
+// permission or a config value the step rejects, will fail the same way
+// again.
+func DefaultRetriable(err error) bool {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, exec.ErrCommandNotFound),
+		errors.Is(err, fs.ErrPermission),
+		errors.Is(err, platform.ErrNotSupported):
+		return false
+	case errors.Is(err, exec.ErrExit),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netErr):
+		return true
+	}
+	return false
 }
 
 // Module represents a discrete unit of system configuration (e.g. "golang",
@@ -71,21 +151,99 @@ type Module struct {
 	// Dependencies lists module IDs that must be applied before this one.
 	Dependencies []string
 
+	// SupportedOS lists the operating systems, as GOOS values (e.g.
+	// "windows"), the module can run on. Empty means all of them. Elsewhere
+	// the runner skips it; see Unsupported.
+	SupportedOS []string
+
 	// Steps are the ordered operations to apply this module.
 	Steps []Step
 }
 
+// Validate checks that every step has a Name and a Run function, and that
+// no two steps share an ID. Check and DryRun are optional. A nil Run would
+// otherwise panic mid-run.
+func (m *Module) Validate() error {
+	var errs []error
+	seen := make(map[string]bool)
+	for i, step := range m.Steps {
+		if step.Name == "" {
+			errs = append(errs, fmt.Errorf("module %q: step %d has no name", m.ID, i+1))
+		} else if id := step.ID(); seen[id] {
+			errs = append(errs, fmt.Errorf("module %q: step %d (%q) has the same ID as an earlier step", m.ID, i+1, step.Name))
+		} else {
+			seen[id] = true
+		}
+		if step.Run == nil {
+			errs = append(errs, fmt.Errorf("module %q: step %d (%q) has no Run function", m.ID, i+1, step.Name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Unsupported returns why m can't run on goos, e.g. "requires Windows", or
+// "" if it can.
+func (m *Module) Unsupported(goos string) string {
+	if len(m.SupportedOS) == 0 || slices.Contains(m.SupportedOS, goos) {
+		return ""
+	}
+	names := make([]string, len(m.SupportedOS))
+	for i, supported := range m.SupportedOS {
+		names[i] = osName(supported)
+	}
+	return "requires " + strings.Join(names, " or ")
+}
+
+// osName returns the name people know goos by.
+func osName(goos string) string {
+	switch goos {
+	case "windows":
+		return "Windows"
+	case "darwin":
+		return "macOS"
+	case "linux":
+		return "Linux"
+	}
+	return goos
+}
+
+// AssumeSatisfied replaces the Check of every step in m, including steps
+// that had none, with one that reports satisfied without looking, so a
+// runner skips the whole module. Setup's --trust-state uses it for modules
+// the state file records as installed.
+func (m *Module) AssumeSatisfied() {
+	for i := range m.Steps {
+		m.Steps[i].Check = func(context.Context) bool { return true }
+	}
+}
+
+// Step returns the step whose ID (see Step.ID) or Name is id, or nil.
+func (m *Module) Step(id string) *Step {
+	for i := range m.Steps {
+		if m.Steps[i].ID() == id || m.Steps[i].Name == id {
+			return &m.Steps[i]
+		}
+	}
+	return nil
+}
+
 // Registry holds registered modules and provides lookup and dependency
 // resolution. It preserves insertion order for deterministic results.
 type Registry struct {
 	modules map[string]*Module
-	order   []string // insertion order for stable iteration
+	order   []string        // insertion order for stable iteration
+	removed map[string]bool // IDs taken out by Remove, for ResolveDeps errors
 }
 
+// ErrModuleRemoved is the error ResolveDeps wraps when a module it needs
+// was taken out of the registry with Remove.
+var ErrModuleRemoved = errors.New("module removed")
+
 // NewRegistry creates an empty Registry.
 func NewRegistry() *Registry {
 	return &Registry{
 		modules: make(map[string]*Module),
+		removed: make(map[string]bool),
 	}
 }
 
@@ -96,6 +254,19 @@ func (r *Registry) Register(m *Module) {
 		r.order = append(r.order, m.ID)
 	}
 	r.modules[m.ID] = m
+	delete(r.removed, m.ID)
+}
+
+// Remove takes the module with the given ID out of the registry, keeping
+// the order of the rest. Removing an unknown ID does nothing. ResolveDeps
+// reports modules that still depend on a removed one.
+func (r *Registry) Remove(id string) {
+	if _, exists := r.modules[id]; !exists {
+		return
+	}
+	delete(r.modules, id)
+	r.order = slices.DeleteFunc(r.order, func(o string) bool { return o == id })
+	r.removed[id] = true
 }
 
 // Get returns the module with the given ID, or nil if not found.
@@ -123,6 +294,18 @@ func (r *Registry) ByCategory(cat Category) []*Module {
 	return result
 }
 
+// Validate calls Validate on every registered module and returns all
+// problems found.
+func (r *Registry) Validate() error {
+	var errs []error
+	for _, m := range r.All() {
+		if err := m.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // ResolveDeps performs a topological sort of the requested module IDs and all
 // their transitive dependencies using Kahn's algorithm. It returns the IDs in
 // an order where every module appears after its dependencies.
@@ -130,7 +313,8 @@ func (r *Registry) ByCategory(cat Category) []*Module {
 // When multiple modules have zero in-degree simultaneously, they are emitted
 // in insertion order (the order they were registered) for deterministic output.
 //
-// Returns an error if a dependency is not registered or if a cycle is detected.
+// Returns an error if a dependency is not registered (wrapping
+// ErrModuleRemoved if it was removed) or if a cycle is detected.
 func (r *Registry) ResolveDeps(ids []string) ([]string, error) {
 	// Collect all needed modules (requested + transitive deps).
 	needed := make(map[string]bool)
@@ -141,10 +325,16 @@ func (r *Registry) ResolveDeps(ids []string) ([]string, error) {
 		}
 		m := r.modules[id]
 		if m == nil {
+			if r.removed[id] {
+				return fmt.Errorf("module %q: %w", id, ErrModuleRemoved)
+			}
 			return fmt.Errorf("module %q not found in registry", id)
 		}
 		needed[id] = true
 		for _, dep := range m.Dependencies {
+			if r.removed[dep] {
+				return fmt.Errorf("module %q depends on %q: %w", id, dep, ErrModuleRemoved)
+			}
 			if err := collect(dep); err != nil {
 				return err
 			}
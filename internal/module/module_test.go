@@ -2,6 +2,9 @@ package module
 
 import (
 	"context"
+	"errors"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -132,6 +135,37 @@ func TestRegistry_ResolveDeps_MissingDepError(t *testing.T) {
 	}
 }
 
+func TestRegistry_Remove(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register(&Module{ID: "base"})
+	reg.Register(&Module{ID: "python", Dependencies: []string{"base"}})
+	reg.Register(&Module{ID: "rust", Dependencies: []string{"base"}})
+	reg.Register(&Module{ID: "tools"})
+
+	reg.Remove("rust")
+	reg.Remove("nonexistent")
+
+	var ids []string
+	for _, m := range reg.All() {
+		ids = append(ids, m.ID)
+	}
+	if !slices.Equal(ids, []string{"base", "python", "tools"}) {
+		t.Errorf("All = %v, want [base python tools]", ids)
+	}
+	if reg.Get("rust") != nil {
+		t.Error("Get should return nil for a removed module")
+	}
+
+	reg.Remove("base")
+	_, err := reg.ResolveDeps([]string{"python"})
+	if !errors.Is(err, ErrModuleRemoved) || !strings.Contains(err.Error(), `module "python" depends on "base"`) {
+		t.Errorf("ResolveDeps = %v, want python's dependency on removed base", err)
+	}
+	if _, err := reg.ResolveDeps([]string{"rust"}); !errors.Is(err, ErrModuleRemoved) {
+		t.Errorf("ResolveDeps(rust) = %v, want ErrModuleRemoved", err)
+	}
+}
+
 func TestStep_CheckSkipsRun(t *testing.T) {
 	ran := false
 	step := Step{
@@ -152,3 +186,88 @@ func TestStep_CheckSkipsRun(t *testing.T) {
 		t.Error("Run should not have been called")
 	}
 }
+
+func TestModule_Validate(t *testing.T) {
+	ok := &Module{ID: "ok", Steps: []Step{
+		{Name: "step", Run: func(ctx context.Context) error { return nil }},
+	}}
+	if err := ok.Validate(); err != nil {
+		t.Errorf("Validate() = %v, want nil", err)
+	}
+
+	bad := &Module{ID: "bad", Steps: []Step{
+		{Name: "no run"},
+		{Run: func(ctx context.Context) error { return nil }},
+	}}
+	err := bad.Validate()
+	if err == nil {
+		t.Fatal("expected validation error for nil Run and empty Name")
+	}
+	for _, want := range []string{"no Run function", "has no name"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestStep_ID(t *testing.T) {
+	for name, want := range map[string]string{
+		"Set git ssl.caInfo":       "set-git-ssl-cainfo",
+		"Add GOBIN to PATH":        "add-gobin-to-path",
+		"Configure Node.js CA ...": "configure-node-js-ca",
+		"ripgrep":                  "ripgrep",
+	} {
+		if got := (Step{Name: name}).ID(); got != want {
+			t.Errorf("Step{Name: %q}.ID() = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestModule_Step(t *testing.T) {
+	run := func(ctx context.Context) error { return nil }
+	m := &Module{ID: "golang", Steps: []Step{
+		{Name: "Install Go", Run: run},
+		{Name: "Set GOPATH", Run: run},
+	}}
+	if s := m.Step("set-gopath"); s == nil || s.Name != "Set GOPATH" {
+		t.Errorf("Step(\"set-gopath\") = %v", s)
+	}
+	if s := m.Step("Install Go"); s == nil || s.Name != "Install Go" {
+		t.Errorf("Step(\"Install Go\") = %v", s)
+	}
+	if s := m.Step("missing"); s != nil {
+		t.Errorf("Step(\"missing\") = %v, want nil", s)
+	}
+
+	dup := &Module{ID: "dup", Steps: []Step{
+		{Name: "Set GOPATH", Run: run},
+		{Name: "set gopath", Run: run},
+	}}
+	if err := dup.Validate(); err == nil || !strings.Contains(err.Error(), "same ID") {
+		t.Errorf("Validate() = %v, want a duplicate ID error", err)
+	}
+}
+
+func TestRegistry_Validate(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register(&Module{ID: "base"})
+	reg.Register(&Module{ID: "broken", Steps: []Step{{Name: "nil run"}}})
+
+	err := reg.Validate()
+	if err == nil || !strings.Contains(err.Error(), `"broken"`) {
+		t.Errorf("Validate() = %v, want error naming module \"broken\"", err)
+	}
+}
+
+func TestModule_Unsupported(t *testing.T) {
+	m := &Module{ID: "docs", SupportedOS: []string{"linux", "darwin"}}
+	if got := m.Unsupported("windows"); got != "requires Linux or macOS" {
+		t.Errorf("Unsupported(windows) = %q", got)
+	}
+	if got := m.Unsupported("linux"); got != "" {
+		t.Errorf("Unsupported(linux) = %q, want \"\"", got)
+	}
+	if got := (&Module{ID: "any"}).Unsupported("plan9"); got != "" {
+		t.Errorf("module without SupportedOS: Unsupported = %q", got)
+	}
+}
@@ -0,0 +1,213 @@
+package module
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/druarnfield/shhh/internal/exec"
+)
+
+// Plan is what running a set of modules would do, worked out from each
+// step's Check and DryRun without calling Run. Explain output, doctor's
+// step checks and setup's environment preview are built from one.
+type Plan struct {
+	Modules []ModulePlan
+}
+
+// ModulePlan is the plan for one module, with a StepPlan per step in run
+// order.
+type ModulePlan struct {
+	Module *Module
+	Steps  []StepPlan
+}
+
+// StepPlan says whether a step would run and why. DryRun is the step's
+// description of what Run would do ("" when it has no DryRun), filled in
+// whether or not it would run so the plan can explain every step. Err says
+// why the step's Check errored: it panicked or didn't finish in time.
+type StepPlan struct {
+	Step    *Step
+	WillRun bool
+	Reason  string
+	DryRun  string
+	Err     error
+}
+
+// Reasons a StepPlan gives for whether its step would run.
+const (
+	ReasonSatisfied = "already done"
+	ReasonPending   = "not done yet"
+	ReasonNoCheck   = "always runs"
+	ReasonErrored   = "check errored"
+	ReasonUnchecked = "not checked"
+)
+
+// PlanOptions tunes how a plan is built. The zero value checks one step
+// at a time.
+type PlanOptions struct {
+	// Concurrency is how many steps' Check and DryRun may be evaluated at
+	// once. Values below 2 evaluate them one at a time, in order. The plan
+	// is the same either way; only the time to build it differs.
+	Concurrency int
+
+	// CheckTimeout bounds each Check, so one hung command doesn't stall
+	// the plan; a Check still running when it expires errors. Zero means
+	// no bound beyond ctx.
+	CheckTimeout time.Duration
+
+	// SkipChecks leaves every Check uncalled, and every step planned to
+	// run, for callers that only need the steps in run order (setup's
+	// environment preview). SkipDryRun leaves DryRun uncalled, for callers
+	// that don't show it (doctor).
+	SkipChecks bool
+	SkipDryRun bool
+
+	// Progress, if set, is called with how many steps have been evaluated
+	// so far and how many there are: once with 0 before the first, then
+	// after each one. Calls are never concurrent and done only increases,
+	// so it can drive a "Checking current state… (12/40)" display.
+	Progress func(done, total int)
+}
+
+// BuildPlan resolves ids and their dependencies, in run order, and plans
+// each module with PlanModules.
+func BuildPlan(ctx context.Context, reg *Registry, ids []string, opts PlanOptions) (*Plan, error) {
+	sorted, err := reg.ResolveDeps(ids)
+	if err != nil {
+		return nil, fmt.Errorf("resolving dependencies: %w", err)
+	}
+	mods := make([]*Module, 0, len(sorted))
+	for _, id := range sorted {
+		if m := reg.Get(id); m != nil {
+			mods = append(mods, m)
+		}
+	}
+	return PlanModules(ctx, mods, opts), nil
+}
+
+// PlanModules plans mods in the order given, calling each step's Check and
+// DryRun. A check sees the system as it is now, before any earlier step
+// has run, so a step that only becomes satisfied once an earlier one runs
+// is reported as pending.
+//
+// Commands run through a CachingRunner while planning are shared by every
+// step (see exec.WithReadOnlyCache), so a "scoop list" or "go env" that
+// several checks make runs once per plan. With opts.Concurrency above 1,
+// that many steps are evaluated at once; the plan keeps run order.
+func PlanModules(ctx context.Context, mods []*Module, opts PlanOptions) *Plan {
+	ctx = exec.WithReadOnlyCache(ctx)
+	plan := &Plan{Modules: make([]ModulePlan, len(mods))}
+	var steps []*StepPlan
+	for i, mod := range mods {
+		mp := ModulePlan{Module: mod, Steps: make([]StepPlan, len(mod.Steps))}
+		for j := range mod.Steps {
+			mp.Steps[j].Step = &mod.Steps[j]
+			steps = append(steps, &mp.Steps[j])
+		}
+		plan.Modules[i] = mp
+	}
+
+	var mu sync.Mutex
+	done := 0
+	progress := func() {
+		if opts.Progress == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		opts.Progress(done, len(steps))
+	}
+	if opts.Progress != nil {
+		opts.Progress(0, len(steps))
+	}
+
+	if opts.Concurrency < 2 {
+		for _, sp := range steps {
+			planStep(ctx, sp, opts)
+			progress()
+		}
+		return plan
+	}
+	sem := make(chan struct{}, opts.Concurrency)
+	var wg sync.WaitGroup
+	for _, sp := range steps {
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			planStep(ctx, sp, opts)
+			progress()
+		})
+	}
+	wg.Wait()
+	return plan
+}
+
+// planStep fills in sp from its step's Check and DryRun, as opts allow.
+func planStep(ctx context.Context, sp *StepPlan, opts PlanOptions) {
+	step := sp.Step
+	sp.WillRun, sp.Reason = true, ReasonNoCheck
+	switch {
+	case step.Check == nil:
+	case opts.SkipChecks:
+		sp.Reason = ReasonUnchecked
+	default:
+		satisfied, err := runCheck(ctx, step, opts.CheckTimeout)
+		switch {
+		case err != nil:
+			sp.Reason, sp.Err = ReasonErrored, err
+		case satisfied:
+			sp.WillRun, sp.Reason = false, ReasonSatisfied
+		default:
+			sp.Reason = ReasonPending
+		}
+	}
+	if step.DryRun != nil && !opts.SkipDryRun {
+		sp.DryRun = step.DryRun(ctx)
+	}
+}
+
+// runCheck runs step's Check, bounded by timeout when it is above zero. A
+// panic, or a context that ended before the Check passed, is an error.
+func runCheck(ctx context.Context, step *Step, timeout time.Duration) (satisfied bool, err error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			satisfied, err = false, fmt.Errorf("check panicked: %v", r)
+		}
+	}()
+
+	if step.Check(ctx) {
+		return true, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return false, fmt.Errorf("check didn't finish: %w", err)
+	}
+	return false, nil
+}
+
+// Steps returns every step in the plan, in run order.
+func (p *Plan) Steps() []StepPlan {
+	var steps []StepPlan
+	for _, mp := range p.Modules {
+		steps = append(steps, mp.Steps...)
+	}
+	return steps
+}
+
+// WillRun returns how many steps in the plan would run.
+func (p *Plan) WillRun() int {
+	n := 0
+	for _, sp := range p.Steps() {
+		if sp.WillRun {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,152 @@
+package module
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildPlan(t *testing.T) {
+	ran := false
+	run := func(context.Context) error { ran = true; return nil }
+	reg := NewRegistry()
+	reg.Register(&Module{ID: "base", Steps: []Step{
+		{Name: "done", Check: func(context.Context) bool { return true }, Run: run,
+			DryRun: func(context.Context) string { return "Would do nothing" }},
+		{Name: "pending", Check: func(context.Context) bool { return false }, Run: run},
+	}})
+	reg.Register(&Module{ID: "golang", Dependencies: []string{"base"}, Steps: []Step{
+		{Name: "unchecked", Run: run, DryRun: func(context.Context) string { return "Would install Go" }},
+	}})
+
+	plan, err := BuildPlan(context.Background(), reg, []string{"golang"}, PlanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ran {
+		t.Error("BuildPlan called a step's Run")
+	}
+	if len(plan.Modules) != 2 || plan.Modules[0].Module.ID != "base" || plan.Modules[1].Module.ID != "golang" {
+		t.Fatalf("modules not resolved in run order: %+v", plan.Modules)
+	}
+
+	want := []struct {
+		name    string
+		willRun bool
+		reason  string
+		dryRun  string
+	}{
+		{"done", false, ReasonSatisfied, "Would do nothing"},
+		{"pending", true, ReasonPending, ""},
+		{"unchecked", true, ReasonNoCheck, "Would install Go"},
+	}
+	steps := plan.Steps()
+	if len(steps) != len(want) {
+		t.Fatalf("got %d steps, want %d", len(steps), len(want))
+	}
+	for i, w := range want {
+		sp := steps[i]
+		if sp.Step.Name != w.name || sp.WillRun != w.willRun || sp.Reason != w.reason || sp.DryRun != w.dryRun {
+			t.Errorf("step %d = {%s %v %q %q}, want %+v", i, sp.Step.Name, sp.WillRun, sp.Reason, sp.DryRun, w)
+		}
+	}
+	if plan.WillRun() != 2 {
+		t.Errorf("WillRun() = %d, want 2", plan.WillRun())
+	}
+}
+
+func TestPlanModules_Progress(t *testing.T) {
+	check := func(context.Context) bool { time.Sleep(time.Millisecond); return false }
+	mods := []*Module{
+		{ID: "base", Steps: []Step{{Name: "a", Check: check}, {Name: "b", Check: check}}},
+		{ID: "golang", Steps: []Step{{Name: "c", Check: check}}},
+	}
+
+	for _, concurrency := range []int{0, 3} {
+		var calls []string
+		PlanModules(context.Background(), mods, PlanOptions{
+			Concurrency: concurrency,
+			Progress:    func(done, total int) { calls = append(calls, fmt.Sprintf("%d/%d", done, total)) },
+		})
+		if got := strings.Join(calls, " "); got != "0/3 1/3 2/3 3/3" {
+			t.Errorf("concurrency %d: progress = %q, want \"0/3 1/3 2/3 3/3\"", concurrency, got)
+		}
+	}
+}
+
+func TestBuildPlan_UnknownModule(t *testing.T) {
+	_, err := BuildPlan(context.Background(), NewRegistry(), []string{"nope"}, PlanOptions{})
+	if err == nil || !strings.Contains(err.Error(), "nope") {
+		t.Errorf("BuildPlan error = %v, want one naming the unknown module", err)
+	}
+}
+
+func TestPlanModules_ConcurrentMatchesSequential(t *testing.T) {
+	var mods []*Module
+	for i := range 5 {
+		var steps []Step
+		for j := range 6 {
+			n := i*6 + j
+			step := Step{Name: fmt.Sprintf("step %d", n), Run: func(context.Context) error { return nil }}
+			if n%3 != 0 {
+				step.Check = func(context.Context) bool {
+					time.Sleep(time.Duration(n%4) * time.Millisecond)
+					return n%2 == 0
+				}
+			}
+			if n%2 == 0 {
+				step.DryRun = func(context.Context) string { return fmt.Sprintf("Would do %d", n) }
+			}
+			steps = append(steps, step)
+		}
+		mods = append(mods, &Module{ID: fmt.Sprintf("m%d", i), Steps: steps})
+	}
+
+	want := PlanModules(context.Background(), mods, PlanOptions{})
+	got := PlanModules(context.Background(), mods, PlanOptions{Concurrency: 4})
+	if len(got.Modules) != len(want.Modules) {
+		t.Fatalf("got %d modules, want %d", len(got.Modules), len(want.Modules))
+	}
+	for i := range want.Modules {
+		if got.Modules[i].Module != want.Modules[i].Module {
+			t.Errorf("module %d = %s, want %s", i, got.Modules[i].Module.ID, want.Modules[i].Module.ID)
+		}
+		for j, w := range want.Modules[i].Steps {
+			if g := got.Modules[i].Steps[j]; g != w {
+				t.Errorf("module %d step %d = %+v, want %+v", i, j, g, w)
+			}
+		}
+	}
+}
+
+func TestPlanModules_CheckErrors(t *testing.T) {
+	mods := []*Module{{ID: "base", Steps: []Step{
+		{Name: "broken", Check: func(context.Context) bool { panic("boom") }},
+		{Name: "hung", Check: func(ctx context.Context) bool { <-ctx.Done(); return false }},
+	}}}
+
+	plan := PlanModules(context.Background(), mods, PlanOptions{CheckTimeout: time.Millisecond})
+	for _, sp := range plan.Steps() {
+		if !sp.WillRun || sp.Reason != ReasonErrored || sp.Err == nil {
+			t.Errorf("%s = {%v %q %v}, want an errored check", sp.Step.Name, sp.WillRun, sp.Reason, sp.Err)
+		}
+	}
+	if err := plan.Steps()[0].Err; !strings.Contains(err.Error(), "boom") {
+		t.Errorf("panicked check Err = %v, want the panic", err)
+	}
+}
+
+func TestPlanModules_SkipChecks(t *testing.T) {
+	mods := []*Module{{ID: "base", Steps: []Step{{
+		Name:   "checked",
+		Check:  func(context.Context) bool { t.Error("Check called with SkipChecks"); return true },
+		DryRun: func(context.Context) string { t.Error("DryRun called with SkipDryRun"); return "" },
+	}}}}
+
+	plan := PlanModules(context.Background(), mods, PlanOptions{SkipChecks: true, SkipDryRun: true})
+	if sp := plan.Steps()[0]; !sp.WillRun || sp.Reason != ReasonUnchecked {
+		t.Errorf("step = {%v %q}, want it planned to run, unchecked", sp.WillRun, sp.Reason)
+	}
+}
@@ -7,9 +7,16 @@
 package module
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"runtime"
+	"slices"
+	"sort"
+	"sync"
 	"time"
 )
 
@@ -24,6 +31,12 @@ type ModuleResult struct {
 	// Skipped is the number of steps whose Check returned true.
 	Skipped int
 
+	// Filtered is the number of steps the runner's step filter left out.
+	Filtered int
+
+	// WouldRun is the number of steps a dry run described instead of running.
+	WouldRun int
+
 	// Total is the total number of steps in the module.
 	Total int
 
@@ -32,21 +45,165 @@ type ModuleResult struct {
 
 	// Err is the error returned by the failed step, or nil on success.
 	Err error
+
+	// Attempts records how many times Run was called for each step that
+	// ran, keyed by step name. Counts above 1 mean the step was retried.
+	Attempts map[string]int
+
+	// Changes lists the text files the module's steps changed; see
+	// RecordChange.
+	Changes []FileChange
+
+	// Durations records how long each step took, Check included, keyed by
+	// step name. Dry runs record nothing.
+	Durations map[string]time.Duration
+
+	// Outcomes records what happened to each step the runner reached, keyed
+	// by step name. Steps after a failure are absent (StepNotReached).
+	Outcomes map[string]StepOutcome
+
+	// Unsupported, when set, is why the module was skipped without running
+	// any step: it doesn't run on this OS ("requires Windows"), or depends
+	// on a module that doesn't. Err is nil.
+	Unsupported string
+}
+
+// StepOutcome is what happened to one step in a run.
+type StepOutcome int
+
+const (
+	StepNotReached StepOutcome = iota // the module stopped before the step
+	StepSkipped                       // Check said it was already done
+	StepWouldRun                      // a dry run described it instead
+	StepCompleted                     // Run succeeded
+	StepFailed                        // Run failed, or the run was cancelled
+	StepFiltered                      // the step filter left it out; see SetStepFilter
+)
+
+func (o StepOutcome) String() string {
+	switch o {
+	case StepSkipped:
+		return "skipped"
+	case StepWouldRun:
+		return "would run"
+	case StepCompleted:
+		return "completed"
+	case StepFailed:
+		return "failed"
+	case StepFiltered:
+		return "filtered"
+	default:
+		return "not run"
+	}
+}
+
+// recordOutcome notes what happened to step.
+func (r *ModuleResult) recordOutcome(step string, o StepOutcome) {
+	if r.Outcomes == nil {
+		r.Outcomes = make(map[string]StepOutcome)
+	}
+	r.Outcomes[step] = o
+}
+
+// StepKey identifies a step across runs, e.g. for timing history.
+func StepKey(moduleID, stepName string) string {
+	return moduleID + "/" + stepName
 }
 
-// StepCallback is invoked after each step is processed (whether skipped, run,
-// or failed). It allows the caller to display progress, update a UI, etc.
-type StepCallback func(module *Module, step *Step, index int, total int, skipped bool, err error)
+// recordDuration notes that step took the time since start.
+func (r *ModuleResult) recordDuration(step string, start time.Time) {
+	if r.Durations == nil {
+		r.Durations = make(map[string]time.Duration)
+	}
+	r.Durations[step] = time.Since(start)
+}
+
+// SlowestSteps returns the names of the n steps that took longest,
+// slowest first. Steps with equal durations are in name order.
+func (r ModuleResult) SlowestSteps(n int) []string {
+	names := slices.Sorted(maps.Keys(r.Durations))
+	slices.SortStableFunc(names, func(a, b string) int {
+		return cmp.Compare(r.Durations[b], r.Durations[a])
+	})
+	if len(names) > n {
+		names = names[:n]
+	}
+	return names
+}
+
+// RetriedSteps returns the names of steps that took more than one attempt,
+// in sorted order.
+func (r ModuleResult) RetriedSteps() []string {
+	var names []string
+	for name, n := range r.Attempts {
+		if n > 1 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AttemptNote describes a retried step's outcome for the summary, e.g.
+// "Install Go (succeeded after 2 retries)". It returns "" for steps that
+// ran at most once.
+func (r ModuleResult) AttemptNote(step string) string {
+	n := r.Attempts[step]
+	if n <= 1 {
+		return ""
+	}
+	if r.Err != nil && step == r.FailedStep {
+		return fmt.Sprintf("%s (failed after %d attempts)", step, n)
+	}
+	retries := "retries"
+	if n == 2 {
+		retries = "retry"
+	}
+	return fmt.Sprintf("%s (succeeded after %d %s)", step, n-1, retries)
+}
+
+// StepCallback is invoked after each step is processed (whether skipped,
+// filtered, run, or failed) with what happened to it. It allows the caller
+// to display progress, update a UI, etc. err is the failed step's error,
+// and nil for every other outcome. In dry-run mode a step that would have
+// run is reported as StepWouldRun.
+type StepCallback func(module *Module, step *Step, index int, total int, outcome StepOutcome, err error)
+
+// StepFilter reports whether the runner should consider step at all; see
+// Runner.SetStepFilter.
+type StepFilter func(module *Module, step *Step) bool
 
 // PreStepCallback is invoked before each step begins processing.
 type PreStepCallback func(module *Module, step *Step, index int, total int)
 
+// ModulePhase says which module boundary a ModuleCallback reports.
+type ModulePhase int
+
+const (
+	ModuleStarted  ModulePhase = iota // before the module's first step
+	ModuleFinished                    // after its last step, or the one that failed
+)
+
+// ModuleCallback is invoked at module boundaries. result is nil for
+// ModuleStarted and the module's final result for ModuleFinished.
+type ModuleCallback func(module *Module, phase ModulePhase, result *ModuleResult)
+
+// ErrDependencyFailed is the error of a module that was not run because a
+// module it depends on failed (see Runner.SetKeepGoing).
+var ErrDependencyFailed = errors.New("dependency failed")
+
 // Runner executes module steps with check-before-run semantics.
 type Runner struct {
 	logger      *slog.Logger
 	dryRun      bool
+	keepGoing   bool
+	parallelism int
+	timeout     time.Duration
+	goos        string
+	filter      StepFilter
 	callback    StepCallback
 	preCallback PreStepCallback
+	modCallback ModuleCallback
 }
 
 // NewRunner creates a Runner. When dryRun is true, steps are not executed;
@@ -55,49 +212,163 @@ func NewRunner(logger *slog.Logger, dryRun bool) *Runner {
 	return &Runner{
 		logger: logger,
 		dryRun: dryRun,
+		goos:   runtime.GOOS,
 	}
 }
 
+// SetOS sets the operating system, as a GOOS value, that modules'
+// SupportedOS are checked against. It defaults to the one shhh runs on.
+func (r *Runner) SetOS(goos string) {
+	r.goos = goos
+}
+
+// DryRun reports whether the runner describes steps instead of running them.
+func (r *Runner) DryRun() bool {
+	return r.dryRun
+}
+
+// SetKeepGoing makes RunModules carry on with the remaining modules after
+// one fails, instead of stopping. Modules depending on a failed module are
+// still not run; see RunModuleAfter.
+func (r *Runner) SetKeepGoing(keepGoing bool) {
+	r.keepGoing = keepGoing
+}
+
+// KeepGoing reports whether the runner carries on after a failed module.
+func (r *Runner) KeepGoing() bool {
+	return r.keepGoing
+}
+
+// SetParallelism limits how many modules RunModulesParallel runs at once.
+// Zero, the default, means runtime.NumCPU().
+func (r *Runner) SetParallelism(n int) {
+	r.parallelism = n
+}
+
+// SetDefaultTimeout bounds each Run of steps that don't set their own
+// Step.Timeout. Zero, the default, leaves them unbounded.
+func (r *Runner) SetDefaultTimeout(d time.Duration) {
+	r.timeout = d
+}
+
 // SetCallback registers a callback that is invoked after each step is
 // processed. Pass nil to clear.
 func (r *Runner) SetCallback(cb StepCallback) {
 	r.callback = cb
 }
 
+// SetStepFilter makes RunModule leave out every step filter rejects: it is
+// reported as StepFiltered without its Check or Run being called. Modules
+// still run in dependency order. A later step that relies on what a
+// filtered one does may then fail; that is the caller's lookout.
+func (r *Runner) SetStepFilter(filter StepFilter) {
+	r.filter = filter
+}
+
 // SetPreStepCallback registers a callback that is invoked before each step
 // begins processing. Pass nil to clear.
 func (r *Runner) SetPreStepCallback(cb PreStepCallback) {
 	r.preCallback = cb
 }
 
+// SetModuleCallback registers a callback that is invoked when each module
+// starts and finishes. Pass nil to clear.
+func (r *Runner) SetModuleCallback(cb ModuleCallback) {
+	r.modCallback = cb
+}
+
 // RunModule executes every step in the given module sequentially. For each
 // step:
 //   - If Check returns true the step is skipped.
 //   - If the runner is in dry-run mode, DryRun is called and logged but Run is
 //     not invoked.
-//   - Otherwise Run is called; on error execution stops immediately.
+//   - Otherwise Run is called, and called again up to step.Retries times while
+//     it fails with an error step.Retriable (default DefaultRetriable)
+//     accepts; if it still fails execution stops immediately. Each retry
+//     waits step.RetryBackoff, doubling every time, then calls Check again
+//     and stops retrying if the step is now satisfied. A nil Run
+//     (e.g. a planning-only step) is a no-op that counts as completed.
+//   - Each call to Run gets a context bounded by step.Timeout (or the
+//     runner's default); a call that overruns it fails the step, with an
+//     error wrapping context.DeadlineExceeded, and isn't retried.
+//
+// If ctx is cancelled, no further steps are started and the result reports
+// the step that would have run next.
+//
+// A module whose SupportedOS leaves out the runner's OS is skipped without
+// calling any step, its result's Unsupported saying why.
 func (r *Runner) RunModule(ctx context.Context, mod *Module) ModuleResult {
+	if r.modCallback != nil {
+		r.modCallback(mod, ModuleStarted, nil)
+	}
+	var result ModuleResult
+	if reason := mod.Unsupported(r.goos); reason != "" {
+		r.logger.Info("module not supported here, skipping", "module", mod.ID, "os", r.goos, "reason", reason)
+		result = ModuleResult{ModuleID: mod.ID, Total: len(mod.Steps), Unsupported: reason}
+	} else {
+		result = r.runSteps(ctx, mod)
+	}
+	if r.modCallback != nil {
+		r.modCallback(mod, ModuleFinished, &result)
+	}
+	return result
+}
+
+// runSteps is RunModule without the module callbacks.
+func (r *Runner) runSteps(ctx context.Context, mod *Module) ModuleResult {
 	result := ModuleResult{
 		ModuleID: mod.ID,
 		Total:    len(mod.Steps),
 	}
+	ctx = withChanges(ctx, &result.Changes)
 
 	for i := range mod.Steps {
 		step := &mod.Steps[i]
 
+		// Stop before starting new work once the run has been cancelled;
+		// in-flight commands are killed via the context passed to Exec.
+		if err := ctx.Err(); err != nil {
+			result.FailedStep = step.Name
+			result.Err = fmt.Errorf("module %q cancelled before step %q: %w", mod.ID, step.Name, err)
+			result.recordOutcome(step.Name, StepFailed)
+			r.logger.Warn("run cancelled",
+				slog.String("module", mod.ID),
+				slog.String("step", step.Name),
+			)
+			return result
+		}
+
 		if r.preCallback != nil {
 			r.preCallback(mod, step, i, result.Total)
 		}
 
+		if r.filter != nil && !r.filter(mod, step) {
+			result.Filtered++
+			result.recordOutcome(step.Name, StepFiltered)
+			r.logger.Info("step filtered out, skipping",
+				slog.String("module", mod.ID),
+				slog.String("step", step.Name),
+			)
+			if r.callback != nil {
+				r.callback(mod, step, i, result.Total, StepFiltered, nil)
+			}
+			continue
+		}
+		stepStart := time.Now()
+
 		// Check precondition -- skip if already satisfied.
 		if step.Check != nil && step.Check(ctx) {
+			if !r.dryRun {
+				result.recordDuration(step.Name, stepStart)
+			}
 			result.Skipped++
+			result.recordOutcome(step.Name, StepSkipped)
 			r.logger.Info("step already satisfied, skipping",
 				slog.String("module", mod.ID),
 				slog.String("step", step.Name),
 			)
 			if r.callback != nil {
-				r.callback(mod, step, i, result.Total, true, nil)
+				r.callback(mod, step, i, result.Total, StepSkipped, nil)
 			}
 			continue
 		}
@@ -113,20 +384,82 @@ func (r *Runner) RunModule(ctx context.Context, mod *Module) ModuleResult {
 				slog.String("step", step.Name),
 				slog.String("would_do", desc),
 			)
+			result.WouldRun++
+			result.recordOutcome(step.Name, StepWouldRun)
 			if r.callback != nil {
-				r.callback(mod, step, i, result.Total, true, nil)
+				r.callback(mod, step, i, result.Total, StepWouldRun, nil)
 			}
 			continue
 		}
 
-		// Execute the step.
+		// Execute the step, retrying up to step.Retries times on failure.
 		start := time.Now()
-		err := step.Run(ctx)
+		var err error
+		if step.Run != nil {
+			retriable := step.Retriable
+			if retriable == nil {
+				retriable = DefaultRetriable
+			}
+			timeout := step.Timeout
+			if timeout == 0 {
+				timeout = r.timeout
+			}
+			attempts := 0
+			for {
+				attempts++
+				var timedOut bool
+				timedOut, err = runWithTimeout(ctx, step, timeout)
+				if err == nil || attempts > step.Retries || ctx.Err() != nil || timedOut {
+					break
+				}
+				if !retriable(err) {
+					r.logger.Info("step failed with a non-retriable error",
+						slog.String("module", mod.ID),
+						slog.String("step", step.Name),
+						slog.String("error", err.Error()),
+					)
+					break
+				}
+				backoff := step.RetryBackoff << (attempts - 1)
+				r.logger.Warn("step failed, retrying",
+					slog.String("module", mod.ID),
+					slog.String("step", step.Name),
+					slog.Int("attempt", attempts),
+					slog.Duration("backoff", backoff),
+					slog.String("error", err.Error()),
+				)
+				if !sleepCtx(ctx, backoff) {
+					break
+				}
+				// A failed attempt may have got far enough (e.g. the install
+				// finished but a later download timed out): don't redo it.
+				if step.Check != nil && step.Check(ctx) {
+					r.logger.Info("step satisfied after a failed attempt, not retrying",
+						slog.String("module", mod.ID),
+						slog.String("step", step.Name),
+						slog.Int("attempt", attempts),
+					)
+					err = nil
+					break
+				}
+			}
+			if result.Attempts == nil {
+				result.Attempts = make(map[string]int)
+			}
+			result.Attempts[step.Name] = attempts
+		} else {
+			r.logger.Debug("step has no Run, treating as no-op",
+				slog.String("module", mod.ID),
+				slog.String("step", step.Name),
+			)
+		}
 		elapsed := time.Since(start)
+		result.recordDuration(step.Name, stepStart)
 
 		if err != nil {
 			result.FailedStep = step.Name
 			result.Err = fmt.Errorf("step %q in module %q failed: %w", step.Name, mod.ID, err)
+			result.recordOutcome(step.Name, StepFailed)
 			r.logger.Error("step failed",
 				slog.String("module", mod.ID),
 				slog.String("step", step.Name),
@@ -134,28 +467,62 @@ func (r *Runner) RunModule(ctx context.Context, mod *Module) ModuleResult {
 				slog.String("error", err.Error()),
 			)
 			if r.callback != nil {
-				r.callback(mod, step, i, result.Total, false, err)
+				r.callback(mod, step, i, result.Total, StepFailed, err)
 			}
 			return result
 		}
 
 		result.Completed++
+		result.recordOutcome(step.Name, StepCompleted)
 		r.logger.Info("step completed",
 			slog.String("module", mod.ID),
 			slog.String("step", step.Name),
 			slog.Duration("elapsed", elapsed),
 		)
 		if r.callback != nil {
-			r.callback(mod, step, i, result.Total, false, nil)
+			r.callback(mod, step, i, result.Total, StepCompleted, nil)
 		}
 	}
 
 	return result
 }
 
+// sleepCtx waits for d, or until ctx ends. It reports whether the whole
+// wait passed.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// runWithTimeout calls step.Run with a context that expires after timeout
+// (none if timeout <= 0). timedOut reports whether Run failed because that
+// deadline passed, as opposed to ctx itself ending.
+func runWithTimeout(ctx context.Context, step *Step, timeout time.Duration) (timedOut bool, err error) {
+	if timeout <= 0 {
+		return false, step.Run(ctx)
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err = step.Run(runCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		return true, fmt.Errorf("timed out after %s: %w", timeout, context.DeadlineExceeded)
+	}
+	return false, err
+}
+
 // RunModules resolves dependencies for the given module IDs using the registry,
 // then runs each module in topological order. It stops on the first module
-// failure.
+// failure, unless SetKeepGoing is on, in which case every failure is joined
+// into the returned error.
 func (r *Runner) RunModules(ctx context.Context, reg *Registry, moduleIDs []string) ([]ModuleResult, error) {
 	sorted, err := reg.ResolveDeps(moduleIDs)
 	if err != nil {
@@ -163,19 +530,195 @@ func (r *Runner) RunModules(ctx context.Context, reg *Registry, moduleIDs []stri
 	}
 
 	results := make([]ModuleResult, 0, len(sorted))
+	var errs []error
 	for _, id := range sorted {
 		mod := reg.Get(id)
 		if mod == nil {
 			return results, fmt.Errorf("module %q not found in registry", id)
 		}
 
-		result := r.RunModule(ctx, mod)
+		result := r.RunModuleAfter(ctx, mod, results)
 		results = append(results, result)
 
 		if result.Err != nil {
-			return results, result.Err
+			if !r.keepGoing {
+				return results, result.Err
+			}
+			errs = append(errs, result.Err)
 		}
 	}
 
-	return results, nil
+	return results, errors.Join(errs...)
+}
+
+// RunModuleAfter runs mod as RunModule does, unless one of its
+// dependencies failed in prior, the results of the modules run so far. Such
+// a module is not started, and its result's error wraps ErrDependencyFailed.
+// Nor is one with a dependency skipped as unsupported, which is skipped
+// too, with a result whose Unsupported names that dependency.
+func (r *Runner) RunModuleAfter(ctx context.Context, mod *Module, prior []ModuleResult) ModuleResult {
+	for _, p := range prior {
+		if !slices.Contains(mod.Dependencies, p.ModuleID) {
+			continue
+		}
+		if p.Err != nil {
+			r.logger.Info("module not run", "module", mod.ID, "failed_dependency", p.ModuleID)
+			return ModuleResult{
+				ModuleID: mod.ID,
+				Total:    len(mod.Steps),
+				Err:      fmt.Errorf("%w: %s", ErrDependencyFailed, p.ModuleID),
+			}
+		}
+		if p.Unsupported != "" {
+			r.logger.Info("module not run", "module", mod.ID, "unsupported_dependency", p.ModuleID)
+			return ModuleResult{
+				ModuleID:    mod.ID,
+				Total:       len(mod.Steps),
+				Unsupported: fmt.Sprintf("needs %s, which %s", p.ModuleID, p.Unsupported),
+			}
+		}
+	}
+	return r.RunModule(ctx, mod)
+}
+
+// RunModulesParallel is RunModules, but runs modules whose dependencies have
+// all finished concurrently, up to the runner's parallelism. Modules in
+// CategoryBase finish before any other module starts, whether or not that
+// module declares the dependency. Each module's steps still run in order.
+//
+// Callbacks are never called concurrently, but those of different modules
+// interleave. Results are returned in topological order, as RunModules
+// returns them, whatever order the modules finished in. Once a module
+// fails no new modules are started (unless SetKeepGoing is on); those
+// already running finish, and modules never started have no result.
+//
+// The steps of modules that can run together must be safe to run
+// concurrently, and the setup package's modules are not: steps that add to
+// PATH read-modify-write the process environment with os.Setenv, and a
+// step that changes the machine invalidates the CachingRunner every module
+// shares, so a check in another module can see a stale or half-updated
+// state. shhh setup therefore runs modules with RunModules; this is for
+// callers whose modules share no such state.
+func (r *Runner) RunModulesParallel(ctx context.Context, reg *Registry, moduleIDs []string) ([]ModuleResult, error) {
+	sorted, err := reg.ResolveDeps(moduleIDs)
+	if err != nil {
+		return nil, fmt.Errorf("resolving dependencies: %w", err)
+	}
+
+	// waitFor lists the modules in the run each module must wait for.
+	mods := make(map[string]*Module, len(sorted))
+	for _, id := range sorted {
+		if mods[id] = reg.Get(id); mods[id] == nil {
+			return nil, fmt.Errorf("module %q not found in registry", id)
+		}
+	}
+	waitFor := make(map[string][]string, len(sorted))
+	for _, id := range sorted {
+		for _, other := range sorted {
+			if other == id {
+				continue
+			}
+			if slices.Contains(mods[id].Dependencies, other) ||
+				(mods[other].Category == CategoryBase && mods[id].Category != CategoryBase) {
+				waitFor[id] = append(waitFor[id], other)
+			}
+		}
+	}
+
+	limit := r.parallelism
+	if limit <= 0 {
+		limit = runtime.NumCPU()
+	}
+	pr := r.serialized()
+
+	done := make(chan ModuleResult)
+	finished := make(map[string]ModuleResult, len(sorted))
+	started := make(map[string]bool, len(sorted))
+	running := 0
+	failed := false
+	for {
+		// Start every module whose wait is over, in topological order.
+		for _, id := range sorted {
+			if running >= limit || (failed && !r.keepGoing) {
+				break
+			}
+			if started[id] || !allFinished(waitFor[id], finished) {
+				continue
+			}
+			var prior []ModuleResult
+			for _, dep := range waitFor[id] {
+				prior = append(prior, finished[dep])
+			}
+			started[id] = true
+			running++
+			go func(mod *Module) {
+				done <- pr.RunModuleAfter(ctx, mod, prior)
+			}(mods[id])
+		}
+		if running == 0 {
+			break
+		}
+		result := <-done
+		running--
+		finished[result.ModuleID] = result
+		if result.Err != nil {
+			failed = true
+		}
+	}
+
+	results := make([]ModuleResult, 0, len(finished))
+	var errs []error
+	for _, id := range sorted {
+		result, ok := finished[id]
+		if !ok {
+			continue
+		}
+		results = append(results, result)
+		if result.Err != nil {
+			errs = append(errs, result.Err)
+		}
+	}
+	if len(errs) > 0 && !r.keepGoing {
+		return results, errs[0]
+	}
+	return results, errors.Join(errs...)
+}
+
+// allFinished reports whether every module in ids has a result in finished.
+func allFinished(ids []string, finished map[string]ModuleResult) bool {
+	for _, id := range ids {
+		if _, ok := finished[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// serialized returns a copy of r whose callbacks hold a shared lock, so
+// modules running concurrently never call them at the same time.
+func (r *Runner) serialized() *Runner {
+	var mu sync.Mutex
+	pr := *r
+	if cb := r.callback; cb != nil {
+		pr.callback = func(module *Module, step *Step, index int, total int, outcome StepOutcome, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			cb(module, step, index, total, outcome, err)
+		}
+	}
+	if cb := r.preCallback; cb != nil {
+		pr.preCallback = func(module *Module, step *Step, index int, total int) {
+			mu.Lock()
+			defer mu.Unlock()
+			cb(module, step, index, total)
+		}
+	}
+	if cb := r.modCallback; cb != nil {
+		pr.modCallback = func(module *Module, phase ModulePhase, result *ModuleResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			cb(module, phase, result)
+		}
+	}
+	return &pr
 }
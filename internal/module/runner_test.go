@@ -3,10 +3,20 @@ package module
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io/fs"
 	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/druarnfield/shhh/internal/exec"
 	"github.com/druarnfield/shhh/internal/logging"
+	"github.com/druarnfield/shhh/internal/platform"
 )
 
 func nopLogger() *slog.Logger {
@@ -83,6 +93,76 @@ func TestRunner_SkipsPassedChecks(t *testing.T) {
 	}
 }
 
+func TestRunner_RecordsDurations(t *testing.T) {
+	mod := &Module{
+		ID:   "test",
+		Name: "Test",
+		Steps: []Step{
+			{Name: "skipped", Check: func(ctx context.Context) bool { return true }},
+			{Name: "ran", Run: func(ctx context.Context) error { return nil }},
+		},
+	}
+
+	result := NewRunner(nopLogger(), false).RunModule(context.Background(), mod)
+	for _, name := range []string{"skipped", "ran"} {
+		if _, ok := result.Durations[name]; !ok {
+			t.Errorf("no duration recorded for %q", name)
+		}
+	}
+
+	dry := NewRunner(nopLogger(), true).RunModule(context.Background(), mod)
+	if len(dry.Durations) != 0 {
+		t.Errorf("dry run recorded durations: %v", dry.Durations)
+	}
+}
+
+func TestModuleResult_SlowestSteps(t *testing.T) {
+	r := ModuleResult{Durations: map[string]time.Duration{
+		"a": time.Second,
+		"b": 3 * time.Second,
+		"c": time.Second,
+		"d": 2 * time.Second,
+	}}
+	if got, want := r.SlowestSteps(3), []string{"b", "d", "a"}; !slices.Equal(got, want) {
+		t.Errorf("SlowestSteps(3) = %v, want %v", got, want)
+	}
+	if got := r.SlowestSteps(10); len(got) != 4 {
+		t.Errorf("SlowestSteps(10) = %v, want all 4 steps", got)
+	}
+	if got := (ModuleResult{}).SlowestSteps(3); len(got) != 0 {
+		t.Errorf("SlowestSteps without durations = %v, want none", got)
+	}
+}
+
+func TestRunner_RecordsOutcomes(t *testing.T) {
+	mod := &Module{
+		ID:   "test",
+		Name: "Test",
+		Steps: []Step{
+			{Name: "skipped", Check: func(ctx context.Context) bool { return true }},
+			{Name: "ran", Run: func(ctx context.Context) error { return nil }},
+			{Name: "fails", Run: func(ctx context.Context) error { return errors.New("boom") }},
+			{Name: "after", Run: func(ctx context.Context) error { return nil }},
+		},
+	}
+
+	result := NewRunner(nopLogger(), false).RunModule(context.Background(), mod)
+	want := map[string]StepOutcome{"skipped": StepSkipped, "ran": StepCompleted, "fails": StepFailed}
+	for name, o := range want {
+		if got := result.Outcomes[name]; got != o {
+			t.Errorf("Outcomes[%q] = %v, want %v", name, got, o)
+		}
+	}
+	if got := result.Outcomes["after"]; got != StepNotReached {
+		t.Errorf("Outcomes[after] = %v, want %v", got, StepNotReached)
+	}
+
+	dry := NewRunner(nopLogger(), true).RunModule(context.Background(), mod)
+	if got := dry.Outcomes["ran"]; got != StepWouldRun {
+		t.Errorf("dry run Outcomes[ran] = %v, want %v", got, StepWouldRun)
+	}
+}
+
 func TestRunner_StopsOnError(t *testing.T) {
 	step2ran := false
 	mod := &Module{
@@ -142,6 +222,10 @@ func TestRunner_DryRun(t *testing.T) {
 	}
 
 	runner := NewRunner(nopLogger(), true)
+	var reported StepOutcome
+	runner.SetCallback(func(_ *Module, _ *Step, _, _ int, outcome StepOutcome, _ error) {
+		reported = outcome
+	})
 	result := runner.RunModule(context.Background(), mod)
 
 	if ran {
@@ -150,6 +234,12 @@ func TestRunner_DryRun(t *testing.T) {
 	if result.Err != nil {
 		t.Fatalf("unexpected error: %v", result.Err)
 	}
+	if result.WouldRun != 1 || result.Completed != 0 {
+		t.Errorf("WouldRun = %d, Completed = %d; want 1, 0", result.WouldRun, result.Completed)
+	}
+	if reported != StepWouldRun {
+		t.Errorf("callback outcome = %v, want %v", reported, StepWouldRun)
+	}
 }
 
 func TestRunner_RunModules(t *testing.T) {
@@ -193,3 +283,631 @@ func TestRunner_RunModules(t *testing.T) {
 		t.Errorf("execution order = %v, want [base, python]", order)
 	}
 }
+
+func TestRunner_KeepGoing(t *testing.T) {
+	fail := func(ctx context.Context) error { return errors.New("boom") }
+	var ran []string
+	ok := func(id string) func(context.Context) error {
+		return func(ctx context.Context) error {
+			ran = append(ran, id)
+			return nil
+		}
+	}
+
+	reg := NewRegistry()
+	reg.Register(&Module{ID: "base", Steps: []Step{{Name: "base-step", Run: ok("base")}}})
+	reg.Register(&Module{ID: "python", Dependencies: []string{"base"}, Steps: []Step{{Name: "python-step", Run: fail}}})
+	reg.Register(&Module{ID: "node", Dependencies: []string{"base"}, Steps: []Step{{Name: "node-step", Run: fail}}})
+	reg.Register(&Module{ID: "tools", Dependencies: []string{"node"}, Steps: []Step{{Name: "tools-step", Run: ok("tools")}}})
+	reg.Register(&Module{ID: "cloud", Dependencies: []string{"base"}, Steps: []Step{{Name: "cloud-step", Run: ok("cloud")}}})
+
+	runner := NewRunner(nopLogger(), false)
+	runner.SetKeepGoing(true)
+	results, err := runner.RunModules(context.Background(), reg, []string{"python", "node", "tools", "cloud"})
+
+	if len(results) != 5 {
+		t.Fatalf("got %d results, want 5", len(results))
+	}
+	if err == nil || strings.Count(err.Error(), "boom") != 2 {
+		t.Errorf("err = %v, want both failures joined", err)
+	}
+	if !slices.Equal(ran, []string{"base", "cloud"}) {
+		t.Errorf("ran = %v, want [base cloud]", ran)
+	}
+	for _, r := range results {
+		if r.ModuleID == "tools" && !errors.Is(r.Err, ErrDependencyFailed) {
+			t.Errorf("tools err = %v, want ErrDependencyFailed", r.Err)
+		}
+	}
+}
+
+func TestRunner_RunModulesParallel(t *testing.T) {
+	var baseDone, started atomic.Int32
+	lang := func(ctx context.Context) error {
+		if baseDone.Load() == 0 {
+			return errors.New("started before base finished")
+		}
+		// Wait for all three language modules to be running at once.
+		started.Add(1)
+		deadline := time.Now().Add(2 * time.Second)
+		for started.Load() < 3 {
+			if time.Now().After(deadline) {
+				return errors.New("modules did not run concurrently")
+			}
+			time.Sleep(time.Millisecond)
+		}
+		return nil
+	}
+
+	reg := NewRegistry()
+	reg.Register(&Module{ID: "base", Category: CategoryBase, Steps: []Step{{
+		Name: "base-step",
+		Run: func(ctx context.Context) error {
+			time.Sleep(10 * time.Millisecond)
+			baseDone.Store(1)
+			return nil
+		},
+	}}})
+	reg.Register(&Module{ID: "golang", Category: CategoryLanguage, Dependencies: []string{"base"}, Steps: []Step{{Name: "go-step", Run: lang}}})
+	reg.Register(&Module{ID: "python", Category: CategoryLanguage, Dependencies: []string{"base"}, Steps: []Step{{Name: "python-step", Run: lang}}})
+	// node doesn't declare base, but still waits for it.
+	reg.Register(&Module{ID: "node", Category: CategoryLanguage, Steps: []Step{{Name: "node-step", Run: lang}}})
+
+	steps := 0
+	runner := NewRunner(nopLogger(), false)
+	runner.SetParallelism(3)
+	runner.SetCallback(func(*Module, *Step, int, int, StepOutcome, error) { steps++ })
+	results, err := runner.RunModulesParallel(context.Background(), reg, []string{"node", "python", "golang"})
+	if err != nil {
+		t.Fatalf("RunModulesParallel: %v", err)
+	}
+
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.ModuleID)
+	}
+	if want := []string{"base", "node", "golang", "python"}; !slices.Equal(ids, want) {
+		t.Errorf("results = %v, want %v", ids, want)
+	}
+	if steps != 4 {
+		t.Errorf("callback ran %d times, want 4", steps)
+	}
+}
+
+func TestRunner_RunModulesParallelStopsOnFailure(t *testing.T) {
+	var ran []string
+	reg := NewRegistry()
+	reg.Register(&Module{ID: "base", Category: CategoryBase, Steps: []Step{{
+		Name: "base-step",
+		Run:  func(ctx context.Context) error { return errors.New("boom") },
+	}}})
+	reg.Register(&Module{ID: "cloud", Category: CategoryTool, Steps: []Step{{
+		Name: "cloud-step",
+		Run: func(ctx context.Context) error {
+			ran = append(ran, "cloud")
+			return nil
+		},
+	}}})
+
+	results, err := NewRunner(nopLogger(), false).RunModulesParallel(context.Background(), reg, []string{"base", "cloud"})
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("err = %v, want the base failure", err)
+	}
+	if len(results) != 1 || len(ran) != 0 {
+		t.Errorf("results = %d, ran = %v; want only base", len(results), ran)
+	}
+}
+
+func TestRunner_SkipsUnsupportedOS(t *testing.T) {
+	var ran []string
+	step := func(name string) Step {
+		return Step{Name: name, Run: func(ctx context.Context) error {
+			ran = append(ran, name)
+			return nil
+		}}
+	}
+	reg := NewRegistry()
+	reg.Register(&Module{ID: "base", SupportedOS: []string{"windows"}, Steps: []Step{step("scoop")}})
+	reg.Register(&Module{ID: "golang", Dependencies: []string{"base"}, Steps: []Step{step("go")}})
+	reg.Register(&Module{ID: "docs", SupportedOS: []string{"linux", "darwin"}, Steps: []Step{step("docs")}})
+
+	runner := NewRunner(nopLogger(), false)
+	runner.SetOS("darwin")
+	results, err := runner.RunModules(context.Background(), reg, []string{"golang", "docs"})
+	if err != nil {
+		t.Fatalf("RunModules: %v", err)
+	}
+	if len(ran) != 1 || ran[0] != "docs" {
+		t.Errorf("ran = %v, want only docs", ran)
+	}
+	want := map[string]string{
+		"base":   "requires Windows",
+		"golang": "needs base, which requires Windows",
+		"docs":   "",
+	}
+	for _, r := range results {
+		if r.Unsupported != want[r.ModuleID] {
+			t.Errorf("%s: Unsupported = %q, want %q", r.ModuleID, r.Unsupported, want[r.ModuleID])
+		}
+		if r.Err != nil {
+			t.Errorf("%s: Err = %v, want nil", r.ModuleID, r.Err)
+		}
+	}
+
+	runner.SetOS("windows")
+	ran = nil
+	if _, err := runner.RunModules(context.Background(), reg, []string{"golang"}); err != nil || len(ran) != 2 {
+		t.Errorf("on Windows: err = %v, ran = %v; want scoop and go", err, ran)
+	}
+}
+
+func TestRunner_StepFilter(t *testing.T) {
+	var ran []string
+	step := func(name string) Step {
+		return Step{
+			Name:  name,
+			Check: func(context.Context) bool { return false },
+			Run: func(context.Context) error {
+				ran = append(ran, name)
+				return nil
+			},
+		}
+	}
+	reg := NewRegistry()
+	reg.Register(&Module{ID: "base", Steps: []Step{step("Install Scoop"), step("Build CA bundle")}})
+	reg.Register(&Module{ID: "golang", Dependencies: []string{"base"}, Steps: []Step{step("Install Go"), step("Set Go CA")}})
+
+	var outcomes []string
+	runner := NewRunner(nopLogger(), false)
+	runner.SetStepFilter(func(_ *Module, step *Step) bool {
+		return !strings.HasPrefix(step.Name, "Install")
+	})
+	runner.SetCallback(func(_ *Module, step *Step, _, _ int, outcome StepOutcome, _ error) {
+		outcomes = append(outcomes, step.Name+": "+outcome.String())
+	})
+	results, err := runner.RunModules(context.Background(), reg, []string{"golang"})
+	if err != nil {
+		t.Fatalf("RunModules: %v", err)
+	}
+
+	if want := []string{"Build CA bundle", "Set Go CA"}; !slices.Equal(ran, want) {
+		t.Errorf("ran = %v, want %v", ran, want)
+	}
+	wantOutcomes := []string{
+		"Install Scoop: filtered", "Build CA bundle: completed",
+		"Install Go: filtered", "Set Go CA: completed",
+	}
+	if !slices.Equal(outcomes, wantOutcomes) {
+		t.Errorf("callback outcomes = %v, want %v", outcomes, wantOutcomes)
+	}
+	if len(results) != 2 || results[0].ModuleID != "base" || results[0].Filtered != 1 || results[1].Completed != 1 {
+		t.Errorf("results = %+v", results)
+	}
+	if results[1].Outcomes["Install Go"] != StepFiltered {
+		t.Errorf("Install Go outcome = %v, want filtered", results[1].Outcomes["Install Go"])
+	}
+}
+
+func TestRunner_ModuleCallback(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register(&Module{
+		ID:    "base",
+		Steps: []Step{{Name: "ok", Run: func(ctx context.Context) error { return nil }}},
+	})
+	reg.Register(&Module{
+		ID:           "python",
+		Dependencies: []string{"base"},
+		Steps:        []Step{{Name: "broken", Run: func(ctx context.Context) error { return errors.New("boom") }}},
+	})
+
+	var events []string
+	runner := NewRunner(nopLogger(), false)
+	runner.SetCallback(func(mod *Module, step *Step, _, _ int, _ StepOutcome, _ error) {
+		events = append(events, "step "+step.Name)
+	})
+	runner.SetModuleCallback(func(mod *Module, phase ModulePhase, result *ModuleResult) {
+		switch phase {
+		case ModuleStarted:
+			if result != nil {
+				t.Errorf("%s: result should be nil at start", mod.ID)
+			}
+			events = append(events, "start "+mod.ID)
+		case ModuleFinished:
+			events = append(events, fmt.Sprintf("finish %s err=%v", mod.ID, result.Err != nil))
+		}
+	})
+
+	if _, err := runner.RunModules(context.Background(), reg, []string{"python"}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	want := []string{
+		"start base", "step ok", "finish base err=false",
+		"start python", "step broken", "finish python err=true",
+	}
+	if fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+}
+
+func TestRunner_StopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	step2ran := false
+	mod := &Module{
+		ID:   "test",
+		Name: "Test",
+		Steps: []Step{
+			{
+				Name: "cancels",
+				Run: func(ctx context.Context) error {
+					cancel()
+					return nil
+				},
+			},
+			{
+				Name: "should not run",
+				Run: func(ctx context.Context) error {
+					step2ran = true
+					return nil
+				},
+			},
+		},
+	}
+
+	runner := NewRunner(nopLogger(), false)
+	result := runner.RunModule(ctx, mod)
+
+	if step2ran {
+		t.Error("no step should start after cancellation")
+	}
+	if !errors.Is(result.Err, context.Canceled) {
+		t.Errorf("Err = %v, want context.Canceled", result.Err)
+	}
+	if result.FailedStep != "should not run" {
+		t.Errorf("FailedStep = %q, want %q", result.FailedStep, "should not run")
+	}
+}
+
+func TestRunner_NilRunIsNoOp(t *testing.T) {
+	ran := false
+	mod := &Module{
+		ID: "test",
+		Steps: []Step{
+			{Name: "planning only"},
+			{
+				Name: "real",
+				Run: func(ctx context.Context) error {
+					ran = true
+					return nil
+				},
+			},
+		},
+	}
+
+	runner := NewRunner(nopLogger(), false)
+	result := runner.RunModule(context.Background(), mod)
+
+	if result.Err != nil {
+		t.Fatalf("RunModule error: %v", result.Err)
+	}
+	if result.Completed != 2 {
+		t.Errorf("completed = %d, want 2", result.Completed)
+	}
+	if !ran {
+		t.Error("step after nil-Run step did not run")
+	}
+}
+
+func TestRunner_RetriesFailedStep(t *testing.T) {
+	calls := 0
+	mod := &Module{
+		ID: "test",
+		Steps: []Step{
+			{
+				Name:    "flaky",
+				Retries: 2,
+				Run: func(ctx context.Context) error {
+					calls++
+					if calls < 2 {
+						return &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}
+					}
+					return nil
+				},
+			},
+			{Name: "steady", Run: func(ctx context.Context) error { return nil }},
+		},
+	}
+
+	runner := NewRunner(nopLogger(), false)
+	result := runner.RunModule(context.Background(), mod)
+
+	if result.Err != nil {
+		t.Fatalf("RunModule error: %v", result.Err)
+	}
+	if result.Attempts["flaky"] != 2 || result.Attempts["steady"] != 1 {
+		t.Errorf("Attempts = %v, want flaky:2 steady:1", result.Attempts)
+	}
+	if got := result.RetriedSteps(); len(got) != 1 || got[0] != "flaky" {
+		t.Errorf("RetriedSteps = %v, want [flaky]", got)
+	}
+	if got, want := result.AttemptNote("flaky"), "flaky (succeeded after 1 retry)"; got != want {
+		t.Errorf("AttemptNote = %q, want %q", got, want)
+	}
+}
+
+func TestRunner_RetryBackoff(t *testing.T) {
+	failures := 0
+	mock := &exec.MockRunner{Match: func(name string, args []string) (exec.Result, bool) {
+		if failures < 2 {
+			failures++
+			return exec.Result{ExitCode: 1, Stderr: "TLS connection reset"}, true
+		}
+		return exec.Result{}, true
+	}}
+	checks := 0
+	mod := &Module{
+		ID: "test",
+		Steps: []Step{{
+			Name:         "Install Go",
+			Retries:      3,
+			RetryBackoff: 10 * time.Millisecond,
+			Check: func(ctx context.Context) bool {
+				checks++
+				return false
+			},
+			Run: func(ctx context.Context) error {
+				_, err := mock.Run(ctx, "scoop", "install", "go")
+				return err
+			},
+		}},
+	}
+
+	start := time.Now()
+	result := NewRunner(nopLogger(), false).RunModule(context.Background(), mod)
+	elapsed := time.Since(start)
+
+	if result.Err != nil {
+		t.Fatalf("RunModule error: %v", result.Err)
+	}
+	if result.Attempts["Install Go"] != 3 || len(mock.Calls) != 3 {
+		t.Errorf("Attempts = %v, calls = %v; want 3", result.Attempts, mock.Calls)
+	}
+	if checks != 3 {
+		t.Errorf("Check called %d times, want once up front and before each retry", checks)
+	}
+	// 10ms, then 20ms.
+	if elapsed < 30*time.Millisecond {
+		t.Errorf("retries took %s, want at least 30ms of backoff", elapsed)
+	}
+}
+
+func TestRunner_RetryStopsWhenCheckPasses(t *testing.T) {
+	installed := false
+	calls := 0
+	mod := &Module{
+		ID: "test",
+		Steps: []Step{{
+			Name:    "Install Node",
+			Retries: 2,
+			Check:   func(ctx context.Context) bool { return installed },
+			Run: func(ctx context.Context) error {
+				calls++
+				// The install landed, but a post-install download failed.
+				installed = true
+				return &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}
+			},
+		}},
+	}
+
+	result := NewRunner(nopLogger(), false).RunModule(context.Background(), mod)
+
+	if result.Err != nil {
+		t.Fatalf("RunModule error: %v", result.Err)
+	}
+	if calls != 1 {
+		t.Errorf("Run called %d times, want 1", calls)
+	}
+	if result.Completed != 1 {
+		t.Errorf("completed = %d, want 1", result.Completed)
+	}
+}
+
+func TestRunner_RetriesExhausted(t *testing.T) {
+	calls := 0
+	mod := &Module{
+		ID: "test",
+		Steps: []Step{
+			{
+				Name:    "down",
+				Retries: 2,
+				Run: func(ctx context.Context) error {
+					calls++
+					return &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("unreachable")}
+				},
+			},
+		},
+	}
+
+	runner := NewRunner(nopLogger(), false)
+	result := runner.RunModule(context.Background(), mod)
+
+	if result.Err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 3 {
+		t.Errorf("Run called %d times, want 3", calls)
+	}
+	if got, want := result.AttemptNote("down"), "down (failed after 3 attempts)"; got != want {
+		t.Errorf("AttemptNote = %q, want %q", got, want)
+	}
+}
+
+func TestRunner_DoesNotRetryNonRetriableError(t *testing.T) {
+	calls := 0
+	mod := &Module{
+		ID: "test",
+		Steps: []Step{
+			{
+				Name:    "missing",
+				Retries: 2,
+				Run: func(ctx context.Context) error {
+					calls++
+					return fmt.Errorf("installing: %w", exec.ErrCommandNotFound)
+				},
+			},
+		},
+	}
+
+	runner := NewRunner(nopLogger(), false)
+	result := runner.RunModule(context.Background(), mod)
+
+	if result.Err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 {
+		t.Errorf("Run called %d times, want 1 (command not found is not retriable)", calls)
+	}
+}
+
+func TestRunner_CustomRetriable(t *testing.T) {
+	calls := 0
+	mod := &Module{
+		ID: "test",
+		Steps: []Step{
+			{
+				Name:      "picky",
+				Retries:   2,
+				Retriable: func(err error) bool { return err.Error() == "busy" },
+				Run: func(ctx context.Context) error {
+					calls++
+					if calls == 1 {
+						return errors.New("busy")
+					}
+					return errors.New("version mismatch")
+				},
+			},
+		},
+	}
+
+	runner := NewRunner(nopLogger(), false)
+	result := runner.RunModule(context.Background(), mod)
+
+	if result.Err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 2 {
+		t.Errorf("Run called %d times, want 2 (retry on busy, stop on mismatch)", calls)
+	}
+}
+
+func TestDefaultRetriable(t *testing.T) {
+	mock := &exec.MockRunner{Results: map[string]exec.Result{"scoop install go": {ExitCode: 1}}}
+	_, exitErr := mock.Run(context.Background(), "scoop", "install", "go")
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"command not found", fmt.Errorf("scoop: %w", exec.ErrCommandNotFound), false},
+		{"permission denied", &fs.PathError{Op: "open", Path: "x", Err: fs.ErrPermission}, false},
+		{"not supported", platform.ErrNotSupported, false},
+		{"network timeout", &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}, true},
+		{"non-zero exit", fmt.Errorf("installing go: %w", exitErr), true},
+		{"timeout", fmt.Errorf("download: %w", context.DeadlineExceeded), true},
+		{"validation", errors.New(`package "go" is not in [scoop] allowed`), false},
+		{"usage", fmt.Errorf("unknown shell %q", "fish"), false},
+	}
+	for _, tt := range tests {
+		if got := DefaultRetriable(tt.err); got != tt.want {
+			t.Errorf("%s: DefaultRetriable = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRunner_AssumeSatisfiedSkipsWithoutChecking(t *testing.T) {
+	checked, ran := false, false
+	run := func(context.Context) error { ran = true; return nil }
+	mod := &Module{
+		ID:   "test",
+		Name: "Test",
+		Steps: []Step{
+			{Name: "checked", Check: func(context.Context) bool { checked = true; return false }, Run: run},
+			{Name: "unchecked", Run: run},
+		},
+	}
+	mod.AssumeSatisfied()
+
+	result := NewRunner(nopLogger(), false).RunModule(context.Background(), mod)
+	if checked {
+		t.Error("original Check should not be called")
+	}
+	if ran {
+		t.Error("no step should run")
+	}
+	if result.Skipped != 2 || result.Err != nil {
+		t.Errorf("result = %+v, want both steps skipped", result)
+	}
+}
+
+func TestRunner_StepTimeout(t *testing.T) {
+	attempts := 0
+	checkCtxHadDeadline := false
+	mod := &Module{
+		ID:   "test",
+		Name: "Test",
+		Steps: []Step{
+			{
+				Name: "hangs",
+				Check: func(ctx context.Context) bool {
+					_, checkCtxHadDeadline = ctx.Deadline()
+					return false
+				},
+				Run: func(ctx context.Context) error {
+					attempts++
+					<-ctx.Done()
+					return ctx.Err()
+				},
+				Retries: 2,
+				Timeout: 10 * time.Millisecond,
+			},
+			{Name: "after", Run: func(context.Context) error { t.Error("module should stop after a timeout"); return nil }},
+		},
+	}
+
+	result := NewRunner(nopLogger(), false).RunModule(context.Background(), mod)
+	if !errors.Is(result.Err, context.DeadlineExceeded) {
+		t.Fatalf("Err = %v, want DeadlineExceeded", result.Err)
+	}
+	if !strings.Contains(result.Err.Error(), `"hangs"`) || !strings.Contains(result.Err.Error(), "timed out after 10ms") {
+		t.Errorf("Err = %v, want the step name and timeout", result.Err)
+	}
+	if result.FailedStep != "hangs" {
+		t.Errorf("FailedStep = %q", result.FailedStep)
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want a timed-out step not retried", attempts)
+	}
+	if checkCtxHadDeadline {
+		t.Error("Check should not be bounded by the step timeout")
+	}
+}
+
+func TestRunner_DefaultTimeout(t *testing.T) {
+	var deadlines []bool
+	run := func(ctx context.Context) error {
+		_, ok := ctx.Deadline()
+		deadlines = append(deadlines, ok)
+		return nil
+	}
+	mod := &Module{ID: "test", Steps: []Step{{Name: "a", Run: run}}}
+
+	NewRunner(nopLogger(), false).RunModule(context.Background(), mod)
+	r := NewRunner(nopLogger(), false)
+	r.SetDefaultTimeout(time.Minute)
+	r.RunModule(context.Background(), mod)
+
+	if len(deadlines) != 2 || deadlines[0] || !deadlines[1] {
+		t.Errorf("deadlines = %v, want none without a default and one with it", deadlines)
+	}
+}
@@ -0,0 +1,107 @@
+package setup
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/druarnfield/shhh/internal/config"
+	"github.com/druarnfield/shhh/internal/platform"
+	"github.com/druarnfield/shhh/internal/state"
+)
+
+// ExpectedEnv returns the value the current config gives each variable the
+// setup modules write to the user environment: the Env of every module's
+// steps, the last step to set a variable winning as it does when they run.
+// A variable the config has a step clear (a proxy variable in direct mode)
+// maps to "".
+func ExpectedEnv(cfg *config.Config) map[string]string {
+	deps := &Dependencies{Config: cfg, State: &state.State{}}
+	want := make(map[string]string)
+	for _, m := range Modules(deps) {
+		for _, step := range m.Steps {
+			maps.Copy(want, step.Env)
+		}
+	}
+	return want
+}
+
+// goEnvKeys are variables shhh records as managed but writes to go env
+// rather than the user environment.
+var goEnvKeys = []string{"GOPROXY"}
+
+// EnvDrift is a managed variable whose persistent value differs from what
+// the current config would set. Want is "" when config no longer sets it.
+type EnvDrift struct {
+	Key     string
+	Current string
+	Want    string
+}
+
+// AuditEnv compares the user-environment value of each managed variable
+// with ExpectedEnv, returning the ones that differ sorted by name. A
+// variable no step sets any more (e.g. PIP_INDEX_URL once pypi_mirror is
+// removed) is expected to be unset.
+func AuditEnv(env platform.UserEnv, cfg *config.Config, managed []string) ([]EnvDrift, error) {
+	want := ExpectedEnv(cfg)
+
+	var drift []EnvDrift
+	for _, key := range managed {
+		if slices.Contains(goEnvKeys, key) {
+			continue
+		}
+		expected := want[key]
+		value, src, err := env.Get(key)
+		if errors.Is(err, platform.ErrNotSupported) {
+			return nil, err
+		}
+		current := ""
+		if err == nil && src == platform.SourceUser {
+			current = value
+		}
+		if current != expected {
+			drift = append(drift, EnvDrift{Key: key, Current: current, Want: expected})
+		}
+	}
+
+	sort.Slice(drift, func(i, j int) bool { return drift[i].Key < drift[j].Key })
+	return drift, nil
+}
+
+// StalePathEntries returns the directories in managed, the PATH entries
+// shhh added, that are still on the user PATH but no longer exist, such as
+// the shim directory of a Scoop package removed by hand. They are returned
+// in managed's order.
+func StalePathEntries(env platform.UserEnv, managed []string) ([]string, error) {
+	entries, err := env.ListPath()
+	if err != nil {
+		return nil, err
+	}
+	var stale []string
+	for _, dir := range managed {
+		// Windows paths are case-insensitive.
+		missing := slices.ContainsFunc(entries, func(e platform.PathEntry) bool {
+			return strings.EqualFold(e.Dir, dir) && e.Source == platform.SourceUser && !e.Exists
+		})
+		if missing {
+			stale = append(stale, dir)
+		}
+	}
+	return stale, nil
+}
+
+// RemoveStalePathEntries takes each of dirs off the user PATH and stops
+// tracking it in st (doctor --fix). It stops at the first failure; dirs
+// removed before it stay removed.
+func RemoveStalePathEntries(env platform.UserEnv, st *state.State, dirs []string) error {
+	for _, dir := range dirs {
+		if err := env.RemovePath(dir); err != nil {
+			return fmt.Errorf("removing %s from PATH: %w", dir, err)
+		}
+		st.RemovePathEntry(dir)
+	}
+	return nil
+}
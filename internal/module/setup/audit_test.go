@@ -0,0 +1,104 @@
+package setup
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/druarnfield/shhh/internal/config"
+	"github.com/druarnfield/shhh/internal/platform/mock"
+	"github.com/druarnfield/shhh/internal/state"
+)
+
+func TestAuditEnv_FlagsStaleValues(t *testing.T) {
+	cfg := testConfig()
+	cfg.Registries.PyPIMirror = "https://pypi.new.example.com/simple"
+	cfg.Proxy.NoProxy = ""
+
+	env := mock.NewUserEnv()
+	env.Set("HTTP_PROXY", cfg.Proxy.HTTP)                           // up to date
+	env.Set("PIP_INDEX_URL", "https://pypi.old.example.com/simple") // stale
+	env.Set("NO_PROXY", "localhost")                                // no longer configured
+	env.Set("SSL_CERT_FILE", config.CABundlePath())                 // up to date
+	// GOPROXY is managed through go env, so it isn't audited.
+	managed := []string{"HTTP_PROXY", "PIP_INDEX_URL", "NO_PROXY", "SSL_CERT_FILE", "GOPROXY", "GIT_SSL_CAINFO"}
+
+	drift, err := AuditEnv(env, cfg, managed)
+	if err != nil {
+		t.Fatalf("AuditEnv: %v", err)
+	}
+
+	want := []EnvDrift{
+		{Key: "GIT_SSL_CAINFO", Current: "", Want: config.CABundlePath()},
+		{Key: "NO_PROXY", Current: "localhost", Want: ""},
+		{Key: "PIP_INDEX_URL", Current: "https://pypi.old.example.com/simple", Want: cfg.Registries.PyPIMirror},
+	}
+	if len(drift) != len(want) {
+		t.Fatalf("drift = %+v, want %+v", drift, want)
+	}
+	for i := range want {
+		if drift[i] != want[i] {
+			t.Errorf("drift[%d] = %+v, want %+v", i, drift[i], want[i])
+		}
+	}
+}
+
+func TestExpectedEnv_DirectProxy(t *testing.T) {
+	cfg := testConfig()
+	cfg.Proxy.Mode = "direct"
+
+	want := ExpectedEnv(cfg)
+	for _, key := range proxyEnvKeys {
+		if v := want[key]; v != "" {
+			t.Errorf("%s = %q, want unset in direct mode", key, v)
+		}
+	}
+}
+
+func TestExpectedEnv_FollowsStepEnv(t *testing.T) {
+	cfg := testConfig()
+	cfg.Registries.PyPIMirror = ""
+
+	want := ExpectedEnv(cfg)
+	if _, ok := want["PIP_INDEX_URL"]; ok {
+		t.Error("PIP_INDEX_URL should not be expected without a pypi_mirror")
+	}
+	for _, m := range Modules(&Dependencies{Config: cfg, State: &state.State{}}) {
+		for _, step := range m.Steps {
+			for key := range step.Env {
+				if _, ok := want[key]; !ok {
+					t.Errorf("%s: %s sets %s, which ExpectedEnv is missing", m.ID, step.Name, key)
+				}
+			}
+		}
+	}
+}
+
+func TestStalePathEntries(t *testing.T) {
+	env := mock.NewUserEnv()
+	env.AppendPath(`C:\Users\dev\scoop\shims`)
+	env.AppendPath(`C:\Users\dev\scoop\apps\jq\current`)
+	env.AppendPath(`C:\Users\dev\go\bin`)
+	env.(*mock.UserEnv).Missing = []string{`C:\Users\dev\scoop\apps\jq\current`, `C:\Users\dev\go\bin`}
+
+	// go\bin is missing too, but shhh didn't add it.
+	managed := []string{`C:\Users\dev\scoop\shims`, `C:\Users\dev\scoop\apps\jq\current`, `C:\gone\never\on\path`}
+	stale, err := StalePathEntries(env, managed)
+	if err != nil {
+		t.Fatalf("StalePathEntries: %v", err)
+	}
+	if len(stale) != 1 || stale[0] != `C:\Users\dev\scoop\apps\jq\current` {
+		t.Fatalf("stale = %q, want the jq directory", stale)
+	}
+
+	st := &state.State{ManagedPathEntries: managed}
+	if err := RemoveStalePathEntries(env, st, stale); err != nil {
+		t.Fatalf("RemoveStalePathEntries: %v", err)
+	}
+	entries, _ := env.ListPath()
+	if len(entries) != 2 {
+		t.Errorf("PATH = %+v, want the jq directory removed", entries)
+	}
+	if slices.Contains(st.ManagedPathEntries, stale[0]) {
+		t.Errorf("state still tracks the removed entry: %q", st.ManagedPathEntries)
+	}
+}
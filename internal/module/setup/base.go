@@ -2,17 +2,27 @@ package setup
 
 import (
 	"context"
+	"crypto/ed25519"
 	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/druarnfield/shhh/internal/config"
 	shexec "github.com/druarnfield/shhh/internal/exec"
+	"github.com/druarnfield/shhh/internal/logging"
 	"github.com/druarnfield/shhh/internal/module"
 	"github.com/druarnfield/shhh/internal/platform"
 	"github.com/druarnfield/shhh/internal/state"
@@ -26,6 +36,218 @@ type Dependencies struct {
 	CertStore platform.CertStore
 	Exec      shexec.Runner
 	State     *state.State
+
+	// Logger receives warnings steps want to surface without failing.
+	// Optional; nil discards them.
+	Logger *slog.Logger
+
+	// Trace logs every value a Check compares, at debug level (--trace).
+	Trace bool
+
+	// Preview makes DryRun write what a step would produce to a temp file
+	// for inspection (setup --dry-run). Only the CA bundle does this.
+	Preview bool
+
+	// ConfirmOverwrite is asked, with [proxy] on_conflict = "ask", before a
+	// proxy variable set by something other than shhh is replaced. Nil
+	// means no one can be asked and the value is left alone.
+	ConfirmOverwrite func(key, current, want string) bool
+
+	// ConfirmPath is asked, with [safety] confirm_path_changes, before dir
+	// is added to the user PATH, whose entries are current. Nil means no
+	// one can be asked and PATH is left alone.
+	ConfirmPath func(current []platform.PathEntry, dir string) bool
+
+	// FreeSpace reports the bytes free on the drive holding a path, for
+	// Preflight. Nil means platform.FreeSpace.
+	FreeSpace func(path string) (uint64, error)
+
+	// OS is the GOOS value modules are built for; empty means the one shhh
+	// runs on. The base module leaves out its Scoop steps elsewhere than
+	// Windows; see hasScoop.
+	OS string
+}
+
+// ErrSetElsewhere is returned by a proxy step that found its variable set
+// by something other than shhh and was not allowed to replace it.
+var ErrSetElsewhere = errors.New("set by something other than shhh")
+
+// ErrPathDeclined is returned by a step whose addition to PATH was needed
+// and, with [safety] confirm_path_changes, not confirmed.
+var ErrPathDeclined = errors.New("PATH change not confirmed")
+
+// installRetries is how many times download-heavy install steps are
+// retried, to ride out flaky proxies and mirrors.
+const installRetries = 2
+
+// installBackoff is the wait before an install step's first retry; a TLS
+// reset from the proxy usually clears within seconds.
+const installBackoff = 2 * time.Second
+
+// scoopOS is the SupportedOS of the modules that install through Scoop,
+// which is Windows-only. The base module sets only variables, files and git
+// config apart from Scoop itself, so it runs anywhere and drops its Scoop
+// steps instead; see hasScoop.
+var scoopOS = []string{"windows"}
+
+// hasScoop reports whether the OS modules are built for is one Scoop runs
+// on (see scoopOS).
+func (d *Dependencies) hasScoop() bool {
+	goos := d.OS
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+	return slices.Contains(scoopOS, goos)
+}
+
+// scoopInstallTimeout bounds each attempt at 'irm get.scoop.sh | iex',
+// which can otherwise hang indefinitely behind a proxy that stalls.
+const scoopInstallTimeout = 10 * time.Minute
+
+// log returns the configured logger, or one that discards everything.
+func (d *Dependencies) log() *slog.Logger {
+	if d.Logger == nil {
+		return slog.New(logging.NopHandler{})
+	}
+	return d.Logger
+}
+
+// envMatches reports whether key is set to want in both the persistent user
+// environment and the current process.
+func (d *Dependencies) envMatches(key, want string) bool {
+	user, _, err := d.Env.Get(key)
+	proc := os.Getenv(key)
+	match := err == nil && user == want && proc == want
+	d.traceCheck(key, want, fmt.Sprintf("user=%q process=%q", user, proc), match)
+	return match
+}
+
+// envScope is where proxyStep and caBundleStep write their variables: the
+// user environment, or the machine-wide one with [proxy] scope = "system".
+func (d *Dependencies) envScope() platform.EnvSource {
+	if d.Config.Proxy.Scope == "system" {
+		return platform.SourceSystem
+	}
+	return platform.SourceUser
+}
+
+// scopedEnvMatches is envMatches that, with a system envScope, also wants
+// the value to come from the system environment rather than the user's.
+func (d *Dependencies) scopedEnvMatches(key, want string) bool {
+	if !d.envMatches(key, want) {
+		return false
+	}
+	if d.envScope() != platform.SourceSystem {
+		return true
+	}
+	_, source, err := d.Env.Get(key)
+	return err == nil && source == platform.SourceSystem
+}
+
+// envScopeName names envScope in DryRun text.
+// managedScope returns the scope shhh last wrote key to, as state records
+// it: platform.SourceSystem for a variable set machine-wide, otherwise
+// platform.SourceUser.
+func (d *Dependencies) managedScope(key string) platform.EnvSource {
+	if d.State.SystemEnv(key) {
+		return platform.SourceSystem
+	}
+	return platform.SourceUser
+}
+
+func (d *Dependencies) envScopeName() string {
+	if d.envScope() == platform.SourceSystem {
+		return "system (machine-wide)"
+	}
+	return "user"
+}
+
+// outputMatches reports whether command output, as shexec.RunOutput returns
+// it, equals want.
+func (d *Dependencies) outputMatches(what, got, want string) bool {
+	match := got == want
+	d.traceCheck(what, want, got, match)
+	return match
+}
+
+// traceCheck logs a Check comparison when tracing is on, so "why does this
+// step keep re-running" can be answered from the log.
+func (d *Dependencies) traceCheck(what, want, got string, match bool) {
+	if !d.Trace {
+		return
+	}
+	d.log().Debug("trace check",
+		slog.String("what", what),
+		slog.String("want", want),
+		slog.String("got", got),
+		slog.Bool("match", match),
+	)
+}
+
+// missingCommandHints tells the user how to get each command shhh relies on
+// when it turns out not to be installed.
+var missingCommandHints = map[string]string{
+	"scoop": `did the "Install Scoop" step run?`,
+	"git":   `install it with "scoop install git" or add it to [tools] core`,
+}
+
+// explainMissing turns a command-not-found error from Exec into one that says
+// what to do about it. Any other error is returned unchanged.
+func explainMissing(name string, err error) error {
+	if !errors.Is(err, shexec.ErrCommandNotFound) {
+		return err
+	}
+	hint := missingCommandHints[name]
+	if hint == "" {
+		return fmt.Errorf("%s is required but was not found on PATH: %w", name, err)
+	}
+	return fmt.Errorf("%s is required but was not found on PATH; %s: %w", name, hint, err)
+}
+
+// configErrorStep stands in for a module's steps when the config lacks
+// something the module needs, or lists a tool [scoop] policy forbids (see
+// config.CheckModule), so setup stops with err instead of running commands
+// built from empty values.
+func configErrorStep(err error) module.Step {
+	return module.Step{
+		Name:        "Check config",
+		Description: err.Error(),
+		Check:       func(context.Context) bool { return false },
+		Run:         func(context.Context) error { return err },
+		DryRun: func(context.Context) string {
+			return fmt.Sprintf("Would fail: %v", err)
+		},
+	}
+}
+
+// ModuleIDs are the IDs of the modules Modules creates, in the same order,
+// for checking module names in a config without building the modules.
+var ModuleIDs = []string{"base", "golang", "python", "node", "rust", "tools", "cloud", "docker"}
+
+// ModuleDependencies maps the ID of each module Modules creates to its
+// Dependencies, for config.ModulesConfig.Check.
+var ModuleDependencies = map[string][]string{
+	"golang": {"base"},
+	"python": {"base"},
+	"node":   {"base"},
+	"rust":   {"base"},
+	"tools":  {"base"},
+	"cloud":  {"base"},
+	"docker": {"base"},
+}
+
+// Modules creates every setup module, base first.
+func Modules(deps *Dependencies) []*module.Module {
+	return []*module.Module{
+		NewBaseModule(deps),
+		NewGolangModule(deps),
+		NewPythonModule(deps),
+		NewNodeModule(deps),
+		NewRustModule(deps),
+		NewToolsModule(deps),
+		NewCloudModule(deps),
+		NewDockerModule(deps),
+	}
 }
 
 // NewBaseModule creates the base setup module which configures proxy
@@ -33,22 +255,54 @@ type Dependencies struct {
 func NewBaseModule(deps *Dependencies) *module.Module {
 	var steps []module.Step
 
-	if deps.Config.Proxy.HTTP != "" {
-		steps = append(steps, proxyStep(deps, "HTTP_PROXY", deps.Config.Proxy.HTTP))
-	}
-	if deps.Config.Proxy.HTTPS != "" {
-		steps = append(steps, proxyStep(deps, "HTTPS_PROXY", deps.Config.Proxy.HTTPS))
+	// Reset runs first so every later step sets its value on a clean slate.
+	if len(deps.Config.Env.Reset) > 0 {
+		steps = append(steps, resetEnvStep(deps))
 	}
-	if deps.Config.Proxy.NoProxy != "" {
-		steps = append(steps, proxyStep(deps, "NO_PROXY", deps.Config.Proxy.NoProxy))
+	if deps.Config.Proxy.Mode == "direct" {
+		steps = append(steps, directProxyStep(deps))
+	} else {
+		if deps.Config.Proxy.HTTP != "" {
+			steps = append(steps, proxyStep(deps, "HTTP_PROXY", deps.Config.Proxy.HTTP))
+		}
+		if deps.Config.Proxy.HTTPS != "" {
+			steps = append(steps, proxyStep(deps, "HTTPS_PROXY", deps.Config.Proxy.HTTPS))
+		}
+		if noProxy := deps.Config.EffectiveNoProxy(); noProxy != "" {
+			steps = append(steps, proxyStep(deps, "NO_PROXY", noProxy))
+		}
 	}
 
 	steps = append(steps, caBundleStep(deps))
-	steps = append(steps, installScoopStep(deps))
-	if len(deps.Config.Scoop.Buckets) > 0 {
-		steps = append(steps, scoopBucketsStep(deps))
+	if deps.hasScoop() {
+		if deps.Config.Paths.Root != "" {
+			root := deps.Config.InstallPath("scoop")
+			// Pointing SCOOP at a new, empty root while an existing
+			// install's shims stay on PATH would send every scoop install
+			// to a root without buckets, so an existing Scoop stays put.
+			if existing := existingScoopRoot(); existing != "" && !strings.EqualFold(filepath.Clean(existing), filepath.Clean(root)) {
+				deps.log().Warn("Scoop is already installed elsewhere; [paths] root doesn't move it",
+					slog.String("scoop", existing), slog.String("root", root))
+			} else {
+				steps = append(steps, installRootStep(deps, "base", "SCOOP", root, "Scoop"))
+			}
+		}
+		steps = append(steps, installScoopStep(deps))
+		if len(deps.Config.Scoop.Buckets) > 0 {
+			steps = append(steps, scoopBucketsStep(deps))
+		}
+		if deps.Config.Scoop.Update {
+			steps = append(steps, scoopUpdateStep(deps))
+		}
+		if deps.Config.Git.Version != "" {
+			steps = append(steps, installGitStep(deps))
+		}
+	}
+	if deps.Config.Git.UseInclude {
+		steps = append(steps, gitIncludeStep(deps))
 	}
 	steps = append(steps, gitSSLCAInfoStep(deps))
+	steps = append(steps, gitCAEnvStep(deps))
 	steps = append(steps, gitDefaultBranchStep(deps))
 
 	return &module.Module{
@@ -66,27 +320,186 @@ func proxyStep(deps *Dependencies, key, value string) module.Step {
 	return module.Step{
 		Name:        fmt.Sprintf("Set %s", key),
 		Description: fmt.Sprintf("Configure %s environment variable", key),
+		Env:         envOf(value, key),
 		Explain: fmt.Sprintf(
 			"%s tells tools like git, curl, and pip how to reach the internet through your corporate proxy. "+
-				"We set it in both your PowerShell $PROFILE (for interactive shells) and the Windows user "+
-				"registry (for GUI apps and other shells).", key),
+				"We set it in the %s environment, so new shells and GUI apps pick it up, and in this session.",
+			key, deps.envScopeName()),
 		Check: func(_ context.Context) bool {
-			val, _, err := deps.Env.Get(key)
-			if err == nil && val == value {
-				return os.Getenv(key) == value
-			}
-			return false
+			return deps.scopedEnvMatches(key, value)
 		},
 		Run: func(_ context.Context) error {
-			if err := deps.Env.Set(key, value); err != nil {
+			if err := confirmProxyOverwrite(deps, key, value); err != nil {
+				return err
+			}
+			if err := deps.Env.SetScoped(key, value, deps.envScope()); err != nil {
 				return fmt.Errorf("setting %s: %w", key, err)
 			}
 			os.Setenv(key, value)
-			deps.State.AddEnvVar(key)
+			deps.State.AddEnvVar("base", key)
+			deps.State.SetEnvValue(key, value)
+			deps.State.SetSystemEnv(key, deps.envScope() == platform.SourceSystem)
+			return nil
+		},
+		DryRun: func(_ context.Context) string {
+			return fmt.Sprintf("Would set %s=%q in %s environment and current process", key, value, deps.envScopeName())
+		},
+	}
+}
+
+// confirmProxyOverwrite checks, with [proxy] on_conflict = "ask", that key
+// may be set to want: its current value is unset, shhh's own (see
+// state.SetElsewhere), already want, or deps.ConfirmOverwrite allows it.
+func confirmProxyOverwrite(deps *Dependencies, key, want string) error {
+	if deps.Config.Proxy.OnConflict != "ask" {
+		return nil
+	}
+	current, _, err := deps.Env.Get(key)
+	if err != nil || current == want || !deps.State.SetElsewhere(key, current) {
+		return nil
+	}
+	deps.log().Warn("proxy variable set by something other than shhh",
+		slog.String("key", key), slog.String("current", current), slog.String("want", want))
+	if deps.ConfirmOverwrite == nil || !deps.ConfirmOverwrite(key, current, want) {
+		return fmt.Errorf("%s is %q, %w; re-run setup with --force to replace it with %q", key, current, ErrSetElsewhere, want)
+	}
+	return nil
+}
+
+// appendPath adds dir to the user PATH. With [safety] confirm_path_changes
+// it asks deps.ConfirmPath first, showing the current PATH.
+func appendPath(deps *Dependencies, dir string) error {
+	if err := confirmPath(deps, dir); err != nil {
+		return err
+	}
+	return deps.Env.AppendPath(dir)
+}
+
+// confirmPath asks deps.ConfirmPath, with [safety] confirm_path_changes,
+// whether dir may be added to the user PATH, unless it is already there.
+// appendPath uses it, as do steps whose installer adds to PATH itself
+// (Scoop's, and uv's standalone one), before running the installer.
+func confirmPath(deps *Dependencies, dir string) error {
+	if !deps.Config.Safety.ConfirmPathChanges {
+		return nil
+	}
+	current, err := deps.Env.ListPath()
+	if err != nil {
+		return fmt.Errorf("reading PATH: %w", err)
+	}
+	if slices.ContainsFunc(current, func(e platform.PathEntry) bool { return e.Dir == dir }) {
+		return nil
+	}
+	if deps.ConfirmPath == nil || !deps.ConfirmPath(current, dir) {
+		return fmt.Errorf("adding %s: %w; re-run setup with --yes to allow it", dir, ErrPathDeclined)
+	}
+	return nil
+}
+
+// proxyEnvKeys are the variables proxyStep manages and directProxyStep removes.
+var proxyEnvKeys = []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY"}
+
+// directProxyStep creates a step for [proxy] mode = "direct": the inverse of
+// the proxy steps, it deletes the proxy variables from the user environment
+// and the current process and stops tracking them in state.
+func directProxyStep(deps *Dependencies) module.Step {
+	// Only user-level values can be removed without admin rights, so a
+	// system-level proxy variable doesn't keep this step from settling,
+	// unless shhh set it there itself ([proxy] scope = "system"). Nor does
+	// one this process inherited: Run clears those, but the next run
+	// inherits them again from whatever set them.
+	present := func() []string {
+		var keys []string
+		for _, key := range proxyEnvKeys {
+			if _, src, err := deps.Env.Get(key); err == nil &&
+				(src == platform.SourceUser || src == platform.SourceSystem && deps.State.SystemEnv(key)) {
+				keys = append(keys, key)
+			}
+		}
+		return keys
+	}
+
+	return module.Step{
+		Name:        "Remove proxy settings",
+		Description: "Unset HTTP_PROXY, HTTPS_PROXY, and NO_PROXY for a direct connection",
+		Env:         envOf("", proxyEnvKeys...),
+		Explain: "Your config says you connect to the internet directly. Leftover proxy variables from a " +
+			"corporate network would send git, curl, and pip to a proxy that isn't reachable, so we remove " +
+			"them from your user environment and this session.",
+		Check: func(_ context.Context) bool {
+			return len(present()) == 0
+		},
+		Run: func(_ context.Context) error {
+			for _, key := range present() {
+				_, src, _ := deps.Env.Get(key)
+				if err := deps.Env.DeleteScoped(key, src); err != nil {
+					return fmt.Errorf("removing %s: %w", key, err)
+				}
+				deps.State.RemoveEnvVar(key)
+			}
+			for _, key := range proxyEnvKeys {
+				os.Unsetenv(key)
+			}
+			return nil
+		},
+		DryRun: func(_ context.Context) string {
+			keys := present()
+			if len(keys) == 0 {
+				return "Would leave environment unchanged (no proxy variables set)"
+			}
+			where := "user environment"
+			if slices.ContainsFunc(keys, deps.State.SystemEnv) {
+				where = "user and system environments"
+			}
+			return fmt.Sprintf("Would delete %s from %s and current process", strings.Join(keys, ", "), where)
+		},
+	}
+}
+
+// resetEnvStep creates a step that deletes the variables listed in
+// [env] reset from the user environment and the current process. Each
+// variable is reset once and recorded in state; later runs leave the value
+// shhh has since set alone.
+func resetEnvStep(deps *Dependencies) module.Step {
+	pending := func() []string {
+		var keys []string
+		for _, key := range deps.Config.Env.Reset {
+			if !deps.State.HasResetEnvVar(key) {
+				keys = append(keys, key)
+			}
+		}
+		return keys
+	}
+
+	return module.Step{
+		Name:        "Reset environment variables",
+		Description: fmt.Sprintf("Clear %s before shhh sets them", strings.Join(deps.Config.Env.Reset, ", ")),
+		Env:         envOf("", pending()...),
+		Explain: "Values you set yourself (e.g. a personal GOPROXY) can conflict with the ones your " +
+			"organisation needs. Variables listed under [env] reset are deleted once so the value shhh " +
+			"sets afterwards is the only one in effect.",
+		Check: func(_ context.Context) bool {
+			return len(pending()) == 0
+		},
+		Run: func(_ context.Context) error {
+			for _, key := range pending() {
+				if platform.IsPathKey(key) {
+					return fmt.Errorf("resetting %s: %w", key, platform.ErrPathViaSet)
+				}
+				if err := deps.Env.DeleteScoped(key, deps.managedScope(key)); err != nil {
+					return fmt.Errorf("resetting %s: %w", key, err)
+				}
+				os.Unsetenv(key)
+				deps.State.AddResetEnvVar(key)
+			}
 			return nil
 		},
 		DryRun: func(_ context.Context) string {
-			return fmt.Sprintf("Would set %s=%q in user environment and current process", key, value)
+			keys := pending()
+			if len(keys) == 0 {
+				return "Would leave environment unchanged (already reset)"
+			}
+			return fmt.Sprintf("Would delete %s from user environment and current process", strings.Join(keys, ", "))
 		},
 	}
 }
@@ -95,11 +508,12 @@ func proxyStep(deps *Dependencies, key, value string) module.Step {
 // certificate store, appends any configured extra PEM files, and writes the
 // result as a single PEM bundle that tools like git, pip, and curl can use.
 func caBundleStep(deps *Dependencies) module.Step {
-	caPath := config.CABundlePath()
+	caPath := deps.Config.CABundlePath()
 
 	return module.Step{
 		Name:        "Build CA bundle",
 		Description: "Extract OS root certificates and write PEM bundle",
+		Env:         envOf(caPath, "SSL_CERT_FILE"),
 		Explain: "Corporate networks often use TLS-intercepting proxies with custom root certificates. " +
 			"Most dev tools (git, pip, npm, curl) need a PEM file with these certificates to verify " +
 			"HTTPS connections. We extract them from your OS certificate store and bundle them into " +
@@ -108,6 +522,10 @@ func caBundleStep(deps *Dependencies) module.Step {
 			if deps.State.CABundleHash == "" {
 				return false
 			}
+			// A user-scope SSL_CERT_FILE doesn't do for a system-scope run.
+			if deps.envScope() == platform.SourceSystem && !deps.scopedEnvMatches("SSL_CERT_FILE", caPath) {
+				return false
+			}
 			if _, err := os.Stat(caPath); err != nil {
 				return false
 			}
@@ -118,34 +536,9 @@ func caBundleStep(deps *Dependencies) module.Step {
 			return hash == deps.State.CABundleHash
 		},
 		Run: func(_ context.Context) error {
-			certs, err := deps.CertStore.SystemRoots()
+			buf, _, err := buildBundle(deps)
 			if err != nil {
-				return fmt.Errorf("reading system certificates: %w", err)
-			}
-			if len(certs) == 0 {
-				return fmt.Errorf("no root certificates found in system store")
-			}
-
-			var buf []byte
-			for _, cert := range certs {
-				block := &pem.Block{
-					Type:  "CERTIFICATE",
-					Bytes: cert.Raw,
-				}
-				buf = append(buf, pem.EncodeToMemory(block)...)
-			}
-
-			// Append extra PEM files.
-			for _, path := range deps.Config.Certs.Extra {
-				data, err := os.ReadFile(path)
-				if err != nil {
-					return fmt.Errorf("reading extra cert file %q: %w", path, err)
-				}
-				// Validate it contains at least one PEM certificate.
-				if block, _ := pem.Decode(data); block == nil {
-					return fmt.Errorf("extra cert file %q contains no valid PEM data", path)
-				}
-				buf = append(buf, data...)
+				return err
 			}
 
 			// Atomic write: temp file + rename.
@@ -172,6 +565,7 @@ func caBundleStep(deps *Dependencies) module.Step {
 				os.Remove(tmpPath)
 				return fmt.Errorf("renaming CA bundle: %w", err)
 			}
+			deps.State.AddManagedFile("base", caPath, buf)
 
 			// Compute and store hash.
 			hash, err := computeBundleHash(deps)
@@ -182,27 +576,228 @@ func caBundleStep(deps *Dependencies) module.Step {
 
 			// Set SSL_CERT_FILE so tools like pip and curl use this bundle.
 			os.Setenv("SSL_CERT_FILE", caPath)
-			deps.State.AddEnvVar("SSL_CERT_FILE")
-			if err := deps.Env.Set("SSL_CERT_FILE", caPath); err != nil {
+			deps.State.AddEnvVar("base", "SSL_CERT_FILE")
+			if err := deps.Env.SetScoped("SSL_CERT_FILE", caPath, deps.envScope()); err != nil {
 				return fmt.Errorf("setting SSL_CERT_FILE: %w", err)
 			}
+			deps.State.SetSystemEnv("SSL_CERT_FILE", deps.envScope() == platform.SourceSystem)
 
 			return nil
 		},
+		// With deps.Preview the would-be bundle is written to a temp file so
+		// it can be inspected; removing it is left to the user.
 		DryRun: func(_ context.Context) string {
-			count := 0
-			if certs, err := deps.CertStore.SystemRoots(); err == nil {
-				count = len(certs)
+			buf, count, err := buildBundle(deps)
+			if err != nil {
+				return fmt.Sprintf("Would build CA bundle at %s, but it can't be built yet: %v", caPath, err)
+			}
+			// count is of the whole bundle, so name every source it draws on.
+			sources := []string{"the system store"}
+			if path := deps.Config.Certs.ImportExisting; path != "" {
+				sources = append(sources, path)
 			}
-			return fmt.Sprintf("Would extract %d certs from system store and write to %s", count, caPath)
+			if n := len(deps.Config.Certs.Extra); n > 0 {
+				sources = append(sources, fmt.Sprintf("%d extra file(s)", n))
+			}
+			msg := fmt.Sprintf("Would write %d certs to %s, from %s", count, caPath, strings.Join(sources, ", "))
+			if !deps.Preview {
+				return msg
+			}
+
+			preview, err := writePreview(buf)
+			if err != nil {
+				return msg + fmt.Sprintf(" (preview not written: %v)", err)
+			}
+			return msg + fmt.Sprintf("; preview written to %s", preview)
 		},
 	}
 }
 
-// computeBundleHash computes a deterministic SHA-256 hash over the system root
-// certificates (sorted by raw DER bytes) and any configured extra PEM files.
-func computeBundleHash(deps *Dependencies) (string, error) {
+// buildBundle returns the PEM bundle contents, the certificates from
+// bundleCerts, and how many there are.
+func buildBundle(deps *Dependencies) (buf []byte, count int, err error) {
+	certs, err := bundleCerts(deps)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, cert := range certs {
+		block := &pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: cert.Raw,
+		}
+		buf = append(buf, pem.EncodeToMemory(block)...)
+	}
+	return buf, len(certs), nil
+}
+
+// writePreview writes a dry-run bundle to a new file in the temp directory
+// and returns its path.
+func writePreview(buf []byte) (string, error) {
+	f, err := os.CreateTemp("", "shhh-ca-bundle-*.pem")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// bundleCerts returns the certificates for the CA bundle, in order: the
+// system roots, then those from [certs] import_existing, then those from
+// each [certs] extra file. A certificate already present (compared by
+// SHA-256 fingerprint) is not added again, so an extra file that repeats a
+// system root doesn't duplicate it. With [certs] verify_signatures, each
+// extra file must be signed (see readSignedPEMCerts).
+func bundleCerts(deps *Dependencies) ([]*x509.Certificate, error) {
 	certs, err := deps.CertStore.SystemRoots()
+	if err != nil {
+		return nil, fmt.Errorf("reading system certificates: %w", err)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no root certificates found in system store")
+	}
+	if len(deps.State.ExcludedCerts) > 0 {
+		certs = selectedCerts(certs, deps.State.ExcludedCerts)
+		if len(certs) == 0 {
+			return nil, fmt.Errorf("every system root certificate is excluded; re-run setup --select-certs")
+		}
+	}
+
+	merged := make([]*x509.Certificate, 0, len(certs))
+	seen := make(map[[sha256.Size]byte]bool, len(certs))
+	add := func(certs []*x509.Certificate) {
+		for _, cert := range certs {
+			fp := sha256.Sum256(cert.Raw)
+			if !seen[fp] {
+				seen[fp] = true
+				merged = append(merged, cert)
+			}
+		}
+	}
+	add(certs)
+
+	if path := deps.Config.Certs.ImportExisting; path != "" {
+		imported, err := readPEMCerts(path)
+		if err != nil {
+			return nil, fmt.Errorf("importing existing bundle %q: %w", path, err)
+		}
+		add(imported)
+	}
+	var key ed25519.PublicKey
+	if deps.Config.Certs.VerifySignatures {
+		if key, err = deps.Config.Certs.PublicKey(); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range deps.Config.Certs.Extra {
+		var extra []*x509.Certificate
+		if key != nil {
+			extra, err = readSignedPEMCerts(path, key)
+		} else {
+			extra, err = readPEMCerts(path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading extra cert file %q: %w", path, err)
+		}
+		add(extra)
+	}
+	return merged, nil
+}
+
+// selectedCerts returns certs minus those whose fingerprint is in excluded.
+func selectedCerts(certs []*x509.Certificate, excluded []string) []*x509.Certificate {
+	skip := make(map[string]bool, len(excluded))
+	for _, fp := range excluded {
+		skip[fp] = true
+	}
+	var kept []*x509.Certificate
+	for _, cert := range certs {
+		if !skip[CertFingerprint(cert)] {
+			kept = append(kept, cert)
+		}
+	}
+	return kept
+}
+
+// CertFingerprint returns the hex SHA-256 fingerprint of cert, the form
+// stored in state.ExcludedCerts.
+func CertFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// readPEMCerts parses every CERTIFICATE block in the PEM file at path.
+func readPEMCerts(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parsePEMCerts(data)
+}
+
+// ErrBadSignature is returned for an extra cert file whose signature
+// doesn't match it under [certs] trusted_key.
+var ErrBadSignature = errors.New("signature doesn't match trusted key")
+
+// readSignedPEMCerts is readPEMCerts for a file that must carry a valid
+// signature by key, in config.SignaturePath(path). The bytes verified are
+// the bytes parsed.
+func readSignedPEMCerts(path string, key ed25519.PublicKey) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sigPath := config.SignaturePath(path)
+	encoded, err := os.ReadFile(sigPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading signature: %w", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: not a base64 signature: %w", sigPath, err)
+	}
+	if !ed25519.Verify(key, data, sig) {
+		return nil, fmt.Errorf("%s: %w", sigPath, ErrBadSignature)
+	}
+	return parsePEMCerts(data)
+}
+
+// parsePEMCerts parses every CERTIFICATE block in data.
+func parsePEMCerts(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM certificates found")
+	}
+	return certs, nil
+}
+
+// computeBundleHash computes a deterministic SHA-256 hash over the
+// deduplicated certificates from bundleCerts, sorted by raw DER bytes.
+func computeBundleHash(deps *Dependencies) (string, error) {
+	certs, err := bundleCerts(deps)
 	if err != nil {
 		return "", err
 	}
@@ -225,46 +820,254 @@ func computeBundleHash(deps *Dependencies) (string, error) {
 	for _, cert := range certs {
 		h.Write(cert.Raw)
 	}
-
-	for _, path := range deps.Config.Certs.Extra {
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return "", fmt.Errorf("reading extra cert file %q: %w", path, err)
-		}
-		h.Write(data)
-	}
-
 	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
 // installScoopStep creates a step that installs the Scoop package manager on Windows.
 func installScoopStep(deps *Dependencies) module.Step {
+	want := deps.Config.Scoop.Version
+
 	return module.Step{
 		Name:        "Install Scoop",
 		Description: "Install Scoop package manager",
-		Explain:     "Scoop installs programs to your user directory without admin privileges.",
+		Explain: "Scoop installs programs to your user directory without admin privileges. Its " +
+			"installer is a PowerShell script, so PowerShell must be allowed to run it: by default " +
+			"we set your execution policy to RemoteSigned (scripts you write run; downloaded ones " +
+			"must be signed). If that's turned off in config or blocked by policy, we run just the " +
+			"installer with -ExecutionPolicy Bypass and leave your policy unchanged.",
+		Retries:      installRetries,
+		RetryBackoff: installBackoff,
+		Timeout:      scoopInstallTimeout,
+		Check: func(ctx context.Context) bool {
+			result, err := deps.Exec.Run(ctx, "scoop", "--version")
+			if err != nil {
+				return false
+			}
+			return want == "" || nodeVersionMatches(scoopVersion(result.Stdout), want)
+		},
+		Run: func(ctx context.Context) error {
+			if _, err := deps.Exec.Run(ctx, "scoop", "--version"); err != nil {
+				// The installer adds the shims to the user PATH itself.
+				shimsDir := deps.Config.InstallPath("scoop", "shims")
+				if err := confirmPath(deps, shimsDir); err != nil {
+					return err
+				}
+				if err := runScoopInstaller(ctx, deps); err != nil {
+					return fmt.Errorf("installing scoop: %w", err)
+				}
+				os.Setenv("PATH", shimsDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+				deps.State.AddPathEntry("base", shimsDir)
+			}
+			if want == "" {
+				return nil
+			}
+			return pinScoopVersion(ctx, deps, want)
+		},
+		DryRun: func(_ context.Context) string {
+			if want != "" {
+				return fmt.Sprintf("Would install or update Scoop to version %s", want)
+			}
+			return "Would install Scoop package manager via get.scoop.sh"
+		},
+	}
+}
+
+// installRootStep creates a step for [paths] root that points key, the
+// variable tool reads its install location from, at dir under the root and
+// creates dir, so the tool installs there rather than under the home
+// directory. Anything already installed elsewhere stays where it is.
+func installRootStep(deps *Dependencies, owner, key, dir, tool string) module.Step {
+	return module.Step{
+		Name:        fmt.Sprintf("Set %s", key),
+		Description: fmt.Sprintf("Install %s under %s", tool, dir),
+		Env:         envOf(dir, key),
+		Explain: fmt.Sprintf("[paths] root moves tooling off the home directory, e.g. onto a larger drive. "+
+			"%s tells %s where to install, so it goes to %s.", key, tool, dir),
+		Check: func(_ context.Context) bool {
+			info, err := os.Stat(dir)
+			return err == nil && info.IsDir() && deps.envMatches(key, dir)
+		},
+		Run: func(_ context.Context) error {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return fmt.Errorf("creating %s under [paths] root: %w", tool, err)
+			}
+			if err := deps.Env.Set(key, dir); err != nil {
+				return fmt.Errorf("setting %s: %w", key, err)
+			}
+			os.Setenv(key, dir)
+			deps.State.AddEnvVar(owner, key)
+			root := deps.Config.Paths.Root
+			if prev := deps.State.SetInstallRoot(root); prev != "" && prev != root {
+				deps.log().Warn("install root changed; tools already installed under the old root stay there",
+					slog.String("old", prev), slog.String("new", root))
+			}
+			return nil
+		},
+		DryRun: func(_ context.Context) string {
+			return fmt.Sprintf("Would create %s and set %s=%s in user environment and current process", dir, key, dir)
+		},
+	}
+}
+
+// existingScoopRoot returns where an installed Scoop lives: $SCOOP when
+// that holds Scoop's shims, or else the directory above the shim directory
+// the scoop command on PATH is in. It returns "" when Scoop isn't found.
+func existingScoopRoot() string {
+	if root := os.Getenv("SCOOP"); root != "" {
+		if info, err := os.Stat(filepath.Join(root, "shims")); err == nil && info.IsDir() {
+			return root
+		}
+	}
+	if shim, err := exec.LookPath("scoop"); err == nil {
+		return filepath.Dir(filepath.Dir(shim))
+	}
+	return ""
+}
+
+// scoopInstaller is the PowerShell that downloads and runs Scoop's installer.
+const scoopInstaller = "irm get.scoop.sh | iex"
+
+// runScoopInstaller runs Scoop's installer script. With [scoop]
+// set_execution_policy it first sets the user's execution policy to
+// RemoteSigned; if that is disabled or fails, the installer runs under
+// -ExecutionPolicy Bypass, which applies to that PowerShell process only.
+func runScoopInstaller(ctx context.Context, deps *Dependencies) error {
+	if deps.Config.Scoop.SetExecutionPolicy {
+		_, err := deps.Exec.Run(ctx, "powershell", "-NoProfile", "-Command",
+			"Set-ExecutionPolicy RemoteSigned -Scope CurrentUser -Force")
+		if err == nil {
+			_, err = deps.Exec.Run(ctx, "powershell", "-NoProfile", "-Command", scoopInstaller)
+			return err
+		}
+		deps.log().Warn("could not set execution policy, running the scoop installer with -ExecutionPolicy Bypass",
+			slog.String("error", err.Error()),
+		)
+	}
+	_, err := deps.Exec.Run(ctx, "powershell", "-NoProfile", "-ExecutionPolicy", "Bypass", "-Command", scoopInstaller)
+	return err
+}
+
+// pinScoopVersion updates Scoop if it isn't at version want, then checks
+// that it is. 'scoop update scoop' can only move to the latest release, so
+// a pin older than that fails here rather than on every later step.
+func pinScoopVersion(ctx context.Context, deps *Dependencies, want string) error {
+	result, err := deps.Exec.Run(ctx, "scoop", "--version")
+	if err == nil && nodeVersionMatches(scoopVersion(result.Stdout), want) {
+		return nil
+	}
+	if _, err := deps.Exec.Run(ctx, "scoop", "update", "scoop"); err != nil {
+		return fmt.Errorf("updating scoop to %s: %w", want, err)
+	}
+	result, err = deps.Exec.Run(ctx, "scoop", "--version")
+	if err != nil {
+		return fmt.Errorf("checking scoop version: %w", err)
+	}
+	if got := scoopVersion(result.Stdout); !nodeVersionMatches(got, want) {
+		return fmt.Errorf("scoop is at version %q after updating, config wants %s", got, want)
+	}
+	return nil
+}
+
+// scoopVersion extracts the version from 'scoop --version' output, whose
+// release line looks like "v0.5.2 - Released at 2024-07-26". It returns ""
+// if there is no such line. Matching against the config uses the same
+// prefix rule as Node versions, so "0.5" accepts "v0.5.2".
+func scoopVersion(out string) string {
+	for _, f := range strings.Fields(out) {
+		if len(f) > 1 && f[0] == 'v' && f[1] >= '0' && f[1] <= '9' {
+			return f
+		}
+	}
+	return ""
+}
+
+// installGitStep creates a step that installs the [git] version pin via
+// Scoop and holds it there, so 'scoop update *' leaves it alone.
+func installGitStep(deps *Dependencies) module.Step {
+	want := deps.Config.Git.Version
+
+	return module.Step{
+		Name:        "Install git",
+		Description: fmt.Sprintf("Install git %s via Scoop", want),
+		Explain: "Your config pins git to a known version, for example one your organisation has " +
+			"approved. We install that version with Scoop and hold it so updating Scoop apps " +
+			"doesn't replace it.",
+		Retries:      installRetries,
+		RetryBackoff: installBackoff,
 		Check: func(ctx context.Context) bool {
-			_, err := deps.Exec.Run(ctx, "scoop", "--version")
-			return err == nil
+			out, err := shexec.RunOutput(ctx, deps.Exec, "git", "--version")
+			return err == nil && nodeVersionMatches(gitVersion(out), want)
 		},
 		Run: func(ctx context.Context) error {
-			_, err := deps.Exec.Run(ctx, "powershell", "-NoProfile", "-Command",
-				"Set-ExecutionPolicy RemoteSigned -Scope CurrentUser -Force; irm get.scoop.sh | iex")
+			// A retry after the shim was slow to appear finds git in place.
+			if out, err := shexec.RunOutput(ctx, deps.Exec, "git", "--version"); err != nil || !nodeVersionMatches(gitVersion(out), want) {
+				if err := installScoopVersion(ctx, deps, "git", want); err != nil {
+					return err
+				}
+			}
+
+			out, err := shexec.RunOutput(ctx, deps.Exec, "git", "--version")
 			if err != nil {
-				return fmt.Errorf("installing scoop: %w", err)
+				return fmt.Errorf("verifying git install: %w", err)
 			}
-			home, _ := os.UserHomeDir()
-			shimsDir := filepath.Join(home, "scoop", "shims")
-			os.Setenv("PATH", shimsDir+string(os.PathListSeparator)+os.Getenv("PATH"))
-			deps.State.AddPathEntry(shimsDir)
+			got := gitVersion(out)
+			if !nodeVersionMatches(got, want) {
+				return fmt.Errorf("git is at version %q after installing, config wants %s", got, want)
+			}
+			deps.State.GitVersion = got
 			return nil
 		},
 		DryRun: func(_ context.Context) string {
-			return "Would install Scoop package manager via get.scoop.sh"
+			return fmt.Sprintf("Would run: scoop install git@%s, then scoop hold git", want)
 		},
 	}
 }
 
+// installScoopVersion installs version of the Scoop app name and holds it.
+// Any other installed version is removed first, since Scoop won't install
+// an app over itself; [scoop] allowed and denied are checked before that,
+// so a forbidden app isn't removed and then refused.
+func installScoopVersion(ctx context.Context, deps *Dependencies, name, version string) error {
+	if err := deps.Config.Scoop.CheckPackage(name); err != nil {
+		return err
+	}
+	if list, err := deps.Exec.Run(ctx, "scoop", "list"); err == nil && ScoopListed(list.Stdout, name) {
+		// Unholding an app that isn't held fails harmlessly.
+		deps.Exec.Run(ctx, "scoop", "unhold", name)
+		if _, err := deps.Exec.Run(ctx, "scoop", "uninstall", name); err != nil {
+			return fmt.Errorf("removing %s before installing %s: %w", name, version, err)
+		}
+	}
+	if err := scoopInstall(ctx, deps, name+"@"+version); err != nil {
+		return fmt.Errorf("installing %s %s: %w", name, version, err)
+	}
+	deps.State.AddScoopPackage("base", name)
+	if _, err := deps.Exec.Run(ctx, "scoop", "hold", name); err != nil {
+		return fmt.Errorf("holding %s: %w", name, err)
+	}
+	return nil
+}
+
+// ScoopListed reports whether 'scoop list' output lists the app name.
+func ScoopListed(out, name string) bool {
+	for _, line := range strings.Split(out, "\n") {
+		if f := strings.Fields(line); len(f) > 0 && strings.EqualFold(f[0], name) {
+			return true
+		}
+	}
+	return false
+}
+
+// gitVersion extracts the version from 'git --version' output such as
+// "git version 2.45.1.windows.1", returning "" if there is none.
+func gitVersion(out string) string {
+	f := strings.Fields(out)
+	if len(f) < 3 || f[0] != "git" || f[1] != "version" {
+		return ""
+	}
+	return f[2]
+}
+
 // scoopBucketsStep creates a step that adds configured Scoop buckets.
 func scoopBucketsStep(deps *Dependencies) module.Step {
 	buckets := deps.Config.Scoop.Buckets
@@ -297,7 +1100,7 @@ func scoopBucketsStep(deps *Dependencies) module.Step {
 					continue
 				}
 				if _, err := deps.Exec.Run(ctx, "scoop", "bucket", "add", b); err != nil {
-					return fmt.Errorf("adding scoop bucket %q: %w", b, err)
+					return fmt.Errorf("adding scoop bucket %q: %w", b, explainMissing("scoop", err))
 				}
 			}
 			return nil
@@ -308,6 +1111,117 @@ func scoopBucketsStep(deps *Dependencies) module.Step {
 	}
 }
 
+// scoopUpdateInterval is how long a 'scoop update' is trusted before the
+// next setup runs it again.
+const scoopUpdateInterval = 24 * time.Hour
+
+// scoopUpdateStep creates a step that refreshes Scoop and its buckets before
+// any module installs from them, at most once per scoopUpdateInterval.
+func scoopUpdateStep(deps *Dependencies) module.Step {
+	return module.Step{
+		Name:        "Update Scoop",
+		Description: "Refresh Scoop and its bucket manifests",
+		Explain: "Scoop installs from manifests in its buckets. If those are out of date, installs fail " +
+			"with \"couldn't find manifest\" or fetch old versions, so we refresh them first (at most daily).",
+		Retries:      installRetries,
+		RetryBackoff: installBackoff,
+		Check: func(_ context.Context) bool {
+			last := deps.State.ScoopUpdated
+			return !last.IsZero() && time.Since(last) < scoopUpdateInterval
+		},
+		Run: func(ctx context.Context) error {
+			if _, err := deps.Exec.Run(ctx, "scoop", "update"); err != nil {
+				return fmt.Errorf("updating scoop: %w", explainMissing("scoop", err))
+			}
+			deps.State.ScoopUpdated = time.Now()
+			return nil
+		},
+		DryRun: func(_ context.Context) string {
+			return "Would run: scoop update"
+		},
+	}
+}
+
+// gitScope returns the git config options selecting where shhh's git
+// settings live, as GitScope does for deps.Config.
+func gitScope(deps *Dependencies) []string {
+	return GitScope(deps.Config)
+}
+
+// GitScope returns the git config options selecting where shhh's git
+// settings live: --global, or with [git] use_include the shhh include file.
+func GitScope(cfg *config.Config) []string {
+	if cfg.Git.UseInclude {
+		return []string{"--file", config.GitIncludePath()}
+	}
+	return []string{"--global"}
+}
+
+// gitConfig runs 'git config' with args against the file gitScope selects,
+// returning its output as shexec.RunOutput does.
+func gitConfig(ctx context.Context, deps *Dependencies, args ...string) (string, error) {
+	argv := append([]string{"config"}, gitScope(deps)...)
+	return shexec.RunOutput(ctx, deps.Exec, "git", append(argv, args...)...)
+}
+
+// setGitConfig sets key to value with gitConfig, tracking the change to
+// the file for --show-diffs. With [git] use_include the include file is then
+// recorded as a managed file, so teardown can tell whether anyone else has
+// edited it.
+func setGitConfig(ctx context.Context, deps *Dependencies, key, value string) error {
+	path := gitConfigFile(deps)
+	err := trackFile(ctx, path, readFile(path), func() error {
+		_, err := gitConfig(ctx, deps, key, value)
+		return err
+	})
+	if err != nil {
+		return explainMissing("git", err)
+	}
+	if deps.Config.Git.UseInclude {
+		if data, err := os.ReadFile(path); err == nil {
+			deps.State.AddManagedFile("base", path, data)
+		}
+	}
+	return nil
+}
+
+// gitIncludeStep creates a step that adds the shhh include file to the
+// global git config's include.path, once.
+func gitIncludeStep(deps *Dependencies) module.Step {
+	path := config.GitIncludePath()
+
+	return module.Step{
+		Name:        "Include shhh git config",
+		Description: fmt.Sprintf("Include %s from the global git config", path),
+		Explain: "shhh keeps its git settings in a separate file and includes it from your global " +
+			"git config, so your own ~/.gitconfig is only touched once and shhh's settings can be " +
+			"removed by deleting the include.",
+		Check: func(ctx context.Context) bool {
+			result, err := deps.Exec.Run(ctx, "git", "config", "--global", "--get-all", "include.path")
+			if err != nil {
+				return false
+			}
+			for _, line := range strings.Split(result.Stdout, "\n") {
+				if filepath.Clean(strings.TrimSpace(line)) == path {
+					return true
+				}
+			}
+			return false
+		},
+		Run: func(ctx context.Context) error {
+			global := globalGitConfigPath()
+			err := trackFile(ctx, global, readFile(global), func() error {
+				_, err := deps.Exec.Run(ctx, "git", "config", "--global", "--add", "include.path", path)
+				return err
+			})
+			return explainMissing("git", err)
+		},
+		DryRun: func(_ context.Context) string {
+			return fmt.Sprintf("Would run: git config --global --add include.path %s", path)
+		},
+	}
+}
+
 // gitDefaultBranchStep creates a step that configures the default git branch name.
 func gitDefaultBranchStep(deps *Dependencies) module.Step {
 	branch := deps.Config.Git.DefaultBranch
@@ -320,25 +1234,24 @@ func gitDefaultBranchStep(deps *Dependencies) module.Step {
 		Description: fmt.Sprintf("Set git init.defaultBranch to %s", branch),
 		Explain: "When you run 'git init', git creates an initial branch. This sets the default name for that branch.",
 		Check: func(ctx context.Context) bool {
-			result, err := deps.Exec.Run(ctx, "git", "config", "--global", "init.defaultBranch")
+			got, err := gitConfig(ctx, deps, "init.defaultBranch")
 			if err != nil {
 				return false
 			}
-			return result.Stdout == branch+"\n" || result.Stdout == branch
+			return deps.outputMatches("git init.defaultBranch", got, branch)
 		},
 		Run: func(ctx context.Context) error {
-			_, err := deps.Exec.Run(ctx, "git", "config", "--global", "init.defaultBranch", branch)
-			return err
+			return setGitConfig(ctx, deps, "init.defaultBranch", branch)
 		},
 		DryRun: func(_ context.Context) string {
-			return fmt.Sprintf("Would run: git config --global init.defaultBranch %s", branch)
+			return fmt.Sprintf("Would run: git config %s init.defaultBranch %s", strings.Join(gitScope(deps), " "), branch)
 		},
 	}
 }
 
 // gitSSLCAInfoStep creates a step that points git at the shhh-managed CA bundle.
 func gitSSLCAInfoStep(deps *Dependencies) module.Step {
-	caPath := config.CABundlePath()
+	caPath := deps.Config.CABundlePath()
 
 	return module.Step{
 		Name:        "Set git ssl.caInfo",
@@ -347,18 +1260,57 @@ func gitSSLCAInfoStep(deps *Dependencies) module.Step {
 			"Git needs to know where to find these certificates to verify HTTPS connections. " +
 			"We point git at the shhh-managed CA bundle that includes your organization's CAs.",
 		Check: func(ctx context.Context) bool {
-			result, err := deps.Exec.Run(ctx, "git", "config", "--global", "http.sslCAInfo")
+			got, err := gitConfig(ctx, deps, "http.sslCAInfo")
 			if err != nil {
 				return false
 			}
-			return result.Stdout == caPath+"\n" || result.Stdout == caPath
+			return deps.outputMatches("git http.sslCAInfo", got, caPath)
 		},
 		Run: func(ctx context.Context) error {
-			_, err := deps.Exec.Run(ctx, "git", "config", "--global", "http.sslCAInfo", caPath)
-			return err
+			return setGitConfig(ctx, deps, "http.sslCAInfo", caPath)
+		},
+		DryRun: func(_ context.Context) string {
+			return fmt.Sprintf("Would run: git config %s http.sslCAInfo %s", strings.Join(gitScope(deps), " "), caPath)
+		},
+	}
+}
+
+// gitCAEnvKeys are the variables libcurl-based git tooling reads for trusted
+// CAs when it doesn't go through git's own http.sslCAInfo.
+var gitCAEnvKeys = []string{"GIT_SSL_CAINFO", "CURL_CA_BUNDLE"}
+
+// gitCAEnvStep creates a step that points GIT_SSL_CAINFO and CURL_CA_BUNDLE
+// at the shhh-managed CA bundle.
+func gitCAEnvStep(deps *Dependencies) module.Step {
+	caPath := deps.Config.CABundlePath()
+
+	return module.Step{
+		Name:        "Set git and curl CA variables",
+		Description: "Point GIT_SSL_CAINFO and CURL_CA_BUNDLE at the shhh CA bundle",
+		Env:         envOf(caPath, gitCAEnvKeys...),
+		Explain: "git's http.sslCAInfo setting only covers git itself. Git LFS, some hooks, and other tools " +
+			"that call libcurl directly read GIT_SSL_CAINFO or CURL_CA_BUNDLE instead, and fail with " +
+			"certificate errors behind corporate proxies without them.",
+		Check: func(_ context.Context) bool {
+			for _, key := range gitCAEnvKeys {
+				if !deps.envMatches(key, caPath) {
+					return false
+				}
+			}
+			return true
+		},
+		Run: func(_ context.Context) error {
+			for _, key := range gitCAEnvKeys {
+				if err := deps.Env.Set(key, caPath); err != nil {
+					return fmt.Errorf("setting %s: %w", key, err)
+				}
+				os.Setenv(key, caPath)
+				deps.State.AddEnvVar("base", key)
+			}
+			return nil
 		},
 		DryRun: func(_ context.Context) string {
-			return fmt.Sprintf("Would run: git config --global http.sslCAInfo %s", caPath)
+			return fmt.Sprintf("Would set %s to %s", strings.Join(gitCAEnvKeys, ", "), caPath)
 		},
 	}
 }
@@ -1,21 +1,30 @@
 package setup
 
 import (
+	"bytes"
 	"context"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/base64"
 	"encoding/pem"
+	"errors"
+	"log/slog"
 	"math/big"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/druarnfield/shhh/internal/config"
 	"github.com/druarnfield/shhh/internal/exec"
+	"github.com/druarnfield/shhh/internal/module"
+	"github.com/druarnfield/shhh/internal/platform"
 	"github.com/druarnfield/shhh/internal/platform/mock"
 	"github.com/druarnfield/shhh/internal/state"
 )
@@ -34,6 +43,9 @@ func TestMain(m *testing.M) {
 	os.Unsetenv("REQUESTS_CA_BUNDLE")
 	os.Unsetenv("PIP_CERT")
 	os.Unsetenv("NODE_EXTRA_CA_CERTS")
+	os.Unsetenv("AWS_CA_BUNDLE")
+	os.Unsetenv("CURL_CA_BUNDLE")
+	os.Unsetenv("GIT_SSL_CAINFO")
 	os.Exit(code)
 }
 
@@ -45,6 +57,7 @@ func testDeps() *Dependencies {
 		CertStore: mock.NewCertStore(testCerts()),
 		Exec:      &exec.MockRunner{Results: map[string]exec.Result{}},
 		State:     &state.State{},
+		OS:        "windows",
 	}
 }
 
@@ -97,9 +110,23 @@ func testConfig() *config.Config {
 	cfg.Tools.Core = []string{"git", "jq", "ripgrep"}
 	cfg.Tools.Data = []string{"sqlcmd"}
 	cfg.Tools.Optional = []string{"bat", "lazygit"}
+	cfg.Cloud.Tools = []string{"azure-cli", "awscli"}
 	return cfg
 }
 
+func TestModuleIDs_MatchModules(t *testing.T) {
+	var ids []string
+	for _, m := range Modules(testDeps()) {
+		ids = append(ids, m.ID)
+		if !slices.Equal(m.Dependencies, ModuleDependencies[m.ID]) {
+			t.Errorf("%s depends on %q, ModuleDependencies says %q", m.ID, m.Dependencies, ModuleDependencies[m.ID])
+		}
+	}
+	if !slices.Equal(ids, ModuleIDs) {
+		t.Errorf("Modules IDs = %q, ModuleIDs = %q", ids, ModuleIDs)
+	}
+}
+
 func TestBaseModule_HasRequiredSteps(t *testing.T) {
 	deps := testDeps()
 	mod := NewBaseModule(deps)
@@ -125,6 +152,32 @@ func TestBaseModule_HasRequiredSteps(t *testing.T) {
 	}
 }
 
+func TestBaseModule_ScoopStepsOnlyOnWindows(t *testing.T) {
+	deps := testDeps()
+	deps.Config.Scoop.Update = true
+	deps.Config.Git.Version = "2.45.1"
+	deps.OS = "linux"
+
+	mod := NewBaseModule(deps)
+	if reason := mod.Unsupported("linux"); reason != "" {
+		t.Errorf("base should run on linux, got %q", reason)
+	}
+	var names []string
+	for _, step := range mod.Steps {
+		names = append(names, step.Name)
+	}
+	for _, name := range []string{"Install Scoop", "Update Scoop", "Install git"} {
+		if slices.Contains(names, name) {
+			t.Errorf("step %q needs Scoop and shouldn't be in base on linux", name)
+		}
+	}
+	for _, name := range []string{"Set HTTP_PROXY", "Build CA bundle", "Set git ssl.caInfo"} {
+		if !slices.Contains(names, name) {
+			t.Errorf("base on linux is missing %q; steps: %q", name, names)
+		}
+	}
+}
+
 func TestProxySteps_SetEnvVars(t *testing.T) {
 	deps := testDeps()
 	mod := NewBaseModule(deps)
@@ -156,6 +209,178 @@ func TestProxySteps_SetEnvVars(t *testing.T) {
 	}
 }
 
+func TestProxySteps_PolicyLockedEnv(t *testing.T) {
+	deps := testDeps()
+	deps.Env.(*mock.UserEnv).Locked = true
+
+	err := proxyStep(deps, "HTTP_PROXY", "http://proxy:8080").Run(context.Background())
+	if !errors.Is(err, platform.ErrPolicyLocked) {
+		t.Errorf("Run error = %v, want ErrPolicyLocked", err)
+	}
+}
+
+func TestProxySteps_SystemScope(t *testing.T) {
+	defer os.Unsetenv("HTTP_PROXY")
+	deps := testDeps()
+	deps.Config.Proxy.Scope = "system"
+	env := deps.Env.(*mock.UserEnv)
+	ctx := context.Background()
+
+	// The same value in the user environment doesn't satisfy a system-scope
+	// step.
+	env.Set("HTTP_PROXY", "http://proxy:8080")
+	os.Setenv("HTTP_PROXY", "http://proxy:8080")
+	step := proxyStep(deps, "HTTP_PROXY", "http://proxy:8080")
+	if step.Check(ctx) {
+		t.Error("Check should be false while the value is only set per user")
+	}
+	if !strings.Contains(step.DryRun(ctx), "system (machine-wide) environment") {
+		t.Errorf("DryRun = %q, want it to name the system environment", step.DryRun(ctx))
+	}
+
+	env.NotElevated = true
+	if err := step.Run(ctx); !errors.Is(err, platform.ErrNotElevated) {
+		t.Errorf("Run error = %v, want ErrNotElevated", err)
+	}
+
+	env.NotElevated = false
+	if err := step.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if env.Scopes["HTTP_PROXY"] != platform.SourceSystem {
+		t.Errorf("HTTP_PROXY written to the %s environment, want system", env.Scopes["HTTP_PROXY"])
+	}
+	if !deps.State.SystemEnv("HTTP_PROXY") {
+		t.Error("state should record HTTP_PROXY as written to the system environment")
+	}
+	if !step.Check(ctx) {
+		t.Error("Check should be true after Run")
+	}
+	if !strings.Contains(step.Explain, "system (machine-wide) environment") {
+		t.Errorf("Explain = %q, want it to name the system environment", step.Explain)
+	}
+}
+
+func TestProxySteps_AskBeforeOverwritingExternalValue(t *testing.T) {
+	defer os.Unsetenv("HTTP_PROXY")
+	deps := testDeps()
+	deps.Config.Proxy.OnConflict = "ask"
+	deps.Env.Set("HTTP_PROXY", "http://other:3128")
+	step := proxyStep(deps, "HTTP_PROXY", "http://proxy:8080")
+
+	err := step.Run(context.Background())
+	if !errors.Is(err, ErrSetElsewhere) || !strings.Contains(err.Error(), "--force") {
+		t.Fatalf("Run error = %v, want ErrSetElsewhere mentioning --force", err)
+	}
+	if val, _, _ := deps.Env.Get("HTTP_PROXY"); val != "http://other:3128" {
+		t.Errorf("external value replaced without asking: %q", val)
+	}
+
+	var asked string
+	deps.ConfirmOverwrite = func(key, current, want string) bool {
+		asked = key + " " + current + " -> " + want
+		return true
+	}
+	if err := step.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if asked != "HTTP_PROXY http://other:3128 -> http://proxy:8080" {
+		t.Errorf("ConfirmOverwrite asked %q", asked)
+	}
+	if deps.State.EnvValues["HTTP_PROXY"] != "http://proxy:8080" {
+		t.Errorf("EnvValues = %v, want shhh's value recorded", deps.State.EnvValues)
+	}
+
+	// Once shhh has set a value, changing the config replaces it without
+	// asking.
+	asked = ""
+	if err := proxyStep(deps, "HTTP_PROXY", "http://new:8080").Run(context.Background()); err != nil || asked != "" {
+		t.Errorf("replacing shhh's own value: err = %v, asked %q", err, asked)
+	}
+}
+
+func TestProxySteps_OverwriteByDefault(t *testing.T) {
+	defer os.Unsetenv("HTTP_PROXY")
+	deps := testDeps()
+	deps.Env.Set("HTTP_PROXY", "http://other:3128")
+	if err := proxyStep(deps, "HTTP_PROXY", "http://proxy:8080").Run(context.Background()); err != nil {
+		t.Fatalf("Run error = %v, want overwrite without asking", err)
+	}
+}
+
+func TestBaseModule_InstallRoot(t *testing.T) {
+	defer os.Unsetenv("SCOOP")
+	deps := testDeps()
+	if NewBaseModule(deps).Step("set-scoop") != nil {
+		t.Error("SCOOP step added without [paths] root")
+	}
+
+	root := filepath.Join(t.TempDir(), "dev")
+	deps.Config.Paths.Root = root
+	step := NewBaseModule(deps).Step("set-scoop")
+	if step == nil {
+		t.Fatal("no SCOOP step with [paths] root set")
+	}
+	ctx := context.Background()
+	if step.Check(ctx) {
+		t.Error("Check passed before the root was created")
+	}
+	if err := step.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	scoop := filepath.Join(root, "scoop")
+	if info, err := os.Stat(scoop); err != nil || !info.IsDir() {
+		t.Errorf("scoop dir not created: %v", err)
+	}
+	if val, _, _ := deps.Env.Get("SCOOP"); val != scoop {
+		t.Errorf("SCOOP = %q, want %q", val, scoop)
+	}
+	if deps.State.InstallRoot != root {
+		t.Errorf("State.InstallRoot = %q, want %q", deps.State.InstallRoot, root)
+	}
+	if !step.Check(ctx) {
+		t.Error("Check should pass after Run")
+	}
+	// ExpectedEnv builds the modules for the OS the test runs on, which
+	// leaves SCOOP out off Windows; the step's Env is what it reads.
+	if got := step.Env["SCOOP"]; got != scoop {
+		t.Errorf("step Env SCOOP = %q, want %q", got, scoop)
+	}
+}
+
+func TestBaseModule_KeepsExistingScoop(t *testing.T) {
+	existing := t.TempDir()
+	os.Mkdir(filepath.Join(existing, "shims"), 0o755)
+	t.Setenv("SCOOP", existing)
+	deps := testDeps()
+	deps.Config.Paths.Root = t.TempDir()
+
+	if step := NewBaseModule(deps).Step("Set SCOOP"); step != nil {
+		t.Errorf("SCOOP moved to %s with Scoop already at %s", step.Env["SCOOP"], existing)
+	}
+
+	// A Scoop shhh already put under the root keeps its step.
+	root := deps.Config.InstallPath("scoop")
+	os.MkdirAll(filepath.Join(root, "shims"), 0o755)
+	t.Setenv("SCOOP", root)
+	if NewBaseModule(deps).Step("Set SCOOP") == nil {
+		t.Error("Set SCOOP left out for a Scoop under the install root")
+	}
+}
+
+func TestInstallRootStep_NotCreatable(t *testing.T) {
+	deps := testDeps()
+	file := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(file, nil, 0644)
+	deps.Config.Paths.Root = file
+
+	err := installRootStep(deps, "base", "SCOOP", deps.Config.InstallPath("scoop"), "Scoop").Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "[paths] root") {
+		t.Errorf("Run error = %v, want a [paths] root error", err)
+	}
+}
+
 func TestProxySteps_CheckSkipsIfDone(t *testing.T) {
 	deps := testDeps()
 	deps.Env.Set("HTTP_PROXY", "http://proxy:8080")
@@ -189,6 +414,115 @@ func TestProxySteps_DryRun(t *testing.T) {
 	}
 }
 
+func TestDirectProxyStep_DeletesProxyVars(t *testing.T) {
+	deps := testDeps()
+	deps.Config.Proxy.Mode = "direct"
+	deps.Env.Set("HTTP_PROXY", "http://old-proxy:8080")
+	deps.Env.Set("NO_PROXY", "localhost")
+	os.Setenv("HTTPS_PROXY", "http://old-proxy:8080")
+	t.Cleanup(func() { os.Unsetenv("HTTPS_PROXY") })
+	deps.State.AddEnvVar("base", "HTTP_PROXY")
+
+	mod := NewBaseModule(deps)
+	var step module.Step
+	for _, s := range mod.Steps {
+		if strings.HasPrefix(s.Name, "Set ") && strings.HasSuffix(s.Name, "_PROXY") {
+			t.Errorf("direct mode should not include %q", s.Name)
+		}
+		if s.Name == "Remove proxy settings" {
+			step = s
+		}
+	}
+	if step.Run == nil {
+		t.Fatal("missing Remove proxy settings step")
+	}
+
+	ctx := context.Background()
+	if step.Check(ctx) {
+		t.Fatal("Check should be false while proxy vars are set")
+	}
+	if msg := step.DryRun(ctx); !strings.Contains(msg, "HTTP_PROXY, NO_PROXY") {
+		t.Errorf("DryRun = %q, want the user-level variables listed", msg)
+	}
+	if err := step.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	for _, key := range proxyEnvKeys {
+		if _, _, err := deps.Env.Get(key); err == nil {
+			t.Errorf("%s still set in user environment", key)
+		}
+		if v := os.Getenv(key); v != "" {
+			t.Errorf("%s still set in process: %q", key, v)
+		}
+	}
+	if len(deps.State.ManagedEnvVars) != 0 {
+		t.Errorf("ManagedEnvVars = %v, want empty", deps.State.ManagedEnvVars)
+	}
+	if !step.Check(ctx) {
+		t.Error("Check should be true once proxy vars are removed")
+	}
+}
+
+func TestDirectProxyStep_IgnoresSystemAndProcessValues(t *testing.T) {
+	deps := testDeps()
+	deps.Config.Proxy.Mode = "direct"
+	deps.Env.SetScoped("HTTP_PROXY", "http://gpo-proxy:8080", platform.SourceSystem)
+	t.Setenv("HTTPS_PROXY", "http://inherited:8080")
+
+	step := directProxyStep(deps)
+	ctx := context.Background()
+	if !step.Check(ctx) {
+		t.Error("Check should be true when no user-level proxy variable is set")
+	}
+	if msg := step.DryRun(ctx); !strings.Contains(msg, "unchanged") {
+		t.Errorf("DryRun = %q, want nothing to delete", msg)
+	}
+
+	if err := step.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if _, src, err := deps.Env.Get("HTTP_PROXY"); err != nil || src != platform.SourceSystem {
+		t.Error("system-level HTTP_PROXY should be left alone")
+	}
+	if v := os.Getenv("HTTPS_PROXY"); v != "" {
+		t.Errorf("HTTPS_PROXY still set in process: %q", v)
+	}
+}
+
+func TestDirectProxyStep_DeletesSystemValuesShhhSet(t *testing.T) {
+	deps := testDeps()
+	deps.Config.Proxy.Mode = "direct"
+	env := deps.Env.(*mock.UserEnv)
+	env.SetScoped("HTTP_PROXY", "http://proxy:8080", platform.SourceSystem)
+	deps.State.AddEnvVar("base", "HTTP_PROXY")
+	deps.State.SetSystemEnv("HTTP_PROXY", true)
+
+	step := directProxyStep(deps)
+	ctx := context.Background()
+	if step.Check(ctx) {
+		t.Error("Check should be false while a system value shhh set remains")
+	}
+	if msg := step.DryRun(ctx); !strings.Contains(msg, "system environments") {
+		t.Errorf("DryRun = %q, want it to name the system environment", msg)
+	}
+
+	env.NotElevated = true
+	if err := step.Run(ctx); !errors.Is(err, platform.ErrNotElevated) {
+		t.Errorf("Run error = %v, want ErrNotElevated", err)
+	}
+	env.NotElevated = false
+	if err := step.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if _, _, err := env.Get("HTTP_PROXY"); err == nil {
+		t.Error("HTTP_PROXY should be deleted from the system environment")
+	}
+	if deps.State.SystemEnv("HTTP_PROXY") {
+		t.Error("state should forget HTTP_PROXY")
+	}
+}
+
 func TestCABundleStep_Run_WritesPEM(t *testing.T) {
 	deps := testDeps()
 	deps.State = &state.State{}
@@ -240,6 +574,14 @@ func TestCABundleStep_Run_WritesPEM(t *testing.T) {
 	if got := os.Getenv("SSL_CERT_FILE"); got != bundlePath {
 		t.Errorf("SSL_CERT_FILE = %q, want %q", got, bundlePath)
 	}
+
+	// The bundle is recorded as a file shhh wrote.
+	if len(deps.State.ManagedFiles) != 1 || deps.State.ManagedFiles[0].Path != bundlePath {
+		t.Fatalf("ManagedFiles = %v, want the CA bundle", deps.State.ManagedFiles)
+	}
+	if status, err := deps.State.ManagedFiles[0].Status(); err != nil || status != state.FileUnchanged {
+		t.Errorf("bundle Status = %v, %v; want FileUnchanged", status, err)
+	}
 }
 
 func TestCABundleStep_Run_AppendsExtras(t *testing.T) {
@@ -262,6 +604,11 @@ func TestCABundleStep_Run_AppendsExtras(t *testing.T) {
 	step := caBundleStep(deps)
 	ctx := context.Background()
 
+	want := "Would write 3 certs to " + config.CABundlePath() + ", from the system store, 1 extra file(s)"
+	if msg := step.DryRun(ctx); msg != want {
+		t.Errorf("DryRun = %q, want %q", msg, want)
+	}
+
 	bundlePath := config.CABundlePath()
 	os.MkdirAll(filepath.Dir(bundlePath), 0755)
 	defer os.Remove(bundlePath)
@@ -291,6 +638,207 @@ func TestCABundleStep_Run_AppendsExtras(t *testing.T) {
 	}
 }
 
+func TestBundleCerts_VerifiesSignatures(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+
+	extraPath := filepath.Join(t.TempDir(), "extra-ca.pem")
+	extraPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testCerts()[0].Raw})
+	os.WriteFile(extraPath, extraPEM, 0644)
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, extraPEM))
+	os.WriteFile(config.SignaturePath(extraPath), []byte(sig+"\n"), 0644)
+
+	deps := testDeps()
+	deps.Config.Certs.Extra = []string{extraPath}
+	deps.Config.Certs.VerifySignatures = true
+	deps.Config.Certs.TrustedKey = base64.StdEncoding.EncodeToString(pub)
+
+	if _, err := bundleCerts(deps); err != nil {
+		t.Fatalf("bundleCerts with a valid signature: %v", err)
+	}
+
+	// Tamper with the file: append another CA after it was signed.
+	tampered := append(extraPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testCerts()[1].Raw})...)
+	os.WriteFile(extraPath, tampered, 0644)
+	if _, err := bundleCerts(deps); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("bundleCerts with a tampered file = %v, want ErrBadSignature", err)
+	}
+	if err := caBundleStep(deps).Run(context.Background()); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("Run with a tampered file = %v, want ErrBadSignature", err)
+	}
+
+	os.Remove(config.SignaturePath(extraPath))
+	if _, err := bundleCerts(deps); err == nil {
+		t.Error("bundleCerts without a signature file should fail")
+	}
+}
+
+func TestCABundleStep_Run_DedupsExtraAgainstSystem(t *testing.T) {
+	deps := testDeps()
+	system, err := deps.CertStore.SystemRoots()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The extra file repeats the second system root and adds one new CA.
+	newCA := testCerts()[0]
+	var extra []byte
+	for _, cert := range []*x509.Certificate{system[1], newCA} {
+		extra = append(extra, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+	extraPath := filepath.Join(t.TempDir(), "extra-ca.pem")
+	if err := os.WriteFile(extraPath, extra, 0644); err != nil {
+		t.Fatalf("writing extra cert: %v", err)
+	}
+	deps.Config.Certs.Extra = []string{extraPath}
+
+	step := caBundleStep(deps)
+	ctx := context.Background()
+
+	want := "Would write 3 certs to " + config.CABundlePath() + ", from the system store, 1 extra file(s)"
+	if msg := step.DryRun(ctx); msg != want {
+		t.Errorf("DryRun = %q, want %q", msg, want)
+	}
+
+	bundlePath := config.CABundlePath()
+	os.MkdirAll(filepath.Dir(bundlePath), 0755)
+	defer os.Remove(bundlePath)
+
+	if err := step.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	data, err := os.ReadFile(bundlePath)
+	if err != nil {
+		t.Fatalf("reading bundle: %v", err)
+	}
+	dup := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: system[1].Raw})
+	if n := bytes.Count(data, dup); n != 1 {
+		t.Errorf("duplicated system root appears %d times, want 1", n)
+	}
+	if n := bytes.Count(data, []byte("BEGIN CERTIFICATE")); n != 3 {
+		t.Errorf("bundle has %d certs, want 3 (2 system + 1 new extra)", n)
+	}
+	if !step.Check(ctx) {
+		t.Error("Check returned false after Run, want true")
+	}
+}
+
+func TestCABundleStep_Run_MergesImportedBundle(t *testing.T) {
+	deps := testDeps()
+	system, err := deps.CertStore.SystemRoots()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The existing bundle repeats one system root and adds a new CA.
+	newCA := testCerts()[0]
+	var existing []byte
+	for _, cert := range []*x509.Certificate{system[0], newCA, newCA} {
+		existing = append(existing, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+	existingPath := filepath.Join(t.TempDir(), "corp-bundle.pem")
+	if err := os.WriteFile(existingPath, existing, 0644); err != nil {
+		t.Fatalf("writing existing bundle: %v", err)
+	}
+	deps.Config.Certs.ImportExisting = existingPath
+
+	step := caBundleStep(deps)
+	ctx := context.Background()
+
+	bundlePath := config.CABundlePath()
+	os.MkdirAll(filepath.Dir(bundlePath), 0755)
+	defer os.Remove(bundlePath)
+
+	if err := step.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	data, err := os.ReadFile(bundlePath)
+	if err != nil {
+		t.Fatalf("reading bundle: %v", err)
+	}
+	if n := bytes.Count(data, []byte("BEGIN CERTIFICATE")); n != 3 {
+		t.Errorf("bundle has %d certs, want 3 (2 system + 1 imported)", n)
+	}
+	if !step.Check(ctx) {
+		t.Error("Check returned false after Run, want true")
+	}
+}
+
+func TestCABundleStep_Run_SkipsExcludedCerts(t *testing.T) {
+	deps := testDeps()
+	system, err := deps.CertStore.SystemRoots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deps.State.ExcludedCerts = []string{CertFingerprint(system[1])}
+
+	step := caBundleStep(deps)
+	ctx := context.Background()
+
+	bundlePath := config.CABundlePath()
+	os.MkdirAll(filepath.Dir(bundlePath), 0755)
+	defer os.Remove(bundlePath)
+
+	if err := step.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	data, err := os.ReadFile(bundlePath)
+	if err != nil {
+		t.Fatalf("reading bundle: %v", err)
+	}
+	if n := bytes.Count(data, []byte("BEGIN CERTIFICATE")); n != 1 {
+		t.Errorf("bundle has %d certs, want 1 (2 system - 1 excluded)", n)
+	}
+
+	// Excluding every root is an error, not an empty bundle.
+	deps.State.ExcludedCerts = append(deps.State.ExcludedCerts, CertFingerprint(system[0]))
+	if err := step.Run(ctx); err == nil {
+		t.Error("Run with every cert excluded: want error")
+	}
+}
+
+func TestCABundleStep_Run_FailsOnEmptyImportedBundle(t *testing.T) {
+	deps := testDeps()
+	path := filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(path, []byte("not a bundle"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	deps.Config.Certs.ImportExisting = path
+
+	if err := caBundleStep(deps).Run(context.Background()); err == nil {
+		t.Error("Run should fail when the imported bundle has no certificates")
+	}
+}
+
+func TestCABundleStep_DryRun_WritesPreview(t *testing.T) {
+	deps := testDeps()
+	deps.Preview = true
+	t.Setenv("TMPDIR", t.TempDir())
+
+	msg := caBundleStep(deps).DryRun(context.Background())
+	_, preview, ok := strings.Cut(msg, "preview written to ")
+	if !ok {
+		t.Fatalf("DryRun = %q, want a preview path", msg)
+	}
+	data, err := os.ReadFile(preview)
+	if err != nil {
+		t.Fatalf("reading preview: %v", err)
+	}
+	if n := bytes.Count(data, []byte("BEGIN CERTIFICATE")); n != 2 {
+		t.Errorf("preview has %d certs, want 2", n)
+	}
+
+	deps.Preview = false
+	if msg := caBundleStep(deps).DryRun(context.Background()); strings.Contains(msg, "preview") {
+		t.Errorf("DryRun without Preview = %q, should not write a preview", msg)
+	}
+}
+
 func TestCABundleStep_Check_TrueWhenHashMatches(t *testing.T) {
 	deps := testDeps()
 
@@ -385,10 +933,166 @@ func TestInstallScoopStep_Check(t *testing.T) {
 	}
 }
 
+func TestInstallScoopStep_Check_PinnedVersion(t *testing.T) {
+	ctx := context.Background()
+	out := "Current Scoop version:\nv0.5.2 - Released at 2024-07-26\n"
+
+	tests := []struct {
+		name string
+		pin  string
+		want bool
+	}{
+		{"unset", "", true},
+		{"matching", "0.5.2", true},
+		{"matching prefix", "0.5", true},
+		{"mismatching", "0.4.1", false},
+	}
+	for _, tt := range tests {
+		deps := testDeps()
+		deps.Config.Scoop.Version = tt.pin
+		deps.Exec.(*exec.MockRunner).Results["scoop --version"] = exec.Result{Stdout: out}
+		if got := installScoopStep(deps).Check(ctx); got != tt.want {
+			t.Errorf("%s: Check = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestInstallScoopStep_Run_UpdatesToPinnedVersion(t *testing.T) {
+	deps := testDeps()
+	deps.Config.Scoop.Version = "0.5.2"
+	mockExec := deps.Exec.(*exec.MockRunner)
+	mockExec.Results["scoop update scoop"] = exec.Result{}
+	// Report the old version until 'scoop update scoop' has run.
+	mockExec.Match = func(name string, args []string) (exec.Result, bool) {
+		if name != "scoop" || strings.Join(args, " ") != "--version" {
+			return exec.Result{}, false
+		}
+		if slices.Contains(mockExec.Calls, "scoop update scoop") {
+			return exec.Result{Stdout: "v0.5.2 - Released at 2024-07-26\n"}, true
+		}
+		return exec.Result{Stdout: "v0.4.1 - Released at 2024-04-23\n"}, true
+	}
+
+	step := installScoopStep(deps)
+	if err := step.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if slices.ContainsFunc(mockExec.Calls, func(c string) bool { return strings.HasPrefix(c, "powershell") }) {
+		t.Error("Run should update an installed scoop, not reinstall it")
+	}
+	if !step.Check(context.Background()) {
+		t.Error("Check should pass after updating to the pinned version")
+	}
+}
+
+func TestInstallScoopStep_Run_PinnedVersionUnreachable(t *testing.T) {
+	deps := testDeps()
+	deps.Config.Scoop.Version = "0.3.0"
+	mockExec := deps.Exec.(*exec.MockRunner)
+	mockExec.Results["scoop --version"] = exec.Result{Stdout: "v0.5.2 - Released at 2024-07-26\n"}
+	mockExec.Results["scoop update scoop"] = exec.Result{}
+
+	err := installScoopStep(deps).Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "0.3.0") {
+		t.Errorf("Run error = %v, want one naming the pinned version", err)
+	}
+}
+
+func TestBaseModule_InstallGitOnlyWhenPinned(t *testing.T) {
+	for _, pin := range []string{"", "2.45.1"} {
+		deps := testDeps()
+		deps.Config.Git.Version = pin
+		found := false
+		for _, s := range NewBaseModule(deps).Steps {
+			found = found || s.Name == "Install git"
+		}
+		if found != (pin != "") {
+			t.Errorf("version %q: Install git step present = %v", pin, found)
+		}
+	}
+}
+
+func TestInstallGitStep_Run_ReplacesOtherVersion(t *testing.T) {
+	deps := testDeps()
+	deps.Config.Git.Version = "2.45.1"
+	mockExec := deps.Exec.(*exec.MockRunner)
+	mockExec.Results["scoop list"] = exec.Result{Stdout: "Name    Version\n----    -------\ngit     2.47.0\nlazygit 0.44.1\n"}
+	mockExec.Results["scoop unhold git"] = exec.Result{}
+	mockExec.Results["scoop uninstall git"] = exec.Result{}
+	mockExec.Results["scoop install git@2.45.1"] = exec.Result{}
+	mockExec.Results["scoop hold git"] = exec.Result{}
+	// Report the newer version until the pinned one has been installed.
+	mockExec.Match = func(name string, args []string) (exec.Result, bool) {
+		if name != "git" || strings.Join(args, " ") != "--version" {
+			return exec.Result{}, false
+		}
+		if slices.Contains(mockExec.Calls, "scoop install git@2.45.1") {
+			return exec.Result{Stdout: "git version 2.45.1.windows.1\n"}, true
+		}
+		return exec.Result{Stdout: "git version 2.47.0.windows.2\n"}, true
+	}
+
+	step := installGitStep(deps)
+	if step.Check(context.Background()) {
+		t.Fatal("Check should fail while another version is installed")
+	}
+	if err := step.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	want := []string{"scoop uninstall git", "scoop install git@2.45.1", "scoop hold git"}
+	for _, call := range want {
+		if !slices.Contains(mockExec.Calls, call) {
+			t.Errorf("missing call %q in %v", call, mockExec.Calls)
+		}
+	}
+	if deps.State.GitVersion != "2.45.1.windows.1" {
+		t.Errorf("GitVersion = %q", deps.State.GitVersion)
+	}
+}
+
+func TestInstallGitStep_Run_VerifyFails(t *testing.T) {
+	deps := testDeps()
+	deps.Config.Git.Version = "2.45.1"
+	mockExec := deps.Exec.(*exec.MockRunner)
+	mockExec.Results["scoop list"] = exec.Result{}
+	mockExec.Results["scoop install git@2.45.1"] = exec.Result{}
+	mockExec.Results["scoop hold git"] = exec.Result{}
+	// git isn't on PATH yet: the shim hasn't appeared.
+	mockExec.Missing = []string{"git"}
+
+	err := installGitStep(deps).Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "verifying git install") {
+		t.Errorf("Run error = %v, want a verification error", err)
+	}
+	if deps.State.GitVersion != "" {
+		t.Errorf("GitVersion = %q, want it unset", deps.State.GitVersion)
+	}
+}
+
+func TestInstallGitStep_Run_DeniedLeavesGitAlone(t *testing.T) {
+	deps := testDeps()
+	deps.Config.Git.Version = "2.45.1"
+	deps.Config.Scoop.Denied = []string{"git"}
+	mockExec := deps.Exec.(*exec.MockRunner)
+	mockExec.Results["git --version"] = exec.Result{Stdout: "git version 2.47.0.windows.2\n"}
+
+	err := installGitStep(deps).Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "denied by [scoop] denied") {
+		t.Errorf("Run error = %v, want a [scoop] denied error", err)
+	}
+	for _, call := range mockExec.Calls {
+		if strings.HasPrefix(call, "scoop ") {
+			t.Errorf("ran %q for a denied package", call)
+		}
+	}
+}
+
 func TestInstallScoopStep_Run(t *testing.T) {
 	deps := testDeps()
 	mockExec := deps.Exec.(*exec.MockRunner)
-	mockExec.Results["powershell -NoProfile -Command Set-ExecutionPolicy RemoteSigned -Scope CurrentUser -Force; irm get.scoop.sh | iex"] = exec.Result{ExitCode: 0}
+	mockExec.Results["powershell -NoProfile -Command Set-ExecutionPolicy RemoteSigned -Scope CurrentUser -Force"] = exec.Result{}
+	mockExec.Results["powershell -NoProfile -Command irm get.scoop.sh | iex"] = exec.Result{}
 	ctx := context.Background()
 
 	step := installScoopStep(deps)
@@ -401,6 +1105,64 @@ func TestInstallScoopStep_Run(t *testing.T) {
 	}
 }
 
+func TestInstallScoopStep_Run_ConfirmPath(t *testing.T) {
+	deps := testDeps()
+	deps.Config.Safety.ConfirmPathChanges = true
+	mockExec := deps.Exec.(*exec.MockRunner)
+	mockExec.Results["powershell -NoProfile -Command Set-ExecutionPolicy RemoteSigned -Scope CurrentUser -Force"] = exec.Result{}
+	mockExec.Results["powershell -NoProfile -Command irm get.scoop.sh | iex"] = exec.Result{}
+	ctx := context.Background()
+
+	if err := installScoopStep(deps).Run(ctx); !errors.Is(err, ErrPathDeclined) {
+		t.Fatalf("Run error = %v, want ErrPathDeclined", err)
+	}
+	if slices.ContainsFunc(mockExec.Calls, func(c string) bool { return strings.HasPrefix(c, "powershell") }) {
+		t.Errorf("installer ran without the PATH change confirmed, calls: %v", mockExec.Calls)
+	}
+
+	var asked string
+	deps.ConfirmPath = func(_ []platform.PathEntry, dir string) bool { asked = dir; return true }
+	if err := installScoopStep(deps).Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if want := deps.Config.InstallPath("scoop", "shims"); asked != want {
+		t.Errorf("asked about %q, want the shims directory %q", asked, want)
+	}
+}
+
+func TestInstallScoopStep_Run_ExecutionPolicy(t *testing.T) {
+	const (
+		setPolicy = "powershell -NoProfile -Command Set-ExecutionPolicy RemoteSigned -Scope CurrentUser -Force"
+		bypass    = "powershell -NoProfile -ExecutionPolicy Bypass -Command irm get.scoop.sh | iex"
+	)
+	ctx := context.Background()
+
+	// Setting the policy fails (e.g. locked by group policy): fall back to
+	// a per-process bypass.
+	deps := testDeps()
+	mockExec := deps.Exec.(*exec.MockRunner)
+	mockExec.Results[setPolicy] = exec.Result{ExitCode: 1}
+	mockExec.Results[bypass] = exec.Result{}
+	if err := installScoopStep(deps).Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !slices.Contains(mockExec.Calls, bypass) {
+		t.Errorf("installer should fall back to Bypass, calls: %v", mockExec.Calls)
+	}
+
+	// Disabled in config: the user's policy is never touched.
+	deps = testDeps()
+	deps.Config.Scoop.SetExecutionPolicy = false
+	mockExec = deps.Exec.(*exec.MockRunner)
+	mockExec.Results[bypass] = exec.Result{}
+	if err := installScoopStep(deps).Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if slices.Contains(mockExec.Calls, setPolicy) {
+		t.Error("execution policy should not be changed when set_execution_policy = false")
+	}
+}
+
 func TestInstallScoopStep_DryRun(t *testing.T) {
 	deps := testDeps()
 	ctx := context.Background()
@@ -472,6 +1234,45 @@ func TestScoopBucketsStep_DryRun(t *testing.T) {
 	}
 }
 
+func TestScoopUpdateStep_AtMostDaily(t *testing.T) {
+	deps := testDeps()
+	mockExec := deps.Exec.(*exec.MockRunner)
+	mockExec.Results["scoop update"] = exec.Result{}
+
+	step := scoopUpdateStep(deps)
+	ctx := context.Background()
+
+	if step.Check(ctx) {
+		t.Fatal("Check should be false before the first update")
+	}
+	if err := step.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if deps.State.ScoopUpdated.IsZero() {
+		t.Error("update time not recorded in state")
+	}
+	if !step.Check(ctx) {
+		t.Error("Check should be true right after an update")
+	}
+
+	deps.State.ScoopUpdated = time.Now().Add(-25 * time.Hour)
+	if step.Check(ctx) {
+		t.Error("Check should be false once the last update is over a day old")
+	}
+}
+
+func TestBaseModule_NoUpdateStep_WhenDisabled(t *testing.T) {
+	deps := testDeps()
+	deps.Config.Scoop.Update = false
+	mod := NewBaseModule(deps)
+
+	for _, s := range mod.Steps {
+		if s.Name == "Update Scoop" {
+			t.Error("update step should be omitted when [scoop] update = false")
+		}
+	}
+}
+
 func TestBaseModule_NoBucketsStep_WhenEmpty(t *testing.T) {
 	deps := testDeps()
 	deps.Config.Scoop.Buckets = nil
@@ -483,3 +1284,173 @@ func TestBaseModule_NoBucketsStep_WhenEmpty(t *testing.T) {
 		}
 	}
 }
+
+func TestGitSteps_UseInclude(t *testing.T) {
+	deps := testDeps()
+	deps.Config.Git.UseInclude = true
+	mockExec := deps.Exec.(*exec.MockRunner)
+	file := config.GitIncludePath()
+	ctx := context.Background()
+
+	// Settings go to the include file, never the global config.
+	mockExec.Results["git config --file "+file+" init.defaultBranch main"] = exec.Result{}
+	if err := gitDefaultBranchStep(deps).Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	mockExec.Results["git config --file "+file+" init.defaultBranch"] = exec.Result{Stdout: "main\n"}
+	if !gitDefaultBranchStep(deps).Check(ctx) {
+		t.Error("Check should read the include file")
+	}
+	for _, call := range mockExec.Calls {
+		if strings.Contains(call, "--global") {
+			t.Errorf("unexpected global git config call %q", call)
+		}
+	}
+
+	// The include is added once.
+	step := gitIncludeStep(deps)
+	if step.Check(ctx) {
+		t.Error("Check should fail before the include is added")
+	}
+	mockExec.Results["git config --global --add include.path "+file] = exec.Result{}
+	if err := step.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	mockExec.Results["git config --global --get-all include.path"] = exec.Result{Stdout: "~/work.gitconfig\n" + file + "\n"}
+	if !step.Check(ctx) {
+		t.Error("Check should pass once include.path lists the shhh file")
+	}
+
+	found := false
+	for _, s := range NewBaseModule(deps).Steps {
+		if s.Name == "Include shhh git config" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("base module should include the git include step with use_include")
+	}
+}
+
+func TestGitCAEnvStep_SetsBothVars(t *testing.T) {
+	deps := testDeps()
+	step := gitCAEnvStep(deps)
+	ctx := context.Background()
+	caPath := config.CABundlePath()
+
+	if step.Check(ctx) {
+		t.Fatal("Check should be false before Run")
+	}
+	if err := step.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for _, key := range []string{"GIT_SSL_CAINFO", "CURL_CA_BUNDLE"} {
+		if val, _, err := deps.Env.Get(key); err != nil || val != caPath {
+			t.Errorf("user %s = %q (err %v), want %q", key, val, err, caPath)
+		}
+		if got := os.Getenv(key); got != caPath {
+			t.Errorf("process %s = %q, want %q", key, got, caPath)
+		}
+		if !contains(deps.State.ManagedEnvVars, key) {
+			t.Errorf("%s not recorded in state", key)
+		}
+	}
+	if !step.Check(ctx) {
+		t.Error("Check should be true after Run")
+	}
+
+	// A value only in the persistent environment still needs this process updated.
+	os.Unsetenv("GIT_SSL_CAINFO")
+	if step.Check(ctx) {
+		t.Error("Check should be false when the process value is missing")
+	}
+}
+
+func TestResetEnvStep_DeletesOnce(t *testing.T) {
+	deps := testDeps()
+	deps.Config.Env.Reset = []string{"GOPROXY"}
+	deps.Env.Set("GOPROXY", "https://personal.example.com")
+	os.Setenv("GOPROXY", "https://personal.example.com")
+	t.Cleanup(func() { os.Unsetenv("GOPROXY") })
+
+	mod := NewBaseModule(deps)
+	step := mod.Steps[0]
+	if step.Name != "Reset environment variables" {
+		t.Fatalf("first step = %q, want reset step", step.Name)
+	}
+
+	ctx := context.Background()
+	if step.Check(ctx) {
+		t.Fatal("Check should be false before the reset")
+	}
+	if err := step.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if _, _, err := deps.Env.Get("GOPROXY"); err == nil {
+		t.Error("GOPROXY still set in user environment")
+	}
+	if v := os.Getenv("GOPROXY"); v != "" {
+		t.Errorf("GOPROXY still set in process: %q", v)
+	}
+	if !deps.State.HasResetEnvVar("GOPROXY") {
+		t.Error("reset not recorded in state")
+	}
+
+	// A value set after the reset (by shhh itself) is not cleared again.
+	if !step.Check(ctx) {
+		t.Error("Check should be true once the reset is recorded")
+	}
+}
+
+func TestBaseModule_NoResetStep_WhenEmpty(t *testing.T) {
+	deps := testDeps()
+	mod := NewBaseModule(deps)
+
+	for _, s := range mod.Steps {
+		if s.Name == "Reset environment variables" {
+			t.Error("reset step should be omitted when [env] reset is empty")
+		}
+	}
+}
+
+func TestAllModules_Validate(t *testing.T) {
+	deps := testDeps()
+	mods := []*module.Module{
+		NewBaseModule(deps),
+		NewGolangModule(deps),
+		NewPythonModule(deps),
+		NewNodeModule(deps),
+		NewToolsModule(deps),
+		NewCloudModule(deps),
+		NewDockerModule(deps),
+	}
+	for _, m := range mods {
+		if err := m.Validate(); err != nil {
+			t.Errorf("%s: %v", m.ID, err)
+		}
+	}
+}
+
+func TestTraceCheck_LogsComparison(t *testing.T) {
+	var buf bytes.Buffer
+	deps := testDeps()
+	deps.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	deps.Exec.(*exec.MockRunner).Results["git config --global init.defaultBranch"] = exec.Result{Stdout: "master\n"}
+
+	step := gitDefaultBranchStep(deps)
+	if step.Check(context.Background()) {
+		t.Fatal("Check should fail when branch differs")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("nothing should be traced without Trace:\n%s", buf.String())
+	}
+
+	deps.Trace = true
+	step.Check(context.Background())
+	out := buf.String()
+	for _, want := range []string{"trace check", "want=main", "got=master", "match=false"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("trace log missing %q:\n%s", want, out)
+		}
+	}
+}
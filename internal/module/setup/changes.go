@@ -0,0 +1,71 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/druarnfield/shhh/internal/config"
+	"github.com/druarnfield/shhh/internal/module"
+	"github.com/druarnfield/shhh/internal/state"
+)
+
+// trackFile runs write and records how it changed the text file at path,
+// as read by read, for setup --show-diffs. A file that can't be read counts
+// as empty.
+func trackFile(ctx context.Context, path string, read func() (string, error), write func() error) error {
+	before, _ := read()
+	if err := write(); err != nil {
+		return err
+	}
+	after, _ := read()
+	module.RecordChange(ctx, module.FileChange{Path: path, Before: before, After: after})
+	return nil
+}
+
+// trackManagedFile is trackFile for a file shhh shares with the user, such
+// as a tool's own config. The file is recorded as a managed file owned by
+// owner when write created it or it still held only what shhh last wrote;
+// a file with anyone else's settings isn't, so teardown never deletes them.
+func trackManagedFile(ctx context.Context, deps *Dependencies, owner, path string, write func() error) error {
+	_, err := os.Stat(path)
+	owned := errors.Is(err, fs.ErrNotExist)
+	if f, ok := deps.State.LookupManagedFile(path); ok {
+		status, err := f.Status()
+		owned = err == nil && status != state.FileModified
+	}
+
+	if err := trackFile(ctx, path, readFile(path), write); err != nil {
+		return err
+	}
+	if owned {
+		if data, err := os.ReadFile(path); err == nil {
+			deps.State.AddManagedFile(owner, path, data)
+		}
+	}
+	return nil
+}
+
+// readFile returns a trackFile reader for the file at path.
+func readFile(path string) func() (string, error) {
+	return func() (string, error) {
+		data, err := os.ReadFile(path)
+		return string(data), err
+	}
+}
+
+// gitConfigFile returns the file gitScope's options select: the shhh
+// include file, or the global ~/.gitconfig.
+func gitConfigFile(deps *Dependencies) string {
+	if deps.Config.Git.UseInclude {
+		return config.GitIncludePath()
+	}
+	return globalGitConfigPath()
+}
+
+func globalGitConfigPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".gitconfig")
+}
@@ -0,0 +1,110 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/druarnfield/shhh/internal/module"
+)
+
+// cloudCAEnvVars maps known cloud CLI scoop packages to the environment
+// variables they read to find trusted CA certificates.
+var cloudCAEnvVars = map[string][]string{
+	"azure-cli": {"REQUESTS_CA_BUNDLE"}, // az is a Python app built on requests
+	"awscli":    {"AWS_CA_BUNDLE"},
+}
+
+// NewCloudModule creates the cloud CLI setup module. It installs the CLIs
+// listed in [cloud] tools and points each at the shhh CA bundle. Proxy
+// settings come from the base module's HTTP(S)_PROXY variables.
+func NewCloudModule(deps *Dependencies) *module.Module {
+	tools := deps.Config.Cloud.Tools
+
+	var steps []module.Step
+	if len(tools) > 0 {
+		steps = append(steps, scoopInstallStep(deps, "cloud",
+			"Install cloud CLIs",
+			"Install cloud provider CLIs via Scoop",
+			"Cloud CLIs such as az and aws for working with your organisation's cloud accounts.",
+			tools,
+		))
+		if len(cloudCertKeys(tools)) > 0 {
+			steps = append(steps, configureCloudCertsStep(deps, tools))
+		}
+	}
+	if err := deps.Config.CheckModule("cloud"); err != nil {
+		steps = []module.Step{configErrorStep(err)}
+	}
+
+	return &module.Module{
+		ID:           "cloud",
+		Name:         "Cloud CLIs",
+		Description:  "Install cloud CLIs and configure their proxy and certificates",
+		Category:     module.CategoryTool,
+		Dependencies: []string{"base"},
+		SupportedOS:  scoopOS,
+		Steps:        steps,
+	}
+}
+
+// cloudCertKeys returns the CA bundle variables needed by the given tools,
+// in a stable order without duplicates. CURL_CA_BUNDLE, which curl-based
+// helpers read, isn't among them: the base module sets it for all tools.
+func cloudCertKeys(tools []string) []string {
+	var keys []string
+	for _, tool := range tools {
+		for _, key := range cloudCAEnvVars[tool] {
+			if !contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+func configureCloudCertsStep(deps *Dependencies, tools []string) module.Step {
+	caPath := deps.Config.CABundlePath()
+	keys := cloudCertKeys(tools)
+
+	return module.Step{
+		Name:        "Configure cloud CLI CA certificates",
+		Description: "Point cloud CLIs at the shhh CA bundle",
+		Env:         envOf(caPath, keys...),
+		Explain: "Cloud CLIs talk to their provider over HTTPS and each has its own setting for trusted CAs. " +
+			"AWS_CA_BUNDLE is read by the aws CLI and the Azure CLI uses REQUESTS_CA_BUNDLE; curl-based " +
+			"helpers use the CURL_CA_BUNDLE the base module sets. Without these, logins and API calls fail with " +
+			"certificate errors behind corporate proxies.",
+		Check: func(_ context.Context) bool {
+			for _, key := range keys {
+				if !deps.envMatches(key, caPath) {
+					return false
+				}
+			}
+			return true
+		},
+		Run: func(_ context.Context) error {
+			for _, key := range keys {
+				if err := deps.Env.Set(key, caPath); err != nil {
+					return fmt.Errorf("setting %s: %w", key, err)
+				}
+				os.Setenv(key, caPath)
+				deps.State.AddEnvVar("cloud", key)
+			}
+			return nil
+		},
+		DryRun: func(_ context.Context) string {
+			return fmt.Sprintf("Would set %s to %s", strings.Join(keys, ", "), caPath)
+		},
+	}
+}
+
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package setup
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/druarnfield/shhh/internal/config"
+	"github.com/druarnfield/shhh/internal/state"
+)
+
+func TestCloudModule_HasRequiredSteps(t *testing.T) {
+	deps := testDeps()
+	mod := NewCloudModule(deps)
+
+	if mod.ID != "cloud" {
+		t.Errorf("ID = %q, want %q", mod.ID, "cloud")
+	}
+	if len(mod.Dependencies) == 0 || mod.Dependencies[0] != "base" {
+		t.Error("expected dependency on base")
+	}
+
+	stepNames := make(map[string]bool)
+	for _, s := range mod.Steps {
+		stepNames[s.Name] = true
+	}
+
+	required := []string{"Install cloud CLIs", "Configure cloud CLI CA certificates"}
+	for _, name := range required {
+		if !stepNames[name] {
+			t.Errorf("missing required step: %q", name)
+		}
+	}
+}
+
+func TestCloudModule_NoToolsNoSteps(t *testing.T) {
+	deps := testDeps()
+	deps.Config.Cloud.Tools = nil
+	mod := NewCloudModule(deps)
+
+	if len(mod.Steps) != 0 {
+		t.Errorf("expected 0 steps with no cloud tools, got %d", len(mod.Steps))
+	}
+}
+
+func TestCloudCertKeys(t *testing.T) {
+	got := cloudCertKeys([]string{"awscli"})
+	want := []string{"AWS_CA_BUNDLE"}
+	if len(got) != len(want) {
+		t.Fatalf("keys = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("keys[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	if keys := cloudCertKeys([]string{"unknown-cli"}); len(keys) != 0 {
+		t.Errorf("unknown tool keys = %v, want none: base owns CURL_CA_BUNDLE", keys)
+	}
+}
+
+func TestConfigureCloudCertsStep_CheckAndRun(t *testing.T) {
+	deps := testDeps()
+	deps.State = &state.State{}
+	ctx := context.Background()
+	caPath := config.CABundlePath()
+	t.Cleanup(func() {
+		os.Unsetenv("REQUESTS_CA_BUNDLE")
+		os.Unsetenv("AWS_CA_BUNDLE")
+	})
+
+	step := configureCloudCertsStep(deps, []string{"azure-cli", "awscli"})
+
+	if step.Check(ctx) {
+		t.Error("Check should return false initially")
+	}
+	if err := step.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !step.Check(ctx) {
+		t.Error("Check should return true after Run")
+	}
+
+	for _, key := range []string{"REQUESTS_CA_BUNDLE", "AWS_CA_BUNDLE"} {
+		if val, _, _ := deps.Env.Get(key); val != caPath {
+			t.Errorf("%s = %q, want %q", key, val, caPath)
+		}
+	}
+	if len(deps.State.ManagedEnvVars) != 2 {
+		t.Errorf("ManagedEnvVars = %v, want 2 entries", deps.State.ManagedEnvVars)
+	}
+	if _, _, err := deps.Env.Get("CURL_CA_BUNDLE"); err == nil {
+		t.Error("CURL_CA_BUNDLE is the base module's to set")
+	}
+}
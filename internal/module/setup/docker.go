@@ -0,0 +1,203 @@
+package setup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/druarnfield/shhh/internal/config"
+	"github.com/druarnfield/shhh/internal/module"
+)
+
+// NewDockerModule creates the Docker CLI setup module. With [docker]
+// install it installs the docker CLI and buildx; with that or [docker]
+// registry_mirror it configures the proxy containers and builds use and
+// where images are pulled from. Without either it has no steps.
+func NewDockerModule(deps *Dependencies) *module.Module {
+	cfg := deps.Config.Docker
+
+	var steps []module.Step
+	if cfg.Install {
+		steps = append(steps, scoopInstallStep(deps, "docker",
+			"Install Docker CLI",
+			"Install the docker CLI and buildx via Scoop",
+			"The docker CLI talks to a Docker engine (Docker Desktop, or a remote host) to build and run "+
+				"containers; buildx is its build plugin.",
+			config.DockerPackages,
+		))
+	}
+	if cfg.Install || cfg.RegistryMirror != "" {
+		if proxies := dockerProxies(deps.Config); proxies != nil {
+			steps = append(steps, configureDockerProxyStep(deps, proxies))
+		}
+		if cfg.RegistryMirror != "" {
+			steps = append(steps, configureDockerMirrorStep(deps))
+		}
+	}
+	if err := deps.Config.CheckModule("docker"); err != nil {
+		steps = []module.Step{configErrorStep(err)}
+	}
+
+	return &module.Module{
+		ID:           "docker",
+		Name:         "Docker",
+		Description:  "Install the Docker CLI and configure its proxy and registry mirror",
+		Category:     module.CategoryTool,
+		Dependencies: []string{"base"},
+		SupportedOS:  scoopOS,
+		Steps:        steps,
+	}
+}
+
+// dockerConfigDir returns the directory the docker CLI keeps its config in:
+// DOCKER_CONFIG, or ~/.docker.
+func dockerConfigDir() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".docker")
+}
+
+// dockerProxies returns the proxies.default settings for the docker CLI
+// config, which it passes to containers and builds, or nil when no proxy is
+// configured.
+func dockerProxies(cfg *config.Config) map[string]any {
+	if cfg.Proxy.Mode == "direct" || (cfg.Proxy.HTTP == "" && cfg.Proxy.HTTPS == "") {
+		return nil
+	}
+	proxies := map[string]any{}
+	if cfg.Proxy.HTTP != "" {
+		proxies["httpProxy"] = cfg.Proxy.HTTP
+	}
+	if cfg.Proxy.HTTPS != "" {
+		proxies["httpsProxy"] = cfg.Proxy.HTTPS
+	}
+	if noProxy := cfg.EffectiveNoProxy(); noProxy != "" {
+		proxies["noProxy"] = noProxy
+	}
+	return proxies
+}
+
+// readJSONObject reads the JSON object in the file at path. A missing file
+// reads as an empty object.
+func readJSONObject(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]any{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	obj := map[string]any{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return obj, nil
+}
+
+// writeJSONObject writes obj to path as indented JSON, the way docker
+// writes its own config.
+func writeJSONObject(path string, obj map[string]any) error {
+	data, err := json.MarshalIndent(obj, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// jsonKeyMatches reports whether the JSON object at path holds want under
+// key. JSON numbers and arrays decode to float64 and []any, so want must
+// use the types json.Unmarshal produces.
+func jsonKeyMatches(path, key string, want any) bool {
+	obj, err := readJSONObject(path)
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(obj[key], want)
+}
+
+// setJSONKey sets key to value in the JSON object at path, leaving every
+// other key (e.g. the CLI's auths and credsStore) as it was. The file is
+// tracked with trackManagedFile.
+func setJSONKey(ctx context.Context, deps *Dependencies, path, key string, value any) error {
+	obj, err := readJSONObject(path)
+	if err != nil {
+		return err
+	}
+	obj[key] = value
+	return trackManagedFile(ctx, deps, "docker", path, func() error {
+		return writeJSONObject(path, obj)
+	})
+}
+
+func configureDockerProxyStep(deps *Dependencies, proxies map[string]any) module.Step {
+	path := filepath.Join(dockerConfigDir(), "config.json")
+
+	return module.Step{
+		Name:        "Configure Docker proxy",
+		Description: fmt.Sprintf("Set proxies.default in %s", path),
+		Explain: "Containers and image builds don't inherit your HTTP_PROXY variables. The docker CLI " +
+			"passes the proxies.default settings in its config.json to every container and build, so " +
+			"apt-get, pip and friends inside them can reach the internet. Only that key is changed; " +
+			"logins (auths) and other settings are kept.",
+		Check: func(_ context.Context) bool {
+			obj, err := readJSONObject(path)
+			if err != nil {
+				return false
+			}
+			current, _ := obj["proxies"].(map[string]any)
+			return reflect.DeepEqual(current["default"], proxies)
+		},
+		Run: func(ctx context.Context) error {
+			obj, err := readJSONObject(path)
+			if err != nil {
+				return err
+			}
+			// proxies.default is shhh's; per-daemon entries are the user's.
+			current, _ := obj["proxies"].(map[string]any)
+			merged := maps.Clone(current)
+			if merged == nil {
+				merged = map[string]any{}
+			}
+			merged["default"] = proxies
+			return setJSONKey(ctx, deps, path, "proxies", merged)
+		},
+		DryRun: func(_ context.Context) string {
+			return fmt.Sprintf("Would set proxies.default in %s to the [proxy] settings", path)
+		},
+	}
+}
+
+func configureDockerMirrorStep(deps *Dependencies) module.Step {
+	mirror := deps.Config.Docker.RegistryMirror
+	// Docker Desktop reads the engine's settings from ~/.docker/daemon.json.
+	path := filepath.Join(dockerConfigDir(), "daemon.json")
+	want := []any{mirror}
+
+	return module.Step{
+		Name:        "Configure Docker registry mirror",
+		Description: fmt.Sprintf("Pull images through %s", mirror),
+		Explain: "Docker pulls images from Docker Hub unless the engine's registry-mirrors setting " +
+			"names a mirror. Corporate networks often block Docker Hub or rate-limit it, and run an " +
+			"internal pull-through cache instead. Only registry-mirrors is changed; restart Docker " +
+			"Desktop for it to take effect.",
+		Check: func(_ context.Context) bool {
+			return jsonKeyMatches(path, "registry-mirrors", want)
+		},
+		Run: func(ctx context.Context) error {
+			return setJSONKey(ctx, deps, path, "registry-mirrors", want)
+		},
+		DryRun: func(_ context.Context) string {
+			return fmt.Sprintf("Would set registry-mirrors in %s to [%s]", path, mirror)
+		},
+	}
+}
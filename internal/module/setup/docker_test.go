@@ -0,0 +1,183 @@
+package setup
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/druarnfield/shhh/internal/state"
+)
+
+func TestDockerModule_HasRequiredSteps(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	deps := testDeps()
+	deps.Config.Docker.Install = true
+	deps.Config.Docker.RegistryMirror = "https://mirror.example.com"
+	mod := NewDockerModule(deps)
+
+	if mod.ID != "docker" {
+		t.Errorf("ID = %q, want %q", mod.ID, "docker")
+	}
+	if len(mod.Dependencies) == 0 || mod.Dependencies[0] != "base" {
+		t.Error("expected dependency on base")
+	}
+
+	stepNames := make(map[string]bool)
+	for _, s := range mod.Steps {
+		stepNames[s.Name] = true
+	}
+
+	required := []string{"Install Docker CLI", "Configure Docker proxy", "Configure Docker registry mirror"}
+	for _, name := range required {
+		if !stepNames[name] {
+			t.Errorf("missing required step: %q", name)
+		}
+	}
+}
+
+func TestDockerModule_NoMirrorNoProxy(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	deps := testDeps()
+	deps.Config.Docker.Install = true
+	deps.Config.Proxy.Mode = "direct"
+	mod := NewDockerModule(deps)
+
+	if len(mod.Steps) != 1 || mod.Steps[0].Name != "Install Docker CLI" {
+		t.Errorf("steps = %v, want only the install", mod.Steps)
+	}
+}
+
+func TestDockerModule_NothingUnlessConfigured(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	deps := testDeps()
+	if steps := NewDockerModule(deps).Steps; len(steps) != 0 {
+		t.Errorf("steps = %v, want none without [docker] settings", steps)
+	}
+
+	deps.Config.Docker.RegistryMirror = "https://mirror.example.com"
+	for _, s := range NewDockerModule(deps).Steps {
+		if s.Name == "Install Docker CLI" {
+			t.Error("docker CLI installed without [docker] install")
+		}
+	}
+}
+
+func TestConfigureDockerProxyStep_PreservesAuths(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	path := filepath.Join(dir, "config.json")
+	existing := `{"auths": {"registry.example.com": {"auth": "dXNlcjpwYXNz"}}, "proxies": {"tcp://build:2376": {"httpProxy": "http://other:3128"}}}`
+	if err := os.WriteFile(path, []byte(existing), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	deps := testDeps()
+	step := configureDockerProxyStep(deps, dockerProxies(testConfig()))
+	if step.Check(ctx) {
+		t.Error("Check should return false before Run")
+	}
+	if err := step.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !step.Check(ctx) {
+		t.Error("Check should return true after Run")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Auths   map[string]map[string]string
+		Proxies map[string]map[string]string
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("config.json: %v", err)
+	}
+	if got.Auths["registry.example.com"]["auth"] != "dXNlcjpwYXNz" {
+		t.Errorf("auths lost: %s", data)
+	}
+	if got.Proxies["tcp://build:2376"]["httpProxy"] != "http://other:3128" {
+		t.Errorf("per-daemon proxy lost: %s", data)
+	}
+	if got.Proxies["default"]["httpsProxy"] != "http://proxy:8080" {
+		t.Errorf("proxies.default = %v", got.Proxies["default"])
+	}
+	if _, ok := deps.State.LookupManagedFile(path); ok {
+		t.Error("a config.json holding the user's logins should not be a managed file")
+	}
+}
+
+func TestConfigureDockerMirrorStep_CheckAndRun(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	path := filepath.Join(dir, "daemon.json")
+	if err := os.WriteFile(path, []byte(`{"features": {"buildkit": true}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	deps := testDeps()
+	deps.Config.Docker.RegistryMirror = "https://mirror.example.com"
+	step := configureDockerMirrorStep(deps)
+	if step.Check(ctx) {
+		t.Error("Check should return false before Run")
+	}
+	if err := step.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !step.Check(ctx) {
+		t.Error("Check should return true after Run")
+	}
+
+	obj, err := readJSONObject(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := obj["features"]; !ok {
+		t.Errorf("features lost: %v", obj)
+	}
+}
+
+func TestConfigureDockerMirrorStep_RecordsCreatedDaemonJSON(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	path := filepath.Join(dir, "daemon.json")
+
+	deps := testDeps()
+	deps.Config.Docker.RegistryMirror = "https://mirror.example.com"
+	if err := configureDockerMirrorStep(deps).Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	f, ok := deps.State.LookupManagedFile(path)
+	if !ok {
+		t.Fatal("daemon.json shhh created should be a managed file")
+	}
+	if status, err := f.Status(); err != nil || status != state.FileUnchanged {
+		t.Errorf("Status = %v, %v; want FileUnchanged", status, err)
+	}
+	if got := deps.State.Owners["docker"].Files; len(got) != 1 || got[0] != path {
+		t.Errorf("docker owns %v, want [%s]", got, path)
+	}
+}
+
+func TestConfigureDockerProxyStep_BadJSON(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	step := configureDockerProxyStep(testDeps(), dockerProxies(testConfig()))
+	if err := step.Run(context.Background()); err == nil {
+		t.Error("Run should fail rather than overwrite a config.json it can't parse")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "{not json" {
+		t.Errorf("config.json changed to %q", data)
+	}
+}
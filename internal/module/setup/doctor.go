@@ -0,0 +1,169 @@
+package setup
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/druarnfield/shhh/internal/module"
+	"github.com/druarnfield/shhh/internal/platform"
+)
+
+// StepStatus is what doctor found when it ran a step's Check.
+type StepStatus int
+
+const (
+	StepSatisfied     StepStatus = iota // Check passed
+	StepNotConfigured                   // Check failed: setup would run the step
+	StepErrored                         // Check panicked or timed out
+)
+
+func (s StepStatus) String() string {
+	switch s {
+	case StepSatisfied:
+		return "satisfied"
+	case StepNotConfigured:
+		return "not configured"
+	default:
+		return "check errored"
+	}
+}
+
+// StepCheck is the outcome of one step's Check. Err says why a check
+// errored.
+type StepCheck struct {
+	Module *module.Module
+	Step   *module.Step
+	Status StepStatus
+	Err    error
+}
+
+// stepCheckTimeout bounds each Check CheckSteps runs, so one hung command
+// doesn't stall the whole report.
+const stepCheckTimeout = 30 * time.Second
+
+// CheckSteps plans ids and their dependencies (see module.BuildPlan) and
+// reports the Check of each step, in run order. Up to concurrency checks
+// run at once. Steps without a Check always run and are left out.
+func CheckSteps(ctx context.Context, reg *module.Registry, ids []string, concurrency int) ([]StepCheck, error) {
+	plan, err := module.BuildPlan(ctx, reg, ids, module.PlanOptions{
+		Concurrency:  concurrency,
+		CheckTimeout: stepCheckTimeout,
+		SkipDryRun:   true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var checks []StepCheck
+	for _, mp := range plan.Modules {
+		for _, sp := range mp.Steps {
+			if sp.Step.Check == nil {
+				continue
+			}
+			status := StepSatisfied
+			switch {
+			case sp.Err != nil:
+				status = StepErrored
+			case sp.WillRun:
+				status = StepNotConfigured
+			}
+			checks = append(checks, StepCheck{Module: mp.Module, Step: sp.Step, Status: status, Err: sp.Err})
+		}
+	}
+	return checks, nil
+}
+
+// caEnvModules maps each variable that points tools at the CA bundle to
+// the module that sets it.
+var caEnvModules = []struct{ key, module string }{
+	{"SSL_CERT_FILE", "base"},
+	{"REQUESTS_CA_BUNDLE", "python"},
+	{"PIP_CERT", "python"},
+	{"NODE_EXTRA_CA_CERTS", "node"},
+	{"CARGO_HTTP_CAINFO", "rust"},
+}
+
+// CAChecks verifies the CA bundle is wired up: the bundle at
+// Config.CABundlePath is a readable PEM file of certificates, and each
+// variable and git's http.sslCAInfo points at it. Only settings made by a
+// module in installed are checked, and nothing is until base is. A failed
+// check's Fix names the setup command that repairs it.
+func CAChecks(ctx context.Context, deps *Dependencies, installed []string) []PreflightCheck {
+	if !slices.Contains(installed, "base") {
+		return nil
+	}
+	caPath := deps.Config.CABundlePath()
+	checks := []PreflightCheck{caBundleCheck(caPath)}
+
+	for _, v := range caEnvModules {
+		if !slices.Contains(installed, v.module) {
+			continue
+		}
+		check := PreflightCheck{Name: v.key, Fix: "run: shhh setup " + v.module}
+		got, _, err := deps.Env.Get(v.key)
+		if errors.Is(err, platform.ErrNotSupported) {
+			got, err = os.Getenv(v.key), nil
+		}
+		switch {
+		case err != nil:
+			check.Err = err
+		case got == "":
+			check.Err = errors.New("not set")
+		case got != caPath:
+			check.Err = fmt.Errorf("is %s, not the CA bundle %s", got, caPath)
+		default:
+			check.Detail = got
+		}
+		checks = append(checks, check)
+	}
+
+	check := PreflightCheck{Name: "git http.sslCAInfo", Fix: "run: shhh setup base"}
+	got, _ := gitConfig(ctx, deps, "http.sslCAInfo")
+	switch {
+	case got == "":
+		check.Err = errors.New("not set")
+	case got != caPath:
+		check.Err = fmt.Errorf("is %s, not the CA bundle %s", got, caPath)
+	default:
+		check.Detail = got
+	}
+	return append(checks, check)
+}
+
+// caBundleCheck checks that path holds at least one PEM certificate and
+// that every certificate in it parses.
+func caBundleCheck(path string) PreflightCheck {
+	check := PreflightCheck{Name: "CA bundle", Fix: "run: shhh setup base"}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		check.Err = err
+		return check
+	}
+	n := 0
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			check.Err = fmt.Errorf("%s: certificate %d doesn't parse: %w", path, n+1, err)
+			return check
+		}
+		n++
+	}
+	if n == 0 {
+		check.Err = fmt.Errorf("%s holds no PEM certificates", path)
+		return check
+	}
+	check.Detail = fmt.Sprintf("%d certificates in %s", n, path)
+	return check
+}
@@ -0,0 +1,125 @@
+package setup
+
+import (
+	"context"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/druarnfield/shhh/internal/exec"
+	"github.com/druarnfield/shhh/internal/module"
+)
+
+func TestCheckSteps(t *testing.T) {
+	run := func(context.Context) error { return nil }
+	mod := &module.Module{ID: "base", Steps: []module.Step{
+		{Name: "done", Check: func(context.Context) bool { return true }, Run: run},
+		{Name: "pending", Check: func(context.Context) bool { return false }, Run: run},
+		{Name: "broken", Check: func(context.Context) bool { panic("boom") }, Run: run},
+		{Name: "unchecked", Run: run},
+	}}
+
+	reg := module.NewRegistry()
+	reg.Register(mod)
+	checks, err := CheckSteps(context.Background(), reg, []string{"base"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []StepStatus{StepSatisfied, StepNotConfigured, StepErrored}
+	if len(checks) != len(want) {
+		t.Fatalf("got %d checks, want %d (steps without a Check left out)", len(checks), len(want))
+	}
+	for i, w := range want {
+		if checks[i].Status != w {
+			t.Errorf("%s: status = %s, want %s", checks[i].Step.Name, checks[i].Status, w)
+		}
+	}
+	if checks[2].Err == nil || !strings.Contains(checks[2].Err.Error(), "boom") {
+		t.Errorf("errored check Err = %v, want the panic", checks[2].Err)
+	}
+}
+
+// caDeps returns testDeps with a CA bundle of testCerts written under a
+// temporary [paths] root and every CA variable and git pointing at it.
+func caDeps(t *testing.T) *Dependencies {
+	t.Helper()
+	deps := testDeps()
+	deps.Config.Paths.Root = t.TempDir()
+	caPath := deps.Config.CABundlePath()
+
+	var bundle []byte
+	for _, cert := range testCerts() {
+		bundle = append(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+	if err := os.MkdirAll(filepath.Dir(caPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(caPath, bundle, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range caEnvModules {
+		deps.Env.Set(v.key, caPath)
+	}
+	deps.Exec.(*exec.MockRunner).Results["git config --global http.sslCAInfo"] = exec.Result{Stdout: caPath + "\n"}
+	return deps
+}
+
+func TestCAChecks_Pass(t *testing.T) {
+	deps := caDeps(t)
+	checks := CAChecks(context.Background(), deps, []string{"base", "python", "node"})
+	if len(checks) != 6 {
+		t.Fatalf("got %d checks, want bundle, 4 variables and git", len(checks))
+	}
+	for _, c := range checks {
+		if c.Err != nil {
+			t.Errorf("%s failed: %v", c.Name, c.Err)
+		}
+	}
+	if !strings.HasPrefix(checks[0].Detail, "2 certificates") {
+		t.Errorf("bundle detail = %q", checks[0].Detail)
+	}
+}
+
+func TestCAChecks_OnlyInstalledModules(t *testing.T) {
+	deps := caDeps(t)
+	if checks := CAChecks(context.Background(), deps, nil); len(checks) != 0 {
+		t.Errorf("nothing installed: got %d checks, want none", len(checks))
+	}
+	for _, c := range CAChecks(context.Background(), deps, []string{"base"}) {
+		if c.Name == "PIP_CERT" || c.Name == "NODE_EXTRA_CA_CERTS" {
+			t.Errorf("%s checked without its module installed", c.Name)
+		}
+	}
+}
+
+func TestCAChecks_Failures(t *testing.T) {
+	deps := caDeps(t)
+	caPath := deps.Config.CABundlePath()
+	os.WriteFile(caPath, []byte("not a certificate\n"), 0644)
+	deps.Env.Set("PIP_CERT", `C:\old\bundle.pem`)
+	deps.Env.Delete("NODE_EXTRA_CA_CERTS")
+	deps.Exec.(*exec.MockRunner).Results["git config --global http.sslCAInfo"] = exec.Result{ExitCode: 1}
+
+	failed := make(map[string]string)
+	for _, c := range CAChecks(context.Background(), deps, []string{"base", "python", "node"}) {
+		if c.Err != nil {
+			failed[c.Name] = c.Err.Error() + "; " + c.Fix
+		}
+	}
+	want := map[string]string{
+		"CA bundle":           "no PEM certificates; run: shhh setup base",
+		"PIP_CERT":            `is C:\old\bundle.pem, not the CA bundle`,
+		"NODE_EXTRA_CA_CERTS": "not set; run: shhh setup node",
+		"git http.sslCAInfo":  "not set; run: shhh setup base",
+	}
+	if len(failed) != len(want) {
+		t.Errorf("failed = %v, want %d failures", failed, len(want))
+	}
+	for name, substr := range want {
+		if !strings.Contains(failed[name], substr) {
+			t.Errorf("%s: %q, want it to contain %q", name, failed[name], substr)
+		}
+	}
+}
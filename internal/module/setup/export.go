@@ -0,0 +1,113 @@
+package setup
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/druarnfield/shhh/internal/platform"
+	"github.com/druarnfield/shhh/internal/state"
+)
+
+// ExportedVar is one managed variable as ExportEnv found it.
+type ExportedVar struct {
+	Key   string
+	Value string
+
+	// Redacted is true when Value had a secret masked.
+	Redacted bool
+}
+
+// redactedValue replaces the value of a variable whose name marks it as
+// secret.
+const redactedValue = "REDACTED"
+
+// secretKeyParts mark a variable name as holding a secret.
+var secretKeyParts = []string{"TOKEN", "SECRET", "PASSWORD", "PASSWD", "API_KEY", "CREDENTIAL", "AUTH"}
+
+// ExportEnv returns the persistent value of each variable st manages, in
+// order, for 'shhh env export': the one in the scope shhh wrote it to (the
+// user environment, or the system one; see state.SystemEnv). Variables that
+// aren't set there are left out. Where the persistent environment can't be
+// read (e.g. on macOS), the process environment is used instead. With
+// redact, a variable whose name looks secret (e.g. GITHUB_TOKEN) has its
+// value replaced, and a password in a URL value (e.g. a proxy's
+// user:password@) is masked.
+func ExportEnv(env platform.UserEnv, st *state.State, redact bool) []ExportedVar {
+	var vars []ExportedVar
+	for _, key := range st.ManagedEnvVars {
+		scope := platform.SourceUser
+		if st.SystemEnv(key) {
+			scope = platform.SourceSystem
+		}
+		value, src, err := env.Get(key)
+		switch {
+		case errors.Is(err, platform.ErrNotSupported):
+			value = os.Getenv(key)
+		case err != nil || src != scope:
+			value = ""
+		}
+		if value == "" {
+			continue
+		}
+		v := ExportedVar{Key: key, Value: value}
+		if redact {
+			v.Value, v.Redacted = redactEnv(key, value)
+		}
+		vars = append(vars, v)
+	}
+	return vars
+}
+
+// redactEnv masks value if key looks secret or value is a URL with a
+// password, reporting whether it did.
+func redactEnv(key, value string) (string, bool) {
+	upper := strings.ToUpper(key)
+	for _, part := range secretKeyParts {
+		if strings.Contains(upper, part) {
+			return redactedValue, true
+		}
+	}
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			return u.Redacted(), true
+		}
+	}
+	return value, false
+}
+
+// FormatDotenv renders vars as a .env file: KEY='value' lines, single-quoted
+// so Windows paths keep their backslashes. A value containing a single
+// quote is double-quoted with \ and " escaped instead.
+func FormatDotenv(vars []ExportedVar) string {
+	var b strings.Builder
+	b.WriteString("# Environment managed by shhh\n")
+	for _, v := range vars {
+		if !strings.Contains(v.Value, "'") {
+			fmt.Fprintf(&b, "%s='%s'\n", v.Key, v.Value)
+			continue
+		}
+		escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v.Value)
+		fmt.Fprintf(&b, "%s=\"%s\"\n", v.Key, escaped)
+	}
+	return b.String()
+}
+
+// FormatPS1 renders vars as a PowerShell script that sets each in the
+// current session, for dot-sourcing.
+func FormatPS1(vars []ExportedVar) string {
+	var b strings.Builder
+	b.WriteString("# Environment managed by shhh; dot-source to apply: . .\\shhh-env.ps1\n")
+	for _, v := range vars {
+		fmt.Fprintf(&b, "$env:%s = %s\n", v.Key, psQuote(v.Value))
+	}
+	return b.String()
+}
+
+// psQuote quotes s as a PowerShell single-quoted string, in which nothing
+// is expanded and a quote is written twice.
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,192 @@
+package setup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	shexec "github.com/druarnfield/shhh/internal/exec"
+	"github.com/druarnfield/shhh/internal/module"
+	"github.com/druarnfield/shhh/internal/platform"
+)
+
+// Fingerprint hashes what decides whether running mods would change
+// anything, so a re-run can tell without running every step's Check
+// (several of which shell out) that nothing has changed since the last
+// successful run. It covers:
+//
+//   - the effective config, flags such as --install-root included
+//   - each module's steps, so a shhh release that adds a step doesn't match
+//   - the value and scope of every variable shhh manages
+//   - whether each PATH entry shhh manages is on PATH and still exists
+//   - the installed version of each Scoop package shhh installed, read from
+//     its manifest rather than by running scoop
+//   - whether each file shhh wrote still holds what it wrote
+//   - the PowerShell profile's managed block
+//   - the system root certificates the CA bundle is built from
+//   - what each module's checks read beyond that (see fingerprintInputs):
+//     the output of its version and toolchain queries, such as "go
+//     version" or "rustup toolchain list", and the content of the configs
+//     it shares with the user, such as .npmrc
+//
+// A part the platform can't read (the profile and certificates off
+// Windows) counts as empty. A change outside all of these, such as a
+// setting in a tool config shhh doesn't read, isn't noticed.
+func Fingerprint(ctx context.Context, deps *Dependencies, mods []*module.Module) (string, error) {
+	h := sha256.New()
+	line := func(fields ...any) {
+		for i, f := range fields {
+			if i > 0 {
+				h.Write([]byte{'\t'})
+			}
+			fmt.Fprint(h, f)
+		}
+		h.Write([]byte{'\n'})
+	}
+
+	cfg := *deps.Config
+	cfg.Warnings = nil
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return "", fmt.Errorf("encoding config: %w", err)
+	}
+	line("config", string(data))
+
+	for _, m := range mods {
+		for _, step := range m.Steps {
+			line("step", module.StepKey(m.ID, step.Name))
+		}
+	}
+
+	st := deps.State
+	for _, key := range slices.Sorted(slices.Values(st.ManagedEnvVars)) {
+		if value, source, err := deps.Env.Get(key); err == nil {
+			line("env", key, value, source)
+		} else {
+			line("env", key, "unset")
+		}
+	}
+
+	entries, err := deps.Env.ListPath()
+	if err != nil && !errors.Is(err, platform.ErrNotSupported) {
+		return "", fmt.Errorf("reading PATH: %w", err)
+	}
+	for _, dir := range slices.Sorted(slices.Values(st.ManagedPathEntries)) {
+		i := slices.IndexFunc(entries, func(e platform.PathEntry) bool { return e.Dir == dir })
+		if i < 0 {
+			line("path", dir, "absent")
+		} else {
+			line("path", dir, entries[i].Source, entries[i].Exists)
+		}
+	}
+
+	for _, pkg := range slices.Sorted(slices.Values(st.ScoopPackages)) {
+		line("scoop", pkg, scoopInstalledVersion(deps, pkg))
+	}
+
+	for _, f := range st.ManagedFiles {
+		status, err := f.Status()
+		if err != nil {
+			return "", fmt.Errorf("reading %s: %w", f.Path, err)
+		}
+		line("file", f.Path, status)
+	}
+
+	block, err := deps.Profile.ManagedBlock()
+	if err != nil && !errors.Is(err, platform.ErrNotSupported) {
+		return "", fmt.Errorf("reading profile: %w", err)
+	}
+	line("profile", block)
+
+	if err := hashRoots(h, deps.CertStore); err != nil {
+		return "", err
+	}
+
+	for _, m := range mods {
+		commands, files := fingerprintInputs(deps, m.ID)
+		for _, argv := range commands {
+			// A failed query is part of the state too: the tool is missing.
+			out, err := shexec.RunOutput(ctx, deps.Exec, argv[0], argv[1:]...)
+			if err != nil {
+				out = "failed"
+			}
+			line("command", strings.Join(argv, " "), out)
+		}
+		for _, path := range files {
+			data, err := os.ReadFile(path)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return "", fmt.Errorf("reading %s: %w", path, err)
+			}
+			line("config", path, fmt.Sprintf("%x", sha256.Sum256(data)))
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fingerprintInputs returns the commands (each as name and arguments) and
+// the files the checks of module id read that state doesn't record: tool
+// versions and toolchains, whether installed by shhh or already there, and
+// configs shared with the user.
+func fingerprintInputs(deps *Dependencies, id string) (commands [][]string, files []string) {
+	switch id {
+	case "base":
+		if deps.hasScoop() {
+			commands = append(commands, []string{"scoop", "list"}, []string{"scoop", "bucket", "list"})
+		}
+		commands = append(commands, []string{"git", "--version"})
+		files = []string{globalGitConfigPath(), gitConfigFile(deps)}
+	case "golang":
+		commands = [][]string{{"go", "version"}, {"go", "env", "GOPROXY"}}
+	case "node":
+		commands = [][]string{{"fnm", "list"}}
+		files = []string{NPMRCPath()}
+	case "python":
+		commands = [][]string{{"uv", "--version"}, {"uv", "python", "list", "--only-installed"}}
+	case "rust":
+		commands = [][]string{{"rustup", "default"}, {"rustup", "toolchain", "list"}}
+		files = []string{filepath.Join(cargoHome(), "config.toml")}
+	case "docker":
+		files = []string{filepath.Join(dockerConfigDir(), "config.json")}
+	}
+	return commands, files
+}
+
+// hashRoots adds the SHA-256 of each system root certificate to h.
+func hashRoots(h hash.Hash, store platform.CertStore) error {
+	certs, err := store.SystemRoots()
+	if errors.Is(err, platform.ErrNotSupported) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading system certificates: %w", err)
+	}
+	for _, c := range certs {
+		sum := sha256.Sum256(c.Raw)
+		fmt.Fprintf(h, "cert\t%x\n", sum)
+	}
+	return nil
+}
+
+// scoopInstalledVersion returns the version of the Scoop app pkg that is
+// installed, from its current manifest, or "" if it isn't installed.
+func scoopInstalledVersion(deps *Dependencies, pkg string) string {
+	dir := os.Getenv("SCOOP")
+	if dir == "" {
+		dir = deps.Config.InstallPath("scoop")
+	}
+	manifest, err := readJSONObject(filepath.Join(dir, "apps", pkg, "current", "manifest.json"))
+	if err != nil {
+		return ""
+	}
+	version, _ := manifest["version"].(string)
+	return version
+}
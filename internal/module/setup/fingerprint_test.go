@@ -0,0 +1,152 @@
+package setup
+
+import (
+	"context"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/druarnfield/shhh/internal/exec"
+	"github.com/druarnfield/shhh/internal/module"
+	"github.com/druarnfield/shhh/internal/platform"
+	"github.com/druarnfield/shhh/internal/platform/mock"
+)
+
+func TestFingerprint(t *testing.T) {
+	scoop := t.TempDir()
+	t.Setenv("SCOOP", scoop)
+	writeManifest := func(pkg, version string) {
+		dir := filepath.Join(scoop, "apps", pkg, "current")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		data := []byte(`{"version": "` + version + `"}`)
+		if err := os.WriteFile(filepath.Join(dir, "manifest.json"), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeManifest("git", "2.45.1")
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	gitconfig := filepath.Join(home, ".gitconfig")
+
+	deps := testDeps()
+	deps.Env.Set("HTTP_PROXY", "http://proxy:8080")
+	deps.Env.AppendPath(`C:\Users\me\go\bin`)
+	deps.State.AddEnvVar("", "HTTP_PROXY")
+	deps.State.AddPathEntry("", `C:\Users\me\go\bin`)
+	deps.State.AddScoopPackage("", "git")
+	mods := []*module.Module{{ID: "base", Steps: []module.Step{{Name: "Install git"}}}}
+
+	fingerprint := func() string {
+		t.Helper()
+		fp, err := Fingerprint(context.Background(), deps, mods)
+		if err != nil {
+			t.Fatalf("Fingerprint: %v", err)
+		}
+		return fp
+	}
+	runner := deps.Exec.(*exec.MockRunner)
+	runner.Results["scoop list"] = exec.Result{Stdout: "git 2.45.1\n"}
+	https := deps.Config.Proxy.HTTPS
+	want := fingerprint()
+	if got := fingerprint(); got != want {
+		t.Fatalf("fingerprint changed with nothing else changing: %s then %s", want, got)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		change func()
+		undo   func()
+	}{
+		{
+			name:   "config",
+			change: func() { deps.Config.Proxy.HTTPS = "http://other:8080" },
+			undo:   func() { deps.Config.Proxy.HTTPS = https },
+		},
+		{
+			name:   "managed variable",
+			change: func() { deps.Env.Set("HTTP_PROXY", "http://other:8080") },
+			undo:   func() { deps.Env.Set("HTTP_PROXY", "http://proxy:8080") },
+		},
+		{
+			name:   "PATH entry removed",
+			change: func() { deps.Env.RemovePath(`C:\Users\me\go\bin`) },
+			undo:   func() { deps.Env.AppendPath(`C:\Users\me\go\bin`) },
+		},
+		{
+			name:   "PATH entry's directory gone",
+			change: func() { deps.Env.(*mock.UserEnv).Missing = []string{`C:\Users\me\go\bin`} },
+			undo:   func() { deps.Env.(*mock.UserEnv).Missing = nil },
+		},
+		{
+			name:   "Scoop package updated",
+			change: func() { writeManifest("git", "2.46.0") },
+			undo:   func() { writeManifest("git", "2.45.1") },
+		},
+		{
+			name:   "tool installed outside shhh",
+			change: func() { runner.Results["scoop list"] = exec.Result{Stdout: "git 2.45.1\njq 1.7\n"} },
+			undo:   func() { runner.Results["scoop list"] = exec.Result{Stdout: "git 2.45.1\n"} },
+		},
+		{
+			name:   "user config",
+			change: func() { os.WriteFile(gitconfig, []byte("[http]\n\tproxy = http://other:8080\n"), 0o644) },
+			undo:   func() { os.Remove(gitconfig) },
+		},
+		{
+			name:   "profile",
+			change: func() { deps.Profile.SetManagedBlock("$env:FOO = 'bar'") },
+			undo:   func() { deps.Profile.SetManagedBlock("") },
+		},
+		{
+			name:   "new step",
+			change: func() { mods[0].Steps = append(mods[0].Steps, module.Step{Name: "Hold git"}) },
+			undo:   func() { mods[0].Steps = mods[0].Steps[:1] },
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.change()
+			if got := fingerprint(); got == want {
+				t.Error("fingerprint should change")
+			}
+			tc.undo()
+			if got := fingerprint(); got != want {
+				t.Error("fingerprint should match again once undone")
+			}
+		})
+	}
+}
+
+// unsupportedProfile and unsupportedCerts stand in for the profile and
+// certificate store off Windows.
+type unsupportedProfile struct{ platform.ProfileManager }
+
+func (unsupportedProfile) ManagedBlock() (string, error) { return "", platform.ErrNotSupported }
+
+type unsupportedCerts struct{}
+
+func (unsupportedCerts) SystemRoots() ([]*x509.Certificate, error) {
+	return nil, platform.ErrNotSupported
+}
+
+func TestFingerprint_UnsupportedPartsCountAsEmpty(t *testing.T) {
+	deps := testDeps()
+	deps.OS = "linux"
+	deps.Profile = unsupportedProfile{deps.Profile}
+	deps.CertStore = unsupportedCerts{}
+	mods := []*module.Module{{ID: "golang", Steps: []module.Step{{Name: "Install Go"}}}}
+	runner := deps.Exec.(*exec.MockRunner)
+	runner.Results["go version"] = exec.Result{Stdout: "go version go1.22.4 linux/amd64\n"}
+
+	want, err := Fingerprint(context.Background(), deps, mods)
+	if err != nil {
+		t.Fatalf("Fingerprint: %v", err)
+	}
+	runner.Results["go version"] = exec.Result{Stdout: "go version go1.23.0 linux/amd64\n"}
+	if got, _ := Fingerprint(context.Background(), deps, mods); got == want {
+		t.Error("fingerprint should change with the Go version")
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/druarnfield/shhh/internal/config"
+	shexec "github.com/druarnfield/shhh/internal/exec"
 	"github.com/druarnfield/shhh/internal/module"
 )
 
@@ -20,6 +22,9 @@ func NewGolangModule(deps *Dependencies) *module.Module {
 	if deps.Config.Registries.GoProxy != "" {
 		steps = append(steps, configureGOPROXYStep(deps))
 	}
+	if err := deps.Config.CheckModule("golang"); err != nil {
+		steps = []module.Step{configErrorStep(err)}
+	}
 
 	return &module.Module{
 		ID:           "golang",
@@ -27,6 +32,7 @@ func NewGolangModule(deps *Dependencies) *module.Module {
 		Description:  "Install Go and configure GOPATH, GOBIN, and GOPROXY",
 		Category:     module.CategoryLanguage,
 		Dependencies: []string{"base"},
+		SupportedOS:  scoopOS,
 		Steps:        steps,
 	}
 }
@@ -35,21 +41,23 @@ func installGoStep(deps *Dependencies) module.Step {
 	version := deps.Config.Golang.Version
 
 	return module.Step{
-		Name:        "Install Go",
-		Description: fmt.Sprintf("Install Go %s via Scoop", version),
-		Explain:     "Go is the programming language used for many internal tools and services.",
+		Name:         "Install Go",
+		Description:  fmt.Sprintf("Install Go %s via Scoop", version),
+		Explain:      "Go is the programming language used for many internal tools and services.",
+		Retries:      installRetries,
+		RetryBackoff: installBackoff,
 		Check: func(ctx context.Context) bool {
-			result, err := deps.Exec.Run(ctx, "go", "version")
+			out, err := shexec.RunOutput(ctx, deps.Exec, "go", "version")
 			if err != nil {
 				return false
 			}
-			return strings.Contains(result.Stdout, version)
+			return strings.Contains(out, version)
 		},
 		Run: func(ctx context.Context) error {
-			if _, err := deps.Exec.Run(ctx, "scoop", "install", "go"); err != nil {
+			if err := scoopInstall(ctx, deps, "go"); err != nil {
 				return fmt.Errorf("installing go: %w", err)
 			}
-			deps.State.AddScoopPackage("go")
+			deps.State.AddScoopPackage("golang", "go")
 			return nil
 		},
 		DryRun: func(_ context.Context) string {
@@ -58,27 +66,28 @@ func installGoStep(deps *Dependencies) module.Step {
 	}
 }
 
+// goPath returns the GOPATH shhh sets: ~/go, or go under [paths] root.
+func goPath(cfg *config.Config) string {
+	return cfg.InstallPath("go")
+}
+
 func setGOPATHStep(deps *Dependencies) module.Step {
-	home, _ := os.UserHomeDir()
-	gopath := filepath.Join(home, "go")
+	gopath := goPath(deps.Config)
 
 	return module.Step{
 		Name:        "Set GOPATH",
-		Description: "Set GOPATH to ~/go",
+		Description: fmt.Sprintf("Set GOPATH to %s", gopath),
+		Env:         envOf(gopath, "GOPATH"),
 		Explain:     "GOPATH tells Go where to store downloaded modules and build artifacts.",
 		Check: func(_ context.Context) bool {
-			val, _, err := deps.Env.Get("GOPATH")
-			if err != nil || val != gopath {
-				return false
-			}
-			return os.Getenv("GOPATH") == gopath
+			return deps.envMatches("GOPATH", gopath)
 		},
 		Run: func(_ context.Context) error {
 			if err := deps.Env.Set("GOPATH", gopath); err != nil {
 				return fmt.Errorf("setting GOPATH: %w", err)
 			}
 			os.Setenv("GOPATH", gopath)
-			deps.State.AddEnvVar("GOPATH")
+			deps.State.AddEnvVar("golang", "GOPATH")
 			return nil
 		},
 		DryRun: func(_ context.Context) string {
@@ -88,12 +97,12 @@ func setGOPATHStep(deps *Dependencies) module.Step {
 }
 
 func addGOBINStep(deps *Dependencies) module.Step {
-	home, _ := os.UserHomeDir()
-	gobin := filepath.Join(home, "go", "bin")
+	gobin := filepath.Join(goPath(deps.Config), "bin")
 
 	return module.Step{
 		Name:        "Add GOBIN to PATH",
-		Description: "Add ~/go/bin to PATH",
+		Description: fmt.Sprintf("Add %s to PATH", gobin),
+		Path:        []string{gobin},
 		Explain:     "Adding GOBIN to your PATH lets you run Go-installed tools directly from the command line.",
 		Check: func(_ context.Context) bool {
 			entries, err := deps.Env.ListPath()
@@ -108,10 +117,10 @@ func addGOBINStep(deps *Dependencies) module.Step {
 			return false
 		},
 		Run: func(_ context.Context) error {
-			if err := deps.Env.AppendPath(gobin); err != nil {
+			if err := appendPath(deps, gobin); err != nil {
 				return fmt.Errorf("appending GOBIN to PATH: %w", err)
 			}
-			deps.State.AddPathEntry(gobin)
+			deps.State.AddPathEntry("golang", gobin)
 			return nil
 		},
 		DryRun: func(_ context.Context) string {
@@ -128,18 +137,18 @@ func configureGOPROXYStep(deps *Dependencies) module.Step {
 		Description: fmt.Sprintf("Set GOPROXY to %s", goProxy),
 		Explain:     "GOPROXY tells Go where to download modules from. Corporate environments often use an internal proxy.",
 		Check: func(ctx context.Context) bool {
-			result, err := deps.Exec.Run(ctx, "go", "env", "GOPROXY")
+			got, err := shexec.RunOutput(ctx, deps.Exec, "go", "env", "GOPROXY")
 			if err != nil {
 				return false
 			}
-			return strings.TrimSpace(result.Stdout) == goProxy
+			return deps.outputMatches("go env GOPROXY", got, goProxy)
 		},
 		Run: func(ctx context.Context) error {
 			if _, err := deps.Exec.Run(ctx, "go", "env", "-w", "GOPROXY="+goProxy); err != nil {
 				return fmt.Errorf("setting GOPROXY: %w", err)
 			}
 			os.Setenv("GOPROXY", goProxy)
-			deps.State.AddEnvVar("GOPROXY")
+			deps.State.AddEnvVar("golang", "GOPROXY")
 			return nil
 		},
 		DryRun: func(_ context.Context) string {
@@ -2,11 +2,13 @@ package setup
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/druarnfield/shhh/internal/exec"
+	"github.com/druarnfield/shhh/internal/platform"
 	"github.com/druarnfield/shhh/internal/state"
 )
 
@@ -138,6 +140,21 @@ func TestSetGOPATHStep_Run(t *testing.T) {
 	t.Cleanup(func() { os.Unsetenv("GOPATH") })
 }
 
+func TestGolangModule_InstallRoot(t *testing.T) {
+	deps := testDeps()
+	root := t.TempDir()
+	deps.Config.Paths.Root = root
+
+	mod := NewGolangModule(deps)
+	gopath := mod.Step("set-gopath")
+	if want := filepath.Join(root, "go"); gopath.Env["GOPATH"] != want {
+		t.Errorf("GOPATH = %q, want %q", gopath.Env["GOPATH"], want)
+	}
+	if gobin := mod.Step("add-gobin-to-path"); gobin.Path[0] != filepath.Join(root, "go", "bin") {
+		t.Errorf("GOBIN = %q, want under %s", gobin.Path[0], root)
+	}
+}
+
 func TestAddGOBINStep_Check(t *testing.T) {
 	deps := testDeps()
 	ctx := context.Background()
@@ -185,6 +202,71 @@ func TestAddGOBINStep_Run(t *testing.T) {
 	}
 }
 
+func TestAddGOBINStep_ConfirmPath(t *testing.T) {
+	ctx := context.Background()
+	home, _ := os.UserHomeDir()
+	gobin := filepath.Join(home, "go", "bin")
+
+	inPath := func(deps *Dependencies) bool {
+		entries, _ := deps.Env.ListPath()
+		for _, e := range entries {
+			if e.Dir == gobin {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Declined: the step fails and PATH is left alone.
+	deps := testDeps()
+	deps.State = &state.State{}
+	deps.Config.Safety.ConfirmPathChanges = true
+	var asked string
+	deps.ConfirmPath = func(_ []platform.PathEntry, dir string) bool {
+		asked = dir
+		return false
+	}
+	err := addGOBINStep(deps).Run(ctx)
+	if !errors.Is(err, ErrPathDeclined) {
+		t.Fatalf("Run error = %v, want ErrPathDeclined", err)
+	}
+	if asked != gobin {
+		t.Errorf("asked about %q, want %q", asked, gobin)
+	}
+	if inPath(deps) {
+		t.Error("GOBIN added to PATH although declined")
+	}
+
+	// Nobody to ask: the same.
+	deps.ConfirmPath = nil
+	if err := addGOBINStep(deps).Run(ctx); !errors.Is(err, ErrPathDeclined) {
+		t.Fatalf("Run error without ConfirmPath = %v, want ErrPathDeclined", err)
+	}
+
+	// Confirmed: added.
+	deps.ConfirmPath = func([]platform.PathEntry, string) bool { return true }
+	if err := addGOBINStep(deps).Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !inPath(deps) {
+		t.Error("GOBIN not added to PATH after confirming")
+	}
+
+	// Option off: added without asking.
+	deps = testDeps()
+	deps.State = &state.State{}
+	deps.ConfirmPath = func([]platform.PathEntry, string) bool {
+		t.Error("asked with confirm_path_changes off")
+		return false
+	}
+	if err := addGOBINStep(deps).Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !inPath(deps) {
+		t.Error("GOBIN not added to PATH")
+	}
+}
+
 func TestConfigureGOPROXYStep_Check(t *testing.T) {
 	deps := testDeps()
 	mockExec := deps.Exec.(*exec.MockRunner)
@@ -3,10 +3,12 @@ package setup
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
 
-	"github.com/druarnfield/shhh/internal/config"
+	shexec "github.com/druarnfield/shhh/internal/exec"
 	"github.com/druarnfield/shhh/internal/module"
 )
 
@@ -16,11 +18,17 @@ func NewNodeModule(deps *Dependencies) *module.Module {
 
 	steps = append(steps, installFnmStep(deps))
 	steps = append(steps, configureFnmShellStep(deps))
+	if deps.Config.Paths.Root != "" {
+		steps = append(steps, installRootStep(deps, "node", "FNM_DIR", deps.Config.InstallPath("fnm"), "fnm's Node.js installs"))
+	}
 	steps = append(steps, installNodeStep(deps))
 	steps = append(steps, configureNodeCertsStep(deps))
 	if deps.Config.Registries.NPMRegistry != "" {
 		steps = append(steps, configureNPMRegistryStep(deps))
 	}
+	if err := deps.Config.CheckModule("node"); err != nil {
+		steps = []module.Step{configErrorStep(err)}
+	}
 
 	return &module.Module{
 		ID:           "node",
@@ -28,24 +36,27 @@ func NewNodeModule(deps *Dependencies) *module.Module {
 		Description:  "Install Node.js via fnm and configure npm registry",
 		Category:     module.CategoryLanguage,
 		Dependencies: []string{"base"},
+		SupportedOS:  scoopOS,
 		Steps:        steps,
 	}
 }
 
 func installFnmStep(deps *Dependencies) module.Step {
 	return module.Step{
-		Name:        "Install fnm",
-		Description: "Install fnm (Fast Node Manager) via Scoop",
-		Explain:     "fnm manages multiple Node.js versions, letting you switch between projects easily.",
+		Name:         "Install fnm",
+		Description:  "Install fnm (Fast Node Manager) via Scoop",
+		Explain:      "fnm manages multiple Node.js versions, letting you switch between projects easily.",
+		Retries:      installRetries,
+		RetryBackoff: installBackoff,
 		Check: func(ctx context.Context) bool {
 			_, err := deps.Exec.Run(ctx, "fnm", "--version")
 			return err == nil
 		},
 		Run: func(ctx context.Context) error {
-			if _, err := deps.Exec.Run(ctx, "scoop", "install", "fnm"); err != nil {
+			if err := scoopInstall(ctx, deps, "fnm"); err != nil {
 				return fmt.Errorf("installing fnm: %w", err)
 			}
-			deps.State.AddScoopPackage("fnm")
+			deps.State.AddScoopPackage("node", "fnm")
 			return nil
 		},
 		DryRun: func(_ context.Context) string {
@@ -68,8 +79,11 @@ func configureFnmShellStep(deps *Dependencies) module.Step {
 			}
 			return strings.Contains(block, "fnm env")
 		},
-		Run: func(_ context.Context) error {
-			if err := deps.Profile.AppendToManagedBlock(fnmInitLine); err != nil {
+		Run: func(ctx context.Context) error {
+			err := trackFile(ctx, deps.Profile.Path(), deps.Profile.Read, func() error {
+				return deps.Profile.AppendToManagedBlock(fnmInitLine)
+			})
+			if err != nil {
 				return fmt.Errorf("adding fnm init to profile: %w", err)
 			}
 			return nil
@@ -84,15 +98,17 @@ func installNodeStep(deps *Dependencies) module.Step {
 	version := deps.Config.Node.Version
 
 	return module.Step{
-		Name:        "Install Node.js",
-		Description: fmt.Sprintf("Install Node.js %s via fnm", version),
-		Explain:     "Node.js is the JavaScript runtime used for frontend tooling and many internal services.",
+		Name:         "Install Node.js",
+		Description:  fmt.Sprintf("Install Node.js %s via fnm", version),
+		Explain:      "Node.js is the JavaScript runtime used for frontend tooling and many internal services.",
+		Retries:      installRetries,
+		RetryBackoff: installBackoff,
 		Check: func(ctx context.Context) bool {
-			result, err := deps.Exec.Run(ctx, "fnm", "list")
+			out, err := shexec.RunOutput(ctx, deps.Exec, "fnm", "list")
 			if err != nil {
 				return false
 			}
-			return strings.Contains(result.Stdout, version)
+			return strings.Contains(out, version)
 		},
 		Run: func(ctx context.Context) error {
 			if _, err := deps.Exec.Run(ctx, "fnm", "install", version); err != nil {
@@ -110,44 +126,45 @@ func installNodeStep(deps *Dependencies) module.Step {
 }
 
 func configureNodeCertsStep(deps *Dependencies) module.Step {
-	caPath := config.CABundlePath()
+	caPath := deps.Config.CABundlePath()
 	version := deps.Config.Node.Version
 
 	return module.Step{
 		Name:        "Configure Node.js CA certificates",
 		Description: "Point Node.js and npm at the shhh CA bundle",
+		Env:         envOf(caPath, "NODE_EXTRA_CA_CERTS"),
 		Explain: "Node.js has its own built-in CA certificate list that doesn't include corporate proxy CAs. " +
 			"NODE_EXTRA_CA_CERTS tells Node to load additional certificates, and npm's cafile setting " +
 			"tells npm specifically where to find trusted CAs. Without these, npm install and any Node.js " +
 			"HTTPS request will fail with UNABLE_TO_VERIFY_LEAF_SIGNATURE behind corporate proxies.",
 		Check: func(ctx context.Context) bool {
-			val, _, err := deps.Env.Get("NODE_EXTRA_CA_CERTS")
-			if err != nil || val != caPath {
+			if !deps.envMatches("NODE_EXTRA_CA_CERTS", caPath) {
 				return false
 			}
-			if os.Getenv("NODE_EXTRA_CA_CERTS") != caPath {
-				return false
-			}
-			result, err := deps.Exec.Run(ctx, "fnm", "exec", "--using", version, "--", "npm", "config", "get", "cafile")
-			if err != nil {
-				return false
+			for _, v := range npmTargetVersions(ctx, deps) {
+				got, err := shexec.RunOutput(ctx, deps.Exec, "fnm", "exec", "--using", v, "--", "npm", "config", "get", "cafile")
+				if err != nil || !deps.outputMatches("npm cafile (node "+v+")", got, caPath) {
+					return false
+				}
 			}
-			return strings.TrimSpace(result.Stdout) == caPath
+			return true
 		},
 		Run: func(ctx context.Context) error {
 			if err := deps.Env.Set("NODE_EXTRA_CA_CERTS", caPath); err != nil {
 				return fmt.Errorf("setting NODE_EXTRA_CA_CERTS: %w", err)
 			}
 			os.Setenv("NODE_EXTRA_CA_CERTS", caPath)
-			deps.State.AddEnvVar("NODE_EXTRA_CA_CERTS")
+			deps.State.AddEnvVar("node", "NODE_EXTRA_CA_CERTS")
 
-			if _, err := deps.Exec.Run(ctx, "fnm", "exec", "--using", version, "--", "npm", "config", "set", "cafile", caPath); err != nil {
-				return fmt.Errorf("setting npm cafile: %w", err)
+			for _, v := range npmTargetVersions(ctx, deps) {
+				if err := setNPMConfig(ctx, deps, v, "cafile", caPath); err != nil {
+					return fmt.Errorf("setting npm cafile for node %s: %w", v, err)
+				}
 			}
 			return nil
 		},
 		DryRun: func(_ context.Context) string {
-			return fmt.Sprintf("Would set NODE_EXTRA_CA_CERTS=%s and npm config set cafile %s", caPath, caPath)
+			return fmt.Sprintf("Would set NODE_EXTRA_CA_CERTS=%s and npm config set cafile %s (node %s)", caPath, caPath, version)
 		},
 	}
 }
@@ -161,17 +178,25 @@ func configureNPMRegistryStep(deps *Dependencies) module.Step {
 		Description: fmt.Sprintf("Set npm registry to %s", registry),
 		Explain:     "Corporate environments often host an internal npm registry for approved packages.",
 		Check: func(ctx context.Context) bool {
-			result, err := deps.Exec.Run(ctx, "fnm", "exec", "--using", version, "--", "npm", "config", "get", "registry")
-			if err != nil {
-				return false
-			}
-			got := strings.TrimRight(strings.TrimSpace(result.Stdout), "/")
 			want := strings.TrimRight(registry, "/")
-			return got == want
+			for _, v := range npmTargetVersions(ctx, deps) {
+				out, err := shexec.RunOutput(ctx, deps.Exec, "fnm", "exec", "--using", v, "--", "npm", "config", "get", "registry")
+				if err != nil {
+					return false
+				}
+				got := strings.TrimRight(out, "/")
+				deps.traceCheck("npm registry (node "+v+")", want, got, got == want)
+				if got != want {
+					return false
+				}
+			}
+			return true
 		},
 		Run: func(ctx context.Context) error {
-			if _, err := deps.Exec.Run(ctx, "fnm", "exec", "--using", version, "--", "npm", "config", "set", "registry", registry); err != nil {
-				return fmt.Errorf("setting npm registry: %w", err)
+			for _, v := range npmTargetVersions(ctx, deps) {
+				if err := setNPMConfig(ctx, deps, v, "registry", registry); err != nil {
+					return fmt.Errorf("setting npm registry for node %s: %w", v, err)
+				}
 			}
 			return nil
 		},
@@ -180,3 +205,66 @@ func configureNPMRegistryStep(deps *Dependencies) module.Step {
 		},
 	}
 }
+
+// NPMRCPath returns the user config file 'npm config set' writes to.
+func NPMRCPath() string {
+	if path := os.Getenv("NPM_CONFIG_USERCONFIG"); path != "" {
+		return path
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".npmrc")
+}
+
+// setNPMConfig runs 'npm config set key value' with the npm of Node
+// version, tracking the change to the user .npmrc with trackManagedFile.
+func setNPMConfig(ctx context.Context, deps *Dependencies, version, key, value string) error {
+	return trackManagedFile(ctx, deps, "node", NPMRCPath(), func() error {
+		_, err := deps.Exec.Run(ctx, "fnm", "exec", "--using", version, "--", "npm", "config", "set", key, value)
+		return err
+	})
+}
+
+// npmTargetVersions returns the fnm Node versions whose npm should be
+// configured: the configured version, plus fnm's default when the two
+// differ, so the npm in the user's everyday shell is covered as well.
+func npmTargetVersions(ctx context.Context, deps *Dependencies) []string {
+	version := deps.Config.Node.Version
+	def := fnmDefaultVersion(ctx, deps)
+	if def == "" || nodeVersionMatches(def, version) {
+		return []string{version}
+	}
+	deps.log().Warn("fnm default Node.js version differs from configured version",
+		slog.String("default", def),
+		slog.String("configured", version),
+	)
+	return []string{version, def}
+}
+
+// fnmDefaultVersion returns the version fnm has marked as default (e.g.
+// "v20.11.0"), or "" if there is none or fnm can't be queried.
+func fnmDefaultVersion(ctx context.Context, deps *Dependencies) string {
+	out, err := shexec.RunOutput(ctx, deps.Exec, "fnm", "list")
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if !contains(fields, "default") {
+			continue
+		}
+		for _, f := range fields {
+			if strings.HasPrefix(f, "v") {
+				return f
+			}
+		}
+	}
+	return ""
+}
+
+// nodeVersionMatches reports whether an installed version such as "v22.11.0"
+// satisfies a configured version such as "22" or "22.11".
+func nodeVersionMatches(installed, configured string) bool {
+	v := strings.TrimPrefix(installed, "v")
+	c := strings.TrimPrefix(configured, "v")
+	return v == c || strings.HasPrefix(v, c+".")
+}
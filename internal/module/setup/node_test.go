@@ -2,11 +2,14 @@ package setup
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/druarnfield/shhh/internal/config"
 	"github.com/druarnfield/shhh/internal/exec"
+	"github.com/druarnfield/shhh/internal/module"
 	"github.com/druarnfield/shhh/internal/state"
 )
 
@@ -109,6 +112,22 @@ func TestConfigureFnmShellStep_Run(t *testing.T) {
 	}
 }
 
+func TestConfigureFnmShellStep_RecordsProfileChange(t *testing.T) {
+	deps := testDeps()
+	mod := &module.Module{ID: "node", Steps: []module.Step{configureFnmShellStep(deps)}}
+
+	result := module.NewRunner(deps.log(), false).RunModule(context.Background(), mod)
+	if result.Err != nil {
+		t.Fatalf("RunModule: %v", result.Err)
+	}
+	if len(result.Changes) != 1 || result.Changes[0].Path != deps.Profile.Path() {
+		t.Fatalf("Changes = %+v, want the profile", result.Changes)
+	}
+	if added, _ := result.Changes[0].DiffStat(); added == 0 {
+		t.Errorf("diff should add the fnm init line: %q", result.Changes[0].Diff())
+	}
+}
+
 func TestConfigureFnmShellStep_DryRun(t *testing.T) {
 	deps := testDeps()
 	ctx := context.Background()
@@ -237,6 +256,75 @@ func TestConfigureNodeCertsStep_DryRun(t *testing.T) {
 	}
 }
 
+func TestConfigureNodeCertsStep_DefaultMismatch(t *testing.T) {
+	deps := testDeps()
+	deps.State = &state.State{}
+	mockExec := deps.Exec.(*exec.MockRunner)
+	caPath := config.CABundlePath()
+	ctx := context.Background()
+	t.Cleanup(func() { os.Unsetenv("NODE_EXTRA_CA_CERTS") })
+
+	mockExec.Results["fnm list"] = exec.Result{Stdout: "* v20.11.0 default\n  v22.3.0\n"}
+	mockExec.Results["fnm exec --using 22 -- npm config set cafile "+caPath] = exec.Result{}
+	mockExec.Results["fnm exec --using v20.11.0 -- npm config set cafile "+caPath] = exec.Result{}
+
+	step := configureNodeCertsStep(deps)
+	if err := step.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !contains(mockExec.Calls, "fnm exec --using v20.11.0 -- npm config set cafile "+caPath) {
+		t.Errorf("default version's npm not configured; calls = %v", mockExec.Calls)
+	}
+
+	// Configured version done but default still unset: Check must fail.
+	mockExec.Results["fnm exec --using 22 -- npm config get cafile"] = exec.Result{Stdout: caPath + "\n"}
+	if step.Check(ctx) {
+		t.Error("Check should return false while default version's npm is unconfigured")
+	}
+	mockExec.Results["fnm exec --using v20.11.0 -- npm config get cafile"] = exec.Result{Stdout: caPath + "\n"}
+	if !step.Check(ctx) {
+		t.Error("Check should return true when both versions are configured")
+	}
+}
+
+func TestNpmTargetVersions(t *testing.T) {
+	deps := testDeps()
+	mockExec := deps.Exec.(*exec.MockRunner)
+	ctx := context.Background()
+
+	// fnm list fails: configured version only.
+	if got := npmTargetVersions(ctx, deps); len(got) != 1 || got[0] != "22" {
+		t.Errorf("targets = %v, want [22]", got)
+	}
+
+	// Default matches configured.
+	mockExec.Results["fnm list"] = exec.Result{Stdout: "* v22.3.0 default\n"}
+	if got := npmTargetVersions(ctx, deps); len(got) != 1 {
+		t.Errorf("targets = %v, want [22]", got)
+	}
+
+	// Default differs.
+	mockExec.Results["fnm list"] = exec.Result{Stdout: "* v20.11.0 default\n  v22.3.0\n"}
+	if got := npmTargetVersions(ctx, deps); len(got) != 2 || got[1] != "v20.11.0" {
+		t.Errorf("targets = %v, want [22 v20.11.0]", got)
+	}
+}
+
+func TestConfigureNPMRegistryStep_DefaultMismatch(t *testing.T) {
+	deps := testDeps()
+	mockExec := deps.Exec.(*exec.MockRunner)
+	ctx := context.Background()
+
+	mockExec.Results["fnm list"] = exec.Result{Stdout: "* v20.11.0 default\n  v22.3.0\n"}
+	mockExec.Results["fnm exec --using 22 -- npm config get registry"] = exec.Result{Stdout: "https://npm.example.com/\n"}
+	mockExec.Results["fnm exec --using v20.11.0 -- npm config get registry"] = exec.Result{Stdout: "https://registry.npmjs.org/\n"}
+
+	step := configureNPMRegistryStep(deps)
+	if step.Check(ctx) {
+		t.Error("Check should return false when default version uses a different registry")
+	}
+}
+
 func TestConfigureNPMRegistryStep_Check(t *testing.T) {
 	deps := testDeps()
 	mockExec := deps.Exec.(*exec.MockRunner)
@@ -273,6 +361,66 @@ func TestConfigureNPMRegistryStep_Run(t *testing.T) {
 	}
 }
 
+// fakeNPMConfigSet answers 'npm config set' by appending "key=value" to
+// the user .npmrc, as npm does.
+func fakeNPMConfigSet(t *testing.T) func(name string, args []string) (exec.Result, bool) {
+	return func(name string, args []string) (exec.Result, bool) {
+		if len(args) < 3 || args[len(args)-3] != "set" {
+			return exec.Result{}, false
+		}
+		f, err := os.OpenFile(NPMRCPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		fmt.Fprintf(f, "%s=%s\n", args[len(args)-2], args[len(args)-1])
+		return exec.Result{}, true
+	}
+}
+
+func TestSetNPMConfig_RecordsCreatedNpmrc(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".npmrc")
+	t.Setenv("NPM_CONFIG_USERCONFIG", path)
+	deps := testDeps()
+	deps.Exec.(*exec.MockRunner).Match = fakeNPMConfigSet(t)
+	ctx := context.Background()
+
+	if err := setNPMConfig(ctx, deps, "22", "cafile", "/ca.pem"); err != nil {
+		t.Fatalf("setNPMConfig: %v", err)
+	}
+	if err := setNPMConfig(ctx, deps, "22", "registry", "https://npm.example.com/"); err != nil {
+		t.Fatalf("setNPMConfig: %v", err)
+	}
+
+	f, ok := deps.State.LookupManagedFile(path)
+	if !ok {
+		t.Fatal(".npmrc shhh created should be a managed file")
+	}
+	if status, err := f.Status(); err != nil || status != state.FileUnchanged {
+		t.Errorf("Status = %v, %v; want FileUnchanged after the second write", status, err)
+	}
+	if got := deps.State.Owners["node"].Files; len(got) != 1 || got[0] != path {
+		t.Errorf("node owns %v, want [%s]", got, path)
+	}
+}
+
+func TestSetNPMConfig_LeavesUsersNpmrcUnmanaged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".npmrc")
+	t.Setenv("NPM_CONFIG_USERCONFIG", path)
+	if err := os.WriteFile(path, []byte("save-exact=true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	deps := testDeps()
+	deps.Exec.(*exec.MockRunner).Match = fakeNPMConfigSet(t)
+
+	if err := setNPMConfig(context.Background(), deps, "22", "cafile", "/ca.pem"); err != nil {
+		t.Fatalf("setNPMConfig: %v", err)
+	}
+	if _, ok := deps.State.LookupManagedFile(path); ok {
+		t.Error("a .npmrc holding the user's own settings should not be a managed file")
+	}
+}
+
 func TestConfigureNPMRegistryStep_DryRun(t *testing.T) {
 	deps := testDeps()
 	ctx := context.Background()
@@ -0,0 +1,137 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/druarnfield/shhh/internal/platform"
+)
+
+// MinFreeSpace is the free space Preflight wants on the drive tooling
+// installs to: room for Go, Python, Node.js and the usual tools, plus
+// their downloads while they unpack.
+const MinFreeSpace = 5 << 30
+
+// PreflightCheck is the outcome of one pre-flight check. Err is nil when
+// the check passed; Detail says what was found either way. Fix, when set,
+// is a one-line hint for repairing a failure. Doctor's CA checks share
+// this shape.
+type PreflightCheck struct {
+	Name   string
+	Detail string
+	Err    error
+	Fix    string
+}
+
+// Preflight checks what setup needs before it downloads anything, so a run
+// doesn't fail halfway: free space on the drive tooling installs to (the
+// [paths] root, or the home directory), that a [paths] root is a directory
+// or can be created, and, on Windows, a working PowerShell, which runs the
+// Scoop and uv installers. goos is the GOOS
+// value setup runs on; elsewhere the modules that need PowerShell are
+// skipped, so it isn't checked for. The returned error joins the failed
+// checks.
+func Preflight(ctx context.Context, deps *Dependencies, goos string) ([]PreflightCheck, error) {
+	checks := []PreflightCheck{freeSpaceCheck(deps)}
+	if goos == "windows" {
+		checks = append(checks, powershellCheck(ctx, deps))
+	}
+	if deps.Config.Paths.Root != "" {
+		checks = append(checks, installRootCheck(deps.Config.Paths.Root))
+	}
+	var errs []error
+	for _, c := range checks {
+		if c.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.Name, c.Err))
+		}
+	}
+	return checks, errors.Join(errs...)
+}
+
+func freeSpaceCheck(deps *Dependencies) PreflightCheck {
+	check := PreflightCheck{Name: "Free space"}
+	dir := deps.Config.InstallPath()
+	freeSpace := deps.FreeSpace
+	if freeSpace == nil {
+		freeSpace = platform.FreeSpace
+	}
+
+	free, err := freeSpace(dir)
+	switch {
+	case err != nil:
+		check.Detail = fmt.Sprintf("can't check %s", dir)
+		check.Err = err
+	default:
+		check.Detail = fmt.Sprintf("%s free for %s", formatBytes(free), dir)
+		if free < MinFreeSpace {
+			check.Err = fmt.Errorf("%s free for %s, at least %s needed (set [paths] root or --install-root to use another drive)",
+				formatBytes(free), dir, formatBytes(MinFreeSpace))
+		}
+	}
+	return check
+}
+
+// installRootCheck checks root is a directory, or that its nearest
+// existing parent is one a directory can be created in.
+func installRootCheck(root string) PreflightCheck {
+	check := PreflightCheck{Name: "Install root", Fix: "set [paths] root or --install-root to a directory you can write to"}
+	dir := root
+	for {
+		info, err := os.Stat(dir)
+		switch {
+		case err == nil && !info.IsDir():
+			check.Detail = fmt.Sprintf("%s is not a directory", dir)
+			check.Err = fmt.Errorf("can't create %s: %s is not a directory", root, dir)
+			return check
+		case err == nil && dir == root:
+			check.Detail = root + " exists"
+			return check
+		case err == nil:
+			probe, err := os.MkdirTemp(dir, ".shhh-")
+			if err != nil {
+				check.Detail = fmt.Sprintf("can't create %s", root)
+				check.Err = fmt.Errorf("can't create %s: %w", root, err)
+				return check
+			}
+			os.Remove(probe)
+			check.Detail = root + " can be created"
+			return check
+		case errors.Is(err, fs.ErrPermission):
+			check.Detail = fmt.Sprintf("can't check %s", root)
+			check.Err = err
+			return check
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			check.Detail = fmt.Sprintf("can't create %s", root)
+			check.Err = fmt.Errorf("can't create %s: no part of it exists", root)
+			return check
+		}
+		dir = parent
+	}
+}
+
+func powershellCheck(ctx context.Context, deps *Dependencies) PreflightCheck {
+	check := PreflightCheck{Name: "PowerShell"}
+	result, err := deps.Exec.Run(ctx, "powershell", "-NoProfile", "-Command", "$PSVersionTable.PSVersion.ToString()")
+	if err != nil {
+		check.Detail = "not available"
+		check.Err = fmt.Errorf("needed to run the Scoop and uv installers: %w", err)
+		return check
+	}
+	check.Detail = "version " + strings.TrimSpace(result.Stdout)
+	return check
+}
+
+// formatBytes renders n in GiB, or MiB below 1 GiB, with one decimal.
+func formatBytes(n uint64) string {
+	if n < 1<<30 {
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	}
+	return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+}
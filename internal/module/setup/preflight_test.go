@@ -0,0 +1,106 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/druarnfield/shhh/internal/exec"
+)
+
+const psVersionCmd = "powershell -NoProfile -Command $PSVersionTable.PSVersion.ToString()"
+
+func TestPreflight_Passes(t *testing.T) {
+	deps := testDeps()
+	deps.Config.Paths.Root = filepath.Join(t.TempDir(), "dev")
+	deps.Exec.(*exec.MockRunner).Results[psVersionCmd] = exec.Result{Stdout: "5.1.19041.4291\r\n"}
+	var asked string
+	deps.FreeSpace = func(path string) (uint64, error) {
+		asked = path
+		return 40 << 30, nil
+	}
+
+	checks, err := Preflight(context.Background(), deps, "windows")
+	if err != nil {
+		t.Fatalf("Preflight error = %v", err)
+	}
+	if asked != deps.Config.InstallPath() {
+		t.Errorf("free space checked for %q, want the install root", asked)
+	}
+	if checks[0].Detail != `40.0 GiB free for `+deps.Config.InstallPath() {
+		t.Errorf("free space detail = %q", checks[0].Detail)
+	}
+	if checks[1].Detail != "version 5.1.19041.4291" {
+		t.Errorf("PowerShell detail = %q", checks[1].Detail)
+	}
+}
+
+func TestPreflight_InstallRoot(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	os.WriteFile(file, nil, 0o644)
+
+	for _, tc := range []struct {
+		root, detail string
+		ok           bool
+	}{
+		{dir, dir + " exists", true},
+		{filepath.Join(dir, "dev", "tools"), filepath.Join(dir, "dev", "tools") + " can be created", true},
+		{filepath.Join(file, "dev"), file + " is not a directory", false},
+	} {
+		deps := testDeps()
+		deps.Config.Paths.Root = tc.root
+		deps.FreeSpace = func(string) (uint64, error) { return 40 << 30, nil }
+
+		checks, err := Preflight(context.Background(), deps, "linux")
+		if (err == nil) != tc.ok {
+			t.Errorf("%s: Preflight error = %v, want ok = %v", tc.root, err, tc.ok)
+		}
+		root := checks[len(checks)-1]
+		if root.Name != "Install root" || root.Detail != tc.detail {
+			t.Errorf("%s: last check = %+v, want the install root, %q", tc.root, root, tc.detail)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "dev")); !os.IsNotExist(err) {
+		t.Error("the check left a directory behind")
+	}
+}
+
+func TestPreflight_ReportsEveryFailure(t *testing.T) {
+	deps := testDeps()
+	deps.Exec.(*exec.MockRunner).Missing = []string{"powershell"}
+	deps.FreeSpace = func(string) (uint64, error) { return 512 << 20, nil }
+
+	checks, err := Preflight(context.Background(), deps, "windows")
+	if err == nil {
+		t.Fatal("expected pre-flight to fail")
+	}
+	for _, want := range []string{"Free space: 512.0 MiB free", "at least 5.0 GiB", "PowerShell:"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %q:\n%v", want, err)
+		}
+	}
+	if !errors.Is(checks[1].Err, exec.ErrCommandNotFound) {
+		t.Errorf("PowerShell check error = %v, want ErrCommandNotFound", checks[1].Err)
+	}
+}
+
+func TestPreflight_SkipsPowerShellOffWindows(t *testing.T) {
+	deps := testDeps()
+	deps.FreeSpace = func(string) (uint64, error) { return 40 << 30, nil }
+
+	// The mock runner fails any command it wasn't told about, so asking
+	// for PowerShell here would fail the check.
+	checks, err := Preflight(context.Background(), deps, "linux")
+	if err != nil {
+		t.Fatalf("Preflight error = %v", err)
+	}
+	for _, c := range checks {
+		if c.Name == "PowerShell" {
+			t.Errorf("PowerShell checked on linux: %+v", c)
+		}
+	}
+}
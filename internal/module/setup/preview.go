@@ -0,0 +1,71 @@
+package setup
+
+import (
+	"errors"
+	"maps"
+	"sort"
+
+	"github.com/druarnfield/shhh/internal/module"
+	"github.com/druarnfield/shhh/internal/platform"
+)
+
+// envOf maps each of keys to value, for Step.Env.
+func envOf(value string, keys ...string) map[string]string {
+	env := make(map[string]string, len(keys))
+	for _, key := range keys {
+		env[key] = value
+	}
+	return env
+}
+
+// PreviewEnv previews the user environment changes running the steps in
+// plan would make, from their Env and Path. Checks aren't consulted, so the
+// plan can be built with SkipChecks: every step counts, since even one
+// whose check passes now can follow an earlier step (a reset, say) that
+// undoes what it found in place. Variables are compared
+// with their user-level value, like AuditEnv, and when several steps set
+// one (a reset followed by a set, say) the last wins. It returns the
+// variables that would change, sorted by name, and the PATH directories
+// not yet on PATH.
+func PreviewEnv(env platform.UserEnv, plan *module.Plan) ([]EnvDrift, []string, error) {
+	want := make(map[string]string)
+	var dirs []string
+	for _, sp := range plan.Steps() {
+		maps.Copy(want, sp.Step.Env)
+		dirs = append(dirs, sp.Step.Path...)
+	}
+
+	var changes []EnvDrift
+	for key, value := range want {
+		current, src, err := env.Get(key)
+		if errors.Is(err, platform.ErrNotSupported) {
+			return nil, nil, err
+		}
+		if err != nil || src != platform.SourceUser {
+			current = ""
+		}
+		if current != value {
+			changes = append(changes, EnvDrift{Key: key, Current: current, Want: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+
+	if len(dirs) == 0 {
+		return changes, nil, nil
+	}
+	entries, err := env.ListPath()
+	if err != nil {
+		return nil, nil, err
+	}
+	var added []string
+	for _, dir := range dirs {
+		onPath := contains(added, dir)
+		for _, e := range entries {
+			onPath = onPath || e.Dir == dir
+		}
+		if !onPath {
+			added = append(added, dir)
+		}
+	}
+	return changes, added, nil
+}
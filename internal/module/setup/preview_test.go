@@ -0,0 +1,48 @@
+package setup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/druarnfield/shhh/internal/module"
+)
+
+func TestPreviewEnv(t *testing.T) {
+	deps := testDeps()
+	deps.Config.Env.Reset = []string{"GOPATH"}
+	deps.Env.Set("HTTP_PROXY", deps.Config.Proxy.HTTP) // up to date
+	deps.Env.Set("GOPATH", goPath(deps.Config))        // reset, then set back
+	mods := []*module.Module{NewBaseModule(deps), NewGolangModule(deps)}
+	plan := module.PlanModules(context.Background(), mods, module.PlanOptions{SkipChecks: true, SkipDryRun: true})
+
+	changes, dirs, err := PreviewEnv(deps.Env, plan)
+	if err != nil {
+		t.Fatalf("PreviewEnv: %v", err)
+	}
+
+	planned := make(map[string]EnvDrift)
+	for _, c := range changes {
+		planned[c.Key] = c
+	}
+	for _, key := range []string{"HTTP_PROXY", "GOPATH"} {
+		if c, ok := planned[key]; ok {
+			t.Errorf("%s should not change, got %+v", key, c)
+		}
+	}
+	if c := planned["HTTPS_PROXY"]; c.Current != "" || c.Want != deps.Config.Proxy.HTTPS {
+		t.Errorf("HTTPS_PROXY = %+v, want it set from unset", c)
+	}
+
+	home, _ := os.UserHomeDir()
+	gobin := filepath.Join(home, "go", "bin")
+	if len(dirs) != 1 || dirs[0] != gobin {
+		t.Errorf("dirs = %v, want [%s]", dirs, gobin)
+	}
+
+	deps.Env.AppendPath(gobin)
+	if _, dirs, _ := PreviewEnv(deps.Env, plan); len(dirs) != 0 {
+		t.Errorf("dirs = %v, want none once GOBIN is on PATH", dirs)
+	}
+}
@@ -4,9 +4,10 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
-	"github.com/druarnfield/shhh/internal/config"
+	shexec "github.com/druarnfield/shhh/internal/exec"
 	"github.com/druarnfield/shhh/internal/module"
 )
 
@@ -15,12 +16,18 @@ func NewPythonModule(deps *Dependencies) *module.Module {
 	var steps []module.Step
 
 	steps = append(steps, installUVStep(deps))
+	if deps.Config.Paths.Root != "" {
+		steps = append(steps, installRootStep(deps, "python", "UV_PYTHON_INSTALL_DIR", deps.Config.InstallPath("python"), "uv's Python installs"))
+	}
 	steps = append(steps, installPythonStep(deps))
 	steps = append(steps, configurePythonCertsStep(deps))
 	steps = append(steps, setUVPythonPreferenceStep(deps))
 	if deps.Config.Registries.PyPIMirror != "" {
 		steps = append(steps, configurePyPIMirrorStep(deps))
 	}
+	if err := deps.Config.CheckModule("python"); err != nil {
+		steps = []module.Step{configErrorStep(err)}
+	}
 
 	return &module.Module{
 		ID:           "python",
@@ -28,45 +35,97 @@ func NewPythonModule(deps *Dependencies) *module.Module {
 		Description:  "Install Python via uv and configure PyPI settings",
 		Category:     module.CategoryLanguage,
 		Dependencies: []string{"base"},
+		SupportedOS:  scoopOS,
 		Steps:        steps,
 	}
 }
 
+// uvInstallerURL is uv's official standalone installer for Windows.
+const uvInstallerURL = "https://astral.sh/uv/install.ps1"
+
 func installUVStep(deps *Dependencies) module.Step {
+	method := deps.Config.Python.UVInstallMethod
+	if method == "" {
+		method = "scoop"
+	}
+
+	description := "Install uv Python package manager via Scoop"
+	if method == "standalone" {
+		description = "Install uv Python package manager via the official installer"
+	}
+
 	return module.Step{
-		Name:        "Install uv",
-		Description: "Install uv Python package manager via Scoop",
-		Explain:     "uv is a fast Python package manager that also manages Python installations.",
+		Name:         "Install uv",
+		Description:  description,
+		Explain:      "uv is a fast Python package manager that also manages Python installations.",
+		Retries:      installRetries,
+		RetryBackoff: installBackoff,
 		Check: func(ctx context.Context) bool {
 			_, err := deps.Exec.Run(ctx, "uv", "--version")
 			return err == nil
 		},
 		Run: func(ctx context.Context) error {
-			if _, err := deps.Exec.Run(ctx, "scoop", "install", "uv"); err != nil {
-				return fmt.Errorf("installing uv: %w", err)
+			switch method {
+			case "scoop":
+				if err := scoopInstall(ctx, deps, "uv"); err != nil {
+					return fmt.Errorf("installing uv: %w", err)
+				}
+				deps.State.AddScoopPackage("python", "uv")
+				return nil
+			case "standalone":
+				return installUVStandalone(ctx, deps)
+			default:
+				return fmt.Errorf("unknown python.uv_install_method %q (want \"scoop\" or \"standalone\")", method)
 			}
-			deps.State.AddScoopPackage("uv")
-			return nil
 		},
 		DryRun: func(_ context.Context) string {
+			if method == "standalone" {
+				return fmt.Sprintf("Would install uv via %s", uvInstallerURL)
+			}
 			return "Would install uv via scoop"
 		},
 	}
 }
 
+// installUVStandalone runs uv's official PowerShell installer, fetching it
+// through the configured proxy, and puts its install directory on PATH for
+// the rest of this run. The installer adds that directory to the user PATH
+// itself, so it isn't recorded as a PATH entry shhh manages.
+func installUVStandalone(ctx context.Context, deps *Dependencies) error {
+	script := fmt.Sprintf("irm %s | iex", uvInstallerURL)
+	if proxy := deps.Config.Proxy.HTTPS; proxy != "" {
+		// Quoted: a proxy password may hold characters PowerShell expands.
+		script = fmt.Sprintf("irm -Proxy %s %s | iex", psQuote(proxy), uvInstallerURL)
+	}
+
+	home, _ := os.UserHomeDir()
+	binDir := filepath.Join(home, ".local", "bin")
+	if err := confirmPath(deps, binDir); err != nil {
+		return err
+	}
+	if _, err := deps.Exec.Run(ctx, "powershell", "-NoProfile", "-ExecutionPolicy", "Bypass", "-Command", script); err != nil {
+		return fmt.Errorf("installing uv: %w", err)
+	}
+
+	os.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return nil
+}
+
 func installPythonStep(deps *Dependencies) module.Step {
 	version := deps.Config.Python.Version
 
 	return module.Step{
-		Name:        "Install Python",
-		Description: fmt.Sprintf("Install Python %s via uv", version),
-		Explain:     "Python is used for scripting, data engineering, and many internal tools.",
+		Name:         "Install Python",
+		Description:  fmt.Sprintf("Install Python %s via uv", version),
+		Explain:      "Python is used for scripting, data engineering, and many internal tools.",
+		Retries:      installRetries,
+		RetryBackoff: installBackoff,
 		Check: func(ctx context.Context) bool {
-			result, err := deps.Exec.Run(ctx, "uv", "python", "list", "--only-installed")
+			out, err := shexec.RunOutput(ctx, deps.Exec, "uv", "python", "list", "--only-installed")
 			if err != nil {
 				return false
 			}
-			return strings.Contains(result.Stdout, version)
+			return strings.Contains(out, version)
 		},
 		Run: func(ctx context.Context) error {
 			if _, err := deps.Exec.Run(ctx, "uv", "python", "install", version); err != nil {
@@ -80,24 +139,24 @@ func installPythonStep(deps *Dependencies) module.Step {
 	}
 }
 
+// pythonCAEnvKeys are the CA bundle variables read by requests and pip.
+var pythonCAEnvKeys = []string{"REQUESTS_CA_BUNDLE", "PIP_CERT"}
+
 func configurePythonCertsStep(deps *Dependencies) module.Step {
-	caPath := config.CABundlePath()
-	keys := []string{"REQUESTS_CA_BUNDLE", "PIP_CERT"}
+	caPath := deps.Config.CABundlePath()
+	keys := pythonCAEnvKeys
 
 	return module.Step{
 		Name:        "Configure Python CA certificates",
 		Description: "Point pip and requests at the shhh CA bundle",
+		Env:         envOf(caPath, keys...),
 		Explain: "Python's requests library and pip each have their own way of finding CA certificates. " +
 			"REQUESTS_CA_BUNDLE tells the requests library (used by most Python HTTP clients) where to " +
 			"find trusted CAs, and PIP_CERT tells pip directly. Without these, pip install and API calls " +
 			"fail with SSL certificate verification errors behind corporate proxies.",
 		Check: func(_ context.Context) bool {
 			for _, key := range keys {
-				val, _, err := deps.Env.Get(key)
-				if err != nil || val != caPath {
-					return false
-				}
-				if os.Getenv(key) != caPath {
+				if !deps.envMatches(key, caPath) {
 					return false
 				}
 			}
@@ -109,7 +168,7 @@ func configurePythonCertsStep(deps *Dependencies) module.Step {
 					return fmt.Errorf("setting %s: %w", key, err)
 				}
 				os.Setenv(key, caPath)
-				deps.State.AddEnvVar(key)
+				deps.State.AddEnvVar("python", key)
 			}
 			return nil
 		},
@@ -119,26 +178,26 @@ func configurePythonCertsStep(deps *Dependencies) module.Step {
 	}
 }
 
+// uvPythonPreference is the UV_PYTHON_PREFERENCE shhh sets.
+const uvPythonPreference = "only-managed"
+
 func setUVPythonPreferenceStep(deps *Dependencies) module.Step {
-	value := "only-managed"
+	value := uvPythonPreference
 
 	return module.Step{
 		Name:        "Set UV_PYTHON_PREFERENCE",
 		Description: "Set UV_PYTHON_PREFERENCE to only-managed",
+		Env:         envOf(value, "UV_PYTHON_PREFERENCE"),
 		Explain:     "This tells uv to only use Python versions it manages, avoiding conflicts with system Python.",
 		Check: func(_ context.Context) bool {
-			val, _, err := deps.Env.Get("UV_PYTHON_PREFERENCE")
-			if err != nil || val != value {
-				return false
-			}
-			return os.Getenv("UV_PYTHON_PREFERENCE") == value
+			return deps.envMatches("UV_PYTHON_PREFERENCE", value)
 		},
 		Run: func(_ context.Context) error {
 			if err := deps.Env.Set("UV_PYTHON_PREFERENCE", value); err != nil {
 				return fmt.Errorf("setting UV_PYTHON_PREFERENCE: %w", err)
 			}
 			os.Setenv("UV_PYTHON_PREFERENCE", value)
-			deps.State.AddEnvVar("UV_PYTHON_PREFERENCE")
+			deps.State.AddEnvVar("python", "UV_PYTHON_PREFERENCE")
 			return nil
 		},
 		DryRun: func(_ context.Context) string {
@@ -153,17 +212,10 @@ func configurePyPIMirrorStep(deps *Dependencies) module.Step {
 	return module.Step{
 		Name:        "Configure PyPI mirror",
 		Description: fmt.Sprintf("Set UV_INDEX_URL and PIP_INDEX_URL to %s", mirror),
+		Env:         envOf(mirror, "UV_INDEX_URL", "PIP_INDEX_URL"),
 		Explain:     "Corporate environments often host an internal PyPI mirror for approved packages.",
 		Check: func(_ context.Context) bool {
-			uvVal, _, err := deps.Env.Get("UV_INDEX_URL")
-			if err != nil || uvVal != mirror {
-				return false
-			}
-			pipVal, _, err := deps.Env.Get("PIP_INDEX_URL")
-			if err != nil || pipVal != mirror {
-				return false
-			}
-			return os.Getenv("UV_INDEX_URL") == mirror && os.Getenv("PIP_INDEX_URL") == mirror
+			return deps.envMatches("UV_INDEX_URL", mirror) && deps.envMatches("PIP_INDEX_URL", mirror)
 		},
 		Run: func(_ context.Context) error {
 			for _, key := range []string{"UV_INDEX_URL", "PIP_INDEX_URL"} {
@@ -171,7 +223,7 @@ func configurePyPIMirrorStep(deps *Dependencies) module.Step {
 					return fmt.Errorf("setting %s: %w", key, err)
 				}
 				os.Setenv(key, mirror)
-				deps.State.AddEnvVar(key)
+				deps.State.AddEnvVar("python", key)
 			}
 			return nil
 		},
@@ -0,0 +1,201 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	shexec "github.com/druarnfield/shhh/internal/exec"
+	"github.com/druarnfield/shhh/internal/module"
+	"github.com/druarnfield/shhh/internal/platform"
+)
+
+// NewRustModule creates the Rust language setup module.
+func NewRustModule(deps *Dependencies) *module.Module {
+	var steps []module.Step
+
+	steps = append(steps, installRustupStep(deps))
+	steps = append(steps, configureCargoCertsStep(deps))
+	steps = append(steps, setRustToolchainStep(deps))
+	if deps.Config.Registries.CargoRegistry != "" {
+		steps = append(steps, configureCargoRegistryStep(deps))
+	}
+	if err := deps.Config.CheckModule("rust"); err != nil {
+		steps = []module.Step{configErrorStep(err)}
+	}
+
+	return &module.Module{
+		ID:           "rust",
+		Name:         "Rust",
+		Description:  "Install Rust via rustup and configure cargo's CA bundle and registry",
+		Category:     module.CategoryLanguage,
+		Dependencies: []string{"base"},
+		SupportedOS:  scoopOS,
+		Steps:        steps,
+	}
+}
+
+func installRustupStep(deps *Dependencies) module.Step {
+	return module.Step{
+		Name:         "Install rustup",
+		Description:  "Install rustup via Scoop",
+		Explain:      "rustup installs and manages Rust toolchains (rustc, cargo) and switches between them.",
+		Retries:      installRetries,
+		RetryBackoff: installBackoff,
+		Check: func(ctx context.Context) bool {
+			_, err := deps.Exec.Run(ctx, "rustup", "--version")
+			return err == nil
+		},
+		Run: func(ctx context.Context) error {
+			if err := scoopInstall(ctx, deps, "rustup"); err != nil {
+				return fmt.Errorf("installing rustup: %w", err)
+			}
+			deps.State.AddScoopPackage("rust", "rustup")
+			return nil
+		},
+		DryRun: func(_ context.Context) string {
+			return "Would install rustup via scoop"
+		},
+	}
+}
+
+func configureCargoCertsStep(deps *Dependencies) module.Step {
+	caPath := deps.Config.CABundlePath()
+
+	return module.Step{
+		Name:        "Configure Cargo CA certificates",
+		Description: "Point cargo at the shhh CA bundle",
+		Env:         envOf(caPath, "CARGO_HTTP_CAINFO"),
+		Explain: "cargo checks TLS certificates against its own list of CAs unless CARGO_HTTP_CAINFO " +
+			"names a bundle. Without it, fetching crates fails with certificate errors behind " +
+			"corporate proxies.",
+		Check: func(_ context.Context) bool {
+			return deps.envMatches("CARGO_HTTP_CAINFO", caPath)
+		},
+		Run: func(_ context.Context) error {
+			if err := deps.Env.Set("CARGO_HTTP_CAINFO", caPath); err != nil {
+				return fmt.Errorf("setting CARGO_HTTP_CAINFO: %w", err)
+			}
+			os.Setenv("CARGO_HTTP_CAINFO", caPath)
+			deps.State.AddEnvVar("rust", "CARGO_HTTP_CAINFO")
+			return nil
+		},
+		DryRun: func(_ context.Context) string {
+			return fmt.Sprintf("Would set CARGO_HTTP_CAINFO=%s in user environment and current process", caPath)
+		},
+	}
+}
+
+func setRustToolchainStep(deps *Dependencies) module.Step {
+	version := deps.Config.Rust.Version
+
+	return module.Step{
+		Name:         "Set default Rust toolchain",
+		Description:  fmt.Sprintf("Install the %s toolchain and make it the default", version),
+		Explain:      "rustup downloads the toolchain and uses it wherever a project doesn't pin its own.",
+		Retries:      installRetries,
+		RetryBackoff: installBackoff,
+		Check: func(ctx context.Context) bool {
+			out, err := shexec.RunOutput(ctx, deps.Exec, "rustup", "default")
+			if err != nil {
+				return false
+			}
+			// e.g. "stable-x86_64-pc-windows-msvc (default)"
+			fields := strings.Fields(out)
+			if len(fields) == 0 {
+				return false
+			}
+			return fields[0] == version || strings.HasPrefix(fields[0], version+"-")
+		},
+		Run: func(ctx context.Context) error {
+			if _, err := deps.Exec.Run(ctx, "rustup", "default", version); err != nil {
+				return fmt.Errorf("setting default toolchain %s: %w", version, explainMissing("rustup", err))
+			}
+			return nil
+		},
+		DryRun: func(_ context.Context) string {
+			return fmt.Sprintf("Would run: rustup default %s", version)
+		},
+	}
+}
+
+// cargoHome returns the directory cargo keeps its config in: CARGO_HOME,
+// or ~/.cargo.
+func cargoHome() string {
+	if dir := os.Getenv("CARGO_HOME"); dir != "" {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".cargo")
+}
+
+// cargoMirrorName is the source shhh's cargo config replaces crates.io with.
+const cargoMirrorName = "shhh-mirror"
+
+// cratesIOSource matches a [source.crates-io] table header.
+var cratesIOSource = regexp.MustCompile(`(?m)^\s*\[\s*source\s*\.\s*("crates-io"|crates-io)\s*\]`)
+
+// cargoRegistryBlock returns the cargo config shhh keeps in its managed
+// block to fetch crates from registry.
+func cargoRegistryBlock(registry string) string {
+	return fmt.Sprintf("[source.crates-io]\nreplace-with = %q\n\n[source.%s]\nregistry = %q",
+		cargoMirrorName, cargoMirrorName, registry)
+}
+
+// outsideManagedBlock returns content without its managed block, if any.
+func outsideManagedBlock(content string) string {
+	start := strings.Index(content, platform.ManagedBlockStart)
+	if start < 0 {
+		return content
+	}
+	end := strings.Index(content[start:], platform.ManagedBlockEnd)
+	if end < 0 {
+		return content
+	}
+	return content[:start] + content[start+end+len(platform.ManagedBlockEnd):]
+}
+
+func configureCargoRegistryStep(deps *Dependencies) module.Step {
+	registry := deps.Config.Registries.CargoRegistry
+	path := filepath.Join(cargoHome(), "config.toml")
+	want := cargoRegistryBlock(registry)
+
+	return module.Step{
+		Name:        "Configure cargo registry mirror",
+		Description: fmt.Sprintf("Fetch crates from %s", registry),
+		Explain: "cargo downloads crates from crates.io unless its config replaces that source. " +
+			"Corporate environments often mirror crates.io internally; shhh adds the replacement " +
+			"in a managed block in cargo's config.toml and leaves the rest of the file alone.",
+		Check: func(_ context.Context) bool {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return false
+			}
+			block, ok := platform.ExtractManagedBlock(string(data))
+			return ok && block == want
+		},
+		Run: func(ctx context.Context) error {
+			data, err := os.ReadFile(path)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("reading %s: %w", path, err)
+			}
+			content := string(data)
+			if cratesIOSource.MatchString(outsideManagedBlock(content)) {
+				return fmt.Errorf("%s already has a [source.crates-io] table; remove it so shhh can set the mirror", path)
+			}
+			return trackManagedFile(ctx, deps, "rust", path, func() error {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					return err
+				}
+				return os.WriteFile(path, []byte(platform.ReplaceManagedBlock(content, want)), 0o644)
+			})
+		},
+		DryRun: func(_ context.Context) string {
+			return fmt.Sprintf("Would replace crates.io with %s in %s", registry, path)
+		},
+	}
+}
@@ -0,0 +1,166 @@
+package setup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/druarnfield/shhh/internal/exec"
+	"github.com/druarnfield/shhh/internal/state"
+)
+
+func TestRustModule_HasRequiredSteps(t *testing.T) {
+	deps := testDeps()
+	deps.Config.Registries.CargoRegistry = "sparse+https://cargo.example.com/index/"
+	mod := NewRustModule(deps)
+
+	if mod.ID != "rust" {
+		t.Errorf("ID = %q, want %q", mod.ID, "rust")
+	}
+	if len(mod.Dependencies) == 0 || mod.Dependencies[0] != "base" {
+		t.Error("expected dependency on base")
+	}
+
+	stepNames := make(map[string]bool)
+	for _, s := range mod.Steps {
+		stepNames[s.Name] = true
+	}
+
+	required := []string{"Install rustup", "Configure Cargo CA certificates", "Set default Rust toolchain", "Configure cargo registry mirror"}
+	for _, name := range required {
+		if !stepNames[name] {
+			t.Errorf("missing required step: %q", name)
+		}
+	}
+}
+
+func TestRustModule_NoRegistryStepWithoutMirror(t *testing.T) {
+	deps := testDeps()
+	deps.Config.Registries.CargoRegistry = ""
+	for _, s := range NewRustModule(deps).Steps {
+		if s.Name == "Configure cargo registry mirror" {
+			t.Error("registry step added without registries.cargo_registry")
+		}
+	}
+}
+
+func TestInstallRustupStep_Run(t *testing.T) {
+	deps := testDeps()
+	deps.State = &state.State{}
+	mockExec := deps.Exec.(*exec.MockRunner)
+	ctx := context.Background()
+
+	step := installRustupStep(deps)
+	if step.Check(ctx) {
+		t.Error("Check should return false when rustup is not installed")
+	}
+
+	mockExec.Results["scoop install rustup"] = exec.Result{ExitCode: 0}
+	if err := step.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(deps.State.ScoopPackages) == 0 || deps.State.ScoopPackages[0] != "rustup" {
+		t.Error("expected 'rustup' in scoop packages")
+	}
+}
+
+func TestSetRustToolchainStep_Check(t *testing.T) {
+	deps := testDeps()
+	deps.Config.Rust.Version = "stable"
+	mockExec := deps.Exec.(*exec.MockRunner)
+	ctx := context.Background()
+
+	step := setRustToolchainStep(deps)
+
+	mockExec.Results["rustup default"] = exec.Result{Stdout: "nightly-x86_64-pc-windows-msvc (default)\n"}
+	if step.Check(ctx) {
+		t.Error("Check should return false for another toolchain")
+	}
+
+	mockExec.Results["rustup default"] = exec.Result{Stdout: "stable-x86_64-pc-windows-msvc (default)\n"}
+	if !step.Check(ctx) {
+		t.Error("Check should return true when the default toolchain matches")
+	}
+}
+
+func TestConfigureCargoRegistryStep_Run(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("CARGO_HOME", home)
+	path := filepath.Join(home, "config.toml")
+	os.WriteFile(path, []byte("[net]\ngit-fetch-with-cli = true\n"), 0o644)
+
+	deps := testDeps()
+	deps.Config.Registries.CargoRegistry = "sparse+https://cargo.example.com/index/"
+	ctx := context.Background()
+
+	step := configureCargoRegistryStep(deps)
+	if step.Check(ctx) {
+		t.Fatal("Check should return false before the mirror is configured")
+	}
+	if err := step.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !step.Check(ctx) {
+		t.Error("Check should return true after Run")
+	}
+
+	data, _ := os.ReadFile(path)
+	got := string(data)
+	if !strings.HasPrefix(got, "[net]\ngit-fetch-with-cli = true\n") {
+		t.Errorf("existing config not preserved:\n%s", got)
+	}
+	if !strings.Contains(got, `registry = "sparse+https://cargo.example.com/index/"`) {
+		t.Errorf("mirror not written:\n%s", got)
+	}
+
+	// Running again rewrites the block in place.
+	if err := step.Run(ctx); err != nil {
+		t.Fatalf("second Run: %v", err)
+	}
+	again, _ := os.ReadFile(path)
+	if string(again) != got {
+		t.Errorf("second Run changed the file:\n%s", again)
+	}
+	if _, ok := deps.State.LookupManagedFile(path); ok {
+		t.Error("a config.toml with the user's own settings should not be a managed file")
+	}
+}
+
+func TestConfigureCargoRegistryStep_RecordsCreatedConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("CARGO_HOME", home)
+	path := filepath.Join(home, "config.toml")
+
+	deps := testDeps()
+	deps.Config.Registries.CargoRegistry = "sparse+https://cargo.example.com/index/"
+	if err := configureCargoRegistryStep(deps).Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	f, ok := deps.State.LookupManagedFile(path)
+	if !ok {
+		t.Fatal("config.toml shhh created should be a managed file")
+	}
+	if status, err := f.Status(); err != nil || status != state.FileUnchanged {
+		t.Errorf("Status = %v, %v; want FileUnchanged", status, err)
+	}
+	if got := deps.State.Owners["rust"].Files; len(got) != 1 || got[0] != path {
+		t.Errorf("rust owns %v, want [%s]", got, path)
+	}
+}
+
+func TestConfigureCargoRegistryStep_RunConflict(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("CARGO_HOME", home)
+	path := filepath.Join(home, "config.toml")
+	os.WriteFile(path, []byte("[source.crates-io]\nreplace-with = \"other\"\n"), 0o644)
+
+	deps := testDeps()
+	deps.Config.Registries.CargoRegistry = "https://cargo.example.com/index/"
+
+	if err := configureCargoRegistryStep(deps).Run(context.Background()); err == nil {
+		t.Error("expected an error when config.toml already replaces crates.io")
+	}
+}
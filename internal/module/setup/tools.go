@@ -2,9 +2,11 @@ package setup
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
+	shexec "github.com/druarnfield/shhh/internal/exec"
 	"github.com/druarnfield/shhh/internal/module"
 )
 
@@ -13,7 +15,7 @@ func NewToolsModule(deps *Dependencies) *module.Module {
 	var steps []module.Step
 
 	if len(deps.Config.Tools.Core) > 0 {
-		steps = append(steps, scoopInstallStep(deps,
+		steps = append(steps, scoopInstallStep(deps, "tools",
 			"Install core tools",
 			"Install foundational developer tools via Scoop",
 			"Foundational tools: git, jq, ripgrep, fd, fzf, delta, etc.",
@@ -21,7 +23,7 @@ func NewToolsModule(deps *Dependencies) *module.Module {
 		))
 	}
 	if len(deps.Config.Tools.Data) > 0 {
-		steps = append(steps, scoopInstallStep(deps,
+		steps = append(steps, scoopInstallStep(deps, "tools",
 			"Install data tools",
 			"Install data engineering tools via Scoop",
 			"Data engineering tools: sqlcmd, bcp, etc.",
@@ -29,13 +31,16 @@ func NewToolsModule(deps *Dependencies) *module.Module {
 		))
 	}
 	if len(deps.Config.Tools.Optional) > 0 {
-		steps = append(steps, scoopInstallStep(deps,
+		steps = append(steps, scoopInstallStep(deps, "tools",
 			"Install optional tools",
 			"Install quality-of-life tools via Scoop",
 			"Quality-of-life: bat, eza, lazygit, starship, etc.",
 			deps.Config.Tools.Optional,
 		))
 	}
+	if err := deps.Config.CheckModule("tools"); err != nil {
+		steps = []module.Step{configErrorStep(err)}
+	}
 
 	return &module.Module{
 		ID:           "tools",
@@ -43,16 +48,20 @@ func NewToolsModule(deps *Dependencies) *module.Module {
 		Description:  "Install developer tools via Scoop",
 		Category:     module.CategoryTool,
 		Dependencies: []string{"base"},
+		SupportedOS:  scoopOS,
 		Steps:        steps,
 	}
 }
 
-// scoopInstallStep creates a step that installs a set of tools via scoop.
-func scoopInstallStep(deps *Dependencies, name, description, explain string, tools []string) module.Step {
+// scoopInstallStep creates a step that installs a set of tools via scoop,
+// recording them as installed for the module owner.
+func scoopInstallStep(deps *Dependencies, owner, name, description, explain string, tools []string) module.Step {
 	return module.Step{
-		Name:        name,
-		Description: description,
-		Explain:     explain,
+		Name:         name,
+		Description:  description,
+		Explain:      explain,
+		Retries:      installRetries,
+		RetryBackoff: installBackoff,
 		Check: func(ctx context.Context) bool {
 			result, err := deps.Exec.Run(ctx, "scoop", "list")
 			if err != nil {
@@ -76,10 +85,10 @@ func scoopInstallStep(deps *Dependencies, name, description, explain string, too
 				if strings.Contains(installed, tool) {
 					continue
 				}
-				if _, err := deps.Exec.Run(ctx, "scoop", "install", tool); err != nil {
+				if err := scoopInstall(ctx, deps, tool); err != nil {
 					return fmt.Errorf("installing %s: %w", tool, err)
 				}
-				deps.State.AddScoopPackage(tool)
+				deps.State.AddScoopPackage(owner, tool)
 			}
 			return nil
 		},
@@ -88,3 +97,86 @@ func scoopInstallStep(deps *Dependencies, name, description, explain string, too
 		},
 	}
 }
+
+// ErrManifestNotFound is returned when Scoop has no manifest for an app in
+// any bucket that is added.
+var ErrManifestNotFound = errors.New("scoop couldn't find a manifest")
+
+// knownBuckets maps apps commonly listed in [tools] to the standard bucket
+// that carries them, so a missing manifest can name the bucket to add.
+var knownBuckets = map[string]string{
+	"azuredatastudio":  "extras",
+	"dbeaver":          "extras",
+	"everything":       "extras",
+	"firefox":          "extras",
+	"googlechrome":     "extras",
+	"keepassxc":        "extras",
+	"notepadplusplus":  "extras",
+	"obsidian":         "extras",
+	"postman":          "extras",
+	"powertoys":        "extras",
+	"sumatrapdf":       "extras",
+	"vscode":           "extras",
+	"wezterm":          "extras",
+	"windows-terminal": "extras",
+	"python310":        "versions",
+	"python311":        "versions",
+	"vscode-insiders":  "versions",
+	"cascadiacode-nf":  "nerd-fonts",
+	"firacode-nf":      "nerd-fonts",
+	"hack-nf":          "nerd-fonts",
+	"jetbrainsmono-nf": "nerd-fonts",
+	"meslo-nf":         "nerd-fonts",
+}
+
+// scoopInstall runs 'scoop install app', unless [scoop] allowed or denied
+// forbids it. When Scoop can't find the app's
+// manifest and the app is known to live in a standard bucket, the bucket
+// is added and the install retried if [scoop] auto_bucket is set;
+// otherwise the error says which bucket to add.
+func scoopInstall(ctx context.Context, deps *Dependencies, app string) error {
+	if err := deps.Config.Scoop.CheckPackage(app); err != nil {
+		return err
+	}
+	result, err := deps.Exec.Run(ctx, "scoop", "install", app)
+	if err == nil {
+		return nil
+	}
+	if !manifestNotFound(result) {
+		return explainMissing("scoop", err)
+	}
+	bucket := bucketFor(app)
+	if bucket == "" {
+		return fmt.Errorf("%w for %q; is it in a bucket listed in [scoop] buckets?", ErrManifestNotFound, app)
+	}
+	if !deps.Config.Scoop.AutoBucket {
+		return fmt.Errorf("%w for %q, which is in the %q bucket; run 'scoop bucket add %s', add it to [scoop] buckets, or set [scoop] auto_bucket = true",
+			ErrManifestNotFound, app, bucket, bucket)
+	}
+	deps.log().Info("adding scoop bucket for missing manifest", "app", app, "bucket", bucket)
+	if _, err := deps.Exec.Run(ctx, "scoop", "bucket", "add", bucket); err != nil {
+		return fmt.Errorf("adding scoop bucket %q for %s: %w", bucket, app, err)
+	}
+	if _, err := deps.Exec.Run(ctx, "scoop", "install", app); err != nil {
+		return fmt.Errorf("after adding bucket %q: %w", bucket, err)
+	}
+	return nil
+}
+
+// manifestNotFound reports whether a failed 'scoop install' said it
+// couldn't find the app's manifest. Scoop writes this to either stream,
+// depending on version.
+func manifestNotFound(r shexec.Result) bool {
+	out := strings.ToLower(r.Stdout + r.Stderr)
+	return strings.Contains(out, "couldn't find manifest")
+}
+
+// bucketFor returns the standard bucket that carries app, or "" if it
+// isn't known. An app given as "bucket/app" names its bucket itself.
+func bucketFor(app string) string {
+	if bucket, _, ok := strings.Cut(app, "/"); ok {
+		return bucket
+	}
+	name, _, _ := strings.Cut(app, "@")
+	return knownBuckets[strings.ToLower(name)]
+}
@@ -2,6 +2,8 @@ package setup
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/druarnfield/shhh/internal/exec"
@@ -44,13 +46,50 @@ func TestToolsModule_EmptyListOmitsStep(t *testing.T) {
 	}
 }
 
+func TestToolsModule_ScoopPolicy(t *testing.T) {
+	tests := []struct {
+		name            string
+		allowed, denied []string
+		wantErr         string
+	}{
+		{name: "allowed", allowed: []string{"git", "jq"}},
+		{name: "denied", denied: []string{"JQ"}, wantErr: `tool "extras/jq" is denied by [scoop] denied`},
+		{name: "unlisted with allowlist", allowed: []string{"git"}, wantErr: `tool "extras/jq" is not in the approved list`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := testDeps()
+			deps.Config.Tools.Core = []string{"git", "extras/jq"}
+			deps.Config.Tools.Data = nil
+			deps.Config.Tools.Optional = nil
+			deps.Config.Scoop.Allowed = tt.allowed
+			deps.Config.Scoop.Denied = tt.denied
+			mod := NewToolsModule(deps)
+
+			if len(mod.Steps) != 1 {
+				t.Fatalf("expected 1 step, got %d", len(mod.Steps))
+			}
+			err := mod.Steps[0].Run(context.Background())
+			if tt.wantErr == "" {
+				if mod.Steps[0].Name != "Install core tools" {
+					t.Errorf("step = %q, want the install step", mod.Steps[0].Name)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Run = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestScoopInstallStep_Check(t *testing.T) {
 	deps := testDeps()
 	mockExec := deps.Exec.(*exec.MockRunner)
 	ctx := context.Background()
 
 	tools := []string{"git", "jq", "ripgrep"}
-	step := scoopInstallStep(deps, "Install core tools", "desc", "explain", tools)
+	step := scoopInstallStep(deps, "tools", "Install core tools", "desc", "explain", tools)
 
 	// Check returns false when scoop list fails.
 	if step.Check(ctx) {
@@ -81,7 +120,7 @@ func TestScoopInstallStep_Run_PartialInstall(t *testing.T) {
 	ctx := context.Background()
 
 	tools := []string{"git", "jq", "ripgrep"}
-	step := scoopInstallStep(deps, "Install core tools", "desc", "explain", tools)
+	step := scoopInstallStep(deps, "tools", "Install core tools", "desc", "explain", tools)
 	if err := step.Run(ctx); err != nil {
 		t.Fatalf("Run: %v", err)
 	}
@@ -109,7 +148,7 @@ func TestScoopInstallStep_Run_AllMissing(t *testing.T) {
 	ctx := context.Background()
 
 	tools := []string{"bat", "lazygit"}
-	step := scoopInstallStep(deps, "Install optional tools", "desc", "explain", tools)
+	step := scoopInstallStep(deps, "tools", "Install optional tools", "desc", "explain", tools)
 	if err := step.Run(ctx); err != nil {
 		t.Fatalf("Run: %v", err)
 	}
@@ -123,9 +162,93 @@ func TestScoopInstallStep_DryRun(t *testing.T) {
 	deps := testDeps()
 	ctx := context.Background()
 	tools := []string{"git", "jq"}
-	step := scoopInstallStep(deps, "Install core tools", "desc", "explain", tools)
+	step := scoopInstallStep(deps, "tools", "Install core tools", "desc", "explain", tools)
 	msg := step.DryRun(ctx)
 	if msg == "" {
 		t.Error("DryRun returned empty string")
 	}
 }
+
+func TestScoopInstallStep_Run_ScoopMissing(t *testing.T) {
+	deps := testDeps()
+	mockExec := deps.Exec.(*exec.MockRunner)
+	mockExec.Missing = []string{"scoop"}
+	ctx := context.Background()
+
+	step := scoopInstallStep(deps, "tools", "Install core tools", "desc", "explain", []string{"git"})
+	err := step.Run(ctx)
+	if !errors.Is(err, exec.ErrCommandNotFound) {
+		t.Fatalf("Run error = %v, want ErrCommandNotFound", err)
+	}
+	if !strings.Contains(err.Error(), "Install Scoop") {
+		t.Errorf("error should point at the Install Scoop step: %v", err)
+	}
+}
+
+func TestScoopInstallStep_Run_ManifestNotFoundSuggestsBucket(t *testing.T) {
+	deps := testDeps()
+	mockExec := deps.Exec.(*exec.MockRunner)
+	mockExec.Results["scoop list"] = exec.Result{}
+	mockExec.Results["scoop install vscode"] = exec.Result{Stdout: "Couldn't find manifest for 'vscode'.\n", ExitCode: 1}
+
+	step := scoopInstallStep(deps, "tools", "Install optional tools", "desc", "explain", []string{"vscode"})
+	err := step.Run(context.Background())
+	if !errors.Is(err, ErrManifestNotFound) {
+		t.Fatalf("Run error = %v, want ErrManifestNotFound", err)
+	}
+	if !strings.Contains(err.Error(), "scoop bucket add extras") {
+		t.Errorf("error should suggest adding the extras bucket: %v", err)
+	}
+	for _, call := range mockExec.Calls {
+		if strings.HasPrefix(call, "scoop bucket add") {
+			t.Errorf("bucket added without auto_bucket: %q", call)
+		}
+	}
+}
+
+func TestScoopInstallStep_Run_ManifestNotFoundUnknownApp(t *testing.T) {
+	deps := testDeps()
+	mockExec := deps.Exec.(*exec.MockRunner)
+	mockExec.Results["scoop list"] = exec.Result{}
+	mockExec.Results["scoop install nosuchtool"] = exec.Result{Stderr: "Couldn't find manifest for 'nosuchtool'.\n", ExitCode: 1}
+
+	step := scoopInstallStep(deps, "tools", "Install optional tools", "desc", "explain", []string{"nosuchtool"})
+	err := step.Run(context.Background())
+	if !errors.Is(err, ErrManifestNotFound) {
+		t.Fatalf("Run error = %v, want ErrManifestNotFound", err)
+	}
+	if strings.Contains(err.Error(), "bucket add") {
+		t.Errorf("error should not guess a bucket for an unknown app: %v", err)
+	}
+}
+
+func TestScoopInstallStep_Run_AutoBucket(t *testing.T) {
+	deps := testDeps()
+	deps.Config.Scoop.AutoBucket = true
+	mockExec := deps.Exec.(*exec.MockRunner)
+	mockExec.Results["scoop list"] = exec.Result{}
+	mockExec.Results["scoop bucket add nerd-fonts"] = exec.Result{}
+	tried := false
+	mockExec.Match = func(name string, args []string) (exec.Result, bool) {
+		if strings.Join(args, " ") != "install JetBrainsMono-NF" {
+			return exec.Result{}, false
+		}
+		if !tried {
+			tried = true
+			return exec.Result{Stdout: "Couldn't find manifest for 'JetBrainsMono-NF'.\n", ExitCode: 1}, true
+		}
+		return exec.Result{}, true
+	}
+
+	step := scoopInstallStep(deps, "tools", "Install optional tools", "desc", "explain", []string{"JetBrainsMono-NF"})
+	if err := step.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := []string{"scoop list", "scoop install JetBrainsMono-NF", "scoop bucket add nerd-fonts", "scoop install JetBrainsMono-NF"}
+	if strings.Join(mockExec.Calls, "|") != strings.Join(want, "|") {
+		t.Errorf("calls = %v, want %v", mockExec.Calls, want)
+	}
+	if len(deps.State.ScoopPackages) != 1 {
+		t.Errorf("ScoopPackages = %v, want the tool recorded", deps.State.ScoopPackages)
+	}
+}
@@ -7,3 +7,16 @@ type CertStore interface {
 	// SystemRoots returns all trusted root certificates from the OS cert store.
 	SystemRoots() ([]*x509.Certificate, error)
 }
+
+// DefaultWindowsStores are the Windows system stores SystemRoots reads when
+// CertStoreOptions.WindowsStores is empty: trusted roots and intermediates.
+var DefaultWindowsStores = []string{"ROOT", "CA"}
+
+// CertStoreOptions configures NewCertStore. Options for other platforms are
+// ignored.
+type CertStoreOptions struct {
+	// WindowsStores names the Windows system stores to read, e.g. just
+	// "ROOT" to leave intermediates out of the bundle. Empty means
+	// DefaultWindowsStores.
+	WindowsStores []string
+}
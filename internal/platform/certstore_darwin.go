@@ -13,7 +13,7 @@ import (
 type darwinCertStore struct{}
 
 // NewCertStore returns a CertStore that reads from macOS system keychains.
-func NewCertStore() CertStore { return &darwinCertStore{} }
+func NewCertStore(CertStoreOptions) CertStore { return &darwinCertStore{} }
 
 func (d *darwinCertStore) SystemRoots() ([]*x509.Certificate, error) {
 	keychains := []string{
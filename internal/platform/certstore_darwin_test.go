@@ -8,7 +8,7 @@ import (
 )
 
 func TestCertStore_SystemRoots(t *testing.T) {
-	store := NewCertStore()
+	store := NewCertStore(CertStoreOptions{})
 	certs, err := store.SystemRoots()
 	if err != nil {
 		t.Fatalf("SystemRoots() error: %v", err)
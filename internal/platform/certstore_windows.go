@@ -6,17 +6,27 @@ import (
 	"crypto/sha256"
 	"crypto/x509"
 	"fmt"
+	"strings"
 	"syscall"
 	"unsafe"
 )
 
-type windowsCertStore struct{}
+type windowsCertStore struct {
+	stores []string
+}
 
-// NewCertStore returns a CertStore that reads from Windows certificate stores.
-func NewCertStore() CertStore { return &windowsCertStore{} }
+// NewCertStore returns a CertStore that reads from the Windows certificate
+// stores opts.WindowsStores names.
+func NewCertStore(opts CertStoreOptions) CertStore {
+	stores := opts.WindowsStores
+	if len(stores) == 0 {
+		stores = DefaultWindowsStores
+	}
+	return &windowsCertStore{stores: stores}
+}
 
 func (w *windowsCertStore) SystemRoots() ([]*x509.Certificate, error) {
-	storeNames := []string{"ROOT", "CA"}
+	storeNames := w.stores
 
 	seen := make(map[[sha256.Size]byte]struct{})
 	var certs []*x509.Certificate
@@ -55,7 +65,7 @@ func (w *windowsCertStore) SystemRoots() ([]*x509.Certificate, error) {
 	}
 
 	if len(certs) == 0 {
-		return nil, fmt.Errorf("no certificates found in Windows certificate stores")
+		return nil, fmt.Errorf("no certificates found in Windows certificate stores %s", strings.Join(storeNames, ", "))
 	}
 
 	return certs, nil
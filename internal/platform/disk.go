@@ -0,0 +1,23 @@
+package platform
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// existingAncestor returns path or its nearest ancestor that exists, so the
+// free space of a directory setup has yet to create can be measured on the
+// drive it will be created on.
+func existingAncestor(path string) string {
+	path = filepath.Clean(path)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package platform
+
+import "golang.org/x/sys/windows"
+
+// FreeSpace returns the bytes available to the current user (which honours
+// disk quotas) on the drive holding path. path need not exist yet.
+func FreeSpace(path string) (uint64, error) {
+	dir, err := windows.UTF16PtrFromString(existingAncestor(path))
+	if err != nil {
+		return 0, err
+	}
+	var avail, total, free uint64
+	if err := windows.GetDiskFreeSpaceEx(dir, &avail, &total, &free); err != nil {
+		return 0, err
+	}
+	return avail, nil
+}
@@ -0,0 +1,254 @@
+//go:build linux
+
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// linuxUserEnv keeps the user environment as exports in an env.sh that
+// shhh owns outright, and sources it from a managed block in ~/.profile,
+// so login shells (and WSL terminals) pick it up and the rest of the
+// profile is left alone. Values are single-quoted, so they are taken
+// literally: a PATH entry can't refer to a variable. env.sh is readable
+// only by the user, since proxy URLs in it may hold a password.
+type linuxUserEnv struct {
+	envFile, profile string
+
+	mu sync.Mutex
+}
+
+// NewUserEnv returns the user environment kept in
+// $XDG_CONFIG_HOME/shhh/env.sh (~/.config/shhh/env.sh by default).
+func NewUserEnv() UserEnv {
+	home, _ := os.UserHomeDir()
+	config := os.Getenv("XDG_CONFIG_HOME")
+	if config == "" {
+		config = filepath.Join(home, ".config")
+	}
+	return newLinuxUserEnv(filepath.Join(config, "shhh", "env.sh"), filepath.Join(home, ".profile"))
+}
+
+func newLinuxUserEnv(envFile, profile string) *linuxUserEnv {
+	return &linuxUserEnv{envFile: envFile, profile: profile}
+}
+
+// envScript is the parsed content of env.sh.
+type envScript struct {
+	keys []string
+	vars map[string]string
+	path []string
+}
+
+const (
+	envFileHeader = "# Written by shhh: change it with shhh, not by hand.\n"
+	exportPrefix  = "export "
+	pathPrefix    = `export PATH="$PATH":`
+)
+
+// shellQuote single-quotes s for sh, so nothing in it is expanded.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellUnquote reverses shellQuote.
+func shellUnquote(s string) (string, bool) {
+	if len(s) < 2 || s[0] != '\'' || s[len(s)-1] != '\'' {
+		return "", false
+	}
+	return strings.ReplaceAll(s[1:len(s)-1], `'\''`, "'"), true
+}
+
+// parseEnvScript reads the lines shhh writes. Anything else is ignored.
+func parseEnvScript(content string) *envScript {
+	s := &envScript{vars: map[string]string{}}
+	for _, line := range strings.Split(content, "\n") {
+		if quoted, ok := strings.CutPrefix(line, pathPrefix+"':"); ok {
+			if dir, ok := shellUnquote("'" + quoted); ok {
+				s.path = append(s.path, dir)
+			}
+			continue
+		}
+		assignment, ok := strings.CutPrefix(line, exportPrefix)
+		if !ok {
+			continue
+		}
+		key, quoted, ok := strings.Cut(assignment, "=")
+		if !ok {
+			continue
+		}
+		if value, ok := shellUnquote(quoted); ok {
+			if _, seen := s.vars[key]; !seen {
+				s.keys = append(s.keys, key)
+			}
+			s.vars[key] = value
+		}
+	}
+	return s
+}
+
+func (s *envScript) String() string {
+	var b strings.Builder
+	b.WriteString(envFileHeader)
+	for _, key := range s.keys {
+		fmt.Fprintf(&b, "%s%s=%s\n", exportPrefix, key, shellQuote(s.vars[key]))
+	}
+	for _, dir := range s.path {
+		fmt.Fprintf(&b, "%s%s\n", pathPrefix, shellQuote(":"+dir))
+	}
+	return b.String()
+}
+
+func (l *linuxUserEnv) read() (*envScript, error) {
+	data, err := os.ReadFile(l.envFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading %s: %w", l.envFile, err)
+	}
+	return parseEnvScript(string(data)), nil
+}
+
+// update applies change to env.sh and makes sure ~/.profile sources it.
+func (l *linuxUserEnv) update(what string, change func(*envScript)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s, err := l.read()
+	if err != nil {
+		return err
+	}
+	change(s)
+	if err := os.MkdirAll(filepath.Dir(l.envFile), 0o755); err != nil {
+		return PolicyError(fmt.Errorf("%s: %w", what, err))
+	}
+	if err := os.WriteFile(l.envFile, []byte(s.String()), 0o600); err != nil {
+		return PolicyError(fmt.Errorf("%s: %w", what, err))
+	}
+	// WriteFile keeps the mode of a file that already exists.
+	if err := os.Chmod(l.envFile, 0o600); err != nil {
+		return PolicyError(fmt.Errorf("%s: %w", what, err))
+	}
+	return PolicyError(l.sourceFromProfile())
+}
+
+// sourceFromProfile adds the managed block that sources env.sh to
+// ~/.profile, unless it is already there.
+func (l *linuxUserEnv) sourceFromProfile() error {
+	data, err := os.ReadFile(l.profile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("reading %s: %w", l.profile, err)
+	}
+	want := fmt.Sprintf("[ -f %[1]s ] && . %[1]s", shellQuote(l.envFile))
+	if block, ok := ExtractManagedBlock(string(data)); ok && block == want {
+		return nil
+	}
+	if err := os.WriteFile(l.profile, []byte(ReplaceManagedBlock(string(data), want)), 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", l.profile, err)
+	}
+	return nil
+}
+
+func (l *linuxUserEnv) Get(key string) (string, EnvSource, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s, err := l.read()
+	if err != nil {
+		return "", SourceUser, err
+	}
+	value, ok := s.vars[key]
+	if !ok {
+		return "", SourceUser, fmt.Errorf("environment variable %q not set", key)
+	}
+	return value, SourceUser, nil
+}
+
+func (l *linuxUserEnv) Set(key, value string) error {
+	// PATH is assembled from the shell's own value plus AppendPath's
+	// entries; setting it outright would replace the system directories.
+	if IsPathKey(key) {
+		return ErrPathViaSet
+	}
+	return l.update("setting "+key, func(s *envScript) {
+		if _, ok := s.vars[key]; !ok {
+			s.keys = append(s.keys, key)
+		}
+		s.vars[key] = value
+	})
+}
+
+func (l *linuxUserEnv) SetScoped(key, value string, scope EnvSource) error {
+	if scope == SourceUser {
+		return l.Set(key, value)
+	}
+	return fmt.Errorf("setting %s in the %s environment: %w", key, scope, ErrNotSupported)
+}
+
+func (l *linuxUserEnv) Delete(key string) error {
+	return l.update("deleting "+key, func(s *envScript) {
+		s.keys = slices.DeleteFunc(s.keys, func(k string) bool { return k == key })
+		delete(s.vars, key)
+	})
+}
+
+func (l *linuxUserEnv) DeleteScoped(key string, scope EnvSource) error {
+	if scope == SourceUser {
+		return l.Delete(key)
+	}
+	return fmt.Errorf("deleting %s from the %s environment: %w", key, scope, ErrNotSupported)
+}
+
+func (l *linuxUserEnv) AppendPath(dir string) error {
+	return l.update("updating PATH", func(s *envScript) {
+		if !slices.Contains(s.path, dir) {
+			s.path = append(s.path, dir)
+		}
+	})
+}
+
+func (l *linuxUserEnv) RemovePath(dir string) error {
+	return l.update("updating PATH", func(s *envScript) {
+		s.path = slices.DeleteFunc(s.path, func(d string) bool { return d == dir })
+	})
+}
+
+// ListPath lists the current $PATH, marking the directories env.sh adds as
+// SourceUser, followed by any env.sh directories the shell hasn't picked
+// up yet.
+func (l *linuxUserEnv) ListPath() ([]PathEntry, error) {
+	l.mu.Lock()
+	s, err := l.read()
+	l.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []PathEntry
+	seen := map[string]bool{}
+	add := func(dir string, source EnvSource) {
+		_, err := os.Stat(dir)
+		entries = append(entries, PathEntry{Dir: dir, Source: source, Exists: err == nil})
+		seen[dir] = true
+	}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" || seen[dir] {
+			continue
+		}
+		source := SourceProcess
+		if slices.Contains(s.path, dir) {
+			source = SourceUser
+		}
+		add(dir, source)
+	}
+	for _, dir := range s.path {
+		if !seen[dir] {
+			add(dir, SourceUser)
+		}
+	}
+	return entries, nil
+}
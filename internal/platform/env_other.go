@@ -1,4 +1,4 @@
-//go:build !windows
+//go:build !windows && !linux
 
 package platform
 
@@ -9,7 +9,13 @@ func (s *StubUserEnv) Get(key string) (string, EnvSource, error) {
 	return "", SourceProcess, ErrNotSupported
 }
 func (s *StubUserEnv) Set(key, value string) error { return ErrNotSupported }
-func (s *StubUserEnv) Delete(key string) error     { return ErrNotSupported }
+func (s *StubUserEnv) SetScoped(key, value string, scope EnvSource) error {
+	return ErrNotSupported
+}
+func (s *StubUserEnv) Delete(key string) error { return ErrNotSupported }
+func (s *StubUserEnv) DeleteScoped(key string, scope EnvSource) error {
+	return ErrNotSupported
+}
 func (s *StubUserEnv) AppendPath(dir string) error { return ErrNotSupported }
 func (s *StubUserEnv) RemovePath(dir string) error { return ErrNotSupported }
 func (s *StubUserEnv) ListPath() ([]PathEntry, error) {
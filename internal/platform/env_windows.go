@@ -2,8 +2,20 @@
 
 package platform
 
-import "errors"
+import (
+	"errors"
+	"fmt"
 
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// systemEnvKey is the machine-wide environment, under HKEY_LOCAL_MACHINE.
+const systemEnvKey = `SYSTEM\CurrentControlSet\Control\Session Manager\Environment`
+
+// windowsUserEnv writes HKCU\Environment, or systemEnvKey for SetScoped
+// with SourceSystem. Registry write errors must go through PolicyError so
+// policy-locked machines get remediation advice.
 type windowsUserEnv struct{}
 
 func NewUserEnv() UserEnv { return &windowsUserEnv{} }
@@ -11,8 +23,76 @@ func NewUserEnv() UserEnv { return &windowsUserEnv{} }
 func (w *windowsUserEnv) Get(key string) (string, EnvSource, error) {
 	return "", SourceProcess, errors.New("not yet implemented")
 }
-func (w *windowsUserEnv) Set(key, value string) error { return errors.New("not yet implemented") }
-func (w *windowsUserEnv) Delete(key string) error     { return errors.New("not yet implemented") }
+func (w *windowsUserEnv) Set(key, value string) error {
+	// Writing PATH as a plain REG_SZ would drop its REG_EXPAND_SZ type and
+	// every %VAR% reference with it.
+	if IsPathKey(key) {
+		return ErrPathViaSet
+	}
+	return errors.New("not yet implemented")
+}
+
+func (w *windowsUserEnv) SetScoped(key, value string, scope EnvSource) error {
+	switch scope {
+	case SourceUser:
+		return w.Set(key, value)
+	case SourceSystem:
+		if IsPathKey(key) {
+			return ErrPathViaSet
+		}
+		// HKLM is writable only by administrators; say so up front rather
+		// than surfacing a bare access-denied from the registry.
+		if !windows.GetCurrentProcessToken().IsElevated() {
+			return fmt.Errorf("setting %s: %w", key, ErrNotElevated)
+		}
+		return setSystemEnv(key, value)
+	}
+	return fmt.Errorf("setting %s: can't write the %s environment", key, scope)
+}
+
+func (w *windowsUserEnv) Delete(key string) error { return errors.New("not yet implemented") }
+
+func (w *windowsUserEnv) DeleteScoped(key string, scope EnvSource) error {
+	switch scope {
+	case SourceUser:
+		return w.Delete(key)
+	case SourceSystem:
+		if !windows.GetCurrentProcessToken().IsElevated() {
+			return fmt.Errorf("deleting %s: %w", key, ErrNotElevated)
+		}
+		return deleteSystemEnv(key)
+	}
+	return fmt.Errorf("deleting %s: can't write the %s environment", key, scope)
+}
+
+// setSystemEnv writes key to systemEnvKey as a REG_SZ. Processes started
+// after the write see it; running ones keep their copy.
+func setSystemEnv(key, value string) error {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, systemEnvKey, registry.SET_VALUE)
+	if err != nil {
+		return PolicyError(fmt.Errorf("setting %s: %w", key, err))
+	}
+	defer k.Close()
+	if err := k.SetStringValue(key, value); err != nil {
+		return PolicyError(fmt.Errorf("setting %s: %w", key, err))
+	}
+	return nil
+}
+
+// deleteSystemEnv removes key from systemEnvKey. A key that isn't set is
+// already deleted.
+func deleteSystemEnv(key string) error {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, systemEnvKey, registry.SET_VALUE)
+	if err != nil {
+		return PolicyError(fmt.Errorf("deleting %s: %w", key, err))
+	}
+	defer k.Close()
+	if err := k.DeleteValue(key); err != nil && !errors.Is(err, registry.ErrNotExist) {
+		return PolicyError(fmt.Errorf("deleting %s: %w", key, err))
+	}
+	return nil
+}
+
 func (w *windowsUserEnv) AppendPath(dir string) error { return errors.New("not yet implemented") }
 func (w *windowsUserEnv) RemovePath(dir string) error { return errors.New("not yet implemented") }
 func (w *windowsUserEnv) ListPath() ([]PathEntry, error) {
@@ -3,6 +3,8 @@ package mock
 import (
 	"crypto/x509"
 	"fmt"
+	"io/fs"
+	"slices"
 	"strings"
 
 	"github.com/druarnfield/shhh/internal/platform"
@@ -15,12 +17,30 @@ import (
 type UserEnv struct {
 	vars map[string]string
 	path []string
+
+	// Scopes records the scope each variable was last written to: Set
+	// writes platform.SourceUser, SetScoped the scope asked for. Get reports
+	// it as the variable's source.
+	Scopes map[string]platform.EnvSource
+
+	// Locked simulates a policy-locked HKCU\Environment: every write fails
+	// with an access-denied error wrapped in platform.ErrPolicyLocked.
+	Locked bool
+
+	// NotElevated simulates a non-administrator process: SetScoped with
+	// platform.SourceSystem fails with platform.ErrNotElevated.
+	NotElevated bool
+
+	// Missing lists PATH directories ListPath reports as not existing, e.g.
+	// a shim directory whose package was removed.
+	Missing []string
 }
 
 func NewUserEnv() platform.UserEnv {
 	return &UserEnv{
-		vars: make(map[string]string),
-		path: nil,
+		vars:   make(map[string]string),
+		path:   nil,
+		Scopes: make(map[string]platform.EnvSource),
 	}
 }
 
@@ -29,20 +49,61 @@ func (u *UserEnv) Get(key string) (string, platform.EnvSource, error) {
 	if !ok {
 		return "", 0, fmt.Errorf("environment variable %q not set", key)
 	}
+	if scope, ok := u.Scopes[key]; ok {
+		return val, scope, nil
+	}
 	return val, platform.SourceUser, nil
 }
 
 func (u *UserEnv) Set(key, value string) error {
+	return u.SetScoped(key, value, platform.SourceUser)
+}
+
+func (u *UserEnv) SetScoped(key, value string, scope platform.EnvSource) error {
+	if platform.IsPathKey(key) {
+		return platform.ErrPathViaSet
+	}
+	if scope == platform.SourceSystem && u.NotElevated {
+		return fmt.Errorf("setting %s: %w", key, platform.ErrNotElevated)
+	}
+	if u.Locked {
+		return lockedError("setting " + key)
+	}
 	u.vars[key] = value
+	u.Scopes[key] = scope
 	return nil
 }
 
 func (u *UserEnv) Delete(key string) error {
+	if u.Locked {
+		return lockedError("deleting " + key)
+	}
+	delete(u.vars, key)
+	delete(u.Scopes, key)
+	return nil
+}
+
+// DeleteScoped deletes key if it was last written to scope; a variable in
+// another scope is left alone, as the real environments are separate.
+func (u *UserEnv) DeleteScoped(key string, scope platform.EnvSource) error {
+	if scope == platform.SourceSystem && u.NotElevated {
+		return fmt.Errorf("deleting %s: %w", key, platform.ErrNotElevated)
+	}
+	if u.Locked {
+		return lockedError("deleting " + key)
+	}
+	if current, ok := u.Scopes[key]; ok && current != scope {
+		return nil
+	}
 	delete(u.vars, key)
+	delete(u.Scopes, key)
 	return nil
 }
 
 func (u *UserEnv) AppendPath(dir string) error {
+	if u.Locked {
+		return lockedError("updating PATH")
+	}
 	for _, d := range u.path {
 		if d == dir {
 			return nil // deduplicate
@@ -53,6 +114,9 @@ func (u *UserEnv) AppendPath(dir string) error {
 }
 
 func (u *UserEnv) RemovePath(dir string) error {
+	if u.Locked {
+		return lockedError("updating PATH")
+	}
 	filtered := u.path[:0]
 	for _, d := range u.path {
 		if d != dir {
@@ -69,7 +133,7 @@ func (u *UserEnv) ListPath() ([]platform.PathEntry, error) {
 		entries[i] = platform.PathEntry{
 			Dir:    d,
 			Source: platform.SourceUser,
-			Exists: true,
+			Exists: !slices.Contains(u.Missing, d),
 		}
 	}
 	return entries, nil
@@ -84,6 +148,10 @@ type ProfileManager struct {
 	exists       bool
 	content      string // user's own content (outside managed block)
 	managedBlock string
+
+	// Locked simulates a policy-locked Documents folder: every write fails
+	// with an access-denied error wrapped in platform.ErrPolicyLocked.
+	Locked bool
 }
 
 func NewProfileManager(path string) platform.ProfileManager {
@@ -101,6 +169,9 @@ func (pm *ProfileManager) Exists() bool {
 }
 
 func (pm *ProfileManager) EnsureExists() error {
+	if pm.Locked && !pm.exists {
+		return lockedError("creating profile")
+	}
 	pm.exists = true
 	return nil
 }
@@ -132,11 +203,25 @@ func (pm *ProfileManager) ManagedBlock() (string, error) {
 }
 
 func (pm *ProfileManager) SetManagedBlock(content string) error {
+	if pm.Locked {
+		return lockedError("writing profile")
+	}
 	pm.managedBlock = content
 	return nil
 }
 
+func (pm *ProfileManager) RemoveManagedBlock() error {
+	if pm.Locked {
+		return lockedError("writing profile")
+	}
+	pm.managedBlock = ""
+	return nil
+}
+
 func (pm *ProfileManager) AppendToManagedBlock(line string) error {
+	if pm.Locked {
+		return lockedError("writing profile")
+	}
 	if pm.managedBlock == "" {
 		pm.managedBlock = line
 	} else {
@@ -149,6 +234,11 @@ func (pm *ProfileManager) Diff() (string, error) {
 	return "", nil
 }
 
+// lockedError is the error a policy-locked write returns on Windows.
+func lockedError(op string) error {
+	return platform.PolicyError(fmt.Errorf("%s: %w", op, fs.ErrPermission))
+}
+
 // ---------------------------------------------------------------------------
 // CertStore — in-memory implementation of platform.CertStore
 // ---------------------------------------------------------------------------
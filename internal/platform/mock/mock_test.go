@@ -1,6 +1,7 @@
 package mock
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/druarnfield/shhh/internal/platform"
@@ -45,6 +46,21 @@ func TestUserEnv_Delete(t *testing.T) {
 	}
 }
 
+func TestUserEnv_SetRejectsPath(t *testing.T) {
+	env := NewUserEnv()
+
+	for _, key := range []string{"PATH", "Path", "path"} {
+		err := env.Set(key, `C:\Windows`)
+		if !errors.Is(err, platform.ErrPathViaSet) {
+			t.Errorf("Set(%q) error = %v, want ErrPathViaSet", key, err)
+		}
+	}
+
+	if _, _, err := env.Get("PATH"); err == nil {
+		t.Error("PATH should not have been stored")
+	}
+}
+
 func TestUserEnv_AppendPath(t *testing.T) {
 	env := NewUserEnv()
 
@@ -152,3 +168,35 @@ func TestProfileManager_PreservesUserContent(t *testing.T) {
 		t.Errorf("ManagedBlock = %q", got)
 	}
 }
+
+func TestProfileManager_RemoveManagedBlock(t *testing.T) {
+	pm := NewProfileManager("/tmp/test_profile.ps1").(*ProfileManager)
+	pm.exists = true
+	pm.content = "# my custom stuff\n"
+	pm.SetManagedBlock("$env:FOO = \"bar\"")
+
+	if err := pm.RemoveManagedBlock(); err != nil {
+		t.Fatalf("RemoveManagedBlock: %v", err)
+	}
+	full, _ := pm.Read()
+	if full != "# my custom stuff\n" {
+		t.Errorf("Read() = %q, want only the user's content", full)
+	}
+}
+
+func TestLocked_WritesFailWithPolicyError(t *testing.T) {
+	env := NewUserEnv().(*UserEnv)
+	env.Locked = true
+	if err := env.Set("FOO", "bar"); !errors.Is(err, platform.ErrPolicyLocked) {
+		t.Errorf("Set error = %v, want ErrPolicyLocked", err)
+	}
+	if err := env.AppendPath(`C:\tools`); !errors.Is(err, platform.ErrPolicyLocked) {
+		t.Errorf("AppendPath error = %v, want ErrPolicyLocked", err)
+	}
+
+	pm := NewProfileManager("profile.ps1").(*ProfileManager)
+	pm.Locked = true
+	if err := pm.SetManagedBlock("x"); !errors.Is(err, platform.ErrPolicyLocked) {
+		t.Errorf("SetManagedBlock error = %v, want ErrPolicyLocked", err)
+	}
+}
@@ -1,13 +1,54 @@
 package platform
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
 
 var ErrNotSupported = errors.New("not supported on this platform")
 
+// ErrPathViaSet is returned by UserEnv.Set when asked to overwrite PATH.
+// PATH must be changed entry by entry so the existing value survives.
+var ErrPathViaSet = errors.New("PATH cannot be set directly; use AppendPath or RemovePath")
+
+// ErrPolicyLocked marks a write to the user environment or the PowerShell
+// profile that the OS refused. On managed machines this is usually group
+// policy locking HKCU\Environment or the Documents folder.
+var ErrPolicyLocked = errors.New("write denied, possibly by IT policy")
+
+// PolicyError wraps err with ErrPolicyLocked if it is an access-denied
+// error, so callers can give remediation advice instead of a raw registry
+// or file error. Other errors, and nil, are returned unchanged.
+func PolicyError(err error) error {
+	if err == nil || !errors.Is(err, fs.ErrPermission) || errors.Is(err, ErrPolicyLocked) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrPolicyLocked, err)
+}
+
+// ErrNotElevated is returned by UserEnv.SetScoped when asked to write the
+// machine-wide environment from a process without administrator rights.
+var ErrNotElevated = errors.New("writing the system environment needs an elevated (administrator) shell")
+
+// IsPathKey reports whether key names the PATH variable. Environment variable
+// names are case-insensitive on Windows, so "Path" and "PATH" both match.
+func IsPathKey(key string) bool {
+	return strings.EqualFold(key, "PATH")
+}
+
 type UserEnv interface {
 	Get(key string) (value string, source EnvSource, err error)
 	Set(key, value string) error
+	// SetScoped is Set for the given scope: SourceUser writes the user
+	// environment as Set does, SourceSystem the machine-wide one, which
+	// fails with ErrNotElevated unless shhh runs as an administrator.
+	SetScoped(key, value string, scope EnvSource) error
 	Delete(key string) error
+	// DeleteScoped is Delete for the given scope, for a variable SetScoped
+	// wrote there; SourceSystem needs an administrator as SetScoped does.
+	DeleteScoped(key string, scope EnvSource) error
 	AppendPath(dir string) error
 	RemovePath(dir string) error
 	ListPath() ([]PathEntry, error)
@@ -45,6 +86,7 @@ type ProfileManager interface {
 	Read() (string, error)
 	ManagedBlock() (string, error)
 	SetManagedBlock(content string) error
+	RemoveManagedBlock() error
 	AppendToManagedBlock(line string) error
 	Diff() (string, error)
 	Exists() bool
@@ -0,0 +1,82 @@
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"testing"
+)
+
+func TestPolicyError(t *testing.T) {
+	denied := &fs.PathError{Op: "open", Path: "profile.ps1", Err: fs.ErrPermission}
+
+	err := PolicyError(fmt.Errorf("writing profile: %w", denied))
+	if !errors.Is(err, ErrPolicyLocked) || !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("access denied not mapped: %v", err)
+	}
+	if again := PolicyError(err); again != err {
+		t.Errorf("already-mapped error wrapped twice: %v", again)
+	}
+
+	other := errors.New("disk full")
+	if got := PolicyError(other); got != other {
+		t.Errorf("other error changed: %v", got)
+	}
+	if PolicyError(nil) != nil {
+		t.Error("nil should stay nil")
+	}
+}
+
+// fakeEnv is a UserEnv whose writes all fail with err.
+type fakeEnv struct {
+	UserEnv
+	err error
+}
+
+func (f fakeEnv) Set(key, value string) error                    { return f.err }
+func (f fakeEnv) AppendPath(dir string) error                    { return f.err }
+func (f fakeEnv) DeleteScoped(key string, scope EnvSource) error { return f.err }
+
+func TestRestartTracker(t *testing.T) {
+	failing := TrackRestart(fakeEnv{err: ErrPolicyLocked})
+	if err := failing.Set("GOPATH", `C:\go`); err == nil {
+		t.Fatal("expected error from wrapped env")
+	}
+	if RestartRequired(failing) {
+		t.Error("failed write should not require a restart")
+	}
+
+	tracker := TrackRestart(fakeEnv{})
+	if RestartRequired(tracker) {
+		t.Error("restart required before any write")
+	}
+	if err := tracker.AppendPath(`C:\go\bin`); err != nil {
+		t.Fatal(err)
+	}
+	if !RestartRequired(tracker) {
+		t.Error("persistent write should require a restart")
+	}
+
+	scoped := TrackRestart(fakeEnv{})
+	if err := scoped.DeleteScoped("HTTP_PROXY", SourceSystem); err != nil {
+		t.Fatal(err)
+	}
+	if !RestartRequired(scoped) {
+		t.Error("scoped delete should require a restart")
+	}
+
+	if RestartRequired(fakeEnv{}) {
+		t.Error("untracked env should never require a restart")
+	}
+}
+
+func TestFreeSpace_MissingDir(t *testing.T) {
+	free, err := FreeSpace(filepath.Join(t.TempDir(), "not", "created", "yet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if free == 0 {
+		t.Error("FreeSpace = 0 for the temp dir's filesystem")
+	}
+}
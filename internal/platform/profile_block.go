@@ -0,0 +1,105 @@
+package platform
+
+import "strings"
+
+// utf8BOM is the byte order mark Windows PowerShell 5.1 needs to read a
+// profile as UTF-8 rather than the system ANSI code page.
+const utf8BOM = "\ufeff"
+
+// stripBOM removes a leading UTF-8 byte order mark, if present.
+func stripBOM(s string) string {
+	return strings.TrimPrefix(s, utf8BOM)
+}
+
+// encodeProfile returns the bytes to write for a profile, optionally
+// prefixed with a UTF-8 BOM.
+func encodeProfile(content string, bom bool) []byte {
+	content = stripBOM(content)
+	if bom {
+		content = utf8BOM + content
+	}
+	return []byte(content)
+}
+
+// ExtractManagedBlock returns the text between the managed block markers in
+// content, with line endings normalised to "\n". The second return value is
+// false when no complete block is present.
+func ExtractManagedBlock(content string) (string, bool) {
+	content = stripBOM(content)
+
+	start := strings.Index(content, ManagedBlockStart)
+	if start < 0 {
+		return "", false
+	}
+	rest := content[start+len(ManagedBlockStart):]
+	end := strings.Index(rest, ManagedBlockEnd)
+	if end < 0 {
+		return "", false
+	}
+
+	block := strings.ReplaceAll(rest[:end], "\r\n", "\n")
+	return strings.Trim(block, "\n"), true
+}
+
+// ReplaceManagedBlock returns content with its managed block replaced by
+// block, or with a new managed block appended if there was none. Everything
+// outside the markers is preserved.
+func ReplaceManagedBlock(content, block string) string {
+	content = stripBOM(content)
+	managed := ManagedBlockStart + "\n" + block + "\n" + ManagedBlockEnd + "\n"
+
+	if start := strings.Index(content, ManagedBlockStart); start >= 0 {
+		if end := strings.Index(content[start:], ManagedBlockEnd); end >= 0 {
+			after := content[start+end+len(ManagedBlockEnd):]
+			if strings.HasPrefix(after, "\r\n") {
+				after = after[2:]
+			} else {
+				after = strings.TrimPrefix(after, "\n")
+			}
+			return content[:start] + managed + after
+		}
+	}
+
+	if content == "" {
+		return managed
+	}
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content + "\n" + managed
+}
+
+// removeManagedBlock returns profile with its managed block, markers and
+// trailing newline included, cut out. The blank line ReplaceManagedBlock
+// puts before a block it appends goes too, so removing a block at the end
+// restores the profile exactly as it was before shhh wrote it. Everything
+// else is preserved. A profile without a complete block is returned
+// unchanged (apart from any BOM).
+func removeManagedBlock(profile string) string {
+	profile = stripBOM(profile)
+
+	start := strings.Index(profile, ManagedBlockStart)
+	if start < 0 {
+		return profile
+	}
+	end := strings.Index(profile[start:], ManagedBlockEnd)
+	if end < 0 {
+		return profile
+	}
+
+	before := profile[:start]
+	after := profile[start+end+len(ManagedBlockEnd):]
+	if strings.HasPrefix(after, "\r\n") {
+		after = after[2:]
+	} else {
+		after = strings.TrimPrefix(after, "\n")
+	}
+	if after == "" {
+		if strings.HasSuffix(before, "\r\n\r\n") {
+			before = before[:len(before)-2]
+		} else if strings.HasSuffix(before, "\n\n") {
+			before = before[:len(before)-1]
+		}
+	}
+	return before + after
+}
@@ -0,0 +1,103 @@
+package platform
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractManagedBlock_BOMPrefixed(t *testing.T) {
+	content := utf8BOM + ManagedBlockStart + "\r\n" +
+		"$env:HTTP_PROXY = \"http://proxy:8080\"\r\n" +
+		ManagedBlockEnd + "\r\n"
+
+	block, ok := ExtractManagedBlock(content)
+	if !ok {
+		t.Fatal("expected managed block to be found")
+	}
+	if block != `$env:HTTP_PROXY = "http://proxy:8080"` {
+		t.Errorf("block = %q", block)
+	}
+}
+
+func TestExtractManagedBlock_Missing(t *testing.T) {
+	if _, ok := ExtractManagedBlock("Set-Alias ll ls\n"); ok {
+		t.Error("expected no managed block")
+	}
+	if _, ok := ExtractManagedBlock(ManagedBlockStart + "\nno end marker\n"); ok {
+		t.Error("expected no managed block without end marker")
+	}
+}
+
+func TestReplaceManagedBlock_PreservesUserContent(t *testing.T) {
+	content := utf8BOM + "# mine\n" + ManagedBlockStart + "\nold\n" + ManagedBlockEnd + "\nSet-Alias ll ls\n"
+
+	got := ReplaceManagedBlock(content, "new")
+	want := "# mine\n" + ManagedBlockStart + "\nnew\n" + ManagedBlockEnd + "\nSet-Alias ll ls\n"
+	if got != want {
+		t.Errorf("ReplaceManagedBlock =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestReplaceManagedBlock_AppendsWhenMissing(t *testing.T) {
+	got := ReplaceManagedBlock("Set-Alias ll ls", "new")
+	if !strings.HasPrefix(got, "Set-Alias ll ls\n") {
+		t.Errorf("user content not preserved: %q", got)
+	}
+	if block, ok := ExtractManagedBlock(got); !ok || block != "new" {
+		t.Errorf("block = %q, %v", block, ok)
+	}
+}
+
+func TestRemoveManagedBlock(t *testing.T) {
+	block := ManagedBlockStart + "\n$env:HTTP_PROXY = \"http://proxy:8080\"\n" + ManagedBlockEnd + "\n"
+	crlfBlock := strings.ReplaceAll(block, "\n", "\r\n")
+
+	tests := []struct {
+		name, content, want string
+	}{
+		{"first", block + "Set-Alias ll ls\n", "Set-Alias ll ls\n"},
+		{"middle", "# mine\n" + block + "Set-Alias ll ls\n", "# mine\nSet-Alias ll ls\n"},
+		{"last", "# mine\n\n" + block, "# mine\n"},
+		{"empty", "", ""},
+		{"only", block, ""},
+		{"only with BOM", utf8BOM + block, ""},
+		{"no trailing newline", "# mine\n" + strings.TrimSuffix(block, "\n"), "# mine\n"},
+		{"last CRLF", "# mine\r\n\r\n" + crlfBlock, "# mine\r\n"},
+		{"middle CRLF", "# mine\r\n" + crlfBlock + "Set-Alias ll ls\r\n", "# mine\r\nSet-Alias ll ls\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := removeManagedBlock(tt.content); got != tt.want {
+				t.Errorf("removeManagedBlock =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveManagedBlock_UndoesAppend(t *testing.T) {
+	original := "Set-Alias ll ls\n"
+	if got := removeManagedBlock(ReplaceManagedBlock(original, "new")); got != original {
+		t.Errorf("got %q, want the original %q", got, original)
+	}
+}
+
+func TestRemoveManagedBlock_Missing(t *testing.T) {
+	for _, content := range []string{"Set-Alias ll ls\n", ManagedBlockStart + "\nno end marker\n"} {
+		if got := removeManagedBlock(content); got != content {
+			t.Errorf("removeManagedBlock(%q) = %q, want it unchanged", content, got)
+		}
+	}
+}
+
+func TestEncodeProfile_BOM(t *testing.T) {
+	with := encodeProfile("é", true)
+	if !strings.HasPrefix(string(with), utf8BOM) {
+		t.Error("expected BOM prefix")
+	}
+	if strings.Count(string(encodeProfile(utf8BOM+"é", true)), utf8BOM) != 1 {
+		t.Error("BOM should not be doubled")
+	}
+	if strings.HasPrefix(string(encodeProfile(utf8BOM+"é", false)), utf8BOM) {
+		t.Error("BOM should be stripped when disabled")
+	}
+}
@@ -2,28 +2,157 @@
 
 package platform
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
-type windowsProfileManager struct{}
+	"github.com/druarnfield/shhh/internal/exec"
+)
 
-func NewProfileManager() ProfileManager { return &windowsProfileManager{} }
+// profileFile is the Windows PowerShell 5.1 console host's profile, the one
+// shhh manages.
+const profileFile = "Microsoft.PowerShell_profile.ps1"
+
+type windowsProfileManager struct {
+	runner   exec.Runner
+	writeBOM bool
+
+	once sync.Once
+	path string
+}
+
+// NewProfileManager returns a ProfileManager for the Windows PowerShell 5.1
+// profile. When writeBOM is true the profile is written as UTF-8 with a BOM,
+// which 5.1 needs to decode non-ASCII content correctly. runner is used to
+// ask PowerShell where the profile lives; see Path.
+func NewProfileManager(writeBOM bool, runner exec.Runner) ProfileManager {
+	return &windowsProfileManager{
+		runner:   runner,
+		writeBOM: writeBOM,
+	}
+}
+
+// Path returns the profile's path. Documents is often redirected (e.g. to
+// OneDrive), so the first call asks PowerShell where its profile directory
+// is, falling back to ~/Documents/WindowsPowerShell; the answer is cached.
 func (w *windowsProfileManager) Path() string {
-	return ""
+	w.once.Do(func() {
+		if dir := w.queryProfileDir(); dir != "" {
+			w.path = filepath.Join(dir, profileFile)
+			return
+		}
+		home, _ := os.UserHomeDir()
+		w.path = filepath.Join(home, "Documents", "WindowsPowerShell", profileFile)
+	})
+	return w.path
 }
+
+// queryProfileDir returns the directory of PowerShell's own idea of the
+// current user's profile, or "" if it can't be asked.
+func (w *windowsProfileManager) queryProfileDir() string {
+	if w.runner == nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	out, err := exec.RunOutput(ctx, w.runner, "powershell", "-NoProfile", "-Command", "$PROFILE.CurrentUserAllHosts")
+	if err != nil || !filepath.IsAbs(out) || strings.Contains(out, "\n") {
+		return ""
+	}
+	return filepath.Dir(out)
+}
+
+// Read returns the profile content with any BOM removed. A missing profile
+// reads as empty.
 func (w *windowsProfileManager) Read() (string, error) {
-	return "", errors.New("not yet implemented")
+	data, err := os.ReadFile(w.Path())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("reading profile: %w", err)
+	}
+	return stripBOM(string(data)), nil
 }
+
 func (w *windowsProfileManager) ManagedBlock() (string, error) {
-	return "", errors.New("not yet implemented")
+	content, err := w.Read()
+	if err != nil {
+		return "", err
+	}
+	block, _ := ExtractManagedBlock(content)
+	return block, nil
 }
+
 func (w *windowsProfileManager) SetManagedBlock(content string) error {
-	return errors.New("not yet implemented")
+	existing, err := w.Read()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(w.Path()), 0755); err != nil {
+		return PolicyError(fmt.Errorf("creating profile directory: %w", err))
+	}
+	updated := ReplaceManagedBlock(existing, content)
+	if err := os.WriteFile(w.Path(), encodeProfile(updated, w.writeBOM), 0644); err != nil {
+		return PolicyError(fmt.Errorf("writing profile: %w", err))
+	}
+	return nil
 }
+
+// RemoveManagedBlock deletes the managed block and its markers, leaving the
+// rest of the profile as it is. A profile without a block is not touched.
+func (w *windowsProfileManager) RemoveManagedBlock() error {
+	existing, err := w.Read()
+	if err != nil {
+		return err
+	}
+	updated := removeManagedBlock(existing)
+	if updated == existing {
+		return nil
+	}
+	if err := os.WriteFile(w.Path(), encodeProfile(updated, w.writeBOM), 0644); err != nil {
+		return PolicyError(fmt.Errorf("writing profile: %w", err))
+	}
+	return nil
+}
+
 func (w *windowsProfileManager) AppendToManagedBlock(line string) error {
-	return errors.New("not yet implemented")
+	block, err := w.ManagedBlock()
+	if err != nil {
+		return err
+	}
+	if block == "" {
+		block = line
+	} else {
+		block = block + "\n" + line
+	}
+	return w.SetManagedBlock(block)
 }
+
 func (w *windowsProfileManager) Diff() (string, error) {
 	return "", errors.New("not yet implemented")
 }
-func (w *windowsProfileManager) Exists() bool      { return false }
-func (w *windowsProfileManager) EnsureExists() error { return errors.New("not yet implemented") }
+
+func (w *windowsProfileManager) Exists() bool {
+	_, err := os.Stat(w.Path())
+	return err == nil
+}
+
+func (w *windowsProfileManager) EnsureExists() error {
+	if w.Exists() {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(w.Path()), 0755); err != nil {
+		return PolicyError(fmt.Errorf("creating profile directory: %w", err))
+	}
+	if err := os.WriteFile(w.Path(), encodeProfile("", w.writeBOM), 0644); err != nil {
+		return PolicyError(fmt.Errorf("creating profile: %w", err))
+	}
+	return nil
+}
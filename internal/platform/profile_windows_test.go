@@ -0,0 +1,48 @@
+//go:build windows
+
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/druarnfield/shhh/internal/exec"
+)
+
+const profileDirCmd = "powershell -NoProfile -Command $PROFILE.CurrentUserAllHosts"
+
+func TestQueryProfileDir(t *testing.T) {
+	tests := []struct {
+		name   string
+		result *exec.Result // nil: the command fails
+		want   string
+	}{
+		{"redirected documents", &exec.Result{Stdout: `C:\Users\dev\OneDrive - Corp\Documents\WindowsPowerShell\profile.ps1` + "\r\n"},
+			`C:\Users\dev\OneDrive - Corp\Documents\WindowsPowerShell`},
+		{"powershell fails", nil, ""},
+		{"relative path", &exec.Result{Stdout: "profile.ps1\r\n"}, ""},
+		{"extra output", &exec.Result{Stdout: "WARNING: something\r\nC:\\Users\\dev\\Documents\\WindowsPowerShell\\profile.ps1\r\n"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &exec.MockRunner{Results: map[string]exec.Result{}}
+			if tt.result != nil {
+				runner.Results[profileDirCmd] = *tt.result
+			}
+			w := &windowsProfileManager{runner: runner}
+			if got := w.queryProfileDir(); got != tt.want {
+				t.Errorf("queryProfileDir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfilePath_FallsBackToDocuments(t *testing.T) {
+	w := &windowsProfileManager{runner: &exec.MockRunner{Results: map[string]exec.Result{}}}
+	home, _ := os.UserHomeDir()
+	want := filepath.Join(home, "Documents", "WindowsPowerShell", profileFile)
+	if got := w.Path(); got != want {
+		t.Errorf("Path() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,63 @@
+package platform
+
+import "strings"
+
+// ProxyConfig is a proxy setup found on the machine, in the form shhh's
+// [proxy] settings take: proxy URLs and a NO_PROXY list.
+type ProxyConfig struct {
+	HTTP, HTTPS string
+	NoProxy     string
+}
+
+// parseWinINETProxy converts WinINET's ProxyServer and ProxyOverride
+// values. ProxyServer is either "host:port", used for every scheme, or a
+// per-scheme list such as "http=host:80;https=host:443;ftp=host:21".
+// ProxyOverride is a ';'-separated bypass list where "<local>" means hosts
+// without a dot and "*.example.com" a domain; entries NO_PROXY can't
+// express, such as "10.*", are dropped.
+func parseWinINETProxy(server, override string) ProxyConfig {
+	var p ProxyConfig
+	if !strings.Contains(server, "=") {
+		p.HTTP = proxyURL(server)
+		p.HTTPS = p.HTTP
+	} else {
+		for _, entry := range strings.Split(server, ";") {
+			scheme, addr, ok := strings.Cut(strings.TrimSpace(entry), "=")
+			if !ok {
+				continue
+			}
+			switch strings.ToLower(scheme) {
+			case "http":
+				p.HTTP = proxyURL(addr)
+			case "https":
+				p.HTTPS = proxyURL(addr)
+			}
+		}
+	}
+
+	var bypass []string
+	for _, entry := range strings.Split(override, ";") {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+		case strings.EqualFold(entry, "<local>"):
+			bypass = append(bypass, "localhost", "127.0.0.1")
+		case strings.HasPrefix(entry, "*.") && !strings.Contains(entry[2:], "*"):
+			bypass = append(bypass, entry[1:])
+		case !strings.Contains(entry, "*"):
+			bypass = append(bypass, entry)
+		}
+	}
+	p.NoProxy = strings.Join(bypass, ",")
+	return p
+}
+
+// proxyURL turns a WinINET proxy address, usually a bare "host:port", into
+// a URL.
+func proxyURL(addr string) string {
+	addr = strings.TrimSpace(addr)
+	if addr == "" || strings.Contains(addr, "://") {
+		return addr
+	}
+	return "http://" + addr
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package platform
+
+// DetectSystemProxy reports the system proxy. Only Windows' WinINET
+// settings are read; elsewhere none is found.
+func DetectSystemProxy() (ProxyConfig, bool) {
+	return ProxyConfig{}, false
+}
@@ -0,0 +1,24 @@
+package platform
+
+import "testing"
+
+func TestParseWinINETProxy(t *testing.T) {
+	tests := []struct {
+		server, override string
+		want             ProxyConfig
+	}{
+		{"proxy.example.com:8080", "", ProxyConfig{HTTP: "http://proxy.example.com:8080", HTTPS: "http://proxy.example.com:8080"}},
+		{"http=web:80;https=secure:443;ftp=files:21", "", ProxyConfig{HTTP: "http://web:80", HTTPS: "http://secure:443"}},
+		{"https=secure:443", "", ProxyConfig{HTTPS: "http://secure:443"}},
+		{"http://proxy:3128", "<local>;*.corp.example.com;10.*;intranet", ProxyConfig{
+			HTTP:    "http://proxy:3128",
+			HTTPS:   "http://proxy:3128",
+			NoProxy: "localhost,127.0.0.1,.corp.example.com,intranet",
+		}},
+	}
+	for _, tt := range tests {
+		if got := parseWinINETProxy(tt.server, tt.override); got != tt.want {
+			t.Errorf("parseWinINETProxy(%q, %q) = %+v, want %+v", tt.server, tt.override, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+//go:build windows
+
+package platform
+
+import "golang.org/x/sys/windows/registry"
+
+// internetSettingsKey holds the WinINET (Internet Options) proxy settings,
+// which browsers and most Windows software use.
+const internetSettingsKey = `Software\Microsoft\Windows\CurrentVersion\Internet Settings`
+
+// DetectSystemProxy reports the proxy set in Internet Options, if one is
+// enabled. Proxy auto-config (PAC) scripts aren't evaluated.
+func DetectSystemProxy() (ProxyConfig, bool) {
+	k, err := registry.OpenKey(registry.CURRENT_USER, internetSettingsKey, registry.QUERY_VALUE)
+	if err != nil {
+		return ProxyConfig{}, false
+	}
+	defer k.Close()
+
+	if enabled, _, err := k.GetIntegerValue("ProxyEnable"); err != nil || enabled == 0 {
+		return ProxyConfig{}, false
+	}
+	server, _, err := k.GetStringValue("ProxyServer")
+	if err != nil || server == "" {
+		return ProxyConfig{}, false
+	}
+	override, _, _ := k.GetStringValue("ProxyOverride")
+
+	p := parseWinINETProxy(server, override)
+	return p, p.HTTP != "" || p.HTTPS != ""
+}
@@ -0,0 +1,61 @@
+package platform
+
+import "sync/atomic"
+
+// RestartTracker wraps a UserEnv and records whether any write reached the
+// persistent user environment. Setup steps also os.Setenv each value, which
+// children of shhh inherit, but the shell that launched shhh only sees
+// persistent changes once it is restarted.
+type RestartTracker struct {
+	UserEnv
+	changed atomic.Bool
+}
+
+// TrackRestart wraps env so RestartRequired reports whether it was written.
+func TrackRestart(env UserEnv) *RestartTracker {
+	return &RestartTracker{UserEnv: env}
+}
+
+func (t *RestartTracker) Set(key, value string) error {
+	return t.record(t.UserEnv.Set(key, value))
+}
+
+func (t *RestartTracker) SetScoped(key, value string, scope EnvSource) error {
+	return t.record(t.UserEnv.SetScoped(key, value, scope))
+}
+
+func (t *RestartTracker) Delete(key string) error {
+	return t.record(t.UserEnv.Delete(key))
+}
+
+func (t *RestartTracker) DeleteScoped(key string, scope EnvSource) error {
+	return t.record(t.UserEnv.DeleteScoped(key, scope))
+}
+
+func (t *RestartTracker) AppendPath(dir string) error {
+	return t.record(t.UserEnv.AppendPath(dir))
+}
+
+func (t *RestartTracker) RemovePath(dir string) error {
+	return t.record(t.UserEnv.RemovePath(dir))
+}
+
+// RestartRequired reports whether a persistent change was made.
+func (t *RestartTracker) RestartRequired() bool {
+	return t.changed.Load()
+}
+
+func (t *RestartTracker) record(err error) error {
+	if err == nil {
+		t.changed.Store(true)
+	}
+	return err
+}
+
+// RestartRequired reports whether env made a persistent change the
+// current shell won't see. Environments not wrapped by TrackRestart
+// report false.
+func RestartRequired(env UserEnv) bool {
+	t, ok := env.(interface{ RestartRequired() bool })
+	return ok && t.RestartRequired()
+}
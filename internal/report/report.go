@@ -0,0 +1,268 @@
+// Package report renders a setup run as a Markdown or HTML document for
+// onboarding sign-off: what was configured, which modules ran, what each
+// step did and how long it took (setup --report). Unlike the state file's
+// run history it is meant to be read by people, e.g. attached to a ticket.
+package report
+
+import (
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/druarnfield/shhh/internal/config"
+	"github.com/druarnfield/shhh/internal/module"
+)
+
+// Format is a report's document format.
+type Format int
+
+const (
+	Markdown Format = iota
+	HTML
+)
+
+// FormatFor picks the format from path's extension: .md or .markdown for
+// Markdown, .html or .htm for HTML.
+func FormatFor(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return Markdown, nil
+	case ".html", ".htm":
+		return HTML, nil
+	}
+	return 0, fmt.Errorf("%s: report must be a .md or .html file", path)
+}
+
+// Report is everything a rendered report shows.
+type Report struct {
+	Generated  time.Time
+	ConfigPath string
+	DryRun     bool
+
+	// Status is the one-line outcome, e.g. "Succeeded"; Failed is true
+	// when any module failed.
+	Status string
+	Failed bool
+
+	Settings []Setting
+	Modules  []Module
+	Warnings []string
+}
+
+// Setting is one config value in the report, with any password masked.
+type Setting struct {
+	Name, Value string
+}
+
+// Module is one module's part of the run.
+type Module struct {
+	ID, Name string
+	Status   string
+	Failed   bool
+	Error    string
+	Steps    []Step
+}
+
+// Step is one step's outcome. Duration is empty when none was recorded
+// (dry runs, steps not reached).
+type Step struct {
+	Name     string
+	Outcome  string
+	Duration string
+	Attempts int
+}
+
+// New builds the report of a run of reg's modules with cfg, read from
+// cfgPath, that produced results.
+func New(cfg *config.Config, cfgPath string, reg *module.Registry, results []module.ModuleResult, dryRun bool) *Report {
+	r := &Report{
+		Generated:  time.Now(),
+		ConfigPath: cfgPath,
+		DryRun:     dryRun,
+		Settings:   settings(cfg),
+	}
+
+	failed := 0
+	for _, res := range results {
+		m := Module{ID: res.ModuleID, Name: res.ModuleID, Status: "done"}
+		mod := reg.Get(res.ModuleID)
+		if mod != nil {
+			m.Name = mod.Name
+		}
+		switch {
+		case errors.Is(res.Err, module.ErrDependencyFailed):
+			m.Status, m.Failed = "not run", true
+		case res.Err != nil:
+			m.Status, m.Failed = fmt.Sprintf("failed at %q", res.FailedStep), true
+		case res.Unsupported != "":
+			m.Status = "skipped: " + res.Unsupported
+		}
+		if res.Err != nil {
+			m.Error = res.Err.Error()
+			failed++
+		}
+		if mod != nil {
+			for _, step := range mod.Steps {
+				s := Step{
+					Name:     step.Name,
+					Outcome:  res.Outcomes[step.Name].String(),
+					Attempts: res.Attempts[step.Name],
+				}
+				if d, ok := res.Durations[step.Name]; ok {
+					s.Duration = d.Round(time.Millisecond).String()
+				}
+				m.Steps = append(m.Steps, s)
+			}
+		}
+		for _, name := range res.RetriedSteps() {
+			r.Warnings = append(r.Warnings, fmt.Sprintf("%s: %s", m.Name, res.AttemptNote(name)))
+		}
+		r.Modules = append(r.Modules, m)
+	}
+
+	switch {
+	case failed > 0:
+		r.Failed = true
+		r.Status = fmt.Sprintf("Failed (%d of %d modules)", failed, len(results))
+	case dryRun:
+		r.Status = "Dry run: nothing was changed"
+	default:
+		r.Status = "Succeeded"
+	}
+	return r
+}
+
+// settings lists the config values worth recording, leaving out empty ones.
+func settings(cfg *config.Config) []Setting {
+	all := []Setting{
+		{"Organisation", cfg.Org.Name},
+		{"Proxy mode", cfg.Proxy.Mode},
+		{"HTTP proxy", redactURL(cfg.Proxy.HTTP)},
+		{"HTTPS proxy", redactURL(cfg.Proxy.HTTPS)},
+		{"NO_PROXY", cfg.EffectiveNoProxy()},
+		{"Certificate source", redactURL(cfg.Certs.Source)},
+		{"CA bundle", cfg.CABundlePath()},
+		{"GitLab host", cfg.GitLab.Host},
+		{"PyPI mirror", redactURL(cfg.Registries.PyPIMirror)},
+		{"npm registry", redactURL(cfg.Registries.NPMRegistry)},
+		{"Go proxy", redactURL(cfg.Registries.GoProxy)},
+		{"Cargo registry", redactURL(cfg.Registries.CargoRegistry)},
+		{"Python", cfg.Python.Version},
+		{"Go", cfg.Golang.Version},
+		{"Node.js", cfg.Node.Version},
+		{"Rust", cfg.Rust.Version},
+		{"Install root", cfg.Paths.Root},
+	}
+	var set []Setting
+	for _, s := range all {
+		if s.Value != "" {
+			set = append(set, s)
+		}
+	}
+	return set
+}
+
+// redactURL masks the password in a URL such as a proxy's user:pass@host.
+// Anything else is returned unchanged.
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return value
+	}
+	return u.Redacted()
+}
+
+// Write renders the report to w in format f.
+func (r *Report) Write(w io.Writer, f Format) error {
+	if f == HTML {
+		return htmlReport.Execute(w, r)
+	}
+	return markdownReport.Execute(w, r)
+}
+
+// mdCell makes s safe inside a Markdown table cell.
+func mdCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+var markdownReport = template.Must(template.New("report.md").Funcs(template.FuncMap{"cell": mdCell}).Parse(
+	`# shhh setup report
+
+- **Status:** {{.Status}}
+- **Generated:** {{.Generated.Format "2006-01-02 15:04 MST"}}
+- **Config:** {{if .ConfigPath}}` + "`{{.ConfigPath}}`" + `{{else}}defaults{{end}}
+
+## Configuration
+
+| Setting | Value |
+| --- | --- |
+{{range .Settings}}| {{.Name}} | {{cell .Value}} |
+{{end}}
+## Modules
+{{range .Modules}}
+### {{.Name}}: {{.Status}}
+
+| Step | Outcome | Duration |
+| --- | --- | --- |
+{{range .Steps}}| {{cell .Name}} | {{.Outcome}}{{if gt .Attempts 1}} ({{.Attempts}} attempts){{end}} | {{.Duration}} |
+{{end}}{{if .Error}}
+**Error:** {{cell .Error}}
+{{end}}{{else}}
+No modules ran.
+{{end}}
+## Warnings
+
+{{range .Warnings}}- {{.}}
+{{else}}None.
+{{end}}`))
+
+var htmlReport = htmltemplate.Must(htmltemplate.New("report.html").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>shhh setup report</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em auto; max-width: 60em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+.failed { color: #b00020; }
+.ok { color: #1b5e20; }
+</style>
+</head>
+<body>
+<h1>shhh setup report</h1>
+<ul>
+<li><strong>Status:</strong> <span class="{{if .Failed}}failed{{else}}ok{{end}}">{{.Status}}</span></li>
+<li><strong>Generated:</strong> {{.Generated.Format "2006-01-02 15:04 MST"}}</li>
+<li><strong>Config:</strong> {{if .ConfigPath}}<code>{{.ConfigPath}}</code>{{else}}defaults{{end}}</li>
+</ul>
+
+<h2>Configuration</h2>
+<table>
+<tr><th>Setting</th><th>Value</th></tr>
+{{range .Settings}}<tr><td>{{.Name}}</td><td>{{.Value}}</td></tr>
+{{end}}</table>
+
+<h2>Modules</h2>
+{{range .Modules}}<h3>{{.Name}}: <span class="{{if .Failed}}failed{{else}}ok{{end}}">{{.Status}}</span></h3>
+<table>
+<tr><th>Step</th><th>Outcome</th><th>Duration</th></tr>
+{{range .Steps}}<tr><td>{{.Name}}</td><td>{{.Outcome}}{{if gt .Attempts 1}} ({{.Attempts}} attempts){{end}}</td><td>{{.Duration}}</td></tr>
+{{end}}</table>
+{{if .Error}}<p class="failed"><strong>Error:</strong> {{.Error}}</p>
+{{end}}{{else}}<p>No modules ran.</p>
+{{end}}
+<h2>Warnings</h2>
+{{if .Warnings}}<ul>
+{{range .Warnings}}<li>{{.}}</li>
+{{end}}</ul>{{else}}<p>None.</p>{{end}}
+</body>
+</html>
+`))
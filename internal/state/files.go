@@ -0,0 +1,126 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// ManagedFile is a file shhh wrote, with the SHA-256 (hex) of the content it
+// last wrote, so teardown can tell whether the user has changed it since.
+type ManagedFile struct {
+	Path string `json:"path"`
+	Hash string `json:"hash"`
+}
+
+// FileStatus compares a managed file on disk with what shhh wrote.
+type FileStatus int
+
+const (
+	FileUnchanged FileStatus = iota // content matches Hash
+	FileModified                    // changed since shhh wrote it
+	FileMissing                     // deleted since shhh wrote it
+)
+
+// Status reports whether f still holds what shhh wrote.
+func (f ManagedFile) Status() (FileStatus, error) {
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return FileMissing, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if hashContent(data) != f.Hash {
+		return FileModified, nil
+	}
+	return FileUnchanged, nil
+}
+
+// AddManagedFile records that shhh wrote content to path for the module
+// owner, replacing any earlier record for path.
+func (s *State) AddManagedFile(owner, path string, content []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file := ManagedFile{Path: path, Hash: hashContent(content)}
+	if i := s.managedFileIndex(path); i >= 0 {
+		s.ManagedFiles[i] = file
+	} else {
+		s.ManagedFiles = append(s.ManagedFiles, file)
+	}
+	if o := s.owned(owner); o != nil && !contains(o.Files, path) {
+		o.Files = append(o.Files, path)
+	}
+}
+
+// LookupManagedFile returns the record for path, if shhh wrote it.
+func (s *State) LookupManagedFile(path string) (ManagedFile, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.managedFileIndex(path); i >= 0 {
+		return s.ManagedFiles[i], true
+	}
+	return ManagedFile{}, false
+}
+
+// Remove deletes the file if it still holds what shhh wrote. A modified
+// or missing file is left alone and removed is false.
+func (f ManagedFile) Remove() (removed bool, err error) {
+	status, err := f.Status()
+	if err != nil || status != FileUnchanged {
+		return false, err
+	}
+	if err := os.Remove(f.Path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RemoveManagedFile deletes path if it still holds what shhh wrote, and
+// stops tracking it. A file the user has modified is left in place, still
+// tracked, and removed is false. A file that is already gone is forgotten.
+func (s *State) RemoveManagedFile(path string) (removed bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.managedFileIndex(path)
+	if i < 0 {
+		return false, nil
+	}
+	f := s.ManagedFiles[i]
+	status, err := f.Status()
+	if err != nil || status == FileModified {
+		return false, err
+	}
+	if removed, err = f.Remove(); err != nil {
+		return false, err
+	}
+	s.forgetManagedFile(path)
+	return removed, nil
+}
+
+// forgetManagedFile stops tracking path, including in every module's
+// ownership record, without touching the file.
+func (s *State) forgetManagedFile(path string) {
+	if i := s.managedFileIndex(path); i >= 0 {
+		s.ManagedFiles = append(s.ManagedFiles[:i], s.ManagedFiles[i+1:]...)
+	}
+	for _, o := range s.Owners {
+		o.Files = remove(o.Files, path)
+	}
+}
+
+func (s *State) managedFileIndex(path string) int {
+	for i, f := range s.ManagedFiles {
+		if f.Path == path {
+			return i
+		}
+	}
+	return -1
+}
+
+func hashContent(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
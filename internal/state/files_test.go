@@ -0,0 +1,90 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestState_AddManagedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ca-bundle.pem")
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &State{}
+	s.AddManagedFile("base", path, []byte("v1"))
+	s.AddManagedFile("base", path, []byte("v1"))
+
+	if len(s.ManagedFiles) != 1 {
+		t.Fatalf("ManagedFiles = %v, want one entry", s.ManagedFiles)
+	}
+	if got := s.Owners["base"].Files; len(got) != 1 || got[0] != path {
+		t.Errorf("base owns %v, want [%s]", got, path)
+	}
+	if status, err := s.ManagedFiles[0].Status(); err != nil || status != FileUnchanged {
+		t.Errorf("Status = %v, %v; want FileUnchanged", status, err)
+	}
+
+	// A rewrite by shhh updates the recorded hash.
+	os.WriteFile(path, []byte("v2"), 0644)
+	if status, _ := s.ManagedFiles[0].Status(); status != FileModified {
+		t.Errorf("Status after edit = %v, want FileModified", status)
+	}
+	s.AddManagedFile("base", path, []byte("v2"))
+	if status, _ := s.ManagedFiles[0].Status(); status != FileUnchanged {
+		t.Errorf("Status after re-record = %v, want FileUnchanged", status)
+	}
+}
+
+func TestState_RemoveManagedFile(t *testing.T) {
+	dir := t.TempDir()
+	unchanged := filepath.Join(dir, "unchanged")
+	edited := filepath.Join(dir, "edited")
+	gone := filepath.Join(dir, "gone")
+
+	s := &State{}
+	for _, path := range []string{unchanged, edited, gone} {
+		os.WriteFile(path, []byte("shhh"), 0644)
+		s.AddManagedFile("", path, []byte("shhh"))
+	}
+	os.WriteFile(edited, []byte("mine now"), 0644)
+	os.Remove(gone)
+
+	if removed, err := s.RemoveManagedFile(unchanged); err != nil || !removed {
+		t.Errorf("unchanged: removed = %v, err = %v; want true, nil", removed, err)
+	}
+	if _, err := os.Stat(unchanged); !os.IsNotExist(err) {
+		t.Error("unchanged file should be deleted")
+	}
+
+	if removed, err := s.RemoveManagedFile(edited); err != nil || removed {
+		t.Errorf("edited: removed = %v, err = %v; want false, nil", removed, err)
+	}
+	if _, err := os.Stat(edited); err != nil {
+		t.Error("edited file should be kept")
+	}
+
+	if removed, err := s.RemoveManagedFile(gone); err != nil || removed {
+		t.Errorf("gone: removed = %v, err = %v; want false, nil", removed, err)
+	}
+
+	if len(s.ManagedFiles) != 1 || s.ManagedFiles[0].Path != edited {
+		t.Errorf("ManagedFiles = %v, want only the edited file still tracked", s.ManagedFiles)
+	}
+}
+
+func TestState_PruneFiles(t *testing.T) {
+	s := &State{InstalledModules: []string{"base", "node"}}
+	s.AddManagedFile("base", "/ca-bundle.pem", []byte("certs"))
+	s.AddManagedFile("node", "/home/u/.npmrc", []byte("registry"))
+
+	res := s.Prune([]string{"base"})
+
+	if len(res.Files) != 1 || res.Files[0].Path != "/home/u/.npmrc" {
+		t.Errorf("pruned files = %v, want .npmrc", res.Files)
+	}
+	if len(s.ManagedFiles) != 1 || s.ManagedFiles[0].Path != "/ca-bundle.pem" {
+		t.Errorf("ManagedFiles = %v, want the CA bundle kept", s.ManagedFiles)
+	}
+}
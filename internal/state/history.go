@@ -0,0 +1,39 @@
+package state
+
+import "time"
+
+// MaxHistory is how many runs History keeps; older runs are dropped.
+const MaxHistory = 20
+
+// Run records the outcome of one setup run for 'shhh history'.
+type Run struct {
+	Time     time.Time       `json:"time"`
+	Selected []string        `json:"selected"`
+	DryRun   bool            `json:"dry_run,omitempty"`
+	Modules  []ModuleOutcome `json:"modules"`
+}
+
+// ModuleOutcome is one module's result within a Run.
+type ModuleOutcome struct {
+	ID         string `json:"id"`
+	Completed  int    `json:"completed"`
+	Skipped    int    `json:"skipped"`
+	FailedStep string `json:"failed_step,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Succeeded reports whether the module finished without error.
+func (o ModuleOutcome) Succeeded() bool {
+	return o.Error == ""
+}
+
+// AddRun appends r to the history, keeping only the most recent MaxHistory
+// runs so the state file stays small.
+func (s *State) AddRun(r Run) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.History = append(s.History, r)
+	if over := len(s.History) - MaxHistory; over > 0 {
+		s.History = append([]Run(nil), s.History[over:]...)
+	}
+}
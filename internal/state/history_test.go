@@ -0,0 +1,53 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestState_AddRunCapsHistory(t *testing.T) {
+	s := &State{}
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	for i := range MaxHistory + 5 {
+		s.AddRun(Run{Time: start.Add(time.Duration(i) * time.Hour)})
+	}
+
+	if len(s.History) != MaxHistory {
+		t.Fatalf("len(History) = %d, want %d", len(s.History), MaxHistory)
+	}
+	if want := start.Add(5 * time.Hour); !s.History[0].Time.Equal(want) {
+		t.Errorf("oldest run = %v, want %v", s.History[0].Time, want)
+	}
+	if want := start.Add(time.Duration(MaxHistory+4) * time.Hour); !s.History[MaxHistory-1].Time.Equal(want) {
+		t.Errorf("newest run = %v, want %v", s.History[MaxHistory-1].Time, want)
+	}
+}
+
+func TestState_HistoryRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s := &State{}
+	s.AddRun(Run{
+		Time:     time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC),
+		Selected: []string{"node"},
+		Modules: []ModuleOutcome{
+			{ID: "base", Completed: 1, Skipped: 4},
+			{ID: "node", FailedStep: "Install Node.js", Error: "boom"},
+		},
+	})
+
+	if err := Save(path, s); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if len(loaded.History) != 1 || len(loaded.History[0].Modules) != 2 {
+		t.Fatalf("History = %+v", loaded.History)
+	}
+	if node := loaded.History[0].Modules[1]; node.Succeeded() || node.FailedStep != "Install Node.js" {
+		t.Errorf("node outcome = %+v", node)
+	}
+}
@@ -4,9 +4,15 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
 	"time"
 )
 
+// State records what shhh has set up. Its methods are safe for concurrent
+// use, so steps of modules running in parallel can record what they set.
+// Fields are not guarded: outside base modules, which never run alongside
+// another module, steps change them only through methods.
 type State struct {
 	InstalledModules   []string  `json:"installed_modules"`
 	LastRun            time.Time `json:"last_run"`
@@ -14,7 +20,80 @@ type State struct {
 	ManagedPathEntries []string  `json:"managed_path_entries"`
 	ScoopPackages      []string  `json:"scoop_packages"`
 	CABundleHash       string    `json:"ca_bundle_hash"`
+	ScoopUpdated       time.Time `json:"scoop_updated,omitempty"`
 	ShhhVersion        string    `json:"shhh_version"`
+
+	// EnvValues records the value shhh last set for some managed variables
+	// (the proxy variables), so a later run can tell its own value from one
+	// set by another tool or by group policy; see SetElsewhere.
+	EnvValues map[string]string `json:"env_values,omitempty"`
+
+	// SystemEnvVars lists the managed variables shhh wrote to the system
+	// (machine-wide) environment rather than the user's ([proxy] scope =
+	// "system"), so they are removed and exported from there; see
+	// SetSystemEnv.
+	SystemEnvVars []string `json:"system_env_vars,omitempty"`
+
+	// InstallRoot is the [paths] root tooling was last installed under.
+	InstallRoot string `json:"install_root,omitempty"`
+
+	// GitVersion is the git version the base module last installed and
+	// verified for a [git] version pin.
+	GitVersion string `json:"git_version,omitempty"`
+
+	// ResetEnvVars lists variables shhh has cleared because the config asked
+	// for them to be reset. Each is cleared once, not on every run.
+	ResetEnvVars []string `json:"reset_env_vars,omitempty"`
+
+	// ManagedFiles lists files shhh wrote, with the hash of what it wrote.
+	ManagedFiles []ManagedFile `json:"managed_files,omitempty"`
+
+	// ExcludedCerts lists the SHA-256 fingerprints (hex) of system root
+	// certificates left out of the CA bundle via setup --select-certs.
+	ExcludedCerts []string `json:"excluded_certs,omitempty"`
+
+	// Owners records which module added each managed entry, so entries can
+	// be pruned when a module is no longer used.
+	Owners map[string]*Owned `json:"owners,omitempty"`
+
+	// History holds the most recent runs, oldest first; see AddRun.
+	History []Run `json:"history,omitempty"`
+
+	// StepTimings holds recent durations of each step, keyed by
+	// module.StepKey, oldest first; see RecordTiming.
+	StepTimings map[string][]time.Duration `json:"step_timings,omitempty"`
+
+	// Fingerprint is setup.Fingerprint of the environment after the last
+	// run in which every module succeeded, or "" if the last run didn't.
+	// Setup skips a re-run whose fingerprint matches it, unless --recheck.
+	Fingerprint string `json:"fingerprint,omitempty"`
+
+	mu sync.Mutex
+}
+
+// Owned lists the managed entries a single module added.
+type Owned struct {
+	EnvVars       []string `json:"env_vars,omitempty"`
+	PathEntries   []string `json:"path_entries,omitempty"`
+	ScoopPackages []string `json:"scoop_packages,omitempty"`
+	Files         []string `json:"files,omitempty"`
+}
+
+// PruneResult lists what Prune stopped tracking.
+type PruneResult struct {
+	Modules []string
+	EnvVars []string
+	// SystemEnvVars are the EnvVars that were in the system environment.
+	SystemEnvVars []string
+	PathEntries   []string
+	ScoopPackages []string
+	Files         []ManagedFile
+}
+
+// Empty reports whether Prune removed nothing.
+func (p PruneResult) Empty() bool {
+	return len(p.Modules) == 0 && len(p.EnvVars) == 0 &&
+		len(p.PathEntries) == 0 && len(p.ScoopPackages) == 0 && len(p.Files) == 0
 }
 
 func Load(path string) (*State, error) {
@@ -37,7 +116,6 @@ func Save(path string, s *State) error {
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return err
 	}
-
 	data, err := json.MarshalIndent(s, "", "  ")
 	if err != nil {
 		return err
@@ -46,27 +124,245 @@ func Save(path string, s *State) error {
 }
 
 func (s *State) AddModule(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if !contains(s.InstalledModules, id) {
 		s.InstalledModules = append(s.InstalledModules, id)
 	}
 }
 
-func (s *State) AddEnvVar(key string) {
+// AddEnvVar records key as a managed variable, added by the module owner.
+// An empty owner records it without attributing it to any module.
+func (s *State) AddEnvVar(owner, key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if !contains(s.ManagedEnvVars, key) {
 		s.ManagedEnvVars = append(s.ManagedEnvVars, key)
 	}
+	if o := s.owned(owner); o != nil && !contains(o.EnvVars, key) {
+		o.EnvVars = append(o.EnvVars, key)
+	}
 }
 
-func (s *State) AddPathEntry(dir string) {
+// SetEnvValue records that shhh set key to value.
+func (s *State) SetEnvValue(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.EnvValues == nil {
+		s.EnvValues = make(map[string]string)
+	}
+	s.EnvValues[key] = value
+}
+
+// SetElsewhere reports whether value, the current value of key, was set by
+// something other than shhh: it is non-empty and not what shhh last set. A
+// managed variable without a recorded value (tracked before values were) is
+// assumed to hold shhh's.
+func (s *State) SetElsewhere(key, value string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if value == "" {
+		return false
+	}
+	if set, ok := s.EnvValues[key]; ok {
+		return value != set
+	}
+	return !contains(s.ManagedEnvVars, key)
+}
+
+// SetSystemEnv records whether shhh last wrote key to the system
+// environment (system true) or the user's.
+func (s *State) SetSystemEnv(key string, system bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !system {
+		s.SystemEnvVars = remove(s.SystemEnvVars, key)
+	} else if !contains(s.SystemEnvVars, key) {
+		s.SystemEnvVars = append(s.SystemEnvVars, key)
+	}
+}
+
+// SystemEnv reports whether shhh last wrote key to the system environment.
+func (s *State) SystemEnv(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return contains(s.SystemEnvVars, key)
+}
+
+// RemoveEnvVar stops tracking key as managed, including in every module's
+// ownership record.
+func (s *State) RemoveEnvVar(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ManagedEnvVars = remove(s.ManagedEnvVars, key)
+	s.SystemEnvVars = remove(s.SystemEnvVars, key)
+	delete(s.EnvValues, key)
+	for _, o := range s.Owners {
+		o.EnvVars = remove(o.EnvVars, key)
+	}
+}
+
+// AddPathEntry records dir as a managed PATH entry, added by the module
+// owner.
+func (s *State) AddPathEntry(owner, dir string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if !contains(s.ManagedPathEntries, dir) {
 		s.ManagedPathEntries = append(s.ManagedPathEntries, dir)
 	}
+	if o := s.owned(owner); o != nil && !contains(o.PathEntries, dir) {
+		o.PathEntries = append(o.PathEntries, dir)
+	}
+}
+
+// RemovePathEntry stops tracking dir as a managed PATH entry, including in
+// every module's ownership record.
+func (s *State) RemovePathEntry(dir string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ManagedPathEntries = remove(s.ManagedPathEntries, dir)
+	for _, o := range s.Owners {
+		o.PathEntries = remove(o.PathEntries, dir)
+	}
 }
 
-func (s *State) AddScoopPackage(pkg string) {
+// AddScoopPackage records pkg as a Scoop package shhh installed for the
+// module owner.
+func (s *State) AddScoopPackage(owner, pkg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if !contains(s.ScoopPackages, pkg) {
 		s.ScoopPackages = append(s.ScoopPackages, pkg)
 	}
+	if o := s.owned(owner); o != nil && !contains(o.ScoopPackages, pkg) {
+		o.ScoopPackages = append(o.ScoopPackages, pkg)
+	}
+}
+
+// SetInstallRoot records root as the install root and returns the one
+// recorded before.
+func (s *State) SetInstallRoot(root string) (prev string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, s.InstallRoot = s.InstallRoot, root
+	return prev
+}
+
+// AddResetEnvVar records that key has been cleared by an [env] reset.
+func (s *State) AddResetEnvVar(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !contains(s.ResetEnvVars, key) {
+		s.ResetEnvVars = append(s.ResetEnvVars, key)
+	}
+}
+
+// HasResetEnvVar reports whether key has already been cleared by an
+// [env] reset.
+func (s *State) HasResetEnvVar(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return contains(s.ResetEnvVars, key)
+}
+
+// owned returns the ownership record for module id, creating it if needed,
+// or nil when id is empty.
+func (s *State) owned(id string) *Owned {
+	if id == "" {
+		return nil
+	}
+	if s.Owners == nil {
+		s.Owners = make(map[string]*Owned)
+	}
+	o, ok := s.Owners[id]
+	if !ok {
+		o = &Owned{}
+		s.Owners[id] = o
+	}
+	return o
+}
+
+// Prune stops tracking modules not listed in active, along with the entries
+// they own. An entry also owned by an active module is kept, as is any entry
+// with no recorded owner (e.g. from state written before owners existed).
+// The returned PruneResult lists what was dropped so callers can reverse it.
+func (s *State) Prune(active []string) PruneResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res PruneResult
+
+	keep := make(map[string]bool)
+	for _, id := range active {
+		keep[id] = true
+	}
+
+	stillOwned := &Owned{}
+	for id, o := range s.Owners {
+		if keep[id] {
+			stillOwned.EnvVars = append(stillOwned.EnvVars, o.EnvVars...)
+			stillOwned.PathEntries = append(stillOwned.PathEntries, o.PathEntries...)
+			stillOwned.ScoopPackages = append(stillOwned.ScoopPackages, o.ScoopPackages...)
+			stillOwned.Files = append(stillOwned.Files, o.Files...)
+		}
+	}
+
+	var modules []string
+	for _, id := range s.InstalledModules {
+		if keep[id] {
+			modules = append(modules, id)
+		} else {
+			res.Modules = append(res.Modules, id)
+		}
+	}
+	s.InstalledModules = modules
+
+	ids := make([]string, 0, len(s.Owners))
+	for id := range s.Owners {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		o := s.Owners[id]
+		if keep[id] {
+			continue
+		}
+		if !contains(res.Modules, id) {
+			res.Modules = append(res.Modules, id)
+		}
+		for _, key := range o.EnvVars {
+			if !contains(stillOwned.EnvVars, key) && contains(s.ManagedEnvVars, key) {
+				s.ManagedEnvVars = remove(s.ManagedEnvVars, key)
+				delete(s.EnvValues, key)
+				res.EnvVars = append(res.EnvVars, key)
+				if contains(s.SystemEnvVars, key) {
+					s.SystemEnvVars = remove(s.SystemEnvVars, key)
+					res.SystemEnvVars = append(res.SystemEnvVars, key)
+				}
+			}
+		}
+		for _, dir := range o.PathEntries {
+			if !contains(stillOwned.PathEntries, dir) && contains(s.ManagedPathEntries, dir) {
+				s.ManagedPathEntries = remove(s.ManagedPathEntries, dir)
+				res.PathEntries = append(res.PathEntries, dir)
+			}
+		}
+		for _, pkg := range o.ScoopPackages {
+			if !contains(stillOwned.ScoopPackages, pkg) && contains(s.ScoopPackages, pkg) {
+				s.ScoopPackages = remove(s.ScoopPackages, pkg)
+				res.ScoopPackages = append(res.ScoopPackages, pkg)
+			}
+		}
+		for _, path := range o.Files {
+			if i := s.managedFileIndex(path); i >= 0 && !contains(stillOwned.Files, path) {
+				res.Files = append(res.Files, s.ManagedFiles[i])
+				s.ManagedFiles = append(s.ManagedFiles[:i], s.ManagedFiles[i+1:]...)
+			}
+		}
+		delete(s.Owners, id)
+	}
+
+	return res
 }
 
 func contains(slice []string, item string) bool {
@@ -77,3 +373,14 @@ func contains(slice []string, item string) bool {
 	}
 	return false
 }
+
+// remove returns slice without any occurrence of item.
+func remove(slice []string, item string) []string {
+	var out []string
+	for _, s := range slice {
+		if s != item {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -1,7 +1,9 @@
 package state
 
 import (
+	"fmt"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 )
@@ -66,10 +68,170 @@ func TestState_AddModule(t *testing.T) {
 
 func TestState_AddEnvVar(t *testing.T) {
 	s := &State{}
-	s.AddEnvVar("HTTP_PROXY")
-	s.AddEnvVar("HTTP_PROXY") // duplicate
+	s.AddEnvVar("", "HTTP_PROXY")
+	s.AddEnvVar("", "HTTP_PROXY") // duplicate
 
 	if len(s.ManagedEnvVars) != 1 {
 		t.Errorf("ManagedEnvVars = %v", s.ManagedEnvVars)
 	}
 }
+
+func TestState_RemoveEnvVar(t *testing.T) {
+	s := &State{}
+	s.AddEnvVar("base", "HTTP_PROXY")
+	s.AddEnvVar("base", "NO_PROXY")
+	s.RemoveEnvVar("HTTP_PROXY")
+
+	if len(s.ManagedEnvVars) != 1 || s.ManagedEnvVars[0] != "NO_PROXY" {
+		t.Errorf("ManagedEnvVars = %v, want [NO_PROXY]", s.ManagedEnvVars)
+	}
+	if o := s.Owners["base"]; len(o.EnvVars) != 1 || o.EnvVars[0] != "NO_PROXY" {
+		t.Errorf("base env vars = %v, want [NO_PROXY]", o.EnvVars)
+	}
+}
+
+func TestState_SetElsewhere(t *testing.T) {
+	s := &State{}
+	if !s.SetElsewhere("HTTP_PROXY", "http://other:3128") {
+		t.Error("untracked variable should count as set elsewhere")
+	}
+	if s.SetElsewhere("HTTP_PROXY", "") {
+		t.Error("an unset variable is not set elsewhere")
+	}
+
+	s.AddEnvVar("", "HTTP_PROXY")
+	if s.SetElsewhere("HTTP_PROXY", "http://old:8080") {
+		t.Error("managed variable without a recorded value should be assumed shhh's")
+	}
+
+	s.SetEnvValue("HTTP_PROXY", "http://proxy:8080")
+	if s.SetElsewhere("HTTP_PROXY", "http://proxy:8080") {
+		t.Error("shhh's own value reported as set elsewhere")
+	}
+	if !s.SetElsewhere("HTTP_PROXY", "http://other:3128") {
+		t.Error("changed value should count as set elsewhere")
+	}
+
+	s.RemoveEnvVar("HTTP_PROXY")
+	if _, ok := s.EnvValues["HTTP_PROXY"]; ok {
+		t.Error("RemoveEnvVar should forget the recorded value")
+	}
+}
+
+func TestState_SystemEnv(t *testing.T) {
+	s := &State{}
+	s.AddEnvVar("base", "HTTP_PROXY")
+	s.SetSystemEnv("HTTP_PROXY", true)
+	s.SetSystemEnv("HTTP_PROXY", true)
+	if !s.SystemEnv("HTTP_PROXY") || len(s.SystemEnvVars) != 1 {
+		t.Errorf("SystemEnvVars = %v, want HTTP_PROXY once", s.SystemEnvVars)
+	}
+
+	s.SetSystemEnv("HTTP_PROXY", false)
+	if s.SystemEnv("HTTP_PROXY") {
+		t.Error("a variable written to the user scope again should no longer be system")
+	}
+
+	s.SetSystemEnv("HTTP_PROXY", true)
+	res := s.Prune(nil)
+	if len(res.SystemEnvVars) != 1 || s.SystemEnv("HTTP_PROXY") {
+		t.Errorf("Prune SystemEnvVars = %v, state still has %v", res.SystemEnvVars, s.SystemEnvVars)
+	}
+}
+
+func TestState_OwnershipFollowsOwner(t *testing.T) {
+	s := &State{}
+	s.AddEnvVar("", "HTTP_PROXY") // no owner: unowned
+
+	s.AddEnvVar("golang", "GOPATH")
+	s.AddPathEntry("golang", "/home/u/go/bin")
+	s.AddScoopPackage("golang", "go")
+
+	o := s.Owners["golang"]
+	if o == nil {
+		t.Fatal("expected ownership record for golang")
+	}
+	if len(o.EnvVars) != 1 || o.EnvVars[0] != "GOPATH" {
+		t.Errorf("golang env vars = %v", o.EnvVars)
+	}
+	if len(s.Owners) != 1 {
+		t.Errorf("Owners = %v, want only golang", s.Owners)
+	}
+}
+
+func TestState_ConcurrentOwners(t *testing.T) {
+	s := &State{}
+	owners := []string{"golang", "python", "node"}
+
+	var wg sync.WaitGroup
+	for _, owner := range owners {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 50 {
+				key := fmt.Sprintf("%s_%d", owner, i)
+				s.AddEnvVar(owner, key)
+				s.AddPathEntry(owner, "/"+key)
+				s.AddScoopPackage(owner, key)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(s.ManagedEnvVars) != 150 {
+		t.Errorf("got %d managed variables, want 150", len(s.ManagedEnvVars))
+	}
+	for _, owner := range owners {
+		if o := s.Owners[owner]; o == nil || len(o.EnvVars) != 50 || len(o.PathEntries) != 50 {
+			t.Errorf("%s owns %+v, want 50 of each", owner, o)
+		}
+	}
+}
+
+func TestState_Prune(t *testing.T) {
+	s := &State{}
+	s.AddModule("base")
+	s.AddModule("python")
+	s.AddModule("cloud")
+
+	s.AddEnvVar("base", "SSL_CERT_FILE")
+	s.AddEnvVar("python", "REQUESTS_CA_BUNDLE")
+	s.AddEnvVar("python", "PIP_CERT")
+	s.AddScoopPackage("python", "uv")
+	s.AddEnvVar("cloud", "REQUESTS_CA_BUNDLE") // shared with python
+	s.AddEnvVar("cloud", "AWS_CA_BUNDLE")
+	s.AddScoopPackage("cloud", "awscli")
+	s.AddEnvVar("", "LEGACY") // unowned
+
+	res := s.Prune([]string{"base", "cloud"})
+
+	if len(res.Modules) != 1 || res.Modules[0] != "python" {
+		t.Errorf("pruned modules = %v, want [python]", res.Modules)
+	}
+	if len(res.EnvVars) != 1 || res.EnvVars[0] != "PIP_CERT" {
+		t.Errorf("pruned env vars = %v, want [PIP_CERT]", res.EnvVars)
+	}
+	if len(res.ScoopPackages) != 1 || res.ScoopPackages[0] != "uv" {
+		t.Errorf("pruned scoop packages = %v, want [uv]", res.ScoopPackages)
+	}
+
+	for _, key := range []string{"SSL_CERT_FILE", "REQUESTS_CA_BUNDLE", "AWS_CA_BUNDLE", "LEGACY"} {
+		if !contains(s.ManagedEnvVars, key) {
+			t.Errorf("%s should still be managed", key)
+		}
+	}
+	if contains(s.InstalledModules, "python") {
+		t.Error("python should no longer be installed")
+	}
+	if _, ok := s.Owners["python"]; ok {
+		t.Error("python ownership record should be dropped")
+	}
+}
+
+func TestState_PruneNothing(t *testing.T) {
+	s := &State{}
+	s.AddModule("base")
+	if res := s.Prune([]string{"base"}); !res.Empty() {
+		t.Errorf("expected empty prune result, got %+v", res)
+	}
+}
@@ -0,0 +1,46 @@
+package state
+
+import (
+	"slices"
+	"time"
+)
+
+// MaxTimings is how many durations StepTimings keeps per step.
+const MaxTimings = 5
+
+// RecordTiming appends d to the timings for step, keeping only the most
+// recent MaxTimings.
+func (s *State) RecordTiming(step string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.StepTimings == nil {
+		s.StepTimings = make(map[string][]time.Duration)
+	}
+	t := append(s.StepTimings[step], d)
+	if over := len(t) - MaxTimings; over > 0 {
+		t = append([]time.Duration(nil), t[over:]...)
+	}
+	s.StepTimings[step] = t
+}
+
+// Estimates returns the median recorded duration of each step with
+// timings. Medians keep one slow run (a cold download, say) from skewing
+// the estimate.
+func (s *State) Estimates() map[string]time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	est := make(map[string]time.Duration, len(s.StepTimings))
+	for step, t := range s.StepTimings {
+		if len(t) == 0 {
+			continue
+		}
+		sorted := slices.Sorted(slices.Values(t))
+		mid := len(sorted) / 2
+		if len(sorted)%2 == 0 {
+			est[step] = (sorted[mid-1] + sorted[mid]) / 2
+		} else {
+			est[step] = sorted[mid]
+		}
+	}
+	return est
+}
@@ -0,0 +1,42 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestState_RecordTimingCaps(t *testing.T) {
+	s := &State{}
+	for i := range MaxTimings + 3 {
+		s.RecordTiming("base/Build CA bundle", time.Duration(i+1)*time.Second)
+	}
+
+	got := s.StepTimings["base/Build CA bundle"]
+	if len(got) != MaxTimings {
+		t.Fatalf("len = %d, want %d", len(got), MaxTimings)
+	}
+	if got[0] != 4*time.Second {
+		t.Errorf("oldest = %v, want 4s", got[0])
+	}
+}
+
+func TestState_EstimatesUseMedian(t *testing.T) {
+	s := &State{}
+	for _, d := range []time.Duration{2 * time.Second, 60 * time.Second, 3 * time.Second} {
+		s.RecordTiming("node/Install Node.js", d)
+	}
+	for _, d := range []time.Duration{time.Second, 3 * time.Second} {
+		s.RecordTiming("git/Configure git", d)
+	}
+
+	est := s.Estimates()
+	if got := est["node/Install Node.js"]; got != 3*time.Second {
+		t.Errorf("odd median = %v, want 3s", got)
+	}
+	if got := est["git/Configure git"]; got != 2*time.Second {
+		t.Errorf("even median = %v, want 2s", got)
+	}
+	if _, ok := est["python/Install Python"]; ok {
+		t.Error("estimate for step with no timings")
+	}
+}
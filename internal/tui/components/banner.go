@@ -8,7 +8,17 @@ const banner = `  ███████╗██╗  ██╗██╗  ██
   ███████║██║  ██║██║  ██║██║  ██║
   ╚══════╝╚═╝  ╚═╝╚═╝  ╚═╝╚═╝  ╚═╝`
 
+// asciiBanner is used when the terminal can't render the block glyphs.
+const asciiBanner = `       _     _     _     _
+   ___| |__ | |__ | |__ | |__
+  / __| '_ \| '_ \| '_ \| '_ \
+  \__ \ | | | | | | | | | | | |
+  |___/_| |_|_| |_|_| |_|_| |_|`
+
 // RenderBanner returns the styled ASCII banner.
 func RenderBanner(styles Styles) string {
+	if styles.Icons == IconsASCII {
+		return styles.Title.Render(asciiBanner)
+	}
 	return styles.Title.Render(banner)
 }
@@ -1,6 +1,11 @@
 package components
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
 
 func TestDefaultStyles(t *testing.T) {
 	s := DefaultStyles()
@@ -41,3 +46,67 @@ func TestNewSpinner(t *testing.T) {
 		t.Error("spinner View() is empty")
 	}
 }
+
+func TestStylesWithIcons_ASCII(t *testing.T) {
+	s := StylesWithIcons(IconsASCII)
+
+	for name, icon := range map[string]string{
+		"StatusDone":    s.StatusDone,
+		"StatusRunning": s.StatusRunning,
+		"StatusPending": s.StatusPending,
+		"StatusSkipped": s.StatusSkipped,
+		"StatusFailed":  s.StatusFailed,
+		"BarFull":       s.BarFull,
+		"BarEmpty":      s.BarEmpty,
+		"Banner":        RenderBanner(s),
+	} {
+		for _, r := range icon {
+			if r > 127 {
+				t.Errorf("%s contains non-ASCII rune %q", name, r)
+				break
+			}
+		}
+	}
+}
+
+func TestParseIconSet(t *testing.T) {
+	cases := map[string]IconSet{
+		"unicode": IconsUnicode,
+		"ASCII":   IconsASCII,
+		"nerd":    IconsNerdFont,
+	}
+	for in, want := range cases {
+		got, err := ParseIconSet(in)
+		if err != nil || got != want {
+			t.Errorf("ParseIconSet(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseIconSet("emoji"); err == nil {
+		t.Error("expected error for unknown icon set")
+	}
+}
+
+func TestRenderTable_AlignsStyledCells(t *testing.T) {
+	s := DefaultStyles()
+	styled := lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Bold(true).Render("FAILED")
+	out := RenderTable([][]string{
+		{"base", "done", "3 completed"},
+		{"python", styled, "1 completed"},
+		{"node"},
+	}, s)
+
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3:\n%s", len(lines), out)
+	}
+	// The third column starts at the same visible offset on both rows.
+	col := func(line, cell string) int {
+		return lipgloss.Width(line[:strings.Index(line, cell)])
+	}
+	if a, b := col(lines[0], "3 completed"), col(lines[1], "1 completed"); a != b {
+		t.Errorf("third column at %d and %d, want aligned:\n%s", a, b, out)
+	}
+	if lines[2] != "  node" {
+		t.Errorf("short row = %q, want %q (no trailing padding)", lines[2], "  node")
+	}
+}
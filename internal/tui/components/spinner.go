@@ -9,6 +9,9 @@ import (
 func NewSpinner(styles Styles) spinner.Model {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
+	if styles.Icons == IconsASCII {
+		s.Spinner = spinner.Line
+	}
 	s.Style = lipgloss.NewStyle().Foreground(styles.AccentColor)
 	return s
 }
@@ -1,6 +1,13 @@
 package components
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
 
 // Styles holds all shared Lipgloss styles used across TUI screens.
 type Styles struct {
@@ -25,11 +32,67 @@ type Styles struct {
 	AccentColor    lipgloss.AdaptiveColor
 	ProgressFull   lipgloss.Style
 	ProgressEmpty  lipgloss.Style
+	BarFull        string
+	BarEmpty       string
+	Icons          IconSet
+}
+
+// IconSet selects the glyphs used for checkboxes, step status, and the
+// progress bar.
+type IconSet int
+
+const (
+	IconsUnicode  IconSet = iota // ✓ ● ○ ~ ✗ — the default
+	IconsASCII                   // plain ASCII for terminals without UTF-8
+	IconsNerdFont                // Nerd Font glyphs
+)
+
+// ParseIconSet converts a flag value ("unicode", "ascii", "nerd") to an IconSet.
+func ParseIconSet(s string) (IconSet, error) {
+	switch strings.ToLower(s) {
+	case "unicode":
+		return IconsUnicode, nil
+	case "ascii":
+		return IconsASCII, nil
+	case "nerd", "nerdfont":
+		return IconsNerdFont, nil
+	default:
+		return IconsUnicode, fmt.Errorf("unknown icon set %q (want unicode, ascii, or nerd)", s)
+	}
+}
+
+// DetectIconSet guesses whether the terminal can render UTF-8 glyphs. On
+// Windows only modern hosts (Windows Terminal, VS Code) are trusted; the
+// legacy console renders them poorly. Elsewhere the locale decides.
+func DetectIconSet() IconSet {
+	if runtime.GOOS == "windows" {
+		if os.Getenv("WT_SESSION") != "" || os.Getenv("TERM_PROGRAM") != "" {
+			return IconsUnicode
+		}
+		return IconsASCII
+	}
+	for _, key := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if v := os.Getenv(key); v != "" {
+			v = strings.ToUpper(v)
+			if strings.Contains(v, "UTF-8") || strings.Contains(v, "UTF8") {
+				return IconsUnicode
+			}
+			return IconsASCII
+		}
+	}
+	return IconsASCII
 }
 
-// DefaultStyles returns a Styles populated with the shhh color palette.
-// Uses AdaptiveColor to work in both light and dark terminals.
+// DefaultStyles returns a Styles populated with the shhh color palette and
+// Unicode icons.
 func DefaultStyles() Styles {
+	return StylesWithIcons(IconsUnicode)
+}
+
+// StylesWithIcons returns a Styles populated with the shhh color palette and
+// the given icon set. Uses AdaptiveColor to work in both light and dark
+// terminals.
+func StylesWithIcons(icons IconSet) Styles {
 	accent := lipgloss.AdaptiveColor{Light: "#7B2FBE", Dark: "#B476F0"}
 	cyan := lipgloss.AdaptiveColor{Light: "#0891B2", Dark: "#22D3EE"}
 	muted := lipgloss.AdaptiveColor{Light: "#6B7280", Dark: "#9CA3AF"}
@@ -37,7 +100,7 @@ func DefaultStyles() Styles {
 	errColor := lipgloss.AdaptiveColor{Light: "#DC2626", Dark: "#F87171"}
 	warn := lipgloss.AdaptiveColor{Light: "#D97706", Dark: "#FBBF24"}
 
-	return Styles{
+	s := Styles{
 		Title: lipgloss.NewStyle().
 			Bold(true).
 			Foreground(accent),
@@ -91,5 +154,30 @@ func DefaultStyles() Styles {
 
 		ProgressEmpty: lipgloss.NewStyle().
 			Foreground(muted),
+
+		BarFull:  "█",
+		BarEmpty: "░",
+		Icons:    icons,
 	}
+
+	switch icons {
+	case IconsASCII:
+		s.StatusDone = "[ok]"
+		s.StatusRunning = "[..]"
+		s.StatusPending = "[  ]"
+		s.StatusSkipped = "[skip]"
+		s.StatusFailed = "[XX]"
+		s.BarFull = "#"
+		s.BarEmpty = "-"
+	case IconsNerdFont:
+		s.CheckboxOn = "\uf14a"    // nf-fa-check_square
+		s.CheckboxOff = "\uf096"   // nf-fa-square_o
+		s.StatusDone = "\uf00c"    // nf-fa-check
+		s.StatusRunning = "\uf110" // nf-fa-spinner
+		s.StatusPending = "\uf10c" // nf-fa-circle_o
+		s.StatusSkipped = "\uf04e" // nf-fa-forward
+		s.StatusFailed = "\uf00d"  // nf-fa-times
+	}
+
+	return s
 }
@@ -0,0 +1,51 @@
+package components
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tableIndent and tableGap are the left margin and the space between
+// columns in RenderTable output.
+const (
+	tableIndent = "  "
+	tableGap    = "  "
+)
+
+// RenderTable lays rows out in aligned columns, one line per row, each
+// ending in a newline. Cells are padded to the widest cell in their column
+// as measured by lipgloss.Width, so cells that are already styled (and so
+// contain ANSI codes) still line up. The first column is the key and is
+// rendered with styles.Body. Rows may have different lengths; the last cell
+// of a row is never padded.
+func RenderTable(rows [][]string, styles Styles) string {
+	styled := make([][]string, len(rows))
+	var widths []int
+	for r, row := range rows {
+		styled[r] = append([]string(nil), row...)
+		if len(row) > 0 {
+			styled[r][0] = styles.Body.Render(row[0])
+		}
+		for i, cell := range styled[r] {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], lipgloss.Width(cell))
+		}
+	}
+
+	var b strings.Builder
+	for _, row := range styled {
+		b.WriteString(tableIndent)
+		for i, cell := range row {
+			b.WriteString(cell)
+			if i < len(row)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-lipgloss.Width(cell)))
+				b.WriteString(tableGap)
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
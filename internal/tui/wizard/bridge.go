@@ -8,6 +8,15 @@ import (
 	"github.com/druarnfield/shhh/internal/module"
 )
 
+// bridgeBuffer is how many messages the run goroutine may get ahead of the
+// TUI. The buffer only smooths out rendering; it doesn't need to hold a
+// whole run. Delivery is pull-based: the wizard asks NextMsg for exactly one
+// message each time it handles one, so a full buffer just makes send wait
+// until the TUI catches up. The run goroutine waits on nothing else, and
+// Cancel releases a blocked send, so this can't deadlock however many
+// steps there are.
+const bridgeBuffer = 64
+
 // Bridge runs modules in a background goroutine and produces tea.Msg values
 // for the TUI via a channel.
 type Bridge struct {
@@ -26,7 +35,7 @@ func NewBridge(runner *module.Runner, reg *module.Registry, ids []string) *Bridg
 		runner:    runner,
 		registry:  reg,
 		moduleIDs: ids,
-		msgs:      make(chan tea.Msg, 64),
+		msgs:      make(chan tea.Msg, bridgeBuffer),
 		ctx:       ctx,
 		cancel:    cancel,
 	}
@@ -38,8 +47,13 @@ func (b *Bridge) Cancel() {
 }
 
 // send delivers a message on the channel, respecting context cancellation
-// to prevent deadlocks if the TUI has been shut down.
+// to prevent deadlocks if the TUI has been shut down. Once cancelled it
+// sends nothing more, even if the buffer has room, so run winds down
+// without the TUI having to drain it.
 func (b *Bridge) send(msg tea.Msg) bool {
+	if b.ctx.Err() != nil {
+		return false
+	}
 	select {
 	case b.msgs <- msg:
 		return true
@@ -63,7 +77,7 @@ func (b *Bridge) Start() tea.Cmd {
 	})
 
 	// Install post-step callback for StepDoneMsg / StepErrorMsg.
-	b.runner.SetCallback(func(mod *module.Module, step *module.Step, index int, total int, skipped bool, err error) {
+	b.runner.SetCallback(func(mod *module.Module, step *module.Step, index int, total int, outcome module.StepOutcome, err error) {
 		if err != nil {
 			b.send(StepErrorMsg{
 				ModuleID: mod.ID,
@@ -79,7 +93,9 @@ func (b *Bridge) Start() tea.Cmd {
 			StepName: step.Name,
 			Index:    index,
 			Total:    total,
-			Skipped:  skipped,
+			Skipped:  outcome == module.StepSkipped,
+			Filtered: outcome == module.StepFiltered,
+			DryRun:   outcome == module.StepWouldRun,
 		})
 	})
 
@@ -88,7 +104,8 @@ func (b *Bridge) Start() tea.Cmd {
 	return b.NextMsg()
 }
 
-// run executes modules one at a time, sending ModuleStartMsg before each.
+// run executes modules one at a time, sending TotalStepsMsg first and
+// ModuleStartMsg before each module.
 // It resolves dependencies itself (rather than using runner.RunModules) so it
 // can inject ModuleStartMsg between modules.
 func (b *Bridge) run() {
@@ -100,6 +117,18 @@ func (b *Bridge) run() {
 		return
 	}
 
+	var plan []string
+	for _, id := range sorted {
+		if mod := b.registry.Get(id); mod != nil {
+			for _, step := range mod.Steps {
+				plan = append(plan, module.StepKey(mod.ID, step.Name))
+			}
+		}
+	}
+	if !b.send(TotalStepsMsg{Total: len(plan), Plan: plan}) {
+		return
+	}
+
 	var results []module.ModuleResult
 	for _, id := range sorted {
 		mod := b.registry.Get(id)
@@ -116,10 +145,10 @@ func (b *Bridge) run() {
 			return
 		}
 
-		result := b.runner.RunModule(b.ctx, mod)
+		result := b.runner.RunModuleAfter(b.ctx, mod, results)
 		results = append(results, result)
 
-		if result.Err != nil {
+		if result.Err != nil && !b.runner.KeepGoing() {
 			b.send(AllDoneMsg{Results: results})
 			return
 		}
@@ -0,0 +1,176 @@
+package wizard
+
+import (
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/druarnfield/shhh/internal/tui/components"
+)
+
+// CertChoice is a certificate offered on the cert selection screen and
+// whether it goes into the CA bundle.
+type CertChoice struct {
+	Cert     *x509.Certificate
+	Selected bool
+}
+
+// CertConfirmMsg is sent when the user confirms their certificate selection.
+type CertConfirmMsg struct {
+	Choices []CertChoice
+}
+
+// certChromeLines is the number of lines the cert picker uses for its
+// banner, title and footer; the rest of the window scrolls the list.
+const certChromeLines = 12
+
+// CertPickerModel is a multi-select list of the certificates extracted from
+// the system store (setup --select-certs).
+type CertPickerModel struct {
+	styles  components.Styles
+	choices []CertChoice
+	cursor  int
+	offset  int // first visible row
+	width   int
+	height  int
+	now     time.Time
+}
+
+// NewCertPickerModel creates a cert picker over choices, which it copies.
+func NewCertPickerModel(styles components.Styles, choices []CertChoice) CertPickerModel {
+	return CertPickerModel{
+		styles:  styles,
+		choices: append([]CertChoice(nil), choices...),
+		now:     time.Now(),
+	}
+}
+
+// SelectedCount returns how many certificates are selected.
+func (m CertPickerModel) SelectedCount() int {
+	n := 0
+	for _, c := range m.choices {
+		if c.Selected {
+			n++
+		}
+	}
+	return n
+}
+
+// Update handles key events for the cert picker.
+func (m CertPickerModel) Update(msg tea.Msg) (CertPickerModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.choices)-1 {
+				m.cursor++
+			}
+		case " ":
+			if m.cursor < len(m.choices) {
+				m.choices[m.cursor].Selected = !m.choices[m.cursor].Selected
+			}
+		case "a":
+			m.setAll(true)
+		case "n":
+			m.setAll(false)
+		case "enter":
+			// An empty bundle would break every tool pointed at it.
+			if m.SelectedCount() > 0 {
+				choices := append([]CertChoice(nil), m.choices...)
+				return m, func() tea.Msg { return CertConfirmMsg{Choices: choices} }
+			}
+		}
+		m.scroll()
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.scroll()
+	}
+	return m, nil
+}
+
+// View renders the cert picker.
+func (m CertPickerModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(components.RenderBanner(m.styles))
+	b.WriteString("\n\n")
+	b.WriteString(m.styles.Title.Render("Select certificates for the CA bundle"))
+	b.WriteString("\n\n")
+
+	end := m.offset + m.visibleRows()
+	if end > len(m.choices) {
+		end = len(m.choices)
+	}
+	for i := m.offset; i < end; i++ {
+		c := m.choices[i]
+		checkbox := m.styles.CheckboxOff
+		if c.Selected {
+			checkbox = m.styles.CheckboxOn
+		}
+
+		expiry := "expires " + c.Cert.NotAfter.Format("2006-01-02")
+		expired := c.Cert.NotAfter.Before(m.now)
+		if expired {
+			expiry = "expired " + c.Cert.NotAfter.Format("2006-01-02")
+		}
+		line := fmt.Sprintf("  %s %s (%s)", checkbox, certLabel(c.Cert), expiry)
+
+		if i == m.cursor {
+			line = m.styles.SelectedItem.Render("> " + line[2:])
+		} else if expired {
+			line = m.styles.Muted.Render(line)
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(m.styles.Footer.Render(fmt.Sprintf(
+		"  space: toggle  a: all  n: none  enter: confirm (%d of %d selected)",
+		m.SelectedCount(), len(m.choices)),
+	))
+
+	return b.String()
+}
+
+// certLabel names a certificate by its subject CN, falling back to the
+// full subject for roots without one.
+func certLabel(cert *x509.Certificate) string {
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+	return cert.Subject.String()
+}
+
+// setAll selects or clears every certificate.
+func (m *CertPickerModel) setAll(selected bool) {
+	for i := range m.choices {
+		m.choices[i].Selected = selected
+	}
+}
+
+// visibleRows is how many certificates fit on screen. Before the first
+// WindowSizeMsg the whole list is shown.
+func (m CertPickerModel) visibleRows() int {
+	if m.height == 0 {
+		return len(m.choices)
+	}
+	return max(m.height-certChromeLines, 1)
+}
+
+// scroll keeps the cursor inside the visible window.
+func (m *CertPickerModel) scroll() {
+	rows := m.visibleRows()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	} else if m.cursor >= m.offset+rows {
+		m.offset = m.cursor - rows + 1
+	}
+}
@@ -0,0 +1,100 @@
+package wizard
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/druarnfield/shhh/internal/platform"
+	"github.com/druarnfield/shhh/internal/tui/components"
+)
+
+// ConfirmPathMsg is sent when a step wants to add Dir to PATH and
+// [safety] confirm_path_changes asks for confirmation first. The run waits
+// until the user answers.
+type ConfirmPathMsg struct {
+	Dir     string
+	Current []platform.PathEntry
+	reply   chan bool
+}
+
+// PathConfirmer asks in the wizard, with a dialog over the progress screen,
+// before setup adds a directory to PATH. Its Confirm is meant for
+// setup.Dependencies.ConfirmPath; pass it to WithPathConfirmer.
+type PathConfirmer struct {
+	mu     sync.Mutex
+	bridge *Bridge
+}
+
+// attach routes questions through b, the bridge of the run that started.
+func (c *PathConfirmer) attach(b *Bridge) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bridge = b
+}
+
+// Confirm shows the dialog and waits for the answer. It answers no if the
+// run hasn't started or is cancelled while waiting.
+func (c *PathConfirmer) Confirm(current []platform.PathEntry, dir string) bool {
+	c.mu.Lock()
+	b := c.bridge
+	c.mu.Unlock()
+	if b == nil {
+		return false
+	}
+	reply := make(chan bool, 1)
+	if !b.send(ConfirmPathMsg{Dir: dir, Current: current, reply: reply}) {
+		return false
+	}
+	select {
+	case ok := <-reply:
+		return ok
+	case <-b.ctx.Done():
+		return false
+	}
+}
+
+// answer replies to msg's question.
+func (msg ConfirmPathMsg) answer(ok bool) {
+	if msg.reply != nil {
+		msg.reply <- ok
+	}
+}
+
+// updateConfirm handles a key while the PATH dialog is open: y or enter
+// allows the change, n or esc refuses it. Either way the run carries on.
+func (m WizardModel) updateConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var ok bool
+	switch msg.String() {
+	case "y", "Y", "enter":
+		ok = true
+	case "n", "N", "esc":
+	default:
+		return m, nil
+	}
+	m.pathQuestion.answer(ok)
+	m.pathQuestion = nil
+	return m, m.bridge.NextMsg()
+}
+
+// renderPathQuestion renders the PATH dialog: the directory to add, the
+// current PATH with missing directories marked, and the keys to answer.
+func renderPathQuestion(s components.Styles, q *ConfirmPathMsg) string {
+	var b strings.Builder
+	b.WriteString(s.Warning.Render("Add to PATH?") + "\n\n")
+	fmt.Fprintf(&b, "  %s\n\n", s.SelectedItem.Render(q.Dir))
+	b.WriteString(s.Subtitle.Render("Current PATH") + "\n")
+	for _, e := range q.Current {
+		line := fmt.Sprintf("  %s (%s)", e.Dir, e.Source)
+		if !e.Exists {
+			line += " missing"
+		}
+		b.WriteString(s.Muted.Render(line) + "\n")
+	}
+	if len(q.Current) == 0 {
+		b.WriteString(s.Muted.Render("  (empty)") + "\n")
+	}
+	b.WriteString("\n" + s.Footer.Render("y/enter add it · n/esc leave PATH alone"))
+	return s.Panel.Render(b.String())
+}
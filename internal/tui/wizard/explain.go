@@ -9,20 +9,29 @@ import (
 
 // ExplainPanel renders a bordered "What's happening" panel with word-wrapped text.
 type ExplainPanel struct {
-	styles  components.Styles
-	text    string
-	visible bool
-	width   int
+	styles   components.Styles
+	title    string
+	text     string
+	visible  bool
+	width    int
+	maxLines int // of wrapped text; 0 means no limit
 }
 
 // NewExplainPanel creates an explain panel (hidden by default).
 func NewExplainPanel(styles components.Styles) ExplainPanel {
 	return ExplainPanel{
 		styles: styles,
+		title:  "What's happening",
 		width:  60,
 	}
 }
 
+// SetTitle returns a copy with updated title.
+func (p ExplainPanel) SetTitle(title string) ExplainPanel {
+	p.title = title
+	return p
+}
+
 // SetText returns a copy with updated text.
 func (p ExplainPanel) SetText(text string) ExplainPanel {
 	p.text = text
@@ -43,6 +52,13 @@ func (p ExplainPanel) SetWidth(w int) ExplainPanel {
 	return p
 }
 
+// SetMaxLines returns a copy that shows at most n lines of wrapped text,
+// ending with "…" when some are cut. n <= 0 shows them all.
+func (p ExplainPanel) SetMaxLines(n int) ExplainPanel {
+	p.maxLines = n
+	return p
+}
+
 // View renders the panel. Returns empty string when not visible.
 func (p ExplainPanel) View() string {
 	if !p.visible || p.text == "" {
@@ -56,12 +72,15 @@ func (p ExplainPanel) View() string {
 	}
 
 	wrapped := wordWrap(p.text, innerWidth)
+	if lines := strings.Split(wrapped, "\n"); p.maxLines > 0 && len(lines) > p.maxLines {
+		wrapped = strings.Join(append(lines[:p.maxLines-1], "…"), "\n")
+	}
 
 	panel := p.styles.Panel.
 		Width(p.width).
 		Render(
 			lipgloss.JoinVertical(lipgloss.Left,
-				p.styles.Subtitle.Render("What's happening"),
+				p.styles.Subtitle.Render(p.title),
 				"",
 				wrapped,
 			),
@@ -70,11 +89,19 @@ func (p ExplainPanel) View() string {
 	return panel
 }
 
-// wordWrap breaks text into lines that fit within the given width.
+// wordWrap breaks text into lines that fit within the given width. Line
+// breaks already in text are kept, so paragraphs and lists survive.
 func wordWrap(text string, width int) string {
 	if width <= 0 {
 		return text
 	}
+	if strings.Contains(text, "\n") {
+		paras := strings.Split(text, "\n")
+		for i, para := range paras {
+			paras[i] = wordWrap(para, width)
+		}
+		return strings.Join(paras, "\n")
+	}
 
 	words := strings.Fields(text)
 	if len(words) == 0 {
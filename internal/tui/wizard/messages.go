@@ -18,6 +18,8 @@ type StepDoneMsg struct {
 	Index    int
 	Total    int
 	Skipped  bool
+	Filtered bool // --only-step or --skip-step left the step out
+	DryRun   bool // the step was only described, not run
 }
 
 // StepErrorMsg is sent when a step fails.
@@ -36,6 +38,15 @@ type ModuleStartMsg struct {
 	Steps    []module.Step
 }
 
+// TotalStepsMsg is sent once dependencies are resolved, carrying the
+// number of steps the bridge will actually process. It replaces the
+// picker's estimate. Plan lists those steps in run order by
+// module.StepKey.
+type TotalStepsMsg struct {
+	Total int
+	Plan  []string
+}
+
 // AllDoneMsg is sent when all modules have finished.
 type AllDoneMsg struct {
 	Results []module.ModuleResult
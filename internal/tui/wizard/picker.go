@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/druarnfield/shhh/internal/module"
 	"github.com/druarnfield/shhh/internal/tui/components"
 )
@@ -32,6 +33,10 @@ type PickerModel struct {
 	cursor   int
 	width    int
 	height   int
+
+	// explain describes the module under the cursor; ? toggles it.
+	explain     ExplainPanel
+	showExplain bool
 }
 
 // NewPickerModel creates a picker from all modules in the registry.
@@ -39,12 +44,19 @@ func NewPickerModel(styles components.Styles, reg *module.Registry) PickerModel
 	m := PickerModel{
 		styles:   styles,
 		selected: make(map[string]bool),
+		explain:  NewExplainPanel(styles).SetTitle("What this sets up"),
 	}
 
-	// Build items grouped by category.
+	// Build items grouped by category. Modules the config left with no
+	// steps have nothing to do and aren't offered.
 	categories := []module.Category{module.CategoryBase, module.CategoryLanguage, module.CategoryTool}
 	for _, cat := range categories {
-		mods := reg.ByCategory(cat)
+		var mods []*module.Module
+		for _, mod := range reg.ByCategory(cat) {
+			if len(mod.Steps) > 0 {
+				mods = append(mods, mod)
+			}
+		}
 		if len(mods) == 0 {
 			continue
 		}
@@ -68,6 +80,20 @@ func NewPickerModel(styles components.Styles, reg *module.Registry) PickerModel
 	return m
 }
 
+// Select checks the given modules, and their dependencies, as if the user
+// had toggled each on. Unknown IDs are ignored.
+func (m PickerModel) Select(ids []string) PickerModel {
+	for _, id := range ids {
+		for _, item := range m.items {
+			if item.module != nil && item.module.ID == id && !m.selected[id] {
+				m.selected[id] = true
+				m.autoSelectDeps(item.module)
+			}
+		}
+	}
+	return m
+}
+
 // SelectedModuleIDs returns the IDs of all selected modules.
 func (m PickerModel) SelectedModuleIDs() []string {
 	var ids []string
@@ -102,6 +128,9 @@ func (m PickerModel) Update(msg tea.Msg) (PickerModel, tea.Cmd) {
 			}
 		case "a":
 			m.selectAll()
+		case "?":
+			m.showExplain = !m.showExplain
+			m.explain = m.explain.SetVisible(m.showExplain)
 		}
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -110,6 +139,10 @@ func (m PickerModel) Update(msg tea.Msg) (PickerModel, tea.Cmd) {
 	return m, nil
 }
 
+// sideBySideWidth is the narrowest terminal the picker puts the explain
+// panel beside the module list rather than below it.
+const sideBySideWidth = 90
+
 // View renders the picker.
 func (m PickerModel) View() string {
 	var b strings.Builder
@@ -119,6 +152,91 @@ func (m PickerModel) View() string {
 	b.WriteString(m.styles.Title.Render("Select modules to set up"))
 	b.WriteString("\n\n")
 
+	list := m.listView()
+	b.WriteString(m.withExplain(list, strings.Count(b.String(), "\n")))
+
+	b.WriteString("\n")
+
+	count := len(m.SelectedModuleIDs())
+	b.WriteString(m.styles.Footer.Render(
+		fmt.Sprintf("  space: toggle  a: select all  ?: explain  enter: confirm (%d selected)", count),
+	))
+
+	return b.String()
+}
+
+// withExplain lays out the module list with the explain panel for the
+// module under the cursor: beside the list when the terminal is wide
+// enough, otherwise below it. The panel is cut short to fit the height
+// left after the above lines of header. Without the panel, list is
+// returned as is.
+func (m PickerModel) withExplain(list string, above int) string {
+	item := m.currentItem()
+	if !m.showExplain || item == nil {
+		return list
+	}
+	panel := m.explain.SetText(moduleExplain(item.module))
+
+	// The panel's border, title and blank line take 4 lines; the footer 2.
+	const panelChrome, footer = 4, 2
+	if m.width >= sideBySideWidth {
+		listWidth := lipgloss.Width(list)
+		panel = panel.SetWidth(min(m.width-listWidth-4, 70))
+		if m.height > 0 {
+			panel = panel.SetMaxLines(max(m.height-above-footer-panelChrome, 1))
+		}
+		if view := panel.View(); view != "" {
+			return lipgloss.JoinHorizontal(lipgloss.Top, lipgloss.NewStyle().Width(listWidth+2).Render(list), view) + "\n"
+		}
+		return list
+	}
+
+	if m.width > 0 {
+		panel = panel.SetWidth(min(m.width-4, 70))
+	}
+	if m.height > 0 {
+		used := above + strings.Count(list, "\n") + 1
+		panel = panel.SetMaxLines(max(m.height-used-footer-panelChrome, 1))
+	}
+	if view := panel.View(); view != "" {
+		return list + "\n" + view + "\n"
+	}
+	return list
+}
+
+// currentItem returns the module row under the cursor, or nil.
+func (m PickerModel) currentItem() *pickerItem {
+	if m.cursor < 0 || m.cursor >= len(m.items) || m.items[m.cursor].module == nil {
+		return nil
+	}
+	return &m.items[m.cursor]
+}
+
+// moduleExplain describes mod for the picker's explain panel: its
+// description, then what each step is for.
+func moduleExplain(mod *module.Module) string {
+	var lines []string
+	if mod.Description != "" {
+		lines = append(lines, mod.Description, "")
+	}
+	for _, s := range mod.Steps {
+		why := s.Explain
+		if why == "" {
+			why = s.Description
+		}
+		if why == "" {
+			lines = append(lines, "• "+s.Name)
+		} else {
+			lines = append(lines, "• "+s.Name+": "+why)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// listView renders the category headers and module rows.
+func (m PickerModel) listView() string {
+	var b strings.Builder
+
 	for i, item := range m.items {
 		if item.isHeader {
 			b.WriteString(m.styles.Subtitle.Render(item.category))
@@ -156,13 +274,6 @@ func (m PickerModel) View() string {
 		b.WriteString("\n")
 	}
 
-	b.WriteString("\n")
-
-	count := len(m.SelectedModuleIDs())
-	b.WriteString(m.styles.Footer.Render(
-		fmt.Sprintf("  space: toggle  a: select all  enter: confirm (%d selected)", count),
-	))
-
 	return b.String()
 }
 
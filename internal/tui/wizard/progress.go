@@ -3,9 +3,11 @@ package wizard
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/druarnfield/shhh/internal/module"
 	"github.com/druarnfield/shhh/internal/tui/components"
 )
 
@@ -17,6 +19,16 @@ const (
 	stepDone
 	stepSkipped
 	stepFailed
+	stepWouldRun // dry run: described, not executed
+	stepFiltered // left out by --only-step or --skip-step
+)
+
+// renderMode selects how much of the progress screen is drawn.
+type renderMode int
+
+const (
+	renderFull    renderMode = iota // banner, title, bar, and every step
+	renderCompact                   // one bar line plus the current step
 )
 
 type stepStatus struct {
@@ -32,14 +44,26 @@ type ProgressModel struct {
 	spinner     spinner.Model
 	explain     ExplainPanel
 	showExplain bool
+	dryRun      bool
+	mode        renderMode
 
 	currentModule string
 	steps         []stepStatus
 	currentStep   int
 	overallDone   int
 	overallTotal  int
+	wouldRun      int
 	width         int
 	height        int
+
+	// ETA: plan lists every step by module.StepKey, estimates holds typical
+	// durations from earlier runs, finished marks steps already processed.
+	plan         []string
+	estimates    map[string]time.Duration
+	finished     map[string]bool
+	running      string
+	runningSince time.Time
+	now          func() time.Time
 }
 
 // NewProgressModel creates a progress view.
@@ -49,6 +73,8 @@ func NewProgressModel(styles components.Styles, showExplain bool) ProgressModel
 		spinner:     components.NewSpinner(styles),
 		explain:     NewExplainPanel(styles),
 		showExplain: showExplain,
+		finished:    make(map[string]bool),
+		now:         time.Now,
 	}
 }
 
@@ -63,11 +89,18 @@ func (m ProgressModel) Update(msg tea.Msg) (ProgressModel, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		if msg.String() == "?" {
+		switch msg.String() {
+		case "?":
 			m.showExplain = !m.showExplain
 			m.explain = m.explain.SetVisible(m.showExplain)
+		case "c":
+			m = m.SetCompact(m.mode != renderCompact)
 		}
 
+	case TotalStepsMsg:
+		m.overallTotal = msg.Total
+		m.plan = msg.Plan
+
 	case ModuleStartMsg:
 		m.currentModule = msg.Name
 		m.steps = make([]stepStatus, len(msg.Steps))
@@ -82,16 +115,25 @@ func (m ProgressModel) Update(msg tea.Msg) (ProgressModel, tea.Cmd) {
 			m.currentStep = msg.Index
 			m.explain = m.explain.SetText(msg.Explain).SetVisible(m.showExplain)
 		}
+		m.running = module.StepKey(msg.ModuleID, msg.StepName)
+		m.runningSince = m.now()
 
 	case StepDoneMsg:
 		if msg.Index < len(m.steps) {
-			if msg.Skipped {
+			switch {
+			case msg.Skipped:
 				m.steps[msg.Index].state = stepSkipped
-			} else {
+			case msg.Filtered:
+				m.steps[msg.Index].state = stepFiltered
+			case msg.DryRun:
+				m.steps[msg.Index].state = stepWouldRun
+				m.wouldRun++
+			default:
 				m.steps[msg.Index].state = stepDone
 			}
 			m.overallDone++
 		}
+		m.finished[module.StepKey(msg.ModuleID, msg.StepName)] = true
 
 	case StepErrorMsg:
 		if msg.Index < len(m.steps) {
@@ -99,6 +141,7 @@ func (m ProgressModel) Update(msg tea.Msg) (ProgressModel, tea.Cmd) {
 			m.steps[msg.Index].err = msg.Err
 			m.overallDone++
 		}
+		m.finished[module.StepKey(msg.ModuleID, msg.StepName)] = true
 
 	case spinner.TickMsg:
 		var cmd tea.Cmd
@@ -114,19 +157,53 @@ func (m ProgressModel) Update(msg tea.Msg) (ProgressModel, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
-// SetOverallTotal sets the total number of steps across all modules.
+// SetOverallTotal sets the total number of steps across all modules. A
+// later TotalStepsMsg from the bridge overrides it.
 func (m ProgressModel) SetOverallTotal(n int) ProgressModel {
 	m.overallTotal = n
 	return m
 }
 
+// SetDryRun marks the run as a dry run, adding a DRY RUN header and a count
+// of steps that would run.
+func (m ProgressModel) SetDryRun(dryRun bool) ProgressModel {
+	m.dryRun = dryRun
+	return m
+}
+
+// SetEstimates sets typical step durations, keyed by module.StepKey, from
+// which the progress line estimates the time remaining.
+func (m ProgressModel) SetEstimates(estimates map[string]time.Duration) ProgressModel {
+	m.estimates = estimates
+	return m
+}
+
+// SetCompact switches between the full step list and a compact view that
+// fits small screens: a single progress line and the current step's name.
+func (m ProgressModel) SetCompact(compact bool) ProgressModel {
+	m.mode = renderFull
+	if compact {
+		m.mode = renderCompact
+	}
+	return m
+}
+
 // View renders the progress screen.
 func (m ProgressModel) View() string {
+	if m.mode == renderCompact {
+		return m.compactView()
+	}
+
 	var b strings.Builder
 
 	b.WriteString(components.RenderBanner(m.styles))
 	b.WriteString("\n\n")
 
+	if m.dryRun {
+		b.WriteString(m.styles.Warning.Render("DRY RUN — nothing will be changed"))
+		b.WriteString("\n\n")
+	}
+
 	if m.currentModule != "" {
 		b.WriteString(m.styles.Title.Render(fmt.Sprintf("Setting up %s", m.currentModule)))
 		b.WriteString("\n\n")
@@ -134,30 +211,31 @@ func (m ProgressModel) View() string {
 
 	// Progress bar.
 	if m.overallTotal > 0 {
-		pct := float64(m.overallDone) / float64(m.overallTotal)
-		barWidth := 20
-		filled := int(pct * float64(barWidth))
-		if filled > barWidth {
-			filled = barWidth
+		b.WriteString(m.progressLine())
+		if m.dryRun {
+			b.WriteString(m.styles.Warning.Render(fmt.Sprintf("  (%d would run)", m.wouldRun)))
 		}
-
-		bar := m.styles.ProgressFull.Render(strings.Repeat("█", filled)) +
-			m.styles.ProgressEmpty.Render(strings.Repeat("░", barWidth-filled))
-
-		b.WriteString(fmt.Sprintf("  Step %d/%d  %s  %d%%\n\n",
-			m.overallDone, m.overallTotal, bar, int(pct*100)))
+		b.WriteString("\n\n")
 	}
 
 	// Step list.
 	for _, s := range m.steps {
 		icon := m.stepIcon(s)
 		line := fmt.Sprintf("  %s %s", icon, s.name)
+		switch s.state {
+		case stepWouldRun:
+			line += " (would run)"
+		case stepFiltered:
+			line += " (filtered out)"
+		}
 
 		switch s.state {
 		case stepDone:
 			line = m.styles.Success.Render(line)
-		case stepSkipped:
+		case stepSkipped, stepFiltered:
 			line = m.styles.Muted.Render(line)
+		case stepWouldRun:
+			line = m.styles.Warning.Render(line)
 		case stepFailed:
 			line = m.styles.Error.Render(line)
 		case stepRunning:
@@ -178,18 +256,95 @@ func (m ProgressModel) View() string {
 	}
 
 	b.WriteString("\n")
-	b.WriteString(m.styles.Footer.Render("  ?: toggle explain"))
+	b.WriteString(m.styles.Footer.Render("  ?: toggle explain  c: compact view"))
 
 	return b.String()
 }
 
+// compactView renders the compact mode: no banner, spinner, or step list,
+// just the progress line and the name of the step being worked on.
+func (m ProgressModel) compactView() string {
+	var b strings.Builder
+
+	if m.dryRun {
+		b.WriteString(m.styles.Warning.Render("DRY RUN"))
+		b.WriteString(" ")
+	}
+	if m.overallTotal > 0 {
+		b.WriteString(strings.TrimPrefix(m.progressLine(), "  "))
+	}
+	b.WriteString("\n")
+
+	if m.currentStep < len(m.steps) {
+		s := m.steps[m.currentStep]
+		line := fmt.Sprintf("  %s: %s", m.currentModule, s.name)
+		if s.state == stepFailed {
+			line = m.styles.Error.Render(line + " FAILED")
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	b.WriteString(m.styles.Footer.Render("  c: full view"))
+
+	return b.String()
+}
+
+// progressLine renders "  Step n/total  <bar>  pct%". A miscounted total
+// is clamped so the text and bar never run past 100%.
+func (m ProgressModel) progressLine() string {
+	done := min(m.overallDone, m.overallTotal)
+	pct := float64(done) / float64(m.overallTotal)
+	barWidth := 20
+	filled := int(pct * float64(barWidth))
+
+	bar := m.styles.ProgressFull.Render(strings.Repeat(m.styles.BarFull, filled)) +
+		m.styles.ProgressEmpty.Render(strings.Repeat(m.styles.BarEmpty, barWidth-filled))
+
+	line := fmt.Sprintf("  Step %d/%d  %s  %d%%", done, m.overallTotal, bar, int(pct*100))
+	if eta, ok := m.eta(); ok {
+		line += m.styles.Muted.Render(fmt.Sprintf("  ~%s remaining", max(eta.Round(time.Second), time.Second)))
+	}
+	return line
+}
+
+// eta sums the estimates of the steps still to run, less the time the
+// current step has already taken. It reports false in dry runs, when
+// nothing is left, and when fewer than half the planned steps have been
+// timed before (a first run, or mostly new modules), since the sum would
+// then be mostly guesswork.
+func (m ProgressModel) eta() (time.Duration, bool) {
+	if m.dryRun || len(m.plan) == 0 || len(m.estimates) == 0 {
+		return 0, false
+	}
+	known := 0
+	var left time.Duration
+	for _, key := range m.plan {
+		est, ok := m.estimates[key]
+		if ok {
+			known++
+		}
+		if m.finished[key] {
+			continue
+		}
+		if key == m.running {
+			est = max(est-m.now().Sub(m.runningSince), 0)
+		}
+		left += est
+	}
+	if known*2 < len(m.plan) || left <= 0 {
+		return 0, false
+	}
+	return left, true
+}
+
 func (m ProgressModel) stepIcon(s stepStatus) string {
 	switch s.state {
 	case stepDone:
 		return m.styles.StatusDone
 	case stepRunning:
 		return m.spinner.View()
-	case stepSkipped:
+	case stepSkipped, stepFiltered:
 		return m.styles.StatusSkipped
 	case stepFailed:
 		return m.styles.StatusFailed
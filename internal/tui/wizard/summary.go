@@ -1,19 +1,39 @@
 package wizard
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/druarnfield/shhh/internal/exec"
 	"github.com/druarnfield/shhh/internal/module"
+	"github.com/druarnfield/shhh/internal/platform"
 	"github.com/druarnfield/shhh/internal/tui/components"
 )
 
+// missingCommandGuidance is shown under a module that failed because a tool
+// it needs is not installed.
+const missingCommandGuidance = "A required tool is missing. Run 'shhh setup base' to install Scoop, then re-run."
+
+// policyLockedGuidance is shown under a module that failed because the OS
+// refused a write to the user environment or the PowerShell profile.
+const policyLockedGuidance = "Your IT policy may be preventing environment changes; contact your admin or run shhh elevated."
+
+// restartGuidance is shown when the run changed the persistent user
+// environment, which the shell that launched shhh won't see.
+const restartGuidance = "Restart your shell (or open a new terminal) to pick up the environment changes."
+
 // SummaryModel shows the final results screen.
 type SummaryModel struct {
 	styles  components.Styles
 	results []module.ModuleResult
 	err     error // runner-level error
+	detail  bool  // show every module, not just those that did work
+	dryRun  bool
+	diffs   bool // list the files the run changed (--show-diffs)
+	restart bool // the run changed the persistent user environment
 	width   int
 	height  int
 }
@@ -29,12 +49,43 @@ func (m SummaryModel) SetResults(results []module.ModuleResult) SummaryModel {
 	return m
 }
 
+// SetDryRun marks the results as coming from a dry run.
+func (m SummaryModel) SetDryRun(dryRun bool) SummaryModel {
+	m.dryRun = dryRun
+	return m
+}
+
+// SetShowDiffs adds a list of the files the run changed, each with a brief
+// diff, below the results.
+func (m SummaryModel) SetShowDiffs(show bool) SummaryModel {
+	m.diffs = show
+	return m
+}
+
+// SetRestartRequired adds a reminder to restart the shell, for runs that
+// changed the persistent user environment.
+func (m SummaryModel) SetRestartRequired(required bool) SummaryModel {
+	m.restart = required
+	return m
+}
+
 // SetError sets a runner-level error.
 func (m SummaryModel) SetError(err error) SummaryModel {
 	m.err = err
 	return m
 }
 
+// Failures returns the results of the modules that failed.
+func (m SummaryModel) Failures() []module.ModuleResult {
+	var failed []module.ModuleResult
+	for _, r := range m.results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
 // HasError returns true if any module failed or there was a runner error.
 func (m SummaryModel) HasError() bool {
 	if m.err != nil {
@@ -60,6 +111,8 @@ func (m SummaryModel) Update(msg tea.Msg) (SummaryModel, tea.Cmd) {
 		switch msg.String() {
 		case "enter", "q":
 			return m, tea.Quit
+		case "d":
+			m.detail = !m.detail
 		}
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -82,41 +135,69 @@ func (m SummaryModel) View() string {
 		b.WriteString("\n\n")
 		b.WriteString(m.styles.Warning.Render("  Fix the issue and re-run — completed steps will be skipped."))
 		b.WriteString("\n\n")
-	} else if m.HasError() {
-		b.WriteString(m.styles.Error.Render("Setup Failed"))
+	} else if n := len(m.Failures()); n > 0 {
+		noun := "failures"
+		if n == 1 {
+			noun = "failure"
+		}
+		b.WriteString(m.styles.Error.Render(fmt.Sprintf("Setup finished with %d %s", n, noun)))
+		b.WriteString("\n\n")
+	} else if m.dryRun {
+		b.WriteString(m.styles.Warning.Render("Dry Run Complete — nothing was changed"))
 		b.WriteString("\n\n")
 	} else {
 		b.WriteString(m.styles.Success.Render("Setup Complete!"))
 		b.WriteString("\n\n")
 	}
 
-	// Per-module results.
+	results := m.visibleResults()
 	totalCompleted := 0
 	totalSkipped := 0
+	totalWouldRun := 0
 	totalSteps := 0
-
-	for _, r := range m.results {
+	for _, r := range results {
 		totalCompleted += r.Completed
 		totalSkipped += r.Skipped
+		totalWouldRun += r.WouldRun
 		totalSteps += r.Total
+	}
 
-		status := m.styles.Success.Render("done")
-		if r.Err != nil {
-			status = m.styles.Error.Render(fmt.Sprintf("FAILED at %q", r.FailedStep))
+	// Failures always get the full breakdown; otherwise collapse to the
+	// modules that actually changed something unless detail is toggled on.
+	switch {
+	case m.detail || m.HasError():
+		b.WriteString(m.renderModuleResults(results))
+	case len(m.results) > 0 && totalCompleted == 0 && totalWouldRun == 0:
+		b.WriteString(m.styles.Muted.Render("  Already up to date — nothing changed."))
+		b.WriteString("\n")
+	default:
+		var changed []module.ModuleResult
+		for _, r := range results {
+			if r.Completed > 0 || r.WouldRun > 0 {
+				changed = append(changed, r)
+			}
 		}
+		b.WriteString(m.renderModuleResults(changed))
+	}
 
-		b.WriteString(fmt.Sprintf("  %s: %s (%d completed, %d skipped)\n",
-			r.ModuleID, status, r.Completed, r.Skipped))
-
-		if r.Err != nil {
-			b.WriteString(m.styles.Error.Render(fmt.Sprintf("    Error: %v", r.Err)))
-			b.WriteString("\n")
+	if len(results) > 0 {
+		if m.dryRun {
+			b.WriteString(fmt.Sprintf("\n  Total: %d steps (%d would run, %d skipped)\n",
+				totalSteps, totalWouldRun, totalSkipped))
+		} else {
+			b.WriteString(fmt.Sprintf("\n  Total: %d steps (%d completed, %d skipped)\n",
+				totalSteps, totalCompleted, totalSkipped))
 		}
 	}
 
-	if len(m.results) > 0 {
-		b.WriteString(fmt.Sprintf("\n  Total: %d steps (%d completed, %d skipped)\n",
-			totalSteps, totalCompleted, totalSkipped))
+	if m.diffs {
+		b.WriteString(m.renderChanges())
+	}
+
+	if m.restart {
+		b.WriteString("\n")
+		b.WriteString(m.styles.Warning.Render("  " + restartGuidance))
+		b.WriteString("\n")
 	}
 
 	if m.HasError() {
@@ -126,7 +207,129 @@ func (m SummaryModel) View() string {
 	}
 
 	b.WriteString("\n")
-	b.WriteString(m.styles.Footer.Render("  Press enter or q to exit"))
+	footer := "  Press enter or q to exit"
+	if !m.HasError() && len(m.results) > 0 {
+		footer = "  d: toggle details  enter/q: exit"
+	}
+	b.WriteString(m.styles.Footer.Render(footer))
 
 	return b.String()
 }
+
+// visibleResults returns the results worth a row: modules with no steps
+// (e.g. tools with every list empty) had nothing to do and are left out
+// unless they failed.
+func (m SummaryModel) visibleResults() []module.ModuleResult {
+	var results []module.ModuleResult
+	for _, r := range m.results {
+		if r.Total > 0 || r.Err != nil {
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
+// slowestSteps is how many of each module's slowest steps the summary lists.
+const slowestSteps = 3
+
+// renderModuleResults renders one aligned status row per module, each
+// followed by its slowest steps, any retried steps and its error if it
+// failed.
+func (m SummaryModel) renderModuleResults(results []module.ModuleResult) string {
+	rows := make([][]string, len(results))
+	for i, r := range results {
+		status := m.styles.Success.Render("done")
+		switch {
+		case errors.Is(r.Err, module.ErrDependencyFailed):
+			status = m.styles.Error.Render("NOT RUN")
+		case r.Err != nil:
+			status = m.styles.Error.Render(fmt.Sprintf("FAILED at %q", r.FailedStep))
+		case r.Unsupported != "":
+			status = m.styles.Warning.Render("skipped: " + r.Unsupported)
+		}
+		counts := fmt.Sprintf("(%d completed, %d skipped)", r.Completed, r.Skipped)
+		if m.dryRun {
+			counts = fmt.Sprintf("(%d would run, %d skipped)", r.WouldRun, r.Skipped)
+		}
+		if r.Filtered > 0 {
+			counts = strings.TrimSuffix(counts, ")") + fmt.Sprintf(", %d filtered)", r.Filtered)
+		}
+		rows[i] = []string{r.ModuleID + ":", status, counts}
+	}
+	lines := strings.SplitAfter(components.RenderTable(rows, m.styles), "\n")
+
+	var b strings.Builder
+	for i, r := range results {
+		b.WriteString(lines[i])
+		if slow := slowestNote(r); slow != "" {
+			b.WriteString(m.styles.Muted.Render("    "+slow) + "\n")
+		}
+		for _, name := range r.RetriedSteps() {
+			b.WriteString(m.styles.Warning.Render("    "+r.AttemptNote(name)) + "\n")
+		}
+		if r.Err != nil {
+			b.WriteString(m.styles.Error.Render(fmt.Sprintf("    Error: %v", r.Err)) + "\n")
+			if errors.Is(r.Err, exec.ErrCommandNotFound) {
+				b.WriteString(m.styles.Warning.Render("    "+missingCommandGuidance) + "\n")
+			}
+			if errors.Is(r.Err, platform.ErrPolicyLocked) {
+				b.WriteString(m.styles.Warning.Render("    "+policyLockedGuidance) + "\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// renderChanges renders the files the run changed, one aligned row per file
+// with its added and removed line counts, each followed by a brief diff.
+func (m SummaryModel) renderChanges() string {
+	var changes []module.FileChange
+	for _, r := range m.results {
+		changes = append(changes, r.Changes...)
+	}
+	if len(changes) == 0 {
+		return "\n" + m.styles.Muted.Render("  No files changed.") + "\n"
+	}
+
+	rows := make([][]string, len(changes))
+	for i, c := range changes {
+		added, removed := c.DiffStat()
+		rows[i] = []string{c.Path, fmt.Sprintf("+%d -%d", added, removed)}
+	}
+	lines := strings.SplitAfter(components.RenderTable(rows, m.styles), "\n")
+
+	var b strings.Builder
+	b.WriteString("\n" + m.styles.Title.Render("Changed files") + "\n")
+	for i, c := range changes {
+		b.WriteString(lines[i])
+		for _, line := range c.BriefDiff() {
+			style := m.styles.Muted
+			switch line[0] {
+			case '+':
+				style = m.styles.Success
+			case '-':
+				style = m.styles.Error
+			}
+			b.WriteString(style.Render("    "+line) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// slowestNote lists r's slowest steps with how long each took, e.g.
+// "slowest: Install Go 42s, Set GOPATH 1.2s (already done)", or returns ""
+// if no step was timed.
+func slowestNote(r module.ModuleResult) string {
+	names := r.SlowestSteps(slowestSteps)
+	if len(names) == 0 {
+		return ""
+	}
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s %s", name, r.Durations[name].Round(100*time.Millisecond))
+		if r.Outcomes[name] == module.StepSkipped {
+			parts[i] += " (already done)"
+		}
+	}
+	return "slowest: " + strings.Join(parts, ", ")
+}
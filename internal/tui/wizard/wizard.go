@@ -1,6 +1,8 @@
 package wizard
 
 import (
+	"time"
+
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/druarnfield/shhh/internal/module"
@@ -11,15 +13,18 @@ type screen int
 
 const (
 	screenPicker screen = iota
+	screenCerts
 	screenProgress
 	screenSummary
 )
 
-// WizardModel is the top-level tea.Model coordinating picker → progress → summary.
+// WizardModel is the top-level tea.Model coordinating picker → (certs) →
+// progress → summary.
 type WizardModel struct {
 	styles   components.Styles
 	screen   screen
 	picker   PickerModel
+	certs    CertPickerModel
 	progress ProgressModel
 	summary  SummaryModel
 
@@ -28,6 +33,36 @@ type WizardModel struct {
 	registry *module.Registry
 	explain  bool
 	dryRun   bool
+	compact  bool
+	diffs    bool
+
+	// selectCerts enables the cert screen. applyCerts receives the cert screen's choices before the run starts;
+	// pendingIDs holds the picked modules while that screen is shown.
+	selectCerts bool
+	applyCerts  func([]CertChoice)
+	pendingIDs  []string
+
+	// preselect is checked in the picker up front (--select); with
+	// autoConfirm the picker is confirmed as soon as the program starts.
+	preselect   []string
+	autoConfirm bool
+
+	// estimates are typical step durations from earlier runs, keyed by
+	// module.StepKey, for the progress screen's ETA.
+	estimates map[string]time.Duration
+
+	// restartRequired reports, once the run is done, whether it changed
+	// the persistent user environment.
+	restartRequired func() bool
+
+	// pathConfirmer, if set, asks before PATH changes; pathQuestion is the
+	// question on screen, if any, which the run is waiting on.
+	pathConfirmer *PathConfirmer
+	pathQuestion  *ConfirmPathMsg
+
+	// notice is a line about how the config was set up (e.g. the detected
+	// system proxy), shown above the picker and the summary.
+	notice string
 
 	width    int
 	height   int
@@ -41,8 +76,8 @@ func New(reg *module.Registry, runner *module.Runner, explain, dryRun bool) Wiza
 		styles:   styles,
 		screen:   screenPicker,
 		picker:   NewPickerModel(styles, reg),
-		progress: NewProgressModel(styles, explain),
-		summary:  NewSummaryModel(styles),
+		progress: NewProgressModel(styles, explain).SetDryRun(dryRun),
+		summary:  NewSummaryModel(styles).SetDryRun(dryRun),
 		runner:   runner,
 		registry: reg,
 		explain:  explain,
@@ -50,9 +85,92 @@ func New(reg *module.Registry, runner *module.Runner, explain, dryRun bool) Wiza
 	}
 }
 
-// Init satisfies tea.Model.
+// WithStyles returns a copy of m whose screens render with styles (e.g. a
+// different icon set). Call it before the program starts.
+func (m WizardModel) WithStyles(styles components.Styles) WizardModel {
+	m.styles = styles
+	m.picker = NewPickerModel(styles, m.registry).Select(m.preselect)
+	m.progress = NewProgressModel(styles, m.explain).SetDryRun(m.dryRun).SetCompact(m.compact).
+		SetEstimates(m.estimates)
+	m.summary = NewSummaryModel(styles).SetDryRun(m.dryRun).SetShowDiffs(m.diffs)
+	m.certs = NewCertPickerModel(styles, m.certs.choices)
+	return m
+}
+
+// WithCompact returns a copy of m whose progress screen starts in compact
+// mode (--compact). The summary is always shown in full.
+func (m WizardModel) WithCompact(compact bool) WizardModel {
+	m.compact = compact
+	m.progress = m.progress.SetCompact(compact)
+	return m
+}
+
+// WithCertSelection returns a copy of m that shows a cert selection screen
+// after the picker (--select-certs). apply receives the final choices
+// before any module runs.
+func (m WizardModel) WithCertSelection(choices []CertChoice, apply func([]CertChoice)) WizardModel {
+	m.certs = NewCertPickerModel(m.styles, choices)
+	m.selectCerts = true
+	m.applyCerts = apply
+	return m
+}
+
+// WithSelection returns a copy of m whose picker starts with ids (and their
+// dependencies) checked (--select). With autoConfirm the picker is skipped
+// and the run starts straight away (--auto-confirm).
+func (m WizardModel) WithSelection(ids []string, autoConfirm bool) WizardModel {
+	m.preselect = ids
+	m.autoConfirm = autoConfirm
+	m.picker = m.picker.Select(ids)
+	return m
+}
+
+// WithShowDiffs returns a copy of m whose summary lists the files the run
+// changed, with a brief diff of each (--show-diffs).
+func (m WizardModel) WithShowDiffs(show bool) WizardModel {
+	m.diffs = show
+	m.summary = m.summary.SetShowDiffs(show)
+	return m
+}
+
+// WithRestartCheck returns a copy of m whose summary reminds the user to
+// restart their shell when required reports true after the run.
+func (m WizardModel) WithRestartCheck(required func() bool) WizardModel {
+	m.restartRequired = required
+	return m
+}
+
+// WithPathConfirmer returns a copy of m that asks c's questions about PATH
+// changes ([safety] confirm_path_changes) in a dialog during the run.
+func (m WizardModel) WithPathConfirmer(c *PathConfirmer) WizardModel {
+	m.pathConfirmer = c
+	return m
+}
+
+// WithNotice returns a copy of m that shows notice above the picker and
+// the summary, where setup's text mode would print it before the run. An
+// empty notice shows nothing.
+func (m WizardModel) WithNotice(notice string) WizardModel {
+	m.notice = notice
+	return m
+}
+
+// WithEstimates returns a copy of m whose progress screen shows an ETA
+// based on estimates, typical step durations keyed by module.StepKey.
+func (m WizardModel) WithEstimates(estimates map[string]time.Duration) WizardModel {
+	m.estimates = estimates
+	m.progress = m.progress.SetEstimates(estimates)
+	return m
+}
+
+// Init satisfies tea.Model. With auto-confirm it confirms the pre-selected
+// modules.
 func (m WizardModel) Init() tea.Cmd {
-	return nil
+	if !m.autoConfirm {
+		return nil
+	}
+	ids := m.picker.SelectedModuleIDs()
+	return func() tea.Msg { return PickerConfirmMsg{ModuleIDs: ids} }
 }
 
 // Update handles messages and delegates to the active screen.
@@ -65,6 +183,7 @@ func (m WizardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 		// Propagate to children.
 		m.picker, _ = m.picker.Update(msg)
+		m.certs, _ = m.certs.Update(msg)
 		m.progress, _ = m.progress.Update(msg)
 		m.summary, _ = m.summary.Update(msg)
 		return m, nil
@@ -77,11 +196,16 @@ func (m WizardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.quitting = true
 			return m, tea.Quit
 		}
+		if m.pathQuestion != nil {
+			return m.updateConfirm(msg)
+		}
 	}
 
 	switch m.screen {
 	case screenPicker:
 		return m.updatePicker(msg)
+	case screenCerts:
+		return m.updateCerts(msg)
 	case screenProgress:
 		return m.updateProgress(msg)
 	case screenSummary:
@@ -98,44 +222,39 @@ func (m WizardModel) View() string {
 	}
 	switch m.screen {
 	case screenPicker:
-		return m.picker.View()
+		return m.withNotice(m.picker.View())
+	case screenCerts:
+		return m.certs.View()
 	case screenProgress:
+		if m.pathQuestion != nil {
+			return m.progress.View() + "\n\n" + renderPathQuestion(m.styles, m.pathQuestion)
+		}
 		return m.progress.View()
 	case screenSummary:
-		return m.summary.View()
+		return m.withNotice(m.summary.View())
 	}
 	return ""
 }
 
+// withNotice puts m's notice, if any, above view.
+func (m WizardModel) withNotice(view string) string {
+	if m.notice == "" {
+		return view
+	}
+	return m.styles.Muted.Render(m.notice) + "\n\n" + view
+}
+
 func (m WizardModel) updatePicker(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
 	case PickerConfirmMsg:
-		// Transition to progress screen.
-		m.screen = screenProgress
-
-		// Resolve deps to get the full set of modules that will run,
-		// then calculate total steps accurately.
-		resolved, err := m.registry.ResolveDeps(msg.ModuleIDs)
-		if err != nil {
-			// If dep resolution fails, the bridge will report the error.
-			resolved = msg.ModuleIDs
+		if m.selectCerts {
+			m.screen = screenCerts
+			m.pendingIDs = msg.ModuleIDs
+			return m, nil
 		}
-		total := 0
-		for _, id := range resolved {
-			mod := m.registry.Get(id)
-			if mod != nil {
-				total += len(mod.Steps)
-			}
-		}
-		m.progress = m.progress.SetOverallTotal(total)
-
-		// Create and start the bridge.
-		m.bridge = NewBridge(m.runner, m.registry, msg.ModuleIDs)
-		startCmd := m.bridge.Start()
-
-		return m, tea.Batch(startCmd, m.progress.Init())
+		return m.start(msg.ModuleIDs)
 
 	default:
 		m.picker, cmd = m.picker.Update(msg)
@@ -144,6 +263,51 @@ func (m WizardModel) updatePicker(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+func (m WizardModel) updateCerts(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(CertConfirmMsg); ok {
+		if m.applyCerts != nil {
+			m.applyCerts(msg.Choices)
+		}
+		return m.start(m.pendingIDs)
+	}
+
+	var cmd tea.Cmd
+	m.certs, cmd = m.certs.Update(msg)
+	return m, cmd
+}
+
+// start switches to the progress screen and runs moduleIDs. There is no
+// planning screen: each step is checked as the run reaches it, so the
+// progress screen covers checks too.
+func (m WizardModel) start(moduleIDs []string) (tea.Model, tea.Cmd) {
+	m.screen = screenProgress
+
+	// Resolve deps to estimate the total steps so the bar starts out
+	// sized; the bridge sends the authoritative total via TotalStepsMsg.
+	resolved, err := m.registry.ResolveDeps(moduleIDs)
+	if err != nil {
+		// If dep resolution fails, the bridge will report the error.
+		resolved = moduleIDs
+	}
+	total := 0
+	for _, id := range resolved {
+		mod := m.registry.Get(id)
+		if mod != nil {
+			total += len(mod.Steps)
+		}
+	}
+	m.progress = m.progress.SetOverallTotal(total)
+
+	// Create and start the bridge.
+	m.bridge = NewBridge(m.runner, m.registry, moduleIDs)
+	if m.pathConfirmer != nil {
+		m.pathConfirmer.attach(m.bridge)
+	}
+	startCmd := m.bridge.Start()
+
+	return m, tea.Batch(startCmd, m.progress.Init())
+}
+
 func (m WizardModel) updateProgress(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 
@@ -151,6 +315,9 @@ func (m WizardModel) updateProgress(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case AllDoneMsg:
 		m.screen = screenSummary
 		m.summary = m.summary.SetResults(msg.Results)
+		if m.restartRequired != nil {
+			m.summary = m.summary.SetRestartRequired(m.restartRequired())
+		}
 		return m, nil
 
 	case RunErrorMsg:
@@ -158,7 +325,13 @@ func (m WizardModel) updateProgress(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.summary = m.summary.SetError(msg.Err)
 		return m, nil
 
-	case ModuleStartMsg, StepStartMsg, StepDoneMsg, StepErrorMsg:
+	case ConfirmPathMsg:
+		// The run waits for the answer, so the next message is only asked
+		// for once it is given (updateConfirm).
+		m.pathQuestion = &msg
+		return m, nil
+
+	case TotalStepsMsg, ModuleStartMsg, StepStartMsg, StepDoneMsg, StepErrorMsg:
 		var cmd tea.Cmd
 		m.progress, cmd = m.progress.Update(msg)
 		cmds = append(cmds, cmd)
@@ -192,6 +365,15 @@ func (m WizardModel) Results() []module.ModuleResult {
 	return m.summary.results
 }
 
+// Selected returns the module IDs picked for the run, or nil if the
+// picker was never confirmed.
+func (m WizardModel) Selected() []string {
+	if m.bridge == nil {
+		return nil
+	}
+	return m.bridge.moduleIDs
+}
+
 // RunError returns the runner-level error, if any.
 func (m WizardModel) RunError() error {
 	return m.summary.err
@@ -2,15 +2,22 @@ package wizard
 
 import (
 	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/druarnfield/shhh/internal/exec"
 	"github.com/druarnfield/shhh/internal/logging"
 	"github.com/druarnfield/shhh/internal/module"
+	"github.com/druarnfield/shhh/internal/platform"
 	"github.com/druarnfield/shhh/internal/tui/components"
 )
 
@@ -207,6 +214,95 @@ func TestPicker_EmptySelectionDoesNotConfirm(t *testing.T) {
 	}
 }
 
+func TestPicker_HidesModulesWithoutSteps(t *testing.T) {
+	s := components.DefaultStyles()
+	reg := testRegistry()
+	reg.Register(&module.Module{
+		ID:       "tools",
+		Name:     "Tools",
+		Category: module.CategoryTool,
+	})
+	p := NewPickerModel(s, reg)
+
+	if strings.Contains(p.View(), "Tools") {
+		t.Error("module with no steps should not be listed")
+	}
+	p, _ = p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}})
+	for _, id := range p.SelectedModuleIDs() {
+		if id == "tools" {
+			t.Error("select all should not pick a module with no steps")
+		}
+	}
+}
+
+func TestPicker_ExplainToggle(t *testing.T) {
+	s := components.DefaultStyles()
+	p := NewPickerModel(s, testRegistry())
+	p, _ = p.Update(tea.WindowSizeMsg{Width: 60, Height: 40})
+
+	if strings.Contains(p.View(), "What this sets up") {
+		t.Fatal("explain panel should be hidden until ? is pressed")
+	}
+	p, _ = p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("?")})
+	out := p.View()
+	if !strings.Contains(out, "What this sets up") {
+		t.Fatalf("? should show the explain panel, got:\n%s", out)
+	}
+	// The cursor starts on Base: its steps' explanations are rolled up.
+	if !strings.Contains(out, "explains step a") || !strings.Contains(out, "explains step b") {
+		t.Errorf("panel should roll up the module's step explanations, got:\n%s", out)
+	}
+
+	p, _ = p.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if out := p.View(); strings.Contains(out, "explains step a") || !strings.Contains(out, "install-python") {
+		t.Errorf("panel should follow the cursor to Python, got:\n%s", out)
+	}
+
+	p, _ = p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("?")})
+	if strings.Contains(p.View(), "What this sets up") {
+		t.Error("? again should hide the panel")
+	}
+}
+
+func TestPicker_ExplainLayout(t *testing.T) {
+	s := components.DefaultStyles()
+	reg := testRegistry()
+	reg.Get("base").Description = "Proxy, certificates and the other basics every machine needs."
+
+	show := func(width, height int) string {
+		p := NewPickerModel(s, reg)
+		p, _ = p.Update(tea.WindowSizeMsg{Width: width, Height: height})
+		p, _ = p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("?")})
+		return p.View()
+	}
+	lineWith := func(out, substr string) int {
+		for i, line := range strings.Split(out, "\n") {
+			if strings.Contains(line, substr) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	wide := show(120, 40)
+	if i := lineWith(wide, "[ ] Python"); i < 0 || !strings.Contains(strings.Split(wide, "\n")[i], "│") {
+		t.Errorf("wide terminal should put the panel beside the list, got:\n%s", wide)
+	}
+	narrow := show(60, 40)
+	if lineWith(narrow, "What this sets up") <= lineWith(narrow, "Go") {
+		t.Errorf("narrow terminal should put the panel below the list, got:\n%s", narrow)
+	}
+
+	// A short terminal cuts the panel rather than pushing the footer off.
+	short := show(60, 24)
+	if !strings.Contains(short, "…") {
+		t.Errorf("panel should be truncated on a short terminal, got:\n%s", short)
+	}
+	if n := strings.Count(short, "\n") + 1; n > 24 {
+		t.Errorf("view is %d lines, want at most 24:\n%s", n, short)
+	}
+}
+
 // --- Progress Model tests ---
 
 func TestProgress_ModuleStart(t *testing.T) {
@@ -269,6 +365,196 @@ func TestProgress_StepDoneCompleted(t *testing.T) {
 	}
 }
 
+func TestProgress_StepDoneFiltered(t *testing.T) {
+	s := components.DefaultStyles()
+	p := NewProgressModel(s, false)
+	p = p.SetOverallTotal(2)
+
+	p, _ = p.Update(ModuleStartMsg{
+		ModuleID: "base",
+		Name:     "Base",
+		Steps:    []module.Step{{Name: "Install Scoop"}, {Name: "s2"}},
+	})
+	p, _ = p.Update(StepStartMsg{ModuleID: "base", StepName: "Install Scoop", Index: 0, Total: 2})
+	p, _ = p.Update(StepDoneMsg{ModuleID: "base", StepName: "Install Scoop", Index: 0, Total: 2, Filtered: true})
+
+	if p.steps[0].state != stepFiltered {
+		t.Errorf("state = %d, want stepFiltered", p.steps[0].state)
+	}
+	if out := p.View(); !strings.Contains(out, "Install Scoop (filtered out)") {
+		t.Errorf("view should mark the step filtered out:\n%s", out)
+	}
+}
+
+func TestProgress_ASCIIIcons(t *testing.T) {
+	s := components.StylesWithIcons(components.IconsASCII)
+	p := NewProgressModel(s, false)
+	p = p.SetOverallTotal(2)
+
+	p, _ = p.Update(ModuleStartMsg{
+		ModuleID: "base",
+		Name:     "Base",
+		Steps:    []module.Step{{Name: "s1"}, {Name: "s2"}},
+	})
+	p, _ = p.Update(StepStartMsg{ModuleID: "base", StepName: "s1", Index: 0, Total: 2})
+	p, _ = p.Update(StepDoneMsg{ModuleID: "base", StepName: "s1", Index: 0, Total: 2, Skipped: true})
+
+	out := p.View()
+	if !strings.Contains(out, s.StatusSkipped) {
+		t.Errorf("should show ASCII skipped icon %q", s.StatusSkipped)
+	}
+	if !strings.Contains(out, s.StatusPending) {
+		t.Errorf("should show ASCII pending icon %q", s.StatusPending)
+	}
+	if strings.Contains(out, "█") {
+		t.Error("progress bar should use ASCII characters")
+	}
+}
+
+func TestProgress_DryRun(t *testing.T) {
+	s := components.DefaultStyles()
+	p := NewProgressModel(s, false).SetDryRun(true)
+	p = p.SetOverallTotal(2)
+
+	p, _ = p.Update(ModuleStartMsg{
+		ModuleID: "base",
+		Name:     "Base",
+		Steps:    []module.Step{{Name: "s1"}, {Name: "s2"}},
+	})
+	p, _ = p.Update(StepDoneMsg{ModuleID: "base", StepName: "s1", Index: 0, Total: 2, Skipped: true})
+	p, _ = p.Update(StepDoneMsg{ModuleID: "base", StepName: "s2", Index: 1, Total: 2, DryRun: true})
+
+	if p.steps[1].state != stepWouldRun {
+		t.Errorf("s2 state = %d, want stepWouldRun", p.steps[1].state)
+	}
+	out := p.View()
+	for _, want := range []string{"DRY RUN", "s2 (would run)", "(1 would run)", "Step 2/2"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("view missing %q", want)
+		}
+	}
+}
+
+func TestProgress_CompactToggle(t *testing.T) {
+	s := components.DefaultStyles()
+	p := NewProgressModel(s, false).SetCompact(true)
+	p = p.SetOverallTotal(2)
+
+	p, _ = p.Update(ModuleStartMsg{
+		ModuleID: "base",
+		Name:     "Base",
+		Steps:    []module.Step{{Name: "s1"}, {Name: "s2"}},
+	})
+	p, _ = p.Update(StepDoneMsg{ModuleID: "base", StepName: "s1", Index: 0, Total: 2})
+	p, _ = p.Update(StepStartMsg{ModuleID: "base", StepName: "s2", Index: 1, Total: 2})
+
+	out := p.View()
+	if !strings.Contains(out, "Step 1/2") || !strings.Contains(out, "Base: s2") {
+		t.Errorf("compact view should show progress and current step, got:\n%s", out)
+	}
+	if strings.Contains(out, "s1") {
+		t.Error("compact view should not list finished steps")
+	}
+	if strings.Contains(out, "Setting up") {
+		t.Error("compact view should not show the title")
+	}
+
+	p, _ = p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'c'}})
+	out = p.View()
+	if !strings.Contains(out, "s1") {
+		t.Error("c should switch back to the full step list")
+	}
+}
+
+func TestProgress_ClampsMiscountedTotal(t *testing.T) {
+	s := components.DefaultStyles()
+	p := NewProgressModel(s, false).SetOverallTotal(1)
+
+	p, _ = p.Update(ModuleStartMsg{
+		ModuleID: "base",
+		Name:     "Base",
+		Steps:    []module.Step{{Name: "s1"}, {Name: "s2"}},
+	})
+	p, _ = p.Update(StepDoneMsg{ModuleID: "base", StepName: "s1", Index: 0, Total: 2})
+	p, _ = p.Update(StepDoneMsg{ModuleID: "base", StepName: "s2", Index: 1, Total: 2})
+
+	out := p.View()
+	if !strings.Contains(out, "Step 1/1") || strings.Contains(out, "200%") {
+		t.Errorf("progress should clamp to the total, got:\n%s", out)
+	}
+
+	// The bridge's authoritative total replaces the estimate.
+	p, _ = p.Update(TotalStepsMsg{Total: 2})
+	if out := p.View(); !strings.Contains(out, "Step 2/2") {
+		t.Errorf("TotalStepsMsg should set the total, got:\n%s", out)
+	}
+}
+
+func TestProgress_ETA(t *testing.T) {
+	s := components.DefaultStyles()
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	p := NewProgressModel(s, false).SetEstimates(map[string]time.Duration{
+		"base/s1": 10 * time.Second,
+		"base/s2": 35 * time.Second,
+	})
+	p.now = func() time.Time { return now }
+
+	p, _ = p.Update(TotalStepsMsg{Total: 2, Plan: []string{"base/s1", "base/s2"}})
+	p, _ = p.Update(ModuleStartMsg{
+		ModuleID: "base",
+		Name:     "Base",
+		Steps:    []module.Step{{Name: "s1"}, {Name: "s2"}},
+	})
+	if out := p.View(); !strings.Contains(out, "~45s remaining") {
+		t.Errorf("want ETA of both steps, got:\n%s", out)
+	}
+
+	p, _ = p.Update(StepStartMsg{ModuleID: "base", StepName: "s1", Index: 0, Total: 2})
+	now = now.Add(4 * time.Second)
+	if out := p.View(); !strings.Contains(out, "~41s remaining") {
+		t.Errorf("ETA should count down during a step, got:\n%s", out)
+	}
+
+	p, _ = p.Update(StepDoneMsg{ModuleID: "base", StepName: "s1", Index: 0, Total: 2})
+	if out := p.View(); !strings.Contains(out, "~35s remaining") {
+		t.Errorf("finished steps should drop out of the ETA, got:\n%s", out)
+	}
+}
+
+func TestProgress_NoETAWithoutHistory(t *testing.T) {
+	s := components.DefaultStyles()
+	plan := []string{"base/s1", "base/s2", "node/s1"}
+
+	p := NewProgressModel(s, false)
+	p, _ = p.Update(TotalStepsMsg{Total: 3, Plan: plan})
+	if out := p.View(); strings.Contains(out, "remaining") {
+		t.Errorf("first run should have no ETA, got:\n%s", out)
+	}
+
+	// One timed step out of three is not enough to go on.
+	p = p.SetEstimates(map[string]time.Duration{"base/s1": time.Minute})
+	if out := p.View(); strings.Contains(out, "remaining") {
+		t.Errorf("mostly untimed plan should have no ETA, got:\n%s", out)
+	}
+}
+
+func TestSummary_DryRun(t *testing.T) {
+	s := components.DefaultStyles()
+	m := NewSummaryModel(s).SetDryRun(true).SetResults([]module.ModuleResult{
+		{ModuleID: "base", WouldRun: 2, Skipped: 1, Total: 3},
+	})
+
+	out := m.View()
+	for _, want := range []string{"Dry Run Complete", "2 would run, 1 skipped"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("view missing %q", want)
+		}
+	}
+	if strings.Contains(out, "nothing changed") {
+		t.Error("dry run with pending steps should not report up to date")
+	}
+}
+
 func TestProgress_ToggleExplain(t *testing.T) {
 	s := components.DefaultStyles()
 	p := NewProgressModel(s, false)
@@ -319,8 +605,8 @@ func TestSummary_Failure(t *testing.T) {
 		},
 	})
 	out := sm.View()
-	if !strings.Contains(out, "Failed") {
-		t.Error("should show 'Failed'")
+	if !strings.Contains(out, "Setup finished with 1 failure") {
+		t.Error("should show 'Setup finished with 1 failure'")
 	}
 	if !strings.Contains(out, "something broke") {
 		t.Error("should show error message")
@@ -330,6 +616,280 @@ func TestSummary_Failure(t *testing.T) {
 	}
 }
 
+func TestSummary_MultipleFailures(t *testing.T) {
+	s := components.DefaultStyles()
+	sm := NewSummaryModel(s).SetResults([]module.ModuleResult{
+		{ModuleID: "base", Completed: 3, Total: 3},
+		{ModuleID: "python", Completed: 1, Total: 2, FailedStep: "Install uv", Err: errors.New("uv download failed")},
+		{ModuleID: "node", Total: 3, FailedStep: "Install fnm", Err: errors.New("fnm not found")},
+		{ModuleID: "tools", Total: 1, Err: fmt.Errorf("%w: node", module.ErrDependencyFailed)},
+	})
+
+	if got := len(sm.Failures()); got != 3 {
+		t.Errorf("Failures() = %d, want 3", got)
+	}
+	out := sm.View()
+	for _, want := range []string{
+		"Setup finished with 3 failures",
+		`FAILED at "Install uv"`, "uv download failed",
+		`FAILED at "Install fnm"`, "fnm not found",
+		"NOT RUN", "dependency failed: node",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("view missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestSummary_ShowDiffs(t *testing.T) {
+	s := components.DefaultStyles()
+	results := []module.ModuleResult{
+		{ModuleID: "node", Completed: 1, Total: 1, Changes: []module.FileChange{{
+			Path:   "profile.ps1",
+			Before: "# mine\n",
+			After:  "# mine\nfnm env | Invoke-Expression\n",
+		}}},
+	}
+
+	if out := NewSummaryModel(s).SetResults(results).View(); strings.Contains(out, "profile.ps1") {
+		t.Error("changed files should only be listed with --show-diffs")
+	}
+	out := NewSummaryModel(s).SetResults(results).SetShowDiffs(true).View()
+	for _, want := range []string{"Changed files", "profile.ps1", "+1 -0", "+ fnm env | Invoke-Expression"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("view missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestSummary_NothingChanged(t *testing.T) {
+	s := components.DefaultStyles()
+	sm := NewSummaryModel(s).SetResults([]module.ModuleResult{
+		{ModuleID: "base", Skipped: 3, Total: 3},
+		{ModuleID: "python", Skipped: 2, Total: 2},
+	})
+	out := sm.View()
+	if !strings.Contains(out, "Already up to date") {
+		t.Error("should show 'Already up to date' when nothing completed")
+	}
+	if strings.Contains(out, "python:") {
+		t.Error("per-module lines should be collapsed")
+	}
+}
+
+func TestSummary_HidesModulesWithoutSteps(t *testing.T) {
+	s := components.DefaultStyles()
+	sm := NewSummaryModel(s).SetResults([]module.ModuleResult{
+		{ModuleID: "python", Completed: 2, Total: 2},
+		{ModuleID: "tools"},
+	})
+	sm, _ = sm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+	if out := sm.View(); strings.Contains(out, "tools:") {
+		t.Errorf("module with no steps should not be listed:\n%s", out)
+	}
+}
+
+func TestSummary_RestartReminder(t *testing.T) {
+	s := components.DefaultStyles()
+	results := []module.ModuleResult{{ModuleID: "golang", Completed: 2, Total: 2}}
+	if out := NewSummaryModel(s).SetResults(results).View(); strings.Contains(out, "Restart your shell") {
+		t.Error("restart reminder shown without a persistent change")
+	}
+	out := NewSummaryModel(s).SetResults(results).SetRestartRequired(true).View()
+	if !strings.Contains(out, "Restart your shell") {
+		t.Errorf("restart reminder missing:\n%s", out)
+	}
+}
+
+func TestWizard_RestartCheckAfterRun(t *testing.T) {
+	w := New(testRegistry(), module.NewRunner(nopLogger(), false), false, false).
+		WithRestartCheck(func() bool { return true })
+	updated, _ := w.Update(PickerConfirmMsg{ModuleIDs: []string{"base"}})
+	updated, _ = updated.(WizardModel).Update(AllDoneMsg{Results: []module.ModuleResult{{ModuleID: "base", Completed: 1, Total: 1}}})
+	if !strings.Contains(updated.(WizardModel).View(), "Restart your shell") {
+		t.Error("summary should remind the user to restart their shell")
+	}
+}
+
+func TestWizard_Notice(t *testing.T) {
+	w := New(testRegistry(), module.NewRunner(nopLogger(), false), false, false).
+		WithNotice("Using the Windows system proxy http://proxy:8080 (--detect-proxy).")
+	if !strings.Contains(w.View(), "Using the Windows system proxy") {
+		t.Errorf("picker should show the notice:\n%s", w.View())
+	}
+	updated, _ := w.Update(PickerConfirmMsg{ModuleIDs: []string{"base"}})
+	updated, _ = updated.(WizardModel).Update(AllDoneMsg{Results: []module.ModuleResult{{ModuleID: "base", Completed: 1, Total: 1}}})
+	if !strings.Contains(updated.(WizardModel).View(), "Using the Windows system proxy") {
+		t.Error("summary should show the notice")
+	}
+}
+
+func TestWizard_ConfirmPathDialog(t *testing.T) {
+	for _, tc := range []struct {
+		key  string
+		want bool
+	}{
+		{"y", true},
+		{"esc", false},
+	} {
+		t.Run(tc.key, func(t *testing.T) {
+			confirmer := &PathConfirmer{}
+			var answer bool
+			reg := module.NewRegistry()
+			reg.Register(&module.Module{
+				ID:       "base",
+				Name:     "Base",
+				Category: module.CategoryBase,
+				Steps: []module.Step{{
+					Name: "add-to-path",
+					Run: func(context.Context) error {
+						current := []platform.PathEntry{{Dir: `C:\old`, Source: platform.SourceUser}}
+						answer = confirmer.Confirm(current, `C:\new`)
+						return nil
+					},
+				}},
+			})
+
+			w := New(reg, module.NewRunner(nopLogger(), false), false, false).WithPathConfirmer(confirmer)
+			updated, _ := w.Update(PickerConfirmMsg{ModuleIDs: []string{"base"}})
+			wm := updated.(WizardModel)
+
+			asked := false
+			for wm.Screen() == screenProgress {
+				msg := wm.bridge.NextMsg()()
+				if msg == nil {
+					t.Fatal("bridge closed before the run finished")
+				}
+				updated, _ = wm.Update(msg)
+				wm = updated.(WizardModel)
+				if _, ok := msg.(ConfirmPathMsg); ok {
+					asked = true
+					out := wm.View()
+					if !strings.Contains(out, `C:\new`) || !strings.Contains(out, `C:\old (user)`) {
+						t.Errorf("dialog should show the new directory and the current PATH:\n%s", out)
+					}
+					var key tea.KeyMsg
+					if tc.key == "esc" {
+						key = tea.KeyMsg{Type: tea.KeyEsc}
+					} else {
+						key = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(tc.key)}
+					}
+					updated, _ = wm.Update(key)
+					wm = updated.(WizardModel)
+					if strings.Contains(wm.View(), "Add to PATH?") {
+						t.Error("dialog should close once answered")
+					}
+				}
+			}
+			if !asked {
+				t.Fatal("the dialog was never shown")
+			}
+			if answer != tc.want {
+				t.Errorf("Confirm = %v, want %v", answer, tc.want)
+			}
+		})
+	}
+}
+
+func TestSummary_ToggleDetail(t *testing.T) {
+	s := components.DefaultStyles()
+	sm := NewSummaryModel(s).SetResults([]module.ModuleResult{
+		{ModuleID: "base", Skipped: 3, Total: 3},
+		{ModuleID: "python", Completed: 1, Skipped: 1, Total: 2},
+	})
+
+	out := sm.View()
+	if strings.Contains(out, "base:") {
+		t.Error("fully skipped module should be hidden by default")
+	}
+	if !strings.Contains(out, "python:") {
+		t.Error("module that did work should be listed")
+	}
+
+	sm, _ = sm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+	out = sm.View()
+	if !strings.Contains(out, "base:") {
+		t.Error("detail view should list every module")
+	}
+}
+
+func TestSummary_RetriedSteps(t *testing.T) {
+	s := components.DefaultStyles()
+	sm := NewSummaryModel(s).SetResults([]module.ModuleResult{
+		{
+			ModuleID:  "golang",
+			Completed: 2,
+			Total:     2,
+			Attempts:  map[string]int{"Install Go": 3, "Set GOPATH": 1},
+		},
+	})
+	out := sm.View()
+	if !strings.Contains(out, "Install Go (succeeded after 2 retries)") {
+		t.Errorf("should show retried step, got:\n%s", out)
+	}
+	if strings.Contains(out, "Set GOPATH") {
+		t.Error("steps that ran once should not be listed")
+	}
+}
+
+func TestSummary_SlowestSteps(t *testing.T) {
+	s := components.DefaultStyles()
+	sm := NewSummaryModel(s).SetResults([]module.ModuleResult{
+		{
+			ModuleID:  "golang",
+			Completed: 3,
+			Skipped:   1,
+			Total:     4,
+			Durations: map[string]time.Duration{
+				"Install Go":  42 * time.Second,
+				"Set GOPATH":  1200 * time.Millisecond,
+				"Add GOBIN":   50 * time.Millisecond,
+				"Set GOPROXY": 800 * time.Millisecond,
+			},
+			Outcomes: map[string]module.StepOutcome{"Set GOPATH": module.StepSkipped},
+		},
+	})
+	out := sm.View()
+	if !strings.Contains(out, "slowest: Install Go 42s, Set GOPATH 1.2s (already done), Set GOPROXY 800ms") {
+		t.Errorf("should list the three slowest steps, got:\n%s", out)
+	}
+	if strings.Contains(out, "Add GOBIN") {
+		t.Error("only the three slowest steps should be listed")
+	}
+}
+
+func TestSummary_MissingCommandGuidance(t *testing.T) {
+	s := components.DefaultStyles()
+	sm := NewSummaryModel(s).SetResults([]module.ModuleResult{
+		{
+			ModuleID:   "tools",
+			Total:      1,
+			FailedStep: "Install core tools",
+			Err:        fmt.Errorf("installing git: %w", exec.ErrCommandNotFound),
+		},
+	})
+	out := sm.View()
+	if !strings.Contains(out, "required tool is missing") {
+		t.Error("should show guidance for a missing command")
+	}
+}
+
+func TestSummary_PolicyLockedGuidance(t *testing.T) {
+	s := components.DefaultStyles()
+	sm := NewSummaryModel(s).SetResults([]module.ModuleResult{
+		{
+			ModuleID:   "base",
+			Total:      1,
+			FailedStep: "Set HTTP_PROXY",
+			Err:        platform.PolicyError(fmt.Errorf("setting HTTP_PROXY: %w", fs.ErrPermission)),
+		},
+	})
+	out := sm.View()
+	if !strings.Contains(out, "IT policy may be preventing environment changes") {
+		t.Error("should show guidance for a policy-locked write")
+	}
+}
+
 func TestSummary_RunnerError(t *testing.T) {
 	s := components.DefaultStyles()
 	sm := NewSummaryModel(s).SetError(errors.New("dep cycle"))
@@ -368,6 +928,42 @@ func TestWizard_PickerToProgress(t *testing.T) {
 	}
 }
 
+func TestWizard_PreselectedModules(t *testing.T) {
+	reg := testRegistry()
+	runner := module.NewRunner(nopLogger(), false)
+	w := New(reg, runner, false, false).
+		WithSelection([]string{"python"}, false).
+		WithStyles(components.StylesWithIcons(components.IconsASCII))
+
+	if w.Init() != nil {
+		t.Error("Init should not confirm without auto-confirm")
+	}
+	ids := w.picker.SelectedModuleIDs()
+	if !sliceContains(ids, "python") || !sliceContains(ids, "base") || sliceContains(ids, "golang") {
+		t.Errorf("selected = %v, want base and python (survives WithStyles)", ids)
+	}
+}
+
+func TestWizard_AutoConfirm(t *testing.T) {
+	reg := testRegistry()
+	runner := module.NewRunner(nopLogger(), false)
+	w := New(reg, runner, false, false).WithSelection([]string{"golang"}, true)
+
+	cmd := w.Init()
+	if cmd == nil {
+		t.Fatal("Init should confirm the selection with auto-confirm")
+	}
+	confirm := assertMsgType[PickerConfirmMsg](t, cmd(), "init")
+	if !sliceContains(confirm.ModuleIDs, "golang") || !sliceContains(confirm.ModuleIDs, "base") {
+		t.Errorf("confirmed %v, want base and golang", confirm.ModuleIDs)
+	}
+
+	updated, _ := w.Update(confirm)
+	if updated.(WizardModel).Screen() != screenProgress {
+		t.Errorf("expected progress screen, got %d", updated.(WizardModel).Screen())
+	}
+}
+
 func TestWizard_AllDoneToSummary(t *testing.T) {
 	reg := testRegistry()
 	runner := module.NewRunner(nopLogger(), false)
@@ -411,6 +1007,47 @@ func TestWizard_RunErrorToSummary(t *testing.T) {
 	}
 }
 
+func TestWizard_CertSelection(t *testing.T) {
+	reg := testRegistry()
+	runner := module.NewRunner(nopLogger(), false)
+	choices := []CertChoice{
+		{Cert: &x509.Certificate{Subject: pkix.Name{CommonName: "Corp Root"}, NotAfter: time.Now().Add(time.Hour)}, Selected: true},
+		{Cert: &x509.Certificate{Subject: pkix.Name{CommonName: "Old Root"}, NotAfter: time.Now().Add(-time.Hour)}, Selected: true},
+	}
+	var applied []CertChoice
+	w := New(reg, runner, false, false).WithCertSelection(choices, func(c []CertChoice) { applied = c })
+
+	updated, _ := w.Update(PickerConfirmMsg{ModuleIDs: []string{"base"}})
+	wm := updated.(WizardModel)
+	if wm.Screen() != screenCerts {
+		t.Fatalf("expected cert screen, got %d", wm.Screen())
+	}
+	view := wm.View()
+	if !strings.Contains(view, "Corp Root") || !strings.Contains(view, "expired") {
+		t.Errorf("cert screen should list CN and expiry, got:\n%s", view)
+	}
+
+	// Untick the second cert and confirm.
+	updated, _ = wm.Update(tea.KeyMsg{Type: tea.KeyDown})
+	updated, _ = updated.(WizardModel).Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	updated, cmd := updated.(WizardModel).Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("enter should confirm the selection")
+	}
+	updated, _ = updated.(WizardModel).Update(cmd())
+	wm = updated.(WizardModel)
+
+	if wm.Screen() != screenProgress {
+		t.Errorf("expected progress screen, got %d", wm.Screen())
+	}
+	if len(applied) != 2 || !applied[0].Selected || applied[1].Selected {
+		t.Errorf("applied = %+v, want first selected, second not", applied)
+	}
+	if wm.Selected() == nil || wm.Selected()[0] != "base" {
+		t.Errorf("Selected() = %v, want [base]", wm.Selected())
+	}
+}
+
 // --- Bridge tests ---
 
 func TestBridge_MessageOrder(t *testing.T) {
@@ -452,29 +1089,37 @@ func TestBridge_MessageOrder(t *testing.T) {
 	}
 
 	// Expected order:
-	// 1. ModuleStartMsg
-	// 2. StepStartMsg (check-me)
-	// 3. StepDoneMsg (check-me, skipped)
-	// 4. StepStartMsg (run-me)
-	// 5. StepDoneMsg (run-me)
-	// 6. AllDoneMsg
-
-	if len(msgs) < 6 {
-		t.Fatalf("expected at least 6 messages, got %d: %v", len(msgs), msgTypes(msgs))
+	// 1. TotalStepsMsg
+	// 2. ModuleStartMsg
+	// 3. StepStartMsg (check-me)
+	// 4. StepDoneMsg (check-me, skipped)
+	// 5. StepStartMsg (run-me)
+	// 6. StepDoneMsg (run-me)
+	// 7. AllDoneMsg
+
+	if len(msgs) < 7 {
+		t.Fatalf("expected at least 7 messages, got %d: %v", len(msgs), msgTypes(msgs))
 	}
 
-	assertMsgType[ModuleStartMsg](t, msgs[0], "msg 0")
-	assertMsgType[StepStartMsg](t, msgs[1], "msg 1")
-	done1 := assertMsgType[StepDoneMsg](t, msgs[2], "msg 2")
+	total := assertMsgType[TotalStepsMsg](t, msgs[0], "msg 0")
+	if total.Total != 2 {
+		t.Errorf("TotalStepsMsg.Total = %d, want 2", total.Total)
+	}
+	if want := []string{"test/check-me", "test/run-me"}; !slices.Equal(total.Plan, want) {
+		t.Errorf("TotalStepsMsg.Plan = %v, want %v", total.Plan, want)
+	}
+	assertMsgType[ModuleStartMsg](t, msgs[1], "msg 1")
+	assertMsgType[StepStartMsg](t, msgs[2], "msg 2")
+	done1 := assertMsgType[StepDoneMsg](t, msgs[3], "msg 3")
 	if !done1.Skipped {
 		t.Error("first step should be skipped")
 	}
-	assertMsgType[StepStartMsg](t, msgs[3], "msg 3")
-	done2 := assertMsgType[StepDoneMsg](t, msgs[4], "msg 4")
+	assertMsgType[StepStartMsg](t, msgs[4], "msg 4")
+	done2 := assertMsgType[StepDoneMsg](t, msgs[5], "msg 5")
 	if done2.Skipped {
 		t.Error("second step should not be skipped")
 	}
-	allDone := assertMsgType[AllDoneMsg](t, msgs[5], "msg 5")
+	allDone := assertMsgType[AllDoneMsg](t, msgs[6], "msg 6")
 	if len(allDone.Results) != 1 {
 		t.Errorf("expected 1 result, got %d", len(allDone.Results))
 	}
@@ -509,23 +1154,253 @@ func TestBridge_ErrorMessage(t *testing.T) {
 		cmd = bridge.NextMsg()
 	}
 
-	// Should have: ModuleStartMsg, StepStartMsg, StepErrorMsg, AllDoneMsg
-	if len(msgs) < 4 {
-		t.Fatalf("expected at least 4 messages, got %d: %v", len(msgs), msgTypes(msgs))
+	// Should have: TotalStepsMsg, ModuleStartMsg, StepStartMsg, StepErrorMsg, AllDoneMsg
+	if len(msgs) < 5 {
+		t.Fatalf("expected at least 5 messages, got %d: %v", len(msgs), msgTypes(msgs))
 	}
 
-	assertMsgType[ModuleStartMsg](t, msgs[0], "msg 0")
-	assertMsgType[StepStartMsg](t, msgs[1], "msg 1")
-	errMsg := assertMsgType[StepErrorMsg](t, msgs[2], "msg 2")
+	assertMsgType[TotalStepsMsg](t, msgs[0], "msg 0")
+	assertMsgType[ModuleStartMsg](t, msgs[1], "msg 1")
+	assertMsgType[StepStartMsg](t, msgs[2], "msg 2")
+	errMsg := assertMsgType[StepErrorMsg](t, msgs[3], "msg 3")
 	if errMsg.Err == nil {
 		t.Error("expected error in StepErrorMsg")
 	}
-	allDone := assertMsgType[AllDoneMsg](t, msgs[3], "msg 3")
+	allDone := assertMsgType[AllDoneMsg](t, msgs[4], "msg 4")
 	if len(allDone.Results) != 1 || allDone.Results[0].Err == nil {
 		t.Error("expected failed result in AllDoneMsg")
 	}
 }
 
+func TestBridge_KeepGoing(t *testing.T) {
+	reg := module.NewRegistry()
+	for _, id := range []string{"first", "second"} {
+		reg.Register(&module.Module{
+			ID:       id,
+			Name:     id,
+			Category: module.CategoryBase,
+			Steps: []module.Step{{
+				Name: "will-fail",
+				Run:  func(context.Context) error { return errors.New(id + " broke") },
+			}},
+		})
+	}
+
+	runner := module.NewRunner(nopLogger(), false)
+	runner.SetKeepGoing(true)
+	bridge := NewBridge(runner, reg, []string{"first", "second"})
+
+	var last tea.Msg
+	for cmd := bridge.Start(); cmd != nil; cmd = bridge.NextMsg() {
+		msg := cmd()
+		if msg == nil {
+			break
+		}
+		last = msg
+	}
+
+	allDone := assertMsgType[AllDoneMsg](t, last, "last msg")
+	if len(allDone.Results) != 2 {
+		t.Fatalf("got %d results, want both modules", len(allDone.Results))
+	}
+	for _, r := range allDone.Results {
+		if r.Err == nil {
+			t.Errorf("%s: expected a failure", r.ModuleID)
+		}
+	}
+}
+
+func TestBridge_CancelStopsInFlightStep(t *testing.T) {
+	started := make(chan struct{})
+	stopped := make(chan error, 1)
+
+	reg := module.NewRegistry()
+	reg.Register(&module.Module{
+		ID:       "slow",
+		Name:     "Slow",
+		Category: module.CategoryBase,
+		Steps: []module.Step{
+			{
+				Name: "blocks",
+				Run: func(ctx context.Context) error {
+					close(started)
+					select {
+					case <-ctx.Done():
+						stopped <- ctx.Err()
+						return ctx.Err()
+					case <-time.After(5 * time.Second):
+						stopped <- nil
+						return nil
+					}
+				},
+			},
+		},
+	})
+
+	runner := module.NewRunner(nopLogger(), false)
+	bridge := NewBridge(runner, reg, []string{"slow"})
+
+	// Drain messages so the runner goroutine never blocks on send.
+	cmd := bridge.Start()
+	go func() {
+		for cmd != nil {
+			if cmd() == nil {
+				return
+			}
+			cmd = bridge.NextMsg()
+		}
+	}()
+
+	<-started
+	bridge.Cancel()
+
+	select {
+	case err := <-stopped:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("step saw %v, want context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("step did not observe cancellation")
+	}
+}
+
+func TestBridge_CancelClosesChannel(t *testing.T) {
+	started := make(chan struct{})
+	reg := module.NewRegistry()
+	reg.Register(&module.Module{
+		ID:       "slow",
+		Name:     "Slow",
+		Category: module.CategoryBase,
+		Steps: []module.Step{
+			{
+				Name: "blocks",
+				Run: func(ctx context.Context) error {
+					close(started)
+					<-ctx.Done()
+					return ctx.Err()
+				},
+			},
+			{Name: "never", Run: func(ctx context.Context) error { return nil }},
+		},
+	})
+
+	runner := module.NewRunner(nopLogger(), false)
+	bridge := NewBridge(runner, reg, []string{"slow"})
+	bridge.Start()
+
+	<-started
+	bridge.Cancel()
+
+	waitClosed(t, bridge)
+	if msg := bridge.NextMsg()(); msg != nil {
+		t.Errorf("NextMsg after close = %T, want nil", msg)
+	}
+}
+
+func TestBridge_CancelWithFullChannel(t *testing.T) {
+	// More steps than the channel buffers, and nobody reading: run blocks
+	// in send until Cancel releases it.
+	steps := make([]module.Step, 100)
+	for i := range steps {
+		steps[i] = module.Step{Name: fmt.Sprintf("step %d", i), Run: func(ctx context.Context) error { return nil }}
+	}
+	reg := module.NewRegistry()
+	reg.Register(&module.Module{ID: "many", Name: "Many", Category: module.CategoryBase, Steps: steps})
+
+	runner := module.NewRunner(nopLogger(), false)
+	bridge := NewBridge(runner, reg, []string{"many"})
+	bridge.Start()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(bridge.msgs) < cap(bridge.msgs) {
+		if time.Now().After(deadline) {
+			t.Fatal("channel never filled")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	bridge.Cancel()
+
+	waitClosed(t, bridge)
+}
+
+func TestBridge_ManyFastStepsNoStall(t *testing.T) {
+	// Hundreds of instant steps, many of them skipped, overflow the buffer
+	// many times over while the reader lags; the run must still finish with
+	// every message delivered once, in order.
+	const n = 500
+	steps := make([]module.Step, n)
+	for i := range steps {
+		skip := i%2 == 0
+		steps[i] = module.Step{
+			Name:  fmt.Sprintf("step %d", i),
+			Check: func(context.Context) bool { return skip },
+			Run:   func(context.Context) error { return nil },
+		}
+	}
+	reg := module.NewRegistry()
+	reg.Register(&module.Module{ID: "many", Name: "Many", Category: module.CategoryBase, Steps: steps})
+
+	bridge := NewBridge(module.NewRunner(nopLogger(), false), reg, []string{"many"})
+	done := make(chan []tea.Msg)
+	go func() {
+		var msgs []tea.Msg
+		for cmd := bridge.Start(); cmd != nil; cmd = bridge.NextMsg() {
+			msg := cmd()
+			if msg == nil {
+				break
+			}
+			msgs = append(msgs, msg)
+			if len(msgs)%50 == 0 {
+				time.Sleep(time.Millisecond) // a slow render
+			}
+		}
+		done <- msgs
+	}()
+
+	var msgs []tea.Msg
+	select {
+	case msgs = <-done:
+	case <-time.After(5 * time.Second):
+		bridge.Cancel()
+		t.Fatal("bridge stalled")
+	}
+
+	// TotalStepsMsg, ModuleStartMsg, a start and a done per step, AllDoneMsg.
+	if want := 3 + 2*n; len(msgs) != want {
+		t.Fatalf("got %d messages, want %d", len(msgs), want)
+	}
+	assertMsgType[TotalStepsMsg](t, msgs[0], "msg 0")
+	assertMsgType[ModuleStartMsg](t, msgs[1], "msg 1")
+	for i := 0; i < n; i++ {
+		start := assertMsgType[StepStartMsg](t, msgs[2+2*i], fmt.Sprintf("step %d start", i))
+		doneMsg := assertMsgType[StepDoneMsg](t, msgs[3+2*i], fmt.Sprintf("step %d done", i))
+		if start.Index != i || doneMsg.Index != i || doneMsg.Skipped != (i%2 == 0) {
+			t.Fatalf("step %d: start index %d, done index %d, skipped %v", i, start.Index, doneMsg.Index, doneMsg.Skipped)
+		}
+	}
+	allDone := assertMsgType[AllDoneMsg](t, msgs[len(msgs)-1], "last msg")
+	if r := allDone.Results[0]; r.Completed != n/2 || r.Skipped != n/2 {
+		t.Errorf("result = %d completed, %d skipped, want %d each", r.Completed, r.Skipped, n/2)
+	}
+}
+
+// waitClosed drains b's channel and fails if it isn't closed promptly,
+// i.e. if the run goroutine is stuck.
+func waitClosed(t *testing.T, b *Bridge) {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case _, ok := <-b.msgs:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("bridge channel not closed after Cancel; run goroutine leaked")
+		}
+	}
+}
+
 // --- helpers ---
 
 func sliceContains(s []string, v string) bool {